@@ -40,11 +40,15 @@ import (
 
 	"github.com/dicot-project/dicot-api/pkg/api"
 	"github.com/dicot-project/dicot-api/pkg/api/identity"
+	"github.com/dicot-project/dicot-api/pkg/audit"
 	"github.com/dicot-project/dicot-api/pkg/auth"
 	"github.com/dicot-project/dicot-api/pkg/rest"
 	computev2_1 "github.com/dicot-project/dicot-api/pkg/rest/compute/v2_1"
+	"github.com/dicot-project/dicot-api/pkg/rest/console"
 	identityv3 "github.com/dicot-project/dicot-api/pkg/rest/identity/v3"
 	imagev2 "github.com/dicot-project/dicot-api/pkg/rest/image/v2"
+	"github.com/dicot-project/dicot-api/pkg/rest/metadata"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
 )
 
 func GetClientConfig(kubeconfig string) (*k8srest.Config, error) {
@@ -83,11 +87,32 @@ func GetTokenManager(cl identity.Interface) (auth.TokenManager, error) {
 	}, time.Hour, cl), nil
 }
 
+func GetAuditor(specs []string, k8sClient k8s.Interface, serverID string) (audit.Auditor, error) {
+	sinks := []audit.Sink{}
+	for _, spec := range specs {
+		sink, err := audit.NewSink(spec, k8sClient)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	observer := audit.Resource{
+		TypeURI: audit.TypeURIService,
+		ID:      serverID,
+		Name:    "dicot-api",
+	}
+	return audit.NewAuditor(observer, sinks...), nil
+}
+
 func main() {
 	var debug bool
 	var logRequests bool
 	var kubeconfig string
 	var imagerepo string
+	var imagerepoURL string
+	var auditSinks []string
+	var metadataListen string
 
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
@@ -95,6 +120,9 @@ func main() {
 	pflag.BoolVarP(&debug, "debug", "d", false, "Debug mode")
 	pflag.BoolVarP(&logRequests, "log-requests", "l", false, "Log requests")
 	pflag.StringVar(&imagerepo, "imagerepo", "/srv/images", "Path to image repository storage.")
+	pflag.StringVar(&imagerepoURL, "imagerepo-url", "", "URL at which the image repository storage is served to KubeVirt.")
+	pflag.StringVar(&metadataListen, "metadata-listen", "", "Address on which to serve instance metadata to guests, disabled if empty.")
+	pflag.StringSliceVar(&auditSinks, "audit-sink", []string{}, "Audit event sinks (stdout, file:PATH, webhook:URL, kube:NAMESPACE).")
 
 	pflag.Parse()
 
@@ -107,6 +135,7 @@ func main() {
 	router := gin.New()
 	router.Use(gin.Recovery())
 	router.Use(rest.AcceptJSON())
+	router.Use(middleware.NewRequestIDHandler().Handler())
 	if logRequests {
 		router.Use(gin.Logger())
 	}
@@ -127,12 +156,22 @@ func main() {
 
 	serverID := "e1552b45-f0cb-4d2b-bfb9-ae0877696e39"
 
+	auditor, err := GetAuditor(auditSinks, k8sClient, serverID)
+	if err != nil {
+		log.Fatal("Auditor: %s\n", err)
+	}
+
 	services := &rest.ServiceList{}
-	services.AddService(identityv3.NewService(client, k8sClient, tm, services, ""))
-	services.AddService(computev2_1.NewService(client, k8sClient, tm, serverID, ""))
-	services.AddService(imagev2.NewService(client, tm, imagerepo, serverID, ""))
+	services.AddService(identityv3.NewService(client, k8sClient, tm, auditor, services, ""))
+	services.AddService(computev2_1.NewService(client, k8sClient, tm, auditor, imagerepoURL, serverID, ""))
+	services.AddService(imagev2.NewService(client, tm, auditor, imagerepo, serverID, ""))
 	services.RegisterRoutes(router)
 
+	// The console proxy is not listed in the service catalog
+	proxies := &rest.ServiceList{}
+	proxies.AddService(console.NewService(client, tm, auditor, ""))
+	proxies.RegisterRoutes(router)
+
 	srv := &http.Server{
 		Addr:    ":8089",
 		Handler: router,
@@ -145,6 +184,30 @@ func main() {
 		}
 	}()
 
+	var mdsrv *http.Server
+	if metadataListen != "" {
+		mdrouter := gin.New()
+		mdrouter.Use(gin.Recovery())
+		if logRequests {
+			mdrouter.Use(gin.Logger())
+		}
+
+		mdservices := &rest.ServiceList{}
+		mdservices.AddService(metadata.NewService(client, k8sClient, ""))
+		mdservices.RegisterRoutes(mdrouter)
+
+		mdsrv = &http.Server{
+			Addr:    metadataListen,
+			Handler: mdrouter,
+		}
+
+		go func() {
+			if err := mdsrv.ListenAndServe(); err != nil {
+				log.Fatal("metadata listen: %s\n", err)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server with
 	// a timeout of 5 seconds.
 	quit := make(chan os.Signal)
@@ -159,5 +222,10 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server failed to shutdown:", err)
 	}
+	if mdsrv != nil {
+		if err := mdsrv.Shutdown(ctx); err != nil {
+			log.Fatal("Metadata server failed to shutdown:", err)
+		}
+	}
 	glog.V(1).Info("Server exiting")
 }
@@ -0,0 +1,134 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/dicot-project/dicot-api/pkg/backup"
+)
+
+func runExport(args []string) error {
+	var kubeconfig string
+	var output string
+
+	flags := newFlagSet("export", &kubeconfig)
+	flags.StringVar(&output, "output", "", "Path to write the export document to, instead of stdout")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	client, k8sClient, err := GetClients(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	doc, err := backup.Export(client, k8sClient)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	// The document contains password hashes
+	return ioutil.WriteFile(output, data, 0600)
+}
+
+func runImport(args []string) error {
+	var kubeconfig string
+	var input string
+	var format string
+
+	flags := newFlagSet("import", &kubeconfig)
+	flags.StringVar(&input, "input", "", "Path to the document to import")
+	flags.StringVar(&format, "format", "dicot", "Format of the document (dicot, keystone)")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if input == "" {
+		return fmt.Errorf("An input file must be given")
+	}
+
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		return err
+	}
+
+	var doc *backup.Document
+	switch format {
+	case "dicot":
+		doc = &backup.Document{}
+		err = json.Unmarshal(data, doc)
+	case "keystone":
+		doc, err = backup.ParseKeystone(data)
+	default:
+		return fmt.Errorf("Unknown document format '%s'", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	client, k8sClient, err := GetClients(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	ids, err := backup.Import(client, k8sClient, doc)
+	if err != nil {
+		return err
+	}
+
+	// IDs only change where an object of the same name
+	// already existed before the import
+	oldIDs := []string{}
+	for oldID, newID := range ids {
+		if oldID != newID {
+			oldIDs = append(oldIDs, oldID)
+		}
+	}
+	sort.Strings(oldIDs)
+
+	fmt.Printf("Imported %d objects\n", len(ids))
+	if len(oldIDs) != 0 {
+		fmt.Println("Objects merged with existing objects (old ID -> new ID):")
+		for _, oldID := range oldIDs {
+			fmt.Printf("  %s -> %s\n", oldID, ids[oldID])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,469 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"syscall"
+
+	"golang.org/x/crypto/ssh/terminal"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api"
+	"github.com/dicot-project/dicot-api/pkg/api/identity"
+	"github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+	"github.com/dicot-project/dicot-api/pkg/crypto"
+	"github.com/dicot-project/dicot-api/pkg/rest"
+)
+
+type bootstrapConfig struct {
+	DomainName    string
+	ProjectName   string
+	AdminName     string
+	AdminPassword string
+	Region        string
+	PublicURL     string
+}
+
+// The default roles and the roles each directly implies
+var bootstrapRoles = []struct {
+	Name    string
+	Implies []string
+}{
+	{"admin", []string{"member"}},
+	{"member", []string{"reader"}},
+	{"reader", []string{}},
+}
+
+func runBootstrap(args []string) error {
+	var kubeconfig string
+	var pwfile string
+	cfg := bootstrapConfig{}
+
+	flags := newFlagSet("bootstrap", &kubeconfig)
+	flags.StringVar(&cfg.DomainName, "domain", "default", "Name of the default domain")
+	flags.StringVar(&cfg.ProjectName, "project", "default", "Name of the default project")
+	flags.StringVar(&cfg.AdminName, "admin-user", "admin", "Name of the admin user")
+	flags.StringVar(&pwfile, "admin-password-file", "", "Path to file containing the admin password")
+	flags.StringVar(&cfg.Region, "region", identity.DefaultRegion, "Name of the region in the service catalog")
+	flags.StringVar(&cfg.PublicURL, "public-url", "", "Base URL of the API in the service catalog")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if pwfile != "" {
+		pw, err := ioutil.ReadFile(pwfile)
+		if err != nil {
+			return fmt.Errorf("Unable to read password file '%s': %s", pwfile, err)
+		}
+		cfg.AdminPassword = strings.TrimSuffix(string(pw), "\n")
+	}
+
+	client, k8sClient, err := GetClients(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	return bootstrap(client, k8sClient, &cfg)
+}
+
+func bootstrap(client api.Interface, k8sClient k8s.Interface, cfg *bootstrapConfig) error {
+	err := checkCRDs(client)
+	if err != nil {
+		return err
+	}
+
+	err = ensureNamespace(k8sClient, v1.NamespaceSystem)
+	if err != nil {
+		return err
+	}
+
+	domain, err := ensureDomain(client, k8sClient, cfg.DomainName)
+	if err != nil {
+		return err
+	}
+
+	_, err = ensureProject(client, k8sClient, domain, cfg.ProjectName)
+	if err != nil {
+		return err
+	}
+
+	err = ensureUser(client, k8sClient, domain, cfg)
+	if err != nil {
+		return err
+	}
+
+	err = ensureRoles(client)
+	if err != nil {
+		return err
+	}
+
+	return ensureCatalog(k8sClient, cfg)
+}
+
+// checkCRDs lists each resource type, which fails with a not
+// found error if the CRD for it has not been registered
+func checkCRDs(client api.Interface) error {
+	checks := []struct {
+		Name string
+		List func() error
+	}{
+		{"projects.identity.dicot.io", func() error {
+			_, err := client.Identity().Projects(v1.NamespaceSystem).List()
+			return err
+		}},
+		{"users.identity.dicot.io", func() error {
+			_, err := client.Identity().Users(v1.NamespaceSystem).List()
+			return err
+		}},
+		{"groups.identity.dicot.io", func() error {
+			_, err := client.Identity().Groups(v1.NamespaceSystem).List()
+			return err
+		}},
+		{"roles.identity.dicot.io", func() error {
+			_, err := client.Identity().Roles(v1.NamespaceSystem).List()
+			return err
+		}},
+		{"revokedtokens.identity.dicot.io", func() error {
+			_, err := client.Identity().RevokedTokens(v1.NamespaceSystem).List()
+			return err
+		}},
+		{"flavors.compute.dicot.io", func() error {
+			_, err := client.Compute().Flavors(v1.NamespaceSystem).List()
+			return err
+		}},
+		{"keypairs.compute.dicot.io", func() error {
+			_, err := client.Compute().Keypairs(v1.NamespaceSystem).List()
+			return err
+		}},
+		{"images.image.dicot.io", func() error {
+			_, err := client.Image().Images(v1.NamespaceSystem).List()
+			return err
+		}},
+	}
+
+	missing := []string{}
+	for _, check := range checks {
+		err := check.List()
+		if err != nil {
+			if errors.IsNotFound(err) {
+				missing = append(missing, check.Name)
+			} else {
+				return err
+			}
+		}
+	}
+
+	if len(missing) != 0 {
+		return fmt.Errorf("Missing custom resource definitions: %s",
+			strings.Join(missing, ", "))
+	}
+	fmt.Println("Custom resource definitions are installed")
+	return nil
+}
+
+func ensureNamespace(k8sClient k8s.Interface, name string) error {
+	_, err := k8sClient.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return err
+	}
+
+	ns := &k8sv1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	_, err = k8sClient.CoreV1().Namespaces().Create(ns)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created namespace %s\n", name)
+	return nil
+}
+
+func ensureDomain(client api.Interface, k8sClient k8s.Interface, name string) (*v1.Project, error) {
+	clnt := client.Identity().Projects(v1.NamespaceSystem)
+
+	domain, err := clnt.Get(name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+
+		domain = &v1.Project{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: v1.ProjectSpec{
+				Name:        name,
+				Enabled:     true,
+				Description: "Default domain",
+				Namespace:   identity.FormatDomainNamespace(name),
+			},
+		}
+		domain, err = clnt.Create(domain)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Created domain %s\n", name)
+	}
+
+	err = ensureNamespace(k8sClient, domain.Spec.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain, nil
+}
+
+func ensureProject(client api.Interface, k8sClient k8s.Interface, domain *v1.Project, name string) (*v1.Project, error) {
+	clnt := client.Identity().Projects(domain.Spec.Namespace)
+
+	project, err := clnt.Get(name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+
+		project = &v1.Project{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: v1.ProjectSpec{
+				Name:        name,
+				Enabled:     true,
+				Description: "Default project",
+				Parent:      domain.GetID(),
+				Domain:      domain.GetID(),
+				Namespace:   identity.FormatProjectNamespace(domain.ObjectMeta.Name, name),
+			},
+		}
+		project, err = clnt.Create(project)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Created project %s\n", name)
+	}
+
+	err = ensureNamespace(k8sClient, project.Spec.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func readPassword() (string, error) {
+	for {
+		fmt.Print("Enter admin password: ")
+		pw1, err := terminal.ReadPassword(int(syscall.Stdin))
+		if err != nil {
+			return "", err
+		}
+		fmt.Println("")
+
+		fmt.Print("Repeat admin password: ")
+		pw2, err := terminal.ReadPassword(int(syscall.Stdin))
+		if err != nil {
+			return "", err
+		}
+		fmt.Println("")
+
+		if bytes.Compare(pw1, pw2) != 0 {
+			fmt.Println("Passwords do not match")
+			continue
+		}
+
+		return string(pw1), nil
+	}
+}
+
+func ensureUser(client api.Interface, k8sClient k8s.Interface, domain *v1.Project, cfg *bootstrapConfig) error {
+	clnt := client.Identity().Users(domain.Spec.Namespace)
+	secretClnt := k8sClient.CoreV1().Secrets(domain.Spec.Namespace)
+
+	name := identity.SanitizeName(cfg.AdminName)
+	user, err := clnt.Get(name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+
+		user = &v1.User{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: v1.UserSpec{
+				Name:     cfg.AdminName,
+				Enabled:  true,
+				DomainID: domain.GetID(),
+				Password: v1.UserPassword{
+					SecretRef: "user-password-" + name,
+				},
+			},
+		}
+		user, err = clnt.Create(user)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created user %s\n", cfg.AdminName)
+	}
+
+	// An existing password is never replaced, so that
+	// re-running bootstrap does not lock out the admin
+	_, err = secretClnt.Get(user.Spec.Password.SecretRef, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return err
+	}
+
+	if cfg.AdminPassword == "" {
+		cfg.AdminPassword, err = readPassword()
+		if err != nil {
+			return err
+		}
+	}
+
+	pwHash, err := crypto.HashPassword(cfg.AdminPassword)
+	if err != nil {
+		return err
+	}
+
+	secret := &k8sv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: user.Spec.Password.SecretRef,
+		},
+		Type: "identity.dicot.io/user-password",
+		Data: map[string][]byte{
+			"password": []byte(pwHash),
+		},
+	}
+	_, err = secretClnt.Create(secret)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created password for user %s\n", cfg.AdminName)
+	return nil
+}
+
+func ensureRoles(client api.Interface) error {
+	clnt := client.Identity().Roles(v1.NamespaceSystem)
+
+	roles := make(map[string]*v1.Role)
+	for _, info := range bootstrapRoles {
+		role, err := clnt.Get(info.Name)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return err
+			}
+
+			role = &v1.Role{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: info.Name,
+				},
+				Spec: v1.RoleSpec{
+					Name:    info.Name,
+					Implies: []string{},
+				},
+			}
+			role, err = clnt.Create(role)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Created role %s\n", info.Name)
+		}
+		roles[info.Name] = role
+	}
+
+	for _, info := range bootstrapRoles {
+		role := roles[info.Name]
+		implies := role.Spec.Implies
+		for _, name := range info.Implies {
+			implies = rest.StringListAdd(implies, roles[name].GetID())
+		}
+		if len(implies) == len(role.Spec.Implies) {
+			continue
+		}
+
+		role.Spec.Implies = implies
+		_, err := clnt.Update(role)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Updated implied roles for %s\n", info.Name)
+	}
+
+	return nil
+}
+
+func ensureCatalog(k8sClient k8s.Interface, cfg *bootstrapConfig) error {
+	clnt := k8sClient.CoreV1().ConfigMaps(v1.NamespaceSystem)
+
+	data := map[string]string{
+		identity.CatalogKeyRegion:    cfg.Region,
+		identity.CatalogKeyPublicURL: cfg.PublicURL,
+	}
+
+	catalog, err := clnt.Get(identity.CatalogConfigMap, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+
+		catalog = &k8sv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: identity.CatalogConfigMap,
+			},
+			Data: data,
+		}
+		_, err = clnt.Create(catalog)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Created service catalog")
+		return nil
+	}
+
+	if catalog.Data[identity.CatalogKeyRegion] == cfg.Region &&
+		catalog.Data[identity.CatalogKeyPublicURL] == cfg.PublicURL {
+		return nil
+	}
+
+	catalog.Data = data
+	_, err = clnt.Update(catalog)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Updated service catalog")
+	return nil
+}
@@ -0,0 +1,129 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+	k8s "k8s.io/client-go/kubernetes"
+	k8srest "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/dicot-project/dicot-api/pkg/api"
+)
+
+type command struct {
+	Name        string
+	Description string
+	Run         func(args []string) error
+}
+
+var commands = []command{
+	command{
+		Name:        "bootstrap",
+		Description: "Initialise the system namespace, default domain, project, admin user, roles and service catalog",
+		Run:         runBootstrap,
+	},
+	command{
+		Name:        "export",
+		Description: "Export all identity objects to a JSON document",
+		Run:         runExport,
+	},
+	command{
+		Name:        "import",
+		Description: "Import identity objects from a Dicot export or Keystone dump",
+		Run:         runImport,
+	},
+	command{
+		Name:        "migrate-keypairs",
+		Description: "Give the keypairs stored in a project by older versions to a user",
+		Run:         runMigrateKeypairs,
+	},
+}
+
+func GetClientConfig(kubeconfig string) (*k8srest.Config, error) {
+	if kubeconfig != "" {
+		return clientcmd.BuildConfigFromFlags("", kubeconfig)
+	}
+	return k8srest.InClusterConfig()
+}
+
+func GetClients(kubeconfig string) (api.Interface, k8s.Interface, error) {
+	config, err := GetClientConfig(kubeconfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := api.NewClientset(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	k8sClient, err := k8s.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return client, k8sClient, nil
+}
+
+// newFlagSet creates the flags for a sub-command, including
+// those which are common to all commands
+func newFlagSet(name string, kubeconfig *string) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(name, pflag.ExitOnError)
+	flags.AddGoFlagSet(flag.CommandLine)
+	flags.StringVar(kubeconfig, "kubeconfig", "", "Path to a kube config. Only required if out-of-cluster.")
+	return flags
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s COMMAND [OPTIONS]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.Name, cmd.Description)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+
+	for _, cmd := range commands {
+		if cmd.Name != os.Args[1] {
+			continue
+		}
+
+		err := cmd.Run(os.Args[2:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", cmd.Name, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command '%s'\n\n", os.Args[1])
+	usage()
+	os.Exit(1)
+}
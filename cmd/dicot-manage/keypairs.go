@@ -0,0 +1,117 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api"
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	computev1 "github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+)
+
+func runMigrateKeypairs(args []string) error {
+	var kubeconfig string
+	var projectID string
+	var userID string
+
+	flags := newFlagSet("migrate-keypairs", &kubeconfig)
+	flags.StringVar(&projectID, "project-id", "", "ID of the project whose keypairs are migrated")
+	flags.StringVar(&userID, "user-id", "", "ID of the user to give the keypairs to")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if projectID == "" || userID == "" {
+		return fmt.Errorf("A project ID and user ID must be given")
+	}
+
+	client, _, err := GetClients(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	return migrateKeypairs(client, projectID, userID)
+}
+
+// migrateKeypairs moves the keypairs which older versions of
+// Dicot stored in the namespace of a project to the user chosen
+// by the admin. The user ID recorded in those keypairs was given
+// by the client which created them, so cannot be trusted to
+// identify the owner.
+func migrateKeypairs(client api.Interface, projectID, userID string) error {
+	project, err := client.Identity().Projects(k8sv1.NamespaceAll).GetByID(projectID)
+	if err != nil {
+		return err
+	}
+
+	user, err := client.Identity().Users(k8sv1.NamespaceAll).GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	legacy := client.Compute().Keypairs(project.Spec.Namespace)
+	keypairs, err := legacy.List()
+	if err != nil {
+		return err
+	}
+
+	clnt := client.Compute().Keypairs(user.ObjectMeta.Namespace)
+	for _, keypair := range keypairs.Items {
+		// Keypairs created since they belonged to users
+		// record their name in the spec
+		if keypair.Spec.Name != "" {
+			continue
+		}
+		name := keypair.ObjectMeta.Name
+
+		migrated := &computev1.Keypair{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: compute.KeypairObjectName(user.GetID(), name),
+			},
+			Spec: keypair.Spec,
+		}
+		migrated.Spec.Name = name
+		migrated.Spec.UserID = user.GetID()
+
+		_, err = clnt.Create(migrated)
+		if err != nil {
+			if !errors.IsAlreadyExists(err) {
+				return err
+			}
+			fmt.Printf("Skipped keypair '%s', which user already has\n", name)
+			continue
+		}
+
+		err = legacy.Delete(keypair.ObjectMeta.Name, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Migrated keypair '%s'\n", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package cdi
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/dicot-project/dicot-api/pkg/api/cdi/v1"
+)
+
+func NewDataVolumeClient(cl rest.Interface, namespace string) DataVolumeInterface {
+	return &dataVolumes{cl: cl, ns: namespace}
+}
+
+type dataVolumes struct {
+	cl rest.Interface
+	ns string
+}
+
+type DataVolumeGetter interface {
+	DataVolumes(namespace string) DataVolumeInterface
+}
+
+type DataVolumeInterface interface {
+	Create(obj *v1.DataVolume) (*v1.DataVolume, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	Get(name string) (*v1.DataVolume, error)
+	List() (*v1.DataVolumeList, error)
+}
+
+func (dvc *dataVolumes) Create(obj *v1.DataVolume) (*v1.DataVolume, error) {
+	var result v1.DataVolume
+	err := dvc.cl.Post().
+		Namespace(dvc.ns).Resource("datavolumes").
+		Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (dvc *dataVolumes) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return dvc.cl.Delete().
+		Namespace(dvc.ns).Resource("datavolumes").
+		Name(name).Body(options).Do().
+		Error()
+}
+
+func (dvc *dataVolumes) Get(name string) (*v1.DataVolume, error) {
+	var result v1.DataVolume
+	err := dvc.cl.Get().
+		Namespace(dvc.ns).Resource("datavolumes").
+		Name(name).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (dvc *dataVolumes) List() (*v1.DataVolumeList, error) {
+	var result v1.DataVolumeList
+	err := dvc.cl.Get().
+		Namespace(dvc.ns).Resource("datavolumes").
+		Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
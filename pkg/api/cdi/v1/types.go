@@ -0,0 +1,139 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/apimachinery/announced"
+	"k8s.io/apimachinery/pkg/apimachinery/registered"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+)
+
+// The subset of the KubeVirt Containerized Data Importer API
+// used to populate VM disks
+
+const GroupName = "cdi.kubevirt.io"
+
+var GroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha1"}
+
+func Resource(resource string) schema.GroupResource {
+	return GroupVersion.WithResource(resource).GroupResource()
+}
+
+var (
+	groupFactoryRegistry = make(announced.APIGroupFactoryRegistry)
+	registry             = registered.NewOrDie(GroupVersion.String())
+)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(GroupVersion,
+		&DataVolume{},
+		&DataVolumeList{},
+	)
+	return nil
+}
+
+func init() {
+	SchemeBuilder := runtime.NewSchemeBuilder(addKnownTypes)
+	if err := announced.NewGroupMetaFactory(
+		&announced.GroupMetaFactoryArgs{
+			GroupName:              GroupName,
+			VersionPreferenceOrder: []string{GroupVersion.Version},
+			ImportPrefix:           "dicot.io/dicot/pkg/api/cdi/v1",
+		},
+		announced.VersionToSchemeFunc{
+			GroupVersion.Version: SchemeBuilder.AddToScheme,
+		},
+	).Announce(groupFactoryRegistry).RegisterAndEnable(registry, scheme.Scheme); err != nil {
+		panic(err)
+	}
+}
+
+type DataVolume struct {
+	metav1.TypeMeta `json:",inline"`
+	ObjectMeta      metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec            DataVolumeSpec    `json:"spec"`
+	Status          DataVolumeStatus  `json:"status,omitempty"`
+}
+
+type DataVolumeList struct {
+	metav1.TypeMeta `json:",inline"`
+	ListMeta        metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DataVolume    `json:"items"`
+}
+
+type DataVolumeSpec struct {
+	Source DataVolumeSource                 `json:"source"`
+	PVC    *k8sv1.PersistentVolumeClaimSpec `json:"pvc"`
+}
+
+type DataVolumeSource struct {
+	HTTP  *DataVolumeSourceHTTP `json:"http,omitempty"`
+	PVC   *DataVolumeSourcePVC  `json:"pvc,omitempty"`
+	Blank *DataVolumeBlankImage `json:"blank,omitempty"`
+}
+
+type DataVolumeSourceHTTP struct {
+	URL string `json:"url"`
+}
+
+type DataVolumeSourcePVC struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+type DataVolumeBlankImage struct {
+}
+
+type DataVolumePhase string
+
+const (
+	DataVolumePhaseUnset       DataVolumePhase = ""
+	DataVolumePending          DataVolumePhase = "Pending"
+	DataVolumePVCBound         DataVolumePhase = "PVCBound"
+	DataVolumeImportInProgress DataVolumePhase = "ImportInProgress"
+	DataVolumeCloneInProgress  DataVolumePhase = "CloneInProgress"
+	DataVolumeSucceeded        DataVolumePhase = "Succeeded"
+	DataVolumeFailed           DataVolumePhase = "Failed"
+)
+
+type DataVolumeStatus struct {
+	Phase    DataVolumePhase `json:"phase,omitempty"`
+	Progress string          `json:"progress,omitempty"`
+}
+
+func (v *DataVolume) GetObjectKind() schema.ObjectKind {
+	return &v.TypeMeta
+}
+
+func (v *DataVolume) GetObjectMeta() metav1.Object {
+	return &v.ObjectMeta
+}
+
+func (vl *DataVolumeList) GetObjectKind() schema.ObjectKind {
+	return &vl.TypeMeta
+}
+
+func (vl *DataVolumeList) GetListMeta() metav1.List {
+	return &vl.ListMeta
+}
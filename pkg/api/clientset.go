@@ -20,23 +20,36 @@
 package api
 
 import (
+	"github.com/dicot-project/dicot-api/pkg/api/cdi"
 	"github.com/dicot-project/dicot-api/pkg/api/compute"
 	"github.com/dicot-project/dicot-api/pkg/api/identity"
 	"github.com/dicot-project/dicot-api/pkg/api/image"
+	"github.com/dicot-project/dicot-api/pkg/api/kubevirt"
+	"github.com/dicot-project/dicot-api/pkg/api/multus"
 
 	"k8s.io/client-go/rest"
 )
 
 type Interface interface {
+	CDI() cdi.Interface
 	Compute() compute.Interface
 	Identity() identity.Interface
 	Image() image.Interface
+	Kubevirt() kubevirt.Interface
+	Multus() multus.Interface
 }
 
 type clientset struct {
+	cdi      cdi.Interface
 	compute  compute.Interface
 	identity identity.Interface
 	image    image.Interface
+	kubevirt kubevirt.Interface
+	multus   multus.Interface
+}
+
+func (c *clientset) CDI() cdi.Interface {
+	return c.cdi
 }
 
 func (c *clientset) Compute() compute.Interface {
@@ -51,8 +64,20 @@ func (c *clientset) Image() image.Interface {
 	return c.image
 }
 
+func (c *clientset) Kubevirt() kubevirt.Interface {
+	return c.kubevirt
+}
+
+func (c *clientset) Multus() multus.Interface {
+	return c.multus
+}
+
 func NewClientset(c *rest.Config) (Interface, error) {
 	cCopy := *c
+	cdiClient, err := cdi.New(&cCopy)
+	if err != nil {
+		return nil, err
+	}
 	computeClient, err := compute.New(&cCopy)
 	if err != nil {
 		return nil, err
@@ -65,9 +90,20 @@ func NewClientset(c *rest.Config) (Interface, error) {
 	if err != nil {
 		return nil, err
 	}
+	kubevirtClient, err := kubevirt.New(&cCopy)
+	if err != nil {
+		return nil, err
+	}
+	multusClient, err := multus.New(&cCopy)
+	if err != nil {
+		return nil, err
+	}
 	return &clientset{
+		cdiClient,
 		computeClient,
 		identityClient,
 		imageClient,
+		kubevirtClient,
+		multusClient,
 	}, nil
 }
@@ -0,0 +1,72 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	identityv1 "github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+)
+
+// FlavorAccessible reports whether servers in the project may
+// use the flavor
+func FlavorAccessible(flv *v1.Flavor, proj *identityv1.Project) bool {
+	if flv.Spec.Public {
+		return true
+	}
+
+	return FlavorHasAccess(flv, proj.GetID())
+}
+
+// FlavorHasAccess reports whether the project ID is on the
+// access list of the flavor
+func FlavorHasAccess(flv *v1.Flavor, projID string) bool {
+	for _, id := range flv.Spec.Projects {
+		if id == projID {
+			return true
+		}
+	}
+	return false
+}
+
+// FlavorAddAccess adds the project ID to the access list of the
+// flavor, returning false if it was already present
+func FlavorAddAccess(flv *v1.Flavor, projID string) bool {
+	if FlavorHasAccess(flv, projID) {
+		return false
+	}
+	flv.Spec.Projects = append(flv.Spec.Projects, projID)
+	return true
+}
+
+// FlavorRemoveAccess removes the project ID from the access
+// list of the flavor, returning false if it was not present
+func FlavorRemoveAccess(flv *v1.Flavor, projID string) bool {
+	projects := []string{}
+	for _, id := range flv.Spec.Projects {
+		if id != projID {
+			projects = append(projects, id)
+		}
+	}
+	if len(projects) == len(flv.Spec.Projects) {
+		return false
+	}
+	flv.Spec.Projects = projects
+	return true
+}
@@ -0,0 +1,69 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"testing"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	identityv1 "github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+)
+
+func TestFlavorAccess(t *testing.T) {
+	proj := &identityv1.Project{
+		Spec: identityv1.ProjectSpec{
+			ID: "8f0d5b10-5a4b-4d7c-9d3f-2f1e1c0b6a11",
+		},
+	}
+	flv := &v1.Flavor{
+		Spec: v1.FlavorSpec{
+			Public: false,
+		},
+	}
+
+	if FlavorAccessible(flv, proj) {
+		t.Errorf("Unexpected access to private flavor")
+	}
+
+	if !FlavorAddAccess(flv, proj.Spec.ID) {
+		t.Errorf("Expected access to be added")
+	}
+	if FlavorAddAccess(flv, proj.Spec.ID) {
+		t.Errorf("Unexpected duplicate access added")
+	}
+	if !FlavorAccessible(flv, proj) {
+		t.Errorf("Expected access to private flavor")
+	}
+
+	if !FlavorRemoveAccess(flv, proj.Spec.ID) {
+		t.Errorf("Expected access to be removed")
+	}
+	if FlavorRemoveAccess(flv, proj.Spec.ID) {
+		t.Errorf("Unexpected removal of missing access")
+	}
+	if FlavorAccessible(flv, proj) {
+		t.Errorf("Unexpected access to private flavor")
+	}
+
+	flv.Spec.Public = true
+	if !FlavorAccessible(flv, proj) {
+		t.Errorf("Expected access to public flavor")
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+)
+
+func NewAggregateClient(cl rest.Interface, namespace string) AggregateInterface {
+	return &aggregates{cl: cl, ns: namespace}
+}
+
+type aggregates struct {
+	cl rest.Interface
+	ns string
+}
+
+type AggregateGetter interface {
+	Aggregates(namespace string) AggregateInterface
+}
+
+type AggregateInterface interface {
+	Create(obj *v1.Aggregate) (*v1.Aggregate, error)
+	Update(obj *v1.Aggregate) (*v1.Aggregate, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	Get(name string) (*v1.Aggregate, error)
+	GetByID(id uint64) (*v1.Aggregate, error)
+	Exists(name string) (bool, error)
+	List() (*v1.AggregateList, error)
+	NewListWatch() *cache.ListWatch
+}
+
+func (ac *aggregates) Create(obj *v1.Aggregate) (*v1.Aggregate, error) {
+	var result v1.Aggregate
+	err := ac.cl.Post().
+		Namespace(ac.ns).Resource("aggregates").
+		Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (ac *aggregates) Update(obj *v1.Aggregate) (*v1.Aggregate, error) {
+	var result v1.Aggregate
+	name := obj.GetObjectMeta().GetName()
+	err := ac.cl.Put().
+		Namespace(ac.ns).Resource("aggregates").
+		Name(name).Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (ac *aggregates) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return ac.cl.Delete().
+		Namespace(ac.ns).Resource("aggregates").
+		Name(name).Body(options).Do().
+		Error()
+}
+
+func (ac *aggregates) Get(name string) (*v1.Aggregate, error) {
+	var result v1.Aggregate
+	err := ac.cl.Get().
+		Namespace(ac.ns).Resource("aggregates").
+		Name(name).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (ac *aggregates) GetByID(id uint64) (*v1.Aggregate, error) {
+	list, err := ac.List()
+	if err != nil {
+		return nil, err
+	}
+	for idx := range list.Items {
+		if list.Items[idx].Spec.ID == id {
+			return &list.Items[idx], nil
+		}
+	}
+
+	return nil, errors.NewNotFound(v1.Resource("aggregate"), fmt.Sprintf("%d", id))
+}
+
+func (ac *aggregates) Exists(name string) (bool, error) {
+	_, err := ac.Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (ac *aggregates) List() (*v1.AggregateList, error) {
+	var result v1.AggregateList
+	err := ac.cl.Get().
+		Namespace(ac.ns).Resource("aggregates").
+		Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (ac *aggregates) NewListWatch() *cache.ListWatch {
+	return cache.NewListWatchFromClient(ac.cl, "aggregates", ac.ns, fields.Everything())
+}
@@ -32,6 +32,10 @@ type Interface interface {
 	RESTClient() rest.Interface
 	FlavorGetter
 	KeypairGetter
+	ServerGroupGetter
+	AggregateGetter
+	InstanceActionGetter
+	ServerUsageGetter
 }
 
 type compute struct {
@@ -64,3 +68,19 @@ func (c *compute) Flavors(namespace string) FlavorInterface {
 func (c *compute) Keypairs(namespace string) KeypairInterface {
 	return NewKeypairClient(c.cl, namespace)
 }
+
+func (c *compute) ServerGroups(namespace string) ServerGroupInterface {
+	return NewServerGroupClient(c.cl, namespace)
+}
+
+func (c *compute) Aggregates(namespace string) AggregateInterface {
+	return NewAggregateClient(c.cl, namespace)
+}
+
+func (c *compute) InstanceActions(namespace string) InstanceActionInterface {
+	return NewInstanceActionClient(c.cl, namespace)
+}
+
+func (c *compute) ServerUsages(namespace string) ServerUsageInterface {
+	return NewServerUsageClient(c.cl, namespace)
+}
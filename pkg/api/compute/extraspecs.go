@@ -0,0 +1,325 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+// Flavor extra specs understood when creating servers. Each is
+// translated into the VirtualMachineInstance spec by
+// ApplyFlavorExtraSpecs
+const (
+	EXTRA_SPEC_CPU_POLICY      = "hw:cpu_policy"
+	EXTRA_SPEC_CPU_SOCKETS     = "hw:cpu_sockets"
+	EXTRA_SPEC_CPU_CORES       = "hw:cpu_cores"
+	EXTRA_SPEC_CPU_THREADS     = "hw:cpu_threads"
+	EXTRA_SPEC_MEM_PAGE_SIZE   = "hw:mem_page_size"
+	EXTRA_SPEC_NUMA_NODES      = "hw:numa_nodes"
+	EXTRA_SPEC_WATCHDOG_ACTION = "hw:watchdog_action"
+	EXTRA_SPEC_RNG_ALLOWED     = "hw_rng:allowed"
+
+	EXTRA_SPEC_PREFIX_RESOURCES = "resources:"
+	EXTRA_SPEC_PREFIX_TRAIT     = "trait:"
+)
+
+// Custom resource classes requested with resources:CUSTOM_FOO=N
+// are requested from Kubernetes as the extended resource
+// resources.dicot.io/custom-foo, which device plugins on the
+// nodes must advertise
+const ResourceClassPrefix = "resources.dicot.io/"
+
+// Nodes providing a trait required with trait:FOO=required are
+// labelled traits.dicot.io/FOO=true
+const TraitLabelPrefix = "traits.dicot.io/"
+
+// Resource classes which can only be set by the flavor itself
+var standardResourceClasses = map[string]bool{
+	"VCPU":      true,
+	"PCPU":      true,
+	"MEMORY_MB": true,
+	"DISK_GB":   true,
+}
+
+// ResourceClassName returns the extended resource name of a
+// custom resource class
+func ResourceClassName(class string) k8sv1.ResourceName {
+	return k8sv1.ResourceName(ResourceClassPrefix +
+		strings.Replace(strings.ToLower(class), "_", "-", -1))
+}
+
+// TraitLabel returns the name of the node label indicating a
+// trait is provided
+func TraitLabel(trait string) string {
+	return TraitLabelPrefix + trait
+}
+
+func parseExtraSpecUInt(key, val string) (uint32, error) {
+	num, err := strconv.ParseUint(val, 10, 32)
+	if err != nil || num == 0 {
+		return 0, fmt.Errorf("Extra spec '%s' must be a positive integer, not '%s'", key, val)
+	}
+	return uint32(num), nil
+}
+
+// parsePageSize converts a Nova page size, in KiB unless it has
+// units, to a KubeVirt hugepage size. Small pages give "".
+func parsePageSize(val string) (string, error) {
+	switch strings.ToLower(val) {
+	case "small", "any":
+		return "", nil
+	case "large":
+		return "2Mi", nil
+	}
+
+	units := []struct {
+		Suffix string
+		KiB    uint64
+	}{
+		{"kib", 1}, {"kb", 1}, {"k", 1},
+		{"mib", 1024}, {"mb", 1024}, {"m", 1024},
+		{"gib", 1024 * 1024}, {"gb", 1024 * 1024}, {"g", 1024 * 1024},
+	}
+	num := strings.ToLower(val)
+	scale := uint64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(num, unit.Suffix) {
+			num = strings.TrimSuffix(num, unit.Suffix)
+			scale = unit.KiB
+			break
+		}
+	}
+
+	size, err := strconv.ParseUint(num, 10, 64)
+	if err == nil {
+		switch size * scale {
+		case 4:
+			return "", nil
+		case 2048:
+			return "2Mi", nil
+		case 1024 * 1024:
+			return "1Gi", nil
+		}
+	}
+	return "", fmt.Errorf("Extra spec '%s' page size '%s' is not supported", EXTRA_SPEC_MEM_PAGE_SIZE, val)
+}
+
+// cpuTopology returns the sockets, cores and threads giving the
+// flavor's vCPU count. The cores, or failing that the sockets,
+// not set in the extra specs take up the remainder.
+func cpuTopology(vcpus, sockets, cores, threads uint32) (uint32, uint32, uint32, error) {
+	if threads == 0 {
+		threads = 1
+	}
+	if cores == 0 {
+		if sockets == 0 {
+			sockets = 1
+		}
+		cores = vcpus / (sockets * threads)
+	} else if sockets == 0 {
+		sockets = vcpus / (cores * threads)
+	}
+
+	if sockets == 0 || cores == 0 || sockets*cores*threads != vcpus {
+		return 0, 0, 0, fmt.Errorf("Extra specs CPU topology cannot give %d vCPUs", vcpus)
+	}
+	return sockets, cores, threads, nil
+}
+
+// ApplyFlavorExtraSpecs updates the spec of a server's instance
+// to honour the extra specs of its flavor. The spec's CPU and
+// memory are expected to have been set from the flavor already.
+func ApplyFlavorExtraSpecs(flv *v1.Flavor, spec *kubevirtv1.VirtualMachineInstanceSpec) error {
+	keys := []string{}
+	for key := range flv.Spec.ExtraSpecs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if spec.Domain.CPU == nil {
+		spec.Domain.CPU = &kubevirtv1.CPU{}
+	}
+
+	var sockets, cores, threads uint32
+	var forbidden []k8sv1.NodeSelectorRequirement
+	for _, key := range keys {
+		val := flv.Spec.ExtraSpecs[key]
+		var err error
+
+		switch {
+		case key == EXTRA_SPEC_CPU_POLICY:
+			switch val {
+			case "dedicated":
+				spec.Domain.CPU.DedicatedCPUPlacement = true
+			case "shared":
+			default:
+				return fmt.Errorf("Extra spec '%s' must be 'dedicated' or 'shared', not '%s'", key, val)
+			}
+
+		case key == EXTRA_SPEC_CPU_SOCKETS:
+			sockets, err = parseExtraSpecUInt(key, val)
+		case key == EXTRA_SPEC_CPU_CORES:
+			cores, err = parseExtraSpecUInt(key, val)
+		case key == EXTRA_SPEC_CPU_THREADS:
+			threads, err = parseExtraSpecUInt(key, val)
+
+		case key == EXTRA_SPEC_MEM_PAGE_SIZE:
+			var size string
+			size, err = parsePageSize(val)
+			if size != "" {
+				if spec.Domain.Memory == nil {
+					spec.Domain.Memory = &kubevirtv1.Memory{}
+				}
+				spec.Domain.Memory.Hugepages = &kubevirtv1.Hugepages{
+					PageSize: size,
+				}
+			}
+
+		case key == EXTRA_SPEC_NUMA_NODES:
+			var nodes uint32
+			nodes, err = parseExtraSpecUInt(key, val)
+			// XXX KubeVirt cannot give guests more than one
+			// NUMA node
+			if err == nil && nodes != 1 {
+				err = fmt.Errorf("Extra spec '%s' greater than 1 is not supported", key)
+			}
+
+		case key == EXTRA_SPEC_WATCHDOG_ACTION:
+			var action kubevirtv1.WatchdogAction
+			switch val {
+			case "disabled":
+			case "reset":
+				action = kubevirtv1.WatchdogActionReset
+			case "poweroff":
+				action = kubevirtv1.WatchdogActionPoweroff
+			default:
+				return fmt.Errorf("Extra spec '%s' must be 'disabled', 'reset' or 'poweroff', not '%s'", key, val)
+			}
+			if action != "" {
+				spec.Domain.Devices.Watchdog = &kubevirtv1.Watchdog{
+					Name: "watchdog",
+					I6300ESB: &kubevirtv1.I6300ESBWatchdog{
+						Action: action,
+					},
+				}
+			}
+
+		case key == EXTRA_SPEC_RNG_ALLOWED:
+			var allowed bool
+			allowed, err = strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("Extra spec '%s' must be a boolean, not '%s'", key, val)
+			}
+			if allowed {
+				spec.Domain.Devices.Rng = &kubevirtv1.Rng{}
+			}
+
+		case strings.HasPrefix(key, EXTRA_SPEC_PREFIX_RESOURCES):
+			class := strings.TrimPrefix(key, EXTRA_SPEC_PREFIX_RESOURCES)
+			if standardResourceClasses[class] {
+				return fmt.Errorf("Extra spec '%s' cannot override the flavor resources", key)
+			}
+			if !strings.HasPrefix(class, "CUSTOM_") || len(class) == len("CUSTOM_") {
+				return fmt.Errorf("Extra spec '%s' is not a custom resource class", key)
+			}
+			var amount uint64
+			amount, err = strconv.ParseUint(val, 10, 32)
+			if err != nil {
+				return fmt.Errorf("Extra spec '%s' must be a non-negative integer, not '%s'", key, val)
+			}
+			if amount == 0 {
+				continue
+			}
+			// Extended resources must have equal requests and
+			// limits
+			name := ResourceClassName(class)
+			qty := *resource.NewQuantity(int64(amount), resource.DecimalSI)
+			if spec.Domain.Resources.Requests == nil {
+				spec.Domain.Resources.Requests = k8sv1.ResourceList{}
+			}
+			if spec.Domain.Resources.Limits == nil {
+				spec.Domain.Resources.Limits = k8sv1.ResourceList{}
+			}
+			spec.Domain.Resources.Requests[name] = qty
+			spec.Domain.Resources.Limits[name] = qty
+
+		case strings.HasPrefix(key, EXTRA_SPEC_PREFIX_TRAIT):
+			trait := strings.TrimPrefix(key, EXTRA_SPEC_PREFIX_TRAIT)
+			if trait == "" {
+				return fmt.Errorf("Extra spec '%s' has no trait name", key)
+			}
+			switch val {
+			case "required":
+				if spec.NodeSelector == nil {
+					spec.NodeSelector = map[string]string{}
+				}
+				spec.NodeSelector[TraitLabel(trait)] = "true"
+			case "forbidden":
+				forbidden = append(forbidden, k8sv1.NodeSelectorRequirement{
+					Key:      TraitLabel(trait),
+					Operator: k8sv1.NodeSelectorOpNotIn,
+					Values:   []string{"true"},
+				})
+			default:
+				return fmt.Errorf("Extra spec '%s' must be 'required' or 'forbidden', not '%s'", key, val)
+			}
+
+		default:
+			return fmt.Errorf("Extra spec '%s' is not supported", key)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if sockets != 0 || cores != 0 || threads != 0 {
+		var err error
+		sockets, cores, threads, err = cpuTopology(uint32(flv.Spec.Resources.CPUCount), sockets, cores, threads)
+		if err != nil {
+			return err
+		}
+		spec.Domain.CPU.Sockets = sockets
+		spec.Domain.CPU.Cores = cores
+		spec.Domain.CPU.Threads = threads
+	}
+
+	if len(forbidden) != 0 {
+		AddNodeSelectorRequirements(spec, forbidden...)
+	}
+
+	return nil
+}
+
+// ValidateFlavorExtraSpecs checks that all the extra specs of
+// the flavor can be honoured when creating servers
+func ValidateFlavorExtraSpecs(flv *v1.Flavor) error {
+	spec := &kubevirtv1.VirtualMachineInstanceSpec{}
+	return ApplyFlavorExtraSpecs(flv, spec)
+}
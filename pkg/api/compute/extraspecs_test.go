@@ -0,0 +1,128 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"testing"
+
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+func newTestFlavor(vcpus uint64, specs map[string]string) *v1.Flavor {
+	return &v1.Flavor{
+		Spec: v1.FlavorSpec{
+			Resources: v1.FlavorResources{
+				CPUCount: vcpus,
+			},
+			ExtraSpecs: specs,
+		},
+	}
+}
+
+func TestApplyFlavorExtraSpecs(t *testing.T) {
+	flv := newTestFlavor(8, map[string]string{
+		"hw:cpu_policy":         "dedicated",
+		"hw:cpu_sockets":        "2",
+		"hw:cpu_threads":        "2",
+		"hw:mem_page_size":      "1GB",
+		"hw:numa_nodes":         "1",
+		"hw:watchdog_action":    "reset",
+		"hw_rng:allowed":        "True",
+		"resources:CUSTOM_GPU":  "1",
+		"trait:HW_CPU_X86_AVX2": "required",
+		"trait:CUSTOM_SLOW":     "forbidden",
+	})
+
+	spec := &kubevirtv1.VirtualMachineInstanceSpec{}
+	err := ApplyFlavorExtraSpecs(flv, spec)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	cpu := spec.Domain.CPU
+	if !cpu.DedicatedCPUPlacement {
+		t.Errorf("Expected dedicated CPU placement")
+	}
+	if cpu.Sockets != 2 || cpu.Cores != 2 || cpu.Threads != 2 {
+		t.Errorf("Expected topology 2/2/2 got %d/%d/%d", cpu.Sockets, cpu.Cores, cpu.Threads)
+	}
+	if spec.Domain.Memory == nil || spec.Domain.Memory.Hugepages == nil ||
+		spec.Domain.Memory.Hugepages.PageSize != "1Gi" {
+		t.Errorf("Expected 1Gi hugepages")
+	}
+	if spec.Domain.Devices.Watchdog == nil ||
+		spec.Domain.Devices.Watchdog.I6300ESB.Action != kubevirtv1.WatchdogActionReset {
+		t.Errorf("Expected watchdog with reset action")
+	}
+	if spec.Domain.Devices.Rng == nil {
+		t.Errorf("Expected RNG device")
+	}
+	gpu := spec.Domain.Resources.Limits[k8sv1.ResourceName("resources.dicot.io/custom-gpu")]
+	if gpu.Value() != 1 {
+		t.Errorf("Expected custom GPU resource limit 1 got %d", gpu.Value())
+	}
+	if spec.NodeSelector["traits.dicot.io/HW_CPU_X86_AVX2"] != "true" {
+		t.Errorf("Expected required trait node selector")
+	}
+	terms := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 || len(terms[0].MatchExpressions) != 1 ||
+		terms[0].MatchExpressions[0].Key != "traits.dicot.io/CUSTOM_SLOW" ||
+		terms[0].MatchExpressions[0].Operator != k8sv1.NodeSelectorOpNotIn {
+		t.Errorf("Expected forbidden trait node affinity got %v", terms)
+	}
+}
+
+func TestValidateFlavorExtraSpecs(t *testing.T) {
+	tests := []struct {
+		VCPUs uint64
+		Specs map[string]string
+		Valid bool
+	}{
+		{1, map[string]string{}, true},
+		{4, map[string]string{"hw:cpu_cores": "2"}, true},
+		{4, map[string]string{"hw:cpu_cores": "3"}, false},
+		{4, map[string]string{"hw:cpu_sockets": "0"}, false},
+		{1, map[string]string{"hw:cpu_policy": "isolated"}, false},
+		{1, map[string]string{"hw:mem_page_size": "large"}, true},
+		{1, map[string]string{"hw:mem_page_size": "2048"}, true},
+		{1, map[string]string{"hw:mem_page_size": "small"}, true},
+		{1, map[string]string{"hw:mem_page_size": "64KB"}, false},
+		{1, map[string]string{"hw:numa_nodes": "2"}, false},
+		{1, map[string]string{"hw:watchdog_action": "pause"}, false},
+		{1, map[string]string{"hw_rng:allowed": "yes please"}, false},
+		{1, map[string]string{"resources:VCPU": "2"}, false},
+		{1, map[string]string{"resources:CUSTOM_FPGA": "0"}, true},
+		{1, map[string]string{"resources:FPGA": "1"}, false},
+		{1, map[string]string{"trait:HW_NIC_SRIOV": "preferred"}, false},
+		{1, map[string]string{"quota:cpu_shares": "1024"}, false},
+	}
+
+	for _, test := range tests {
+		err := ValidateFlavorExtraSpecs(newTestFlavor(test.VCPUs, test.Specs))
+		if test.Valid && err != nil {
+			t.Errorf("Unexpected error for %v: %s", test.Specs, err)
+		} else if !test.Valid && err == nil {
+			t.Errorf("Expected error for %v", test.Specs)
+		}
+	}
+}
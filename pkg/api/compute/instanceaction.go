@@ -0,0 +1,252 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"time"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+)
+
+// The names of instance actions, following those used by Nova
+const (
+	INSTANCE_ACTION_CREATE           = "create"
+	INSTANCE_ACTION_DELETE           = "delete"
+	INSTANCE_ACTION_START            = "start"
+	INSTANCE_ACTION_STOP             = "stop"
+	INSTANCE_ACTION_REBOOT           = "reboot"
+	INSTANCE_ACTION_PAUSE            = "pause"
+	INSTANCE_ACTION_UNPAUSE          = "unpause"
+	INSTANCE_ACTION_SUSPEND          = "suspend"
+	INSTANCE_ACTION_RESUME           = "resume"
+	INSTANCE_ACTION_SHELVE           = "shelve"
+	INSTANCE_ACTION_UNSHELVE         = "unshelve"
+	INSTANCE_ACTION_MIGRATE          = "migrate"
+	INSTANCE_ACTION_LIVE_MIGRATION   = "live-migration"
+	INSTANCE_ACTION_CREATE_IMAGE     = "createImage"
+	INSTANCE_ACTION_RESIZE           = "resize"
+	INSTANCE_ACTION_CONFIRM_RESIZE   = "confirmResize"
+	INSTANCE_ACTION_REVERT_RESIZE    = "revertResize"
+	INSTANCE_ACTION_REBUILD          = "rebuild"
+	INSTANCE_ACTION_CHANGE_PASSWORD  = "changePassword"
+	INSTANCE_ACTION_ATTACH_INTERFACE = "attach_interface"
+	INSTANCE_ACTION_DETACH_INTERFACE = "detach_interface"
+
+	INSTANCE_ACTION_RESULT_SUCCESS = "Success"
+	INSTANCE_ACTION_RESULT_ERROR   = "Error"
+)
+
+// The instance action recorded for each server action
+var serverInstanceActions = map[string]string{
+	SERVER_ACTION_START:            INSTANCE_ACTION_START,
+	SERVER_ACTION_STOP:             INSTANCE_ACTION_STOP,
+	SERVER_ACTION_REBOOT:           INSTANCE_ACTION_REBOOT,
+	SERVER_ACTION_PAUSE:            INSTANCE_ACTION_PAUSE,
+	SERVER_ACTION_UNPAUSE:          INSTANCE_ACTION_UNPAUSE,
+	SERVER_ACTION_SUSPEND:          INSTANCE_ACTION_SUSPEND,
+	SERVER_ACTION_RESUME:           INSTANCE_ACTION_RESUME,
+	SERVER_ACTION_SHELVE:           INSTANCE_ACTION_SHELVE,
+	SERVER_ACTION_UNSHELVE:         INSTANCE_ACTION_UNSHELVE,
+	SERVER_ACTION_MIGRATE:          INSTANCE_ACTION_MIGRATE,
+	SERVER_ACTION_LIVE_MIGRATE:     INSTANCE_ACTION_LIVE_MIGRATION,
+	SERVER_ACTION_CREATE_IMAGE:     INSTANCE_ACTION_CREATE_IMAGE,
+	SERVER_ACTION_RESIZE:           INSTANCE_ACTION_RESIZE,
+	SERVER_ACTION_CONFIRM_RESIZE:   INSTANCE_ACTION_CONFIRM_RESIZE,
+	SERVER_ACTION_REVERT_RESIZE:    INSTANCE_ACTION_REVERT_RESIZE,
+	SERVER_ACTION_REBUILD:          INSTANCE_ACTION_REBUILD,
+	SERVER_ACTION_CHANGE_PASSWORD:  INSTANCE_ACTION_CHANGE_PASSWORD,
+	SERVER_ACTION_ATTACH_INTERFACE: INSTANCE_ACTION_ATTACH_INTERFACE,
+	SERVER_ACTION_DETACH_INTERFACE: INSTANCE_ACTION_DETACH_INTERFACE,
+}
+
+// The event recorded for the step performing each instance
+// action, named after the equivalent Nova compute manager method
+var instanceActionEvents = map[string]string{
+	INSTANCE_ACTION_CREATE:           "compute__do_build_and_run_instance",
+	INSTANCE_ACTION_DELETE:           "compute_terminate_instance",
+	INSTANCE_ACTION_START:            "compute_start_instance",
+	INSTANCE_ACTION_STOP:             "compute_stop_instance",
+	INSTANCE_ACTION_REBOOT:           "compute_reboot_instance",
+	INSTANCE_ACTION_PAUSE:            "compute_pause_instance",
+	INSTANCE_ACTION_UNPAUSE:          "compute_unpause_instance",
+	INSTANCE_ACTION_SUSPEND:          "compute_suspend_instance",
+	INSTANCE_ACTION_RESUME:           "compute_resume_instance",
+	INSTANCE_ACTION_SHELVE:           "compute_shelve_offload_instance",
+	INSTANCE_ACTION_UNSHELVE:         "compute_unshelve_instance",
+	INSTANCE_ACTION_MIGRATE:          "compute_live_migration",
+	INSTANCE_ACTION_LIVE_MIGRATION:   "compute_live_migration",
+	INSTANCE_ACTION_CREATE_IMAGE:     "compute_snapshot_instance",
+	INSTANCE_ACTION_RESIZE:           "compute_resize_instance",
+	INSTANCE_ACTION_CONFIRM_RESIZE:   "compute_confirm_resize",
+	INSTANCE_ACTION_REVERT_RESIZE:    "compute_revert_resize",
+	INSTANCE_ACTION_REBUILD:          "compute_rebuild_instance",
+	INSTANCE_ACTION_CHANGE_PASSWORD:  "compute_set_admin_password",
+	INSTANCE_ACTION_ATTACH_INTERFACE: "compute_attach_interface",
+	INSTANCE_ACTION_DETACH_INTERFACE: "compute_detach_interface",
+}
+
+// ServerInstanceAction returns the name of the instance action
+// recorded for a server action
+func ServerInstanceAction(action string) string {
+	if name, ok := serverInstanceActions[action]; ok {
+		return name
+	}
+	return action
+}
+
+// InstanceActionEvent returns the name of the event recorded for
+// an instance action
+func InstanceActionEvent(action string) string {
+	if event, ok := instanceActionEvents[action]; ok {
+		return event
+	}
+	return "compute_" + action
+}
+
+func InstanceActionName(serverID, requestID string) string {
+	return serverID + "-" + requestID
+}
+
+// NewInstanceAction creates the record of an action performed on
+// a server by a request, which is yet to have any events. It is
+// not owned by the server, so that the actions can still be
+// listed after the server is deleted.
+func NewInstanceAction(serverID, action, requestID, userID, projectID string, start time.Time) *v1.InstanceAction {
+	return &v1.InstanceAction{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: InstanceActionName(serverID, requestID),
+			Labels: map[string]string{
+				LabelServerID: serverID,
+			},
+		},
+		Spec: v1.InstanceActionSpec{
+			Action:    action,
+			ServerID:  serverID,
+			RequestID: requestID,
+			UserID:    userID,
+			ProjectID: projectID,
+			StartTime: start.UTC().Format(time.RFC3339),
+			UpdatedAt: start.UTC().Format(time.RFC3339),
+			Events:    []v1.InstanceActionEvent{},
+		},
+	}
+}
+
+// AddInstanceActionEvent records a step of an instance action,
+// which failed if err is not nil. The failure of any step marks
+// the whole action as failed.
+func AddInstanceActionEvent(act *v1.InstanceAction, event string, start, finish time.Time, err error) {
+	ev := v1.InstanceActionEvent{
+		Event:      event,
+		StartTime:  start.UTC().Format(time.RFC3339),
+		FinishTime: finish.UTC().Format(time.RFC3339),
+		Result:     INSTANCE_ACTION_RESULT_SUCCESS,
+	}
+	if err != nil {
+		ev.Result = INSTANCE_ACTION_RESULT_ERROR
+		ev.Traceback = err.Error()
+		act.Spec.Message = INSTANCE_ACTION_RESULT_ERROR
+	}
+	act.Spec.Events = append(act.Spec.Events, ev)
+	act.Spec.UpdatedAt = finish.UTC().Format(time.RFC3339)
+}
+
+func NewInstanceActionClient(cl rest.Interface, namespace string) InstanceActionInterface {
+	return &instanceactions{cl: cl, ns: namespace}
+}
+
+type instanceactions struct {
+	cl rest.Interface
+	ns string
+}
+
+type InstanceActionGetter interface {
+	InstanceActions(namespace string) InstanceActionInterface
+}
+
+type InstanceActionInterface interface {
+	Create(obj *v1.InstanceAction) (*v1.InstanceAction, error)
+	Update(obj *v1.InstanceAction) (*v1.InstanceAction, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	Get(name string) (*v1.InstanceAction, error)
+	List() (*v1.InstanceActionList, error)
+	NewListWatch() *cache.ListWatch
+}
+
+func (iac *instanceactions) Create(obj *v1.InstanceAction) (*v1.InstanceAction, error) {
+	var result v1.InstanceAction
+	err := iac.cl.Post().
+		Namespace(iac.ns).Resource("instanceactions").
+		Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (iac *instanceactions) Update(obj *v1.InstanceAction) (*v1.InstanceAction, error) {
+	var result v1.InstanceAction
+	name := obj.GetObjectMeta().GetName()
+	err := iac.cl.Put().
+		Namespace(iac.ns).Resource("instanceactions").
+		Name(name).Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (iac *instanceactions) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return iac.cl.Delete().
+		Namespace(iac.ns).Resource("instanceactions").
+		Name(name).Body(options).Do().
+		Error()
+}
+
+func (iac *instanceactions) Get(name string) (*v1.InstanceAction, error) {
+	var result v1.InstanceAction
+	err := iac.cl.Get().
+		Namespace(iac.ns).Resource("instanceactions").
+		Name(name).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (iac *instanceactions) List() (*v1.InstanceActionList, error) {
+	var result v1.InstanceActionList
+	err := iac.cl.Get().
+		Namespace(iac.ns).Resource("instanceactions").
+		Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (iac *instanceactions) NewListWatch() *cache.ListWatch {
+	return cache.NewListWatchFromClient(iac.cl, "instanceactions", iac.ns, fields.Everything())
+}
@@ -0,0 +1,88 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestServerInstanceAction(t *testing.T) {
+	tests := map[string]string{
+		SERVER_ACTION_START:        INSTANCE_ACTION_START,
+		SERVER_ACTION_REBOOT:       INSTANCE_ACTION_REBOOT,
+		SERVER_ACTION_LIVE_MIGRATE: INSTANCE_ACTION_LIVE_MIGRATION,
+		"os-unknown":               "os-unknown",
+	}
+
+	for action, expect := range tests {
+		if got := ServerInstanceAction(action); got != expect {
+			t.Errorf("Expected instance action %s for %s got %s", expect, action, got)
+		}
+	}
+}
+
+func TestInstanceAction(t *testing.T) {
+	id := "b1d5ec84-6ab3-4a2b-9f8c-2a0e6b5a5c1e"
+	start := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	act := NewInstanceAction(id, INSTANCE_ACTION_STOP, "req-1", "user1", "proj1", start)
+	if act.ObjectMeta.Name != id+"-req-1" {
+		t.Errorf("Unexpected name %s", act.ObjectMeta.Name)
+	}
+	if act.ObjectMeta.Labels[LabelServerID] != id {
+		t.Errorf("Expected server ID label got %v", act.ObjectMeta.Labels)
+	}
+	if act.Spec.ServerID != id {
+		t.Errorf("Expected server ID %s got %s", id, act.Spec.ServerID)
+	}
+	if len(act.ObjectMeta.OwnerReferences) != 0 {
+		t.Errorf("Unexpected owner references %v", act.ObjectMeta.OwnerReferences)
+	}
+	if act.Spec.StartTime != "2017-10-01T12:00:00Z" {
+		t.Errorf("Unexpected start time %s", act.Spec.StartTime)
+	}
+
+	finish := start.Add(time.Second)
+	AddInstanceActionEvent(act, InstanceActionEvent(INSTANCE_ACTION_STOP), start, finish, nil)
+	if act.Spec.Message != "" {
+		t.Errorf("Unexpected message %s", act.Spec.Message)
+	}
+	AddInstanceActionEvent(act, "compute_other", start, finish, fmt.Errorf("Failed"))
+	if act.Spec.Message != INSTANCE_ACTION_RESULT_ERROR {
+		t.Errorf("Expected error message got '%s'", act.Spec.Message)
+	}
+	if act.Spec.UpdatedAt != "2017-10-01T12:00:01Z" {
+		t.Errorf("Unexpected update time %s", act.Spec.UpdatedAt)
+	}
+
+	if len(act.Spec.Events) != 2 {
+		t.Fatalf("Expected 2 events got %d", len(act.Spec.Events))
+	}
+	ev := act.Spec.Events[0]
+	if ev.Event != "compute_stop_instance" || ev.Result != INSTANCE_ACTION_RESULT_SUCCESS || ev.Traceback != "" {
+		t.Errorf("Unexpected event %v", ev)
+	}
+	ev = act.Spec.Events[1]
+	if ev.Result != INSTANCE_ACTION_RESULT_ERROR || ev.Traceback != "Failed" {
+		t.Errorf("Unexpected event %v", ev)
+	}
+}
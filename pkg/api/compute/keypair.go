@@ -20,6 +20,9 @@
 package compute
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+
 	"k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -29,6 +32,36 @@ import (
 	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
 )
 
+const (
+	KEYPAIR_NAME_MAX_LENGTH = 255
+
+	KEYPAIR_TYPE_SSH  = "ssh"
+	KEYPAIR_TYPE_X509 = "x509"
+)
+
+// KeypairObjectName gives the name of the object holding a
+// keypair. Keypair names may contain characters which are not
+// valid in object names, and are only unique for each user.
+func KeypairObjectName(userID, name string) string {
+	sum := sha256.Sum256([]byte(userID + "/" + name))
+	return "keypair-" + hex.EncodeToString(sum[:])
+}
+
+// IsValidKeypairName applies Nova's restrictions on the length
+// of keypair names and the characters they may contain
+func IsValidKeypairName(name string) bool {
+	if len(name) == 0 || len(name) > KEYPAIR_NAME_MAX_LENGTH {
+		return false
+	}
+	for _, c := range name {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(c >= '0' && c <= '9') || c == '_' || c == '-' || c == ' ') {
+			return false
+		}
+	}
+	return true
+}
+
 func NewKeypairClient(cl rest.Interface, namespace string) KeypairInterface {
 	return &keypairs{cl: cl, ns: namespace}
 }
@@ -0,0 +1,59 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestKeypairName(t *testing.T) {
+	tests := map[string]bool{
+		"mykey":                  true,
+		"My Key_2-a":             true,
+		"":                       false,
+		"key/1":                  false,
+		"key.pub":                false,
+		"clé":                    false,
+		strings.Repeat("a", 255): true,
+		strings.Repeat("a", 256): false,
+	}
+
+	for name, valid := range tests {
+		if IsValidKeypairName(name) != valid {
+			t.Errorf("Expected valid %t for keypair name '%s'", valid, name)
+		}
+	}
+}
+
+func TestKeypairObjectName(t *testing.T) {
+	name := KeypairObjectName("a1b2c3", "My Key")
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+		t.Errorf("Invalid object name %s: %v", name, errs)
+	}
+	if name == KeypairObjectName("d4e5f6", "My Key") {
+		t.Errorf("Expected object names to differ between users")
+	}
+	if name != KeypairObjectName("a1b2c3", "My Key") {
+		t.Errorf("Expected object name to be stable")
+	}
+}
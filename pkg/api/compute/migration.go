@@ -0,0 +1,155 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+// Server migrations are KubeVirt VirtualMachineInstanceMigrations
+// in the project namespace, labelled with the server ID. The
+// details that KubeVirt forgets once the instance migrates again
+// are recorded in annotations.
+const (
+	AnnotationMigrationType   = "compute.dicot.io/migration-type"
+	AnnotationMigrationSource = "compute.dicot.io/migration-source"
+	AnnotationMigrationDest   = "compute.dicot.io/migration-dest"
+)
+
+const (
+	MIGRATION_TYPE_LIVE = "live-migration"
+	MIGRATION_TYPE_COLD = "migration"
+
+	MIGRATION_STATUS_QUEUED    = "queued"
+	MIGRATION_STATUS_PREPARING = "preparing"
+	MIGRATION_STATUS_RUNNING   = "running"
+	MIGRATION_STATUS_COMPLETED = "completed"
+	MIGRATION_STATUS_FAILED    = "failed"
+	MIGRATION_STATUS_CANCELLED = "cancelled"
+)
+
+// MigrationID gives the integer Nova identifies the migration
+// by, which is taken from the random part of its UID so that
+// migrations started together cannot clash. It is limited to 52
+// bits to be exact in clients parsing JSON numbers as doubles.
+func MigrationID(mig *v1.VirtualMachineInstanceMigration) uint64 {
+	uid := strings.Replace(string(mig.ObjectMeta.UID), "-", "", -1)
+	if len(uid) < 13 {
+		return 0
+	}
+	id, err := strconv.ParseUint(uid[len(uid)-13:], 16, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// MigrationStatus maps the phase of a migration to the Nova
+// migration status
+func MigrationStatus(mig *v1.VirtualMachineInstanceMigration) string {
+	switch mig.Status.Phase {
+	case v1.MigrationSucceeded:
+		return MIGRATION_STATUS_COMPLETED
+	case v1.MigrationFailed:
+		return MIGRATION_STATUS_FAILED
+	}
+
+	// Deleting an unfinished migration aborts it
+	if mig.ObjectMeta.DeletionTimestamp != nil {
+		return MIGRATION_STATUS_CANCELLED
+	}
+
+	switch mig.Status.Phase {
+	case v1.MigrationScheduling, v1.MigrationScheduled,
+		v1.MigrationPreparingTarget, v1.MigrationTargetReady:
+		return MIGRATION_STATUS_PREPARING
+	case v1.MigrationRunning:
+		return MIGRATION_STATUS_RUNNING
+	}
+	return MIGRATION_STATUS_QUEUED
+}
+
+// MigrationInProgress reports whether the migration has yet to
+// finish
+func MigrationInProgress(mig *v1.VirtualMachineInstanceMigration) bool {
+	switch MigrationStatus(mig) {
+	case MIGRATION_STATUS_QUEUED, MIGRATION_STATUS_PREPARING, MIGRATION_STATUS_RUNNING:
+		return true
+	}
+	return false
+}
+
+// InstanceHost returns the host the instance is restricted to
+// running on, if any
+func InstanceHost(spec *v1.VirtualMachineInstanceSpec) string {
+	if host, ok := spec.NodeSelector[LabelHostname]; ok {
+		return host
+	}
+
+	if spec.Affinity == nil || spec.Affinity.NodeAffinity == nil ||
+		spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+	for _, term := range spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, req := range term.MatchExpressions {
+			if req.Key == LabelHostname && req.Operator == k8sv1.NodeSelectorOpIn &&
+				len(req.Values) == 1 {
+				return req.Values[0]
+			}
+		}
+	}
+	return ""
+}
+
+// NewMigration creates a migration of the running instance of
+// the server. KubeVirt does not allow the placement of a running
+// instance to change, so a target host only restricts the nodes
+// this migration can choose.
+func NewMigration(vm *v1.VirtualMachine, vmi *v1.VirtualMachineInstance, migType, host string) *v1.VirtualMachineInstanceMigration {
+	mig := &v1.VirtualMachineInstanceMigration{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: vm.ObjectMeta.Name + "-",
+			Namespace:    vm.ObjectMeta.Namespace,
+			Labels: map[string]string{
+				LabelServerID: vm.ObjectMeta.Name,
+			},
+			Annotations: map[string]string{
+				AnnotationMigrationType:   migType,
+				AnnotationMigrationSource: vmi.Status.NodeName,
+				AnnotationMigrationDest:   host,
+			},
+		},
+		Spec: v1.VirtualMachineInstanceMigrationSpec{
+			VMIName: vmi.ObjectMeta.Name,
+		},
+	}
+	if host != "" {
+		mig.Spec.AddedNodeSelector = map[string]string{
+			LabelHostname: host,
+		}
+	}
+	return mig
+}
@@ -0,0 +1,174 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+func TestMigrationStatus(t *testing.T) {
+	tests := []struct {
+		Phase    v1.VirtualMachineInstanceMigrationPhase
+		Deleting bool
+		Status   string
+	}{
+		{v1.MigrationPhaseUnset, false, MIGRATION_STATUS_QUEUED},
+		{v1.MigrationPending, false, MIGRATION_STATUS_QUEUED},
+		{v1.MigrationScheduling, false, MIGRATION_STATUS_PREPARING},
+		{v1.MigrationTargetReady, false, MIGRATION_STATUS_PREPARING},
+		{v1.MigrationRunning, false, MIGRATION_STATUS_RUNNING},
+		{v1.MigrationRunning, true, MIGRATION_STATUS_CANCELLED},
+		{v1.MigrationSucceeded, false, MIGRATION_STATUS_COMPLETED},
+		{v1.MigrationSucceeded, true, MIGRATION_STATUS_COMPLETED},
+		{v1.MigrationFailed, false, MIGRATION_STATUS_FAILED},
+	}
+
+	for _, test := range tests {
+		mig := &v1.VirtualMachineInstanceMigration{
+			Status: v1.VirtualMachineInstanceMigrationStatus{
+				Phase: test.Phase,
+			},
+		}
+		if test.Deleting {
+			now := metav1.Now()
+			mig.ObjectMeta.DeletionTimestamp = &now
+		}
+		if status := MigrationStatus(mig); status != test.Status {
+			t.Errorf("Expected status %s for phase '%s' got %s", test.Status, test.Phase, status)
+		}
+	}
+}
+
+func TestInstanceHost(t *testing.T) {
+	spec := &v1.VirtualMachineInstanceSpec{}
+	if host := InstanceHost(spec); host != "" {
+		t.Errorf("Unexpected host %s", host)
+	}
+
+	SetAvailabilityZone(spec, DefaultAvailabilityZone)
+	if host := InstanceHost(spec); host != "" {
+		t.Errorf("Unexpected host %s for zone", host)
+	}
+
+	AddNodeSelectorRequirements(spec, k8sv1.NodeSelectorRequirement{
+		Key:      LabelHostname,
+		Operator: k8sv1.NodeSelectorOpIn,
+		Values:   []string{"node2"},
+	})
+	if host := InstanceHost(spec); host != "node2" {
+		t.Errorf("Expected host node2 from affinity got '%s'", host)
+	}
+
+	SetHost(spec, "node1")
+	if host := InstanceHost(spec); host != "node1" {
+		t.Errorf("Expected host node1 from node selector got '%s'", host)
+	}
+}
+
+func TestNewMigration(t *testing.T) {
+	vm := &v1.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "f3a2",
+			Namespace: "proj",
+		},
+	}
+	vmi := &v1.VirtualMachineInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "f3a2",
+			Namespace: "proj",
+		},
+		Status: v1.VirtualMachineInstanceStatus{
+			NodeName: "node1",
+		},
+	}
+
+	mig := NewMigration(vm, vmi, MIGRATION_TYPE_LIVE, "node2")
+	if mig.Spec.VMIName != "f3a2" || mig.ObjectMeta.Labels[LabelServerID] != "f3a2" {
+		t.Errorf("Migration is not for the server")
+	}
+	if mig.ObjectMeta.Annotations[AnnotationMigrationSource] != "node1" ||
+		mig.ObjectMeta.Annotations[AnnotationMigrationDest] != "node2" {
+		t.Errorf("Unexpected migration hosts %v", mig.ObjectMeta.Annotations)
+	}
+	if len(mig.Spec.AddedNodeSelector) != 1 || mig.Spec.AddedNodeSelector[LabelHostname] != "node2" {
+		t.Errorf("Expected node selector for node2 got %v", mig.Spec.AddedNodeSelector)
+	}
+
+	mig = NewMigration(vm, vmi, MIGRATION_TYPE_COLD, "")
+	if mig.Spec.AddedNodeSelector != nil {
+		t.Errorf("Unexpected node selector %v", mig.Spec.AddedNodeSelector)
+	}
+}
+
+func TestMigrationID(t *testing.T) {
+	tests := []struct {
+		UID string
+		ID  uint64
+	}{
+		{"", 0},
+		{"3c4ac7a1-1c4b-4a4e-9e8f-0ff4c5d1e3a2", 0xf0ff4c5d1e3a2},
+	}
+
+	for _, test := range tests {
+		mig := &v1.VirtualMachineInstanceMigration{
+			ObjectMeta: metav1.ObjectMeta{
+				UID: types.UID(test.UID),
+			},
+		}
+		if id := MigrationID(mig); id != test.ID {
+			t.Errorf("Expected ID %d for '%s' got %d", test.ID, test.UID, id)
+		}
+	}
+}
+
+func TestServerStatusMigrating(t *testing.T) {
+	yes := true
+	vm := &v1.VirtualMachine{
+		Spec: v1.VirtualMachineSpec{
+			Running: &yes,
+		},
+	}
+	vmi := &v1.VirtualMachineInstance{
+		Status: v1.VirtualMachineInstanceStatus{
+			Phase: v1.Running,
+			MigrationState: &v1.VirtualMachineInstanceMigrationState{
+				SourceNode: "node1",
+				TargetNode: "node2",
+			},
+		},
+	}
+
+	status, taskState, _ := ServerStatus(vm, vmi)
+	if status != SERVER_STATUS_MIGRATING || taskState != SERVER_TASK_STATE_MIGRATING {
+		t.Errorf("Expected migrating status got %s/%s", status, taskState)
+	}
+
+	vmi.Status.MigrationState.Completed = true
+	status, taskState, _ = ServerStatus(vm, vmi)
+	if status != SERVER_STATUS_ACTIVE || taskState != "" {
+		t.Errorf("Expected active status got %s/%s", status, taskState)
+	}
+}
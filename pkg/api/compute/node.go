@@ -0,0 +1,71 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+)
+
+// The state of the compute service on a node which has no
+// Kubernetes equivalent is recorded in node annotations. A
+// disabled service is a cordoned node.
+const (
+	AnnotationDisabledReason = "compute.dicot.io/disabled-reason"
+	AnnotationForcedDown     = "compute.dicot.io/forced-down"
+)
+
+// NodeDisabledReason returns the reason the compute service on
+// the node was disabled, or nil if it is enabled
+func NodeDisabledReason(node *k8sv1.Node) *string {
+	if !node.Spec.Unschedulable {
+		return nil
+	}
+	reason := node.ObjectMeta.Annotations[AnnotationDisabledReason]
+	return &reason
+}
+
+// SetNodeDisabled cordons or uncordons the node, recording the
+// reason for disabling it
+func SetNodeDisabled(node *k8sv1.Node, disabled bool, reason string) {
+	node.Spec.Unschedulable = disabled
+	if disabled && reason != "" {
+		if node.ObjectMeta.Annotations == nil {
+			node.ObjectMeta.Annotations = map[string]string{}
+		}
+		node.ObjectMeta.Annotations[AnnotationDisabledReason] = reason
+	} else {
+		delete(node.ObjectMeta.Annotations, AnnotationDisabledReason)
+	}
+}
+
+func NodeForcedDown(node *k8sv1.Node) bool {
+	return node.ObjectMeta.Annotations[AnnotationForcedDown] == "true"
+}
+
+func SetNodeForcedDown(node *k8sv1.Node, down bool) {
+	if down {
+		if node.ObjectMeta.Annotations == nil {
+			node.ObjectMeta.Annotations = map[string]string{}
+		}
+		node.ObjectMeta.Annotations[AnnotationForcedDown] = "true"
+	} else {
+		delete(node.ObjectMeta.Annotations, AnnotationForcedDown)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"testing"
+
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+)
+
+func TestSetNodeDisabled(t *testing.T) {
+	node := &k8sv1.Node{}
+	if reason := NodeDisabledReason(node); reason != nil {
+		t.Errorf("Unexpected disabled reason %s", *reason)
+	}
+
+	SetNodeDisabled(node, true, "maintenance")
+	if !node.Spec.Unschedulable {
+		t.Errorf("Expected node to be cordoned")
+	}
+	reason := NodeDisabledReason(node)
+	if reason == nil || *reason != "maintenance" {
+		t.Errorf("Expected disabled reason maintenance got %v", reason)
+	}
+
+	SetNodeDisabled(node, false, "")
+	if node.Spec.Unschedulable {
+		t.Errorf("Expected node to be uncordoned")
+	}
+	if _, ok := node.ObjectMeta.Annotations[AnnotationDisabledReason]; ok {
+		t.Errorf("Unexpected disabled reason annotation")
+	}
+
+	// Cordoned outside of the compute API
+	node.Spec.Unschedulable = true
+	reason = NodeDisabledReason(node)
+	if reason == nil || *reason != "" {
+		t.Errorf("Expected empty disabled reason got %v", reason)
+	}
+}
+
+func TestSetNodeForcedDown(t *testing.T) {
+	node := &k8sv1.Node{}
+	if NodeForcedDown(node) {
+		t.Errorf("Unexpected forced down node")
+	}
+	SetNodeForcedDown(node, true)
+	if !NodeForcedDown(node) {
+		t.Errorf("Expected forced down node")
+	}
+	SetNodeForcedDown(node, false)
+	if NodeForcedDown(node) {
+		t.Errorf("Unexpected forced down node")
+	}
+}
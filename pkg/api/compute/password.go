@@ -0,0 +1,125 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+	"github.com/dicot-project/dicot-api/pkg/crypto"
+)
+
+// The encrypted password of a server, as given to the owner of
+// its keypair by os-server-password. It is either stored by the
+// guest through the metadata service, or set when the password
+// is changed.
+const AnnotationPassword = "compute.dicot.io/password"
+
+// Nova stores the password in four chunks of 255 characters
+const SERVER_PASSWORD_MAX_LENGTH = 4 * 255
+
+func GetServerPassword(vm *kubevirtv1.VirtualMachine) string {
+	return vm.ObjectMeta.Annotations[AnnotationPassword]
+}
+
+// SetServerPassword records the encrypted password, or clears
+// it if password is empty
+func SetServerPassword(vm *kubevirtv1.VirtualMachine, password string) {
+	if password == "" {
+		delete(vm.ObjectMeta.Annotations, AnnotationPassword)
+		return
+	}
+	if vm.ObjectMeta.Annotations == nil {
+		vm.ObjectMeta.Annotations = make(map[string]string)
+	}
+	vm.ObjectMeta.Annotations[AnnotationPassword] = password
+}
+
+// EncryptServerPassword encrypts a password with the public key
+// of the server's keypair, giving the base64 encoding used by
+// the metadata service
+func EncryptServerPassword(keypair *v1.Keypair, password string) (string, error) {
+	var mgr crypto.KeyManager
+	switch keypair.Spec.Type {
+	case KEYPAIR_TYPE_SSH:
+		mgr = crypto.NewSSHKeyManager()
+	case KEYPAIR_TYPE_X509:
+		mgr = crypto.NewX509KeyManager()
+	default:
+		return "", fmt.Errorf("Unknown keypair type '%s'", keypair.Spec.Type)
+	}
+
+	data, err := mgr.Encrypt(keypair.Spec.PublicKey, []byte(password))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// The password of the guest's administrative user is held in a
+// Secret named after the server, keyed by the user name, which
+// KubeVirt applies to the guest through the QEMU guest agent
+func ServerCredentialsSecretName(id string) string {
+	return id + "-credentials"
+}
+
+// ServerAdminUser gives the name of the administrative user of
+// the guest, using the image properties Nova consults
+func ServerAdminUser(properties map[string]string) string {
+	if user, ok := properties["os_admin_user"]; ok && user != "" {
+		return user
+	}
+	if properties["os_type"] == "windows" {
+		return "Administrator"
+	}
+	return "root"
+}
+
+// SetServerAccessCredentials makes the instance apply the
+// password held in the server's credentials Secret
+func SetServerAccessCredentials(spec *kubevirtv1.VirtualMachineInstanceSpec, id string) {
+	spec.AccessCredentials = []kubevirtv1.AccessCredential{
+		kubevirtv1.AccessCredential{
+			UserPassword: &kubevirtv1.UserPasswordAccessCredential{
+				Source: kubevirtv1.UserPasswordAccessCredentialSource{
+					Secret: &kubevirtv1.AccessCredentialSecretSource{
+						SecretName: ServerCredentialsSecretName(id),
+					},
+				},
+				PropagationMethod: kubevirtv1.UserPasswordAccessCredentialPropagationMethod{
+					QemuGuestAgent: &kubevirtv1.QemuGuestAgentUserPasswordAccessCredentialPropagation{},
+				},
+			},
+		},
+	}
+}
+
+// HasServerAccessCredentials reports whether the server's
+// password can be changed through the guest agent, which is not
+// the case if the access credentials were removed from the
+// VirtualMachine outside of the compute API.
+func HasServerAccessCredentials(vm *kubevirtv1.VirtualMachine) bool {
+	if vm.Spec.Template == nil {
+		return false
+	}
+	return len(vm.Spec.Template.Spec.AccessCredentials) != 0
+}
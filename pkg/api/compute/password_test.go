@@ -0,0 +1,141 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+	"github.com/dicot-project/dicot-api/pkg/crypto"
+)
+
+func TestServerAdminUser(t *testing.T) {
+	tests := []struct {
+		Properties map[string]string
+		User       string
+	}{
+		{map[string]string{}, "root"},
+		{map[string]string{"os_type": "linux"}, "root"},
+		{map[string]string{"os_type": "windows"}, "Administrator"},
+		{map[string]string{"os_type": "windows", "os_admin_user": "admin"}, "admin"},
+		{map[string]string{"os_admin_user": "fedora"}, "fedora"},
+	}
+
+	for _, test := range tests {
+		user := ServerAdminUser(test.Properties)
+		if user != test.User {
+			t.Errorf("Expected admin user '%s' got '%s' for %s", test.User, user, test.Properties)
+		}
+	}
+}
+
+func TestServerPassword(t *testing.T) {
+	vm := &kubevirtv1.VirtualMachine{}
+
+	if GetServerPassword(vm) != "" {
+		t.Fatalf("Unexpected password on new server")
+	}
+
+	SetServerPassword(vm, "c2VjcmV0")
+	if GetServerPassword(vm) != "c2VjcmV0" {
+		t.Fatalf("Password was not recorded")
+	}
+
+	SetServerPassword(vm, "")
+	if _, ok := vm.ObjectMeta.Annotations[AnnotationPassword]; ok {
+		t.Fatalf("Password was not cleared")
+	}
+}
+
+func TestEncryptServerPassword(t *testing.T) {
+	priv, pub, err := crypto.NewSSHKeyManager().CreateKeyPair(crypto.AlgRSA, 2048)
+	if err != nil {
+		t.Fatalf("Unable to create keypair: %s", err)
+	}
+
+	keypair := &v1.Keypair{
+		Spec: v1.KeypairSpec{
+			Type:      KEYPAIR_TYPE_SSH,
+			PublicKey: pub,
+		},
+	}
+
+	enc, err := EncryptServerPassword(keypair, "Passw0rd")
+	if err != nil {
+		t.Fatalf("Unable to encrypt password: %s", err)
+	}
+	if len(enc) > SERVER_PASSWORD_MAX_LENGTH {
+		t.Fatalf("Encrypted password is too long to store")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		t.Fatalf("Unable to decode password: %s", err)
+	}
+	block, _ := pem.Decode([]byte(priv))
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("Unable to parse private key: %s", err)
+	}
+	clear, err := rsa.DecryptPKCS1v15(rand.Reader, key, data)
+	if err != nil {
+		t.Fatalf("Unable to decrypt password: %s", err)
+	}
+	if string(clear) != "Passw0rd" {
+		t.Errorf("Incorrect decrypted password '%s'", clear)
+	}
+
+	keypair.Spec.PublicKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMeGTaIPN+dyaRwMFSg0KvuSfFj03pmlQar4tXSIr9eI"
+	_, err = EncryptServerPassword(keypair, "Passw0rd")
+	if err == nil {
+		t.Errorf("Expected error encrypting with ed25519 key")
+	}
+}
+
+func TestServerAccessCredentials(t *testing.T) {
+	vm := &kubevirtv1.VirtualMachine{
+		Spec: kubevirtv1.VirtualMachineSpec{
+			Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+		},
+	}
+
+	if HasServerAccessCredentials(vm) {
+		t.Fatalf("Unexpected access credentials on new server")
+	}
+
+	SetServerAccessCredentials(&vm.Spec.Template.Spec, "f3a2")
+	if !HasServerAccessCredentials(vm) {
+		t.Fatalf("Access credentials were not set")
+	}
+
+	cred := vm.Spec.Template.Spec.AccessCredentials[0].UserPassword
+	if cred.Source.Secret.SecretName != "f3a2-credentials" {
+		t.Errorf("Unexpected secret name '%s'", cred.Source.Secret.SecretName)
+	}
+	if cred.PropagationMethod.QemuGuestAgent == nil {
+		t.Errorf("Password not propagated by guest agent")
+	}
+}
@@ -0,0 +1,141 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+// Project quotas are stored in a ResourceQuota of this name in
+// the project namespace. Object counts use the Kubernetes
+// object count quota names, so they are also enforced by the
+// API server. Cores and RAM are counted by Dicot against the
+// flavors of servers, since the virt-launcher pods request more
+// than the guest is given. Keypairs belong to users and are not
+// in the project namespace, so their limit is applied by Dicot to
+// each user.
+const QuotaName = "dicot-quota"
+
+const (
+	QuotaResourceInstances          k8sv1.ResourceName = "count/virtualmachines.kubevirt.io"
+	QuotaResourceCores              k8sv1.ResourceName = "compute.dicot.io/cores"
+	QuotaResourceRAM                k8sv1.ResourceName = "compute.dicot.io/ram"
+	QuotaResourceKeyPairs           k8sv1.ResourceName = "count/keypairs.compute.dicot.io"
+	QuotaResourceServerGroups       k8sv1.ResourceName = "count/servergroups.compute.dicot.io"
+	QuotaResourceServerGroupMembers k8sv1.ResourceName = "compute.dicot.io/server-group-members"
+)
+
+const QUOTA_UNLIMITED = -1
+
+// Quota holds the limits of a project, or its usage of them.
+// RAM is in MiB.
+type Quota struct {
+	Instances          int64
+	Cores              int64
+	RAM                int64
+	KeyPairs           int64
+	ServerGroups       int64
+	ServerGroupMembers int64
+}
+
+// DefaultQuota returns the limits of projects which have no
+// ResourceQuota, matching the Nova defaults
+func DefaultQuota() Quota {
+	return Quota{
+		Instances:          10,
+		Cores:              20,
+		RAM:                51200,
+		KeyPairs:           100,
+		ServerGroups:       10,
+		ServerGroupMembers: 10,
+	}
+}
+
+func (q *Quota) fields() map[k8sv1.ResourceName]*int64 {
+	return map[k8sv1.ResourceName]*int64{
+		QuotaResourceInstances:          &q.Instances,
+		QuotaResourceCores:              &q.Cores,
+		QuotaResourceRAM:                &q.RAM,
+		QuotaResourceKeyPairs:           &q.KeyPairs,
+		QuotaResourceServerGroups:       &q.ServerGroups,
+		QuotaResourceServerGroupMembers: &q.ServerGroupMembers,
+	}
+}
+
+// QuotaFromResourceQuota returns the limits recorded in a
+// ResourceQuota. The defaults apply if rq is nil, while a
+// resource missing from an existing ResourceQuota is unlimited.
+func QuotaFromResourceQuota(rq *k8sv1.ResourceQuota) Quota {
+	if rq == nil {
+		return DefaultQuota()
+	}
+
+	q := Quota{}
+	for name, field := range q.fields() {
+		val, ok := rq.Spec.Hard[name]
+		if ok {
+			*field = val.Value()
+		} else {
+			*field = QUOTA_UNLIMITED
+		}
+	}
+	return q
+}
+
+// ResourceList returns the limits in the form used for the hard
+// limits of a ResourceQuota, omitting unlimited resources
+func (q Quota) ResourceList() k8sv1.ResourceList {
+	res := k8sv1.ResourceList{}
+	for name, field := range q.fields() {
+		if *field < 0 {
+			continue
+		}
+		res[name] = *resource.NewQuantity(*field, resource.DecimalSI)
+	}
+	return res
+}
+
+// ServerResources returns the number of cores and MiB of RAM
+// given to a server
+func ServerResources(vm *v1.VirtualMachine) (int64, int64) {
+	if vm.Spec.Template == nil {
+		return 0, 0
+	}
+	domain := vm.Spec.Template.Spec.Domain
+
+	cores := int64(1)
+	if domain.CPU != nil {
+		for _, count := range []uint32{domain.CPU.Sockets, domain.CPU.Cores, domain.CPU.Threads} {
+			if count != 0 {
+				cores *= int64(count)
+			}
+		}
+	}
+
+	ram := int64(0)
+	if mem, ok := domain.Resources.Requests[k8sv1.ResourceMemory]; ok {
+		ram = mem.Value() / (1024 * 1024)
+	}
+
+	return cores, ram
+}
@@ -0,0 +1,93 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+func TestQuotaResourceQuota(t *testing.T) {
+	if QuotaFromResourceQuota(nil) != DefaultQuota() {
+		t.Errorf("Expected defaults without a resource quota")
+	}
+
+	quota := Quota{
+		Instances:          5,
+		Cores:              QUOTA_UNLIMITED,
+		RAM:                4096,
+		KeyPairs:           0,
+		ServerGroups:       QUOTA_UNLIMITED,
+		ServerGroupMembers: 3,
+	}
+
+	hard := quota.ResourceList()
+	if _, ok := hard[QuotaResourceCores]; ok {
+		t.Errorf("Unexpected limit for unlimited cores")
+	}
+	if val := hard[QuotaResourceRAM]; val.Value() != 4096 {
+		t.Errorf("Expected RAM limit 4096 got %d", val.Value())
+	}
+
+	rq := &k8sv1.ResourceQuota{
+		Spec: k8sv1.ResourceQuotaSpec{
+			Hard: hard,
+		},
+	}
+	actual := QuotaFromResourceQuota(rq)
+	if actual != quota {
+		t.Errorf("Expected quota %v got %v", quota, actual)
+	}
+}
+
+func TestServerResources(t *testing.T) {
+	vm := &v1.VirtualMachine{
+		Spec: v1.VirtualMachineSpec{
+			Template: &v1.VirtualMachineInstanceTemplateSpec{
+				Spec: v1.VirtualMachineInstanceSpec{
+					Domain: v1.DomainSpec{
+						Resources: v1.ResourceRequirements{
+							Requests: k8sv1.ResourceList{
+								k8sv1.ResourceMemory: resource.MustParse("2Gi"),
+							},
+						},
+						CPU: &v1.CPU{
+							Cores: 4,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cores, ram := ServerResources(vm)
+	if cores != 4 || ram != 2048 {
+		t.Errorf("Expected 4 cores 2048 MiB got %d cores %d MiB", cores, ram)
+	}
+
+	cores, ram = ServerResources(&v1.VirtualMachine{})
+	if cores != 0 || ram != 0 {
+		t.Errorf("Expected no resources got %d cores %d MiB", cores, ram)
+	}
+}
@@ -0,0 +1,187 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+// While a resize awaits confirmation the server records the
+// flavor it had before, so that the resize can be reverted
+// even if that flavor has since been deleted
+const AnnotationResize = "compute.dicot.io/resize"
+
+type ServerResize struct {
+	FlavorID   string             `json:"flavor_id"`
+	FlavorName string             `json:"flavor_name"`
+	Resources  v1.FlavorResources `json:"resources"`
+	ExtraSpecs map[string]string  `json:"extra_specs"`
+}
+
+// Flavor returns the flavor to restore when reverting
+func (r *ServerResize) Flavor() *v1.Flavor {
+	return &v1.Flavor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: r.FlavorName,
+		},
+		Spec: v1.FlavorSpec{
+			ID:         r.FlavorID,
+			Resources:  r.Resources,
+			ExtraSpecs: r.ExtraSpecs,
+		},
+	}
+}
+
+// GetServerResize returns the resize awaiting confirmation, or
+// nil if there is none
+func GetServerResize(vm *kubevirtv1.VirtualMachine) (*ServerResize, error) {
+	data, ok := vm.ObjectMeta.Annotations[AnnotationResize]
+	if !ok {
+		return nil, nil
+	}
+	resize := &ServerResize{}
+	err := json.Unmarshal([]byte(data), resize)
+	if err != nil {
+		return nil, err
+	}
+	return resize, nil
+}
+
+// SetServerResize records the resize awaiting confirmation, or
+// clears it if resize is nil
+func SetServerResize(vm *kubevirtv1.VirtualMachine, resize *ServerResize) error {
+	if resize == nil {
+		delete(vm.ObjectMeta.Annotations, AnnotationResize)
+		return nil
+	}
+	data, err := json.Marshal(resize)
+	if err != nil {
+		return err
+	}
+	if vm.ObjectMeta.Annotations == nil {
+		vm.ObjectMeta.Annotations = make(map[string]string)
+	}
+	vm.ObjectMeta.Annotations[AnnotationResize] = string(data)
+	return nil
+}
+
+// SetServerFlavor replaces everything in the spec of a server's
+// instance that was derived from its flavor
+func SetServerFlavor(flv *v1.Flavor, spec *kubevirtv1.VirtualMachineInstanceSpec) error {
+	spec.Domain.Resources = kubevirtv1.ResourceRequirements{
+		Requests: k8sv1.ResourceList{
+			k8sv1.ResourceMemory: resource.MustParse(fmt.Sprintf("%dMi", flv.Spec.Resources.MemoryMB)),
+		},
+	}
+	spec.Domain.CPU = &kubevirtv1.CPU{
+		Cores: uint32(flv.Spec.Resources.CPUCount),
+	}
+	spec.Domain.Memory = nil
+	spec.Domain.Devices.Watchdog = nil
+	spec.Domain.Devices.Rng = nil
+	RemoveNodeSelectorRequirements(spec, func(key string) bool {
+		return strings.HasPrefix(key, TraitLabelPrefix)
+	})
+	setEphemeralDisk(spec, flv.Spec.Resources.EphemeralDiskMB)
+
+	return ApplyFlavorExtraSpecs(flv, spec)
+}
+
+// ServerFlavorResources returns the resources given to the server
+// by its template and the size of its root disk, for use when the
+// flavor it was created with no longer exists
+func ServerFlavorResources(vm *kubevirtv1.VirtualMachine) v1.FlavorResources {
+	cores, ram := ServerResources(vm)
+	res := v1.FlavorResources{
+		CPUCount:   uint64(cores),
+		MemoryMB:   uint64(ram),
+		RxTxFactor: 1.0,
+	}
+	if vm.Spec.Template == nil {
+		return res
+	}
+
+	rootDisk := ""
+	for _, volume := range vm.Spec.Template.Spec.Volumes {
+		if volume.Name == "root" && volume.DataVolume != nil {
+			rootDisk = volume.DataVolume.Name
+		}
+		if volume.Name == ephemeralDiskName && volume.EmptyDisk != nil {
+			res.EphemeralDiskMB = uint64(volume.EmptyDisk.Capacity.Value() / (1024 * 1024))
+		}
+	}
+
+	for _, dv := range vm.Spec.DataVolumeTemplates {
+		if rootDisk == "" || dv.ObjectMeta.Name != rootDisk || dv.Spec.PVC == nil {
+			continue
+		}
+		if size, ok := dv.Spec.PVC.Resources.Requests[k8sv1.ResourceStorage]; ok {
+			res.RootDiskMB = uint64(size.Value() / (1024 * 1024))
+		}
+	}
+
+	return res
+}
+
+// The ephemeral disk given by the flavor is an empty disk, which
+// is recreated each time the instance starts
+const ephemeralDiskName = "ephemeral0"
+
+func setEphemeralDisk(spec *kubevirtv1.VirtualMachineInstanceSpec, sizeMB uint64) {
+	disks := []kubevirtv1.Disk{}
+	for _, disk := range spec.Domain.Devices.Disks {
+		if disk.Name != ephemeralDiskName {
+			disks = append(disks, disk)
+		}
+	}
+	volumes := []kubevirtv1.Volume{}
+	for _, volume := range spec.Volumes {
+		if volume.Name != ephemeralDiskName {
+			volumes = append(volumes, volume)
+		}
+	}
+
+	if sizeMB != 0 {
+		disks = append(disks, kubevirtv1.Disk{
+			Name: ephemeralDiskName,
+			Disk: &kubevirtv1.DiskTarget{
+				Bus: "virtio",
+			},
+		})
+		volumes = append(volumes, kubevirtv1.Volume{
+			Name: ephemeralDiskName,
+			EmptyDisk: &kubevirtv1.EmptyDiskSource{
+				Capacity: resource.MustParse(fmt.Sprintf("%dMi", sizeMB)),
+			},
+		})
+	}
+
+	spec.Domain.Devices.Disks = disks
+	spec.Volumes = volumes
+}
@@ -0,0 +1,220 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	cdiv1 "github.com/dicot-project/dicot-api/pkg/api/cdi/v1"
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+func TestServerStatusResize(t *testing.T) {
+	yes := true
+	no := false
+
+	tests := []struct {
+		Running   *bool
+		Phase     *kubevirtv1.VirtualMachineInstancePhase
+		Status    string
+		TaskState string
+	}{
+		{&yes, nil, SERVER_STATUS_RESIZE, SERVER_TASK_STATE_RESIZE_FINISH},
+		{&yes, phase(kubevirtv1.Scheduling), SERVER_STATUS_RESIZE, SERVER_TASK_STATE_RESIZE_FINISH},
+		{&yes, phase(kubevirtv1.Running), SERVER_STATUS_VERIFY_RESIZE, ""},
+		{&no, nil, SERVER_STATUS_VERIFY_RESIZE, ""},
+		{&yes, phase(kubevirtv1.Failed), SERVER_STATUS_ERROR, ""},
+	}
+
+	for _, test := range tests {
+		vm := &kubevirtv1.VirtualMachine{
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Running: test.Running,
+			},
+		}
+		err := SetServerResize(vm, &ServerResize{FlavorID: "1"})
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+		var vmi *kubevirtv1.VirtualMachineInstance
+		if test.Phase != nil {
+			vmi = &kubevirtv1.VirtualMachineInstance{
+				Status: kubevirtv1.VirtualMachineInstanceStatus{
+					Phase: *test.Phase,
+				},
+			}
+		}
+
+		status, taskState, _ := ServerStatus(vm, vmi)
+		if status != test.Status {
+			t.Errorf("Expected status %s but got %s", test.Status, status)
+		}
+		if taskState != test.TaskState {
+			t.Errorf("Expected task state '%s' but got '%s'", test.TaskState, taskState)
+		}
+	}
+}
+
+func TestServerResize(t *testing.T) {
+	vm := &kubevirtv1.VirtualMachine{}
+	resize, err := GetServerResize(vm)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if resize != nil {
+		t.Errorf("Expected no resize got %v", resize)
+	}
+
+	err = SetServerResize(vm, &ServerResize{
+		FlavorID:   "42",
+		FlavorName: "m1.small",
+		Resources: v1.FlavorResources{
+			CPUCount: 2,
+			MemoryMB: 2048,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	resize, err = GetServerResize(vm)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	flv := resize.Flavor()
+	if flv.Spec.ID != "42" || flv.ObjectMeta.Name != "m1.small" ||
+		flv.Spec.Resources.CPUCount != 2 || flv.Spec.Resources.MemoryMB != 2048 {
+		t.Errorf("Unexpected flavor %v", flv)
+	}
+
+	SetServerResize(vm, nil)
+	if _, ok := vm.ObjectMeta.Annotations[AnnotationResize]; ok {
+		t.Errorf("Expected resize to be cleared")
+	}
+}
+
+func TestSetServerFlavor(t *testing.T) {
+	spec := &kubevirtv1.VirtualMachineInstanceSpec{
+		Volumes: []kubevirtv1.Volume{
+			kubevirtv1.Volume{
+				Name: "root",
+			},
+		},
+	}
+
+	big := newTestFlavor(8, map[string]string{
+		"hw:watchdog_action":    "reset",
+		"trait:HW_CPU_X86_AVX2": "required",
+	})
+	big.Spec.Resources.MemoryMB = 4096
+	big.Spec.Resources.EphemeralDiskMB = 1024
+	err := SetServerFlavor(big, spec)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if len(spec.Volumes) != 2 || spec.Volumes[1].Name != ephemeralDiskName {
+		t.Errorf("Expected ephemeral disk got %v", spec.Volumes)
+	}
+	if spec.Domain.Devices.Watchdog == nil {
+		t.Errorf("Expected watchdog")
+	}
+
+	small := newTestFlavor(1, nil)
+	small.Spec.Resources.MemoryMB = 512
+	err = SetServerFlavor(small, spec)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if spec.Domain.CPU.Cores != 1 {
+		t.Errorf("Expected 1 core got %d", spec.Domain.CPU.Cores)
+	}
+	mem := spec.Domain.Resources.Requests[k8sv1.ResourceMemory]
+	if mem.Value() != 512*1024*1024 {
+		t.Errorf("Expected 512 MiB memory got %s", mem.String())
+	}
+	if len(spec.Volumes) != 1 || spec.Volumes[0].Name != "root" {
+		t.Errorf("Expected only root disk got %v", spec.Volumes)
+	}
+	if spec.Domain.Devices.Watchdog != nil {
+		t.Errorf("Unexpected watchdog")
+	}
+	if _, ok := spec.NodeSelector[TraitLabel("HW_CPU_X86_AVX2")]; ok {
+		t.Errorf("Unexpected trait requirement %v", spec.NodeSelector)
+	}
+}
+
+func TestServerFlavorResources(t *testing.T) {
+	vm := &kubevirtv1.VirtualMachine{
+		Spec: kubevirtv1.VirtualMachineSpec{
+			DataVolumeTemplates: []cdiv1.DataVolume{
+				cdiv1.DataVolume{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "server-root",
+					},
+					Spec: cdiv1.DataVolumeSpec{
+						PVC: &k8sv1.PersistentVolumeClaimSpec{
+							Resources: k8sv1.ResourceRequirements{
+								Requests: k8sv1.ResourceList{
+									k8sv1.ResourceStorage: resource.MustParse("10Gi"),
+								},
+							},
+						},
+					},
+				},
+			},
+			Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+				Spec: kubevirtv1.VirtualMachineInstanceSpec{
+					Volumes: []kubevirtv1.Volume{
+						kubevirtv1.Volume{
+							Name: "root",
+							DataVolume: &kubevirtv1.DataVolumeSource{
+								Name: "server-root",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	flv := newTestFlavor(4, nil)
+	flv.Spec.Resources.MemoryMB = 2048
+	flv.Spec.Resources.EphemeralDiskMB = 512
+	err := SetServerFlavor(flv, &vm.Spec.Template.Spec)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	res := ServerFlavorResources(vm)
+	expect := v1.FlavorResources{
+		CPUCount:        4,
+		MemoryMB:        2048,
+		RootDiskMB:      10 * 1024,
+		EphemeralDiskMB: 512,
+		RxTxFactor:      1.0,
+	}
+	if res != expect {
+		t.Errorf("Expected resources %v got %v", expect, res)
+	}
+}
@@ -0,0 +1,327 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"encoding/json"
+
+	"github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+// Servers are stored as KubeVirt VirtualMachines in the project
+// namespace, named after the server ID. Attributes that have no
+// home in the VirtualMachine spec are recorded as annotations.
+const (
+	AnnotationServerName       = "compute.dicot.io/server-name"
+	AnnotationFlavorID         = "compute.dicot.io/flavor-id"
+	AnnotationFlavorName       = "compute.dicot.io/flavor-name"
+	AnnotationImageID          = "compute.dicot.io/image-id"
+	AnnotationKeyName          = "compute.dicot.io/key-name"
+	AnnotationUserID           = "compute.dicot.io/user-id"
+	AnnotationProjectID        = "compute.dicot.io/project-id"
+	AnnotationCreated          = "compute.dicot.io/created"
+	AnnotationSuspended        = "compute.dicot.io/suspended"
+	AnnotationShelved          = "compute.dicot.io/shelved"
+	AnnotationMetadata         = "compute.dicot.io/metadata"
+	AnnotationTags             = "compute.dicot.io/tags"
+	AnnotationConfigDrive      = "compute.dicot.io/config-drive"
+	AnnotationAvailabilityZone = "compute.dicot.io/availability-zone"
+
+	LabelServerID = "compute.dicot.io/server-id"
+)
+
+// The user data delivered to the guest by cloud-init is kept
+// in a Secret named after the server, under this key
+const ServerCloudInitSecretKey = "userdata"
+
+func ServerCloudInitSecretName(id string) string {
+	return id + "-cloudinit"
+}
+
+const (
+	SERVER_STATUS_ACTIVE            = "ACTIVE"
+	SERVER_STATUS_BUILD             = "BUILD"
+	SERVER_STATUS_SHUTOFF           = "SHUTOFF"
+	SERVER_STATUS_ERROR             = "ERROR"
+	SERVER_STATUS_UNKNOWN           = "UNKNOWN"
+	SERVER_STATUS_PAUSED            = "PAUSED"
+	SERVER_STATUS_SUSPENDED         = "SUSPENDED"
+	SERVER_STATUS_SHELVED_OFFLOADED = "SHELVED_OFFLOADED"
+	SERVER_STATUS_MIGRATING         = "MIGRATING"
+	SERVER_STATUS_RESIZE            = "RESIZE"
+	SERVER_STATUS_VERIFY_RESIZE     = "VERIFY_RESIZE"
+
+	SERVER_TASK_STATE_DELETING      = "deleting"
+	SERVER_TASK_STATE_POWERING_OFF  = "powering-off"
+	SERVER_TASK_STATE_MIGRATING     = "migrating"
+	SERVER_TASK_STATE_RESIZE_FINISH = "resize_finish"
+
+	SERVER_POWER_STATE_NOSTATE   = 0
+	SERVER_POWER_STATE_RUNNING   = 1
+	SERVER_POWER_STATE_PAUSED    = 3
+	SERVER_POWER_STATE_SHUTDOWN  = 4
+	SERVER_POWER_STATE_CRASHED   = 6
+	SERVER_POWER_STATE_SUSPENDED = 7
+)
+
+// ServerStatus maps the state of a VirtualMachine and its
+// running instance, if any, to the Nova server status, task
+// state and power state. The vmi parameter is nil when no
+// instance exists.
+func ServerStatus(vm *v1.VirtualMachine, vmi *v1.VirtualMachineInstance) (string, string, int) {
+	status, taskState, powerState := serverStatus(vm, vmi)
+
+	// A resized server is restarted with the new flavor, then
+	// awaits confirmation
+	if _, ok := vm.ObjectMeta.Annotations[AnnotationResize]; ok {
+		switch status {
+		case SERVER_STATUS_BUILD:
+			status = SERVER_STATUS_RESIZE
+			if taskState == "" {
+				taskState = SERVER_TASK_STATE_RESIZE_FINISH
+			}
+		case SERVER_STATUS_ACTIVE, SERVER_STATUS_SHUTOFF:
+			status = SERVER_STATUS_VERIFY_RESIZE
+		}
+	}
+
+	return status, taskState, powerState
+}
+
+func serverStatus(vm *v1.VirtualMachine, vmi *v1.VirtualMachineInstance) (string, string, int) {
+	taskState := ""
+	if vm.ObjectMeta.DeletionTimestamp != nil {
+		taskState = SERVER_TASK_STATE_DELETING
+	}
+
+	running := vm.Spec.Running != nil && *vm.Spec.Running
+
+	if vmi == nil {
+		if running {
+			return SERVER_STATUS_BUILD, taskState, SERVER_POWER_STATE_NOSTATE
+		}
+		if _, ok := vm.ObjectMeta.Annotations[AnnotationShelved]; ok {
+			return SERVER_STATUS_SHELVED_OFFLOADED, taskState, SERVER_POWER_STATE_SHUTDOWN
+		}
+		return SERVER_STATUS_SHUTOFF, taskState, SERVER_POWER_STATE_SHUTDOWN
+	}
+
+	switch vmi.Status.Phase {
+	case v1.VmPhaseUnset, v1.Pending, v1.Scheduling, v1.Scheduled:
+		return SERVER_STATUS_BUILD, taskState, SERVER_POWER_STATE_NOSTATE
+	case v1.Running:
+		if !running && taskState == "" {
+			taskState = SERVER_TASK_STATE_POWERING_OFF
+		}
+		if vmi.IsMigrating() && taskState == "" {
+			taskState = SERVER_TASK_STATE_MIGRATING
+		}
+		if vmi.IsPaused() {
+			if _, ok := vm.ObjectMeta.Annotations[AnnotationSuspended]; ok {
+				return SERVER_STATUS_SUSPENDED, taskState, SERVER_POWER_STATE_SUSPENDED
+			}
+			return SERVER_STATUS_PAUSED, taskState, SERVER_POWER_STATE_PAUSED
+		}
+		if taskState == SERVER_TASK_STATE_MIGRATING {
+			return SERVER_STATUS_MIGRATING, taskState, SERVER_POWER_STATE_RUNNING
+		}
+		return SERVER_STATUS_ACTIVE, taskState, SERVER_POWER_STATE_RUNNING
+	case v1.Succeeded:
+		return SERVER_STATUS_SHUTOFF, taskState, SERVER_POWER_STATE_SHUTDOWN
+	case v1.Failed:
+		return SERVER_STATUS_ERROR, taskState, SERVER_POWER_STATE_CRASHED
+	}
+
+	return SERVER_STATUS_UNKNOWN, taskState, SERVER_POWER_STATE_NOSTATE
+}
+
+const (
+	SERVER_ACTION_START     = "os-start"
+	SERVER_ACTION_STOP      = "os-stop"
+	SERVER_ACTION_REBOOT    = "reboot"
+	SERVER_ACTION_PAUSE     = "pause"
+	SERVER_ACTION_UNPAUSE   = "unpause"
+	SERVER_ACTION_SUSPEND   = "suspend"
+	SERVER_ACTION_RESUME    = "resume"
+	SERVER_ACTION_SHELVE    = "shelve"
+	SERVER_ACTION_UNSHELVE  = "unshelve"
+	SERVER_REBOOT_TYPE_SOFT = "SOFT"
+	SERVER_REBOOT_TYPE_HARD = "HARD"
+
+	SERVER_ACTION_MIGRATE      = "migrate"
+	SERVER_ACTION_LIVE_MIGRATE = "os-migrateLive"
+	SERVER_ACTION_CREATE_IMAGE = "createImage"
+
+	SERVER_ACTION_RESIZE         = "resize"
+	SERVER_ACTION_CONFIRM_RESIZE = "confirmResize"
+	SERVER_ACTION_REVERT_RESIZE  = "revertResize"
+	SERVER_ACTION_REBUILD        = "rebuild"
+
+	SERVER_ACTION_CHANGE_PASSWORD = "changePassword"
+
+	// Not server actions in the API, but subject to the
+	// same restrictions on the server status
+	SERVER_ACTION_ATTACH_INTERFACE = "attach_interface"
+	SERVER_ACTION_DETACH_INTERFACE = "detach_interface"
+)
+
+// The server statuses from which each action may be performed,
+// following the rules applied by Nova
+var serverActionStatuses = map[string][]string{
+	SERVER_ACTION_START: []string{SERVER_STATUS_SHUTOFF},
+	SERVER_ACTION_STOP:  []string{SERVER_STATUS_ACTIVE, SERVER_STATUS_ERROR},
+	SERVER_ACTION_REBOOT + ":" + SERVER_REBOOT_TYPE_SOFT: []string{SERVER_STATUS_ACTIVE},
+	SERVER_ACTION_REBOOT + ":" + SERVER_REBOOT_TYPE_HARD: []string{
+		SERVER_STATUS_ACTIVE, SERVER_STATUS_SHUTOFF, SERVER_STATUS_PAUSED,
+		SERVER_STATUS_SUSPENDED, SERVER_STATUS_ERROR,
+	},
+	SERVER_ACTION_PAUSE:   []string{SERVER_STATUS_ACTIVE},
+	SERVER_ACTION_UNPAUSE: []string{SERVER_STATUS_PAUSED},
+	SERVER_ACTION_SUSPEND: []string{SERVER_STATUS_ACTIVE},
+	SERVER_ACTION_RESUME:  []string{SERVER_STATUS_SUSPENDED},
+	SERVER_ACTION_SHELVE: []string{
+		SERVER_STATUS_ACTIVE, SERVER_STATUS_SHUTOFF, SERVER_STATUS_PAUSED,
+		SERVER_STATUS_SUSPENDED,
+	},
+	SERVER_ACTION_UNSHELVE: []string{SERVER_STATUS_SHELVED_OFFLOADED},
+	// KubeVirt can only move running instances
+	SERVER_ACTION_MIGRATE:      []string{SERVER_STATUS_ACTIVE, SERVER_STATUS_PAUSED},
+	SERVER_ACTION_LIVE_MIGRATE: []string{SERVER_STATUS_ACTIVE, SERVER_STATUS_PAUSED},
+	// The root disk is cloned by CDI, which cannot start while
+	// the volume is in use by a running instance
+	SERVER_ACTION_CREATE_IMAGE:   []string{SERVER_STATUS_SHUTOFF},
+	SERVER_ACTION_RESIZE:         []string{SERVER_STATUS_ACTIVE, SERVER_STATUS_SHUTOFF},
+	SERVER_ACTION_CONFIRM_RESIZE: []string{SERVER_STATUS_VERIFY_RESIZE},
+	SERVER_ACTION_REVERT_RESIZE:  []string{SERVER_STATUS_VERIFY_RESIZE},
+	SERVER_ACTION_REBUILD: []string{
+		SERVER_STATUS_ACTIVE, SERVER_STATUS_SHUTOFF, SERVER_STATUS_ERROR,
+	},
+	// The guest agent must be running to set the password
+	SERVER_ACTION_CHANGE_PASSWORD: []string{SERVER_STATUS_ACTIVE},
+	SERVER_ACTION_ATTACH_INTERFACE: []string{
+		SERVER_STATUS_ACTIVE, SERVER_STATUS_PAUSED, SERVER_STATUS_SHUTOFF,
+	},
+	SERVER_ACTION_DETACH_INTERFACE: []string{
+		SERVER_STATUS_ACTIVE, SERVER_STATUS_PAUSED, SERVER_STATUS_SHUTOFF,
+	},
+}
+
+// ServerActionAllowed reports whether an action can be applied
+// to a server in the given status and task state. Reboots are
+// identified by the action name and reboot type joined by ':'.
+// No action is allowed while another task is in progress.
+func ServerActionAllowed(action, status, taskState string) bool {
+	if taskState != "" {
+		return false
+	}
+	for _, allowed := range serverActionStatuses[action] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
+
+func GetServerMetadata(vm *v1.VirtualMachine) (map[string]string, error) {
+	md := map[string]string{}
+	data, ok := vm.ObjectMeta.Annotations[AnnotationMetadata]
+	if !ok {
+		return md, nil
+	}
+	err := json.Unmarshal([]byte(data), &md)
+	if err != nil {
+		return nil, err
+	}
+	return md, nil
+}
+
+func SetServerMetadata(vm *v1.VirtualMachine, md map[string]string) error {
+	data, err := json.Marshal(md)
+	if err != nil {
+		return err
+	}
+	if vm.ObjectMeta.Annotations == nil {
+		vm.ObjectMeta.Annotations = make(map[string]string)
+	}
+	vm.ObjectMeta.Annotations[AnnotationMetadata] = string(data)
+	return nil
+}
+
+func GetServerTags(vm *v1.VirtualMachine) ([]string, error) {
+	tags := []string{}
+	data, ok := vm.ObjectMeta.Annotations[AnnotationTags]
+	if !ok {
+		return tags, nil
+	}
+	err := json.Unmarshal([]byte(data), &tags)
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func SetServerTags(vm *v1.VirtualMachine, tags []string) error {
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+	if vm.ObjectMeta.Annotations == nil {
+		vm.ObjectMeta.Annotations = make(map[string]string)
+	}
+	vm.ObjectMeta.Annotations[AnnotationTags] = string(data)
+	return nil
+}
+
+const (
+	SERVER_METADATA_MAX_ITEMS  = 128
+	SERVER_METADATA_MAX_LENGTH = 255
+	SERVER_TAGS_MAX_ITEMS      = 50
+	SERVER_TAG_MAX_LENGTH      = 60
+)
+
+// IsValidServerMetadata applies Nova's limits on the number of
+// metadata items and the length of their keys and values
+func IsValidServerMetadata(md map[string]string) bool {
+	if len(md) > SERVER_METADATA_MAX_ITEMS {
+		return false
+	}
+	for key, val := range md {
+		if len(key) == 0 || len(key) > SERVER_METADATA_MAX_LENGTH ||
+			len(val) > SERVER_METADATA_MAX_LENGTH {
+			return false
+		}
+	}
+	return true
+}
+
+// IsValidServerTag checks a tag is non-empty, within Nova's
+// length limit, and can be used in a URL path and in the
+// comma separated list filters
+func IsValidServerTag(tag string) bool {
+	if len(tag) == 0 || len(tag) > SERVER_TAG_MAX_LENGTH {
+		return false
+	}
+	for _, c := range tag {
+		if c == '/' || c == ',' {
+			return false
+		}
+	}
+	return true
+}
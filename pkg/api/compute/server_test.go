@@ -0,0 +1,267 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+func TestServerStatus(t *testing.T) {
+	yes := true
+	no := false
+
+	tests := []struct {
+		Running   *bool
+		Deleting  bool
+		Phase     *v1.VirtualMachineInstancePhase
+		Status    string
+		TaskState string
+		Power     int
+	}{
+		{&no, false, nil, SERVER_STATUS_SHUTOFF, "", SERVER_POWER_STATE_SHUTDOWN},
+		{nil, false, nil, SERVER_STATUS_SHUTOFF, "", SERVER_POWER_STATE_SHUTDOWN},
+		{&yes, false, nil, SERVER_STATUS_BUILD, "", SERVER_POWER_STATE_NOSTATE},
+		{&yes, false, phase(v1.VmPhaseUnset), SERVER_STATUS_BUILD, "", SERVER_POWER_STATE_NOSTATE},
+		{&yes, false, phase(v1.Pending), SERVER_STATUS_BUILD, "", SERVER_POWER_STATE_NOSTATE},
+		{&yes, false, phase(v1.Scheduling), SERVER_STATUS_BUILD, "", SERVER_POWER_STATE_NOSTATE},
+		{&yes, false, phase(v1.Scheduled), SERVER_STATUS_BUILD, "", SERVER_POWER_STATE_NOSTATE},
+		{&yes, false, phase(v1.Running), SERVER_STATUS_ACTIVE, "", SERVER_POWER_STATE_RUNNING},
+		{&no, false, phase(v1.Succeeded), SERVER_STATUS_SHUTOFF, "", SERVER_POWER_STATE_SHUTDOWN},
+		{&yes, false, phase(v1.Failed), SERVER_STATUS_ERROR, "", SERVER_POWER_STATE_CRASHED},
+		{&yes, false, phase(v1.Unknown), SERVER_STATUS_UNKNOWN, "", SERVER_POWER_STATE_NOSTATE},
+		{&yes, true, phase(v1.Running), SERVER_STATUS_ACTIVE, SERVER_TASK_STATE_DELETING, SERVER_POWER_STATE_RUNNING},
+	}
+
+	for _, test := range tests {
+		vm := &v1.VirtualMachine{
+			Spec: v1.VirtualMachineSpec{
+				Running: test.Running,
+			},
+		}
+		if test.Deleting {
+			now := metav1.Now()
+			vm.ObjectMeta.DeletionTimestamp = &now
+		}
+		var vmi *v1.VirtualMachineInstance
+		if test.Phase != nil {
+			vmi = &v1.VirtualMachineInstance{
+				Status: v1.VirtualMachineInstanceStatus{
+					Phase: *test.Phase,
+				},
+			}
+		}
+
+		status, taskState, power := ServerStatus(vm, vmi)
+		if status != test.Status {
+			t.Errorf("Expected status %s but got %s", test.Status, status)
+		}
+		if taskState != test.TaskState {
+			t.Errorf("Expected task state '%s' but got '%s'", test.TaskState, taskState)
+		}
+		if power != test.Power {
+			t.Errorf("Expected power state %d but got %d", test.Power, power)
+		}
+	}
+}
+
+func TestServerStatusAnnotated(t *testing.T) {
+	yes := true
+	no := false
+
+	tests := []struct {
+		Running    *bool
+		Annotation string
+		Paused     bool
+		Phase      *v1.VirtualMachineInstancePhase
+		Status     string
+		TaskState  string
+		Power      int
+	}{
+		{&yes, "", true, phase(v1.Running), SERVER_STATUS_PAUSED, "", SERVER_POWER_STATE_PAUSED},
+		{&yes, AnnotationSuspended, true, phase(v1.Running), SERVER_STATUS_SUSPENDED, "", SERVER_POWER_STATE_SUSPENDED},
+		{&yes, AnnotationSuspended, false, phase(v1.Running), SERVER_STATUS_ACTIVE, "", SERVER_POWER_STATE_RUNNING},
+		{&no, AnnotationShelved, false, nil, SERVER_STATUS_SHELVED_OFFLOADED, "", SERVER_POWER_STATE_SHUTDOWN},
+		{&no, "", false, phase(v1.Running), SERVER_STATUS_ACTIVE, SERVER_TASK_STATE_POWERING_OFF, SERVER_POWER_STATE_RUNNING},
+	}
+
+	for _, test := range tests {
+		vm := &v1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{},
+			},
+			Spec: v1.VirtualMachineSpec{
+				Running: test.Running,
+			},
+		}
+		if test.Annotation != "" {
+			vm.ObjectMeta.Annotations[test.Annotation] = "true"
+		}
+		var vmi *v1.VirtualMachineInstance
+		if test.Phase != nil {
+			vmi = &v1.VirtualMachineInstance{
+				Status: v1.VirtualMachineInstanceStatus{
+					Phase: *test.Phase,
+				},
+			}
+			if test.Paused {
+				vmi.Status.Conditions = []v1.VirtualMachineInstanceCondition{
+					v1.VirtualMachineInstanceCondition{
+						Type:   v1.VirtualMachineInstancePaused,
+						Status: k8sv1.ConditionTrue,
+					},
+				}
+			}
+		}
+
+		status, taskState, power := ServerStatus(vm, vmi)
+		if status != test.Status {
+			t.Errorf("Expected status %s but got %s", test.Status, status)
+		}
+		if taskState != test.TaskState {
+			t.Errorf("Expected task state '%s' but got '%s'", test.TaskState, taskState)
+		}
+		if power != test.Power {
+			t.Errorf("Expected power state %d but got %d", test.Power, power)
+		}
+	}
+}
+
+func TestServerActionAllowed(t *testing.T) {
+	tests := []struct {
+		Action    string
+		Status    string
+		TaskState string
+		Allowed   bool
+	}{
+		{SERVER_ACTION_START, SERVER_STATUS_SHUTOFF, "", true},
+		{SERVER_ACTION_START, SERVER_STATUS_ACTIVE, "", false},
+		{SERVER_ACTION_STOP, SERVER_STATUS_ACTIVE, "", true},
+		{SERVER_ACTION_STOP, SERVER_STATUS_SHUTOFF, "", false},
+		{SERVER_ACTION_STOP, SERVER_STATUS_ACTIVE, SERVER_TASK_STATE_POWERING_OFF, false},
+		{SERVER_ACTION_REBOOT + ":" + SERVER_REBOOT_TYPE_SOFT, SERVER_STATUS_ACTIVE, "", true},
+		{SERVER_ACTION_REBOOT + ":" + SERVER_REBOOT_TYPE_SOFT, SERVER_STATUS_PAUSED, "", false},
+		{SERVER_ACTION_REBOOT + ":" + SERVER_REBOOT_TYPE_HARD, SERVER_STATUS_PAUSED, "", true},
+		{SERVER_ACTION_REBOOT + ":" + SERVER_REBOOT_TYPE_HARD, SERVER_STATUS_BUILD, "", false},
+		{SERVER_ACTION_PAUSE, SERVER_STATUS_ACTIVE, "", true},
+		{SERVER_ACTION_PAUSE, SERVER_STATUS_PAUSED, "", false},
+		{SERVER_ACTION_UNPAUSE, SERVER_STATUS_PAUSED, "", true},
+		{SERVER_ACTION_UNPAUSE, SERVER_STATUS_SUSPENDED, "", false},
+		{SERVER_ACTION_SUSPEND, SERVER_STATUS_ACTIVE, "", true},
+		{SERVER_ACTION_RESUME, SERVER_STATUS_SUSPENDED, "", true},
+		{SERVER_ACTION_RESUME, SERVER_STATUS_PAUSED, "", false},
+		{SERVER_ACTION_SHELVE, SERVER_STATUS_SHUTOFF, "", true},
+		{SERVER_ACTION_SHELVE, SERVER_STATUS_SHELVED_OFFLOADED, "", false},
+		{SERVER_ACTION_UNSHELVE, SERVER_STATUS_SHELVED_OFFLOADED, "", true},
+		{SERVER_ACTION_UNSHELVE, SERVER_STATUS_SHUTOFF, "", false},
+		{SERVER_ACTION_CREATE_IMAGE, SERVER_STATUS_SHUTOFF, "", true},
+		{SERVER_ACTION_CREATE_IMAGE, SERVER_STATUS_ACTIVE, "", false},
+		{SERVER_ACTION_CREATE_IMAGE, SERVER_STATUS_PAUSED, "", false},
+		{"bogus", SERVER_STATUS_ACTIVE, "", false},
+	}
+
+	for _, test := range tests {
+		allowed := ServerActionAllowed(test.Action, test.Status, test.TaskState)
+		if allowed != test.Allowed {
+			t.Errorf("Expected action %s in status %s/'%s' allowed %t but got %t",
+				test.Action, test.Status, test.TaskState, test.Allowed, allowed)
+		}
+	}
+}
+
+func TestServerMetadata(t *testing.T) {
+	vm := &v1.VirtualMachine{}
+
+	md, err := GetServerMetadata(vm)
+	if err != nil {
+		t.Fatalf("Cannot get metadata %s", err)
+	}
+	if len(md) != 0 {
+		t.Errorf("Expected no metadata but got %s", md)
+	}
+
+	md["role"] = "webserver"
+	err = SetServerMetadata(vm, md)
+	if err != nil {
+		t.Fatalf("Cannot set metadata %s", err)
+	}
+
+	md, err = GetServerMetadata(vm)
+	if err != nil {
+		t.Fatalf("Cannot get metadata %s", err)
+	}
+	if len(md) != 1 || md["role"] != "webserver" {
+		t.Errorf("Expected role metadata but got %s", md)
+	}
+
+	tags, err := GetServerTags(vm)
+	if err != nil {
+		t.Fatalf("Cannot get tags %s", err)
+	}
+	if len(tags) != 0 {
+		t.Errorf("Expected no tags but got %s", tags)
+	}
+
+	err = SetServerTags(vm, []string{"prod", "web"})
+	if err != nil {
+		t.Fatalf("Cannot set tags %s", err)
+	}
+
+	tags, err = GetServerTags(vm)
+	if err != nil {
+		t.Fatalf("Cannot get tags %s", err)
+	}
+	if len(tags) != 2 || tags[0] != "prod" || tags[1] != "web" {
+		t.Errorf("Expected prod and web tags but got %s", tags)
+	}
+
+	vm.ObjectMeta.Annotations[AnnotationTags] = "not json"
+	_, err = GetServerTags(vm)
+	if err == nil {
+		t.Errorf("Expected error from malformed tags")
+	}
+}
+
+func TestServerTagValid(t *testing.T) {
+	tests := []struct {
+		Tag   string
+		Valid bool
+	}{
+		{"prod", true},
+		{"", false},
+		{"a/b", false},
+		{"a,b", false},
+		{"0123456789012345678901234567890123456789012345678901234567890", false},
+	}
+
+	for _, test := range tests {
+		if IsValidServerTag(test.Tag) != test.Valid {
+			t.Errorf("Expected tag '%s' valid %t", test.Tag, test.Valid)
+		}
+	}
+}
+
+func phase(p v1.VirtualMachineInstancePhase) *v1.VirtualMachineInstancePhase {
+	return &p
+}
@@ -0,0 +1,197 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+)
+
+const (
+	SERVER_GROUP_POLICY_AFFINITY           = "affinity"
+	SERVER_GROUP_POLICY_ANTI_AFFINITY      = "anti-affinity"
+	SERVER_GROUP_POLICY_SOFT_AFFINITY      = "soft-affinity"
+	SERVER_GROUP_POLICY_SOFT_ANTI_AFFINITY = "soft-anti-affinity"
+)
+
+// The members of a server group are the servers, and so their
+// virt-launcher pods, with this label set to the group ID
+const LabelServerGroup = "compute.dicot.io/server-group"
+
+// Servers in a group are placed relative to each other by the
+// node they run on
+const ServerGroupTopologyKey = "kubernetes.io/hostname"
+
+func IsValidServerGroupPolicy(policy string) bool {
+	switch policy {
+	case SERVER_GROUP_POLICY_AFFINITY,
+		SERVER_GROUP_POLICY_ANTI_AFFINITY,
+		SERVER_GROUP_POLICY_SOFT_AFFINITY,
+		SERVER_GROUP_POLICY_SOFT_ANTI_AFFINITY:
+		return true
+	}
+	return false
+}
+
+// ServerGroupAffinity returns the pod affinity rules which
+// place the members of a server group according to its policy
+func ServerGroupAffinity(group *v1.ServerGroup) *k8sv1.Affinity {
+	term := k8sv1.PodAffinityTerm{
+		LabelSelector: &meta_v1.LabelSelector{
+			MatchLabels: map[string]string{
+				LabelServerGroup: group.ObjectMeta.Name,
+			},
+		},
+		TopologyKey: ServerGroupTopologyKey,
+	}
+	preferred := []k8sv1.WeightedPodAffinityTerm{
+		k8sv1.WeightedPodAffinityTerm{
+			Weight:          100,
+			PodAffinityTerm: term,
+		},
+	}
+
+	switch group.Spec.Policy {
+	case SERVER_GROUP_POLICY_AFFINITY:
+		return &k8sv1.Affinity{
+			PodAffinity: &k8sv1.PodAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []k8sv1.PodAffinityTerm{term},
+			},
+		}
+	case SERVER_GROUP_POLICY_ANTI_AFFINITY:
+		return &k8sv1.Affinity{
+			PodAntiAffinity: &k8sv1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []k8sv1.PodAffinityTerm{term},
+			},
+		}
+	case SERVER_GROUP_POLICY_SOFT_AFFINITY:
+		return &k8sv1.Affinity{
+			PodAffinity: &k8sv1.PodAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: preferred,
+			},
+		}
+	case SERVER_GROUP_POLICY_SOFT_ANTI_AFFINITY:
+		return &k8sv1.Affinity{
+			PodAntiAffinity: &k8sv1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: preferred,
+			},
+		}
+	}
+	return nil
+}
+
+func NewServerGroupClient(cl rest.Interface, namespace string) ServerGroupInterface {
+	return &servergroups{cl: cl, ns: namespace}
+}
+
+type servergroups struct {
+	cl rest.Interface
+	ns string
+}
+
+type ServerGroupGetter interface {
+	ServerGroups(namespace string) ServerGroupInterface
+}
+
+type ServerGroupInterface interface {
+	Create(obj *v1.ServerGroup) (*v1.ServerGroup, error)
+	Update(obj *v1.ServerGroup) (*v1.ServerGroup, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	Get(name string) (*v1.ServerGroup, error)
+	Exists(name string) (bool, error)
+	List() (*v1.ServerGroupList, error)
+	NewListWatch() *cache.ListWatch
+}
+
+func (sgc *servergroups) Create(obj *v1.ServerGroup) (*v1.ServerGroup, error) {
+	var result v1.ServerGroup
+	err := sgc.cl.Post().
+		Namespace(sgc.ns).Resource("servergroups").
+		Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (sgc *servergroups) Update(obj *v1.ServerGroup) (*v1.ServerGroup, error) {
+	var result v1.ServerGroup
+	name := obj.GetObjectMeta().GetName()
+	err := sgc.cl.Put().
+		Namespace(sgc.ns).Resource("servergroups").
+		Name(name).Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (sgc *servergroups) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return sgc.cl.Delete().
+		Namespace(sgc.ns).Resource("servergroups").
+		Name(name).Body(options).Do().
+		Error()
+}
+
+func (sgc *servergroups) Get(name string) (*v1.ServerGroup, error) {
+	var result v1.ServerGroup
+	err := sgc.cl.Get().
+		Namespace(sgc.ns).Resource("servergroups").
+		Name(name).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (sgc *servergroups) Exists(name string) (bool, error) {
+	_, err := sgc.Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (sgc *servergroups) List() (*v1.ServerGroupList, error) {
+	var result v1.ServerGroupList
+	err := sgc.cl.Get().
+		Namespace(sgc.ns).Resource("servergroups").
+		Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (sgc *servergroups) NewListWatch() *cache.ListWatch {
+	return cache.NewListWatchFromClient(sgc.cl, "servergroups", sgc.ns, fields.Everything())
+}
@@ -0,0 +1,78 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+)
+
+func TestServerGroupAffinity(t *testing.T) {
+	group := &v1.ServerGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "5f1a3b9e-1a7c-4c56-8f31-4e8c2b7d9a60",
+		},
+	}
+
+	group.Spec.Policy = SERVER_GROUP_POLICY_ANTI_AFFINITY
+	affinity := ServerGroupAffinity(group)
+	if affinity == nil || affinity.PodAntiAffinity == nil || affinity.PodAffinity != nil {
+		t.Fatalf("Expected only pod anti-affinity")
+	}
+	terms := affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(terms) != 1 {
+		t.Fatalf("Expected one required term got %d", len(terms))
+	}
+	if terms[0].LabelSelector.MatchLabels[LabelServerGroup] != group.ObjectMeta.Name {
+		t.Errorf("Expected term to select group members")
+	}
+	if terms[0].TopologyKey != ServerGroupTopologyKey {
+		t.Errorf("Expected topology key %s got %s", ServerGroupTopologyKey, terms[0].TopologyKey)
+	}
+
+	group.Spec.Policy = SERVER_GROUP_POLICY_AFFINITY
+	affinity = ServerGroupAffinity(group)
+	if affinity == nil || affinity.PodAffinity == nil ||
+		len(affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution) != 1 {
+		t.Errorf("Expected required pod affinity")
+	}
+
+	group.Spec.Policy = SERVER_GROUP_POLICY_SOFT_AFFINITY
+	affinity = ServerGroupAffinity(group)
+	if affinity == nil || affinity.PodAffinity == nil ||
+		len(affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution) != 1 {
+		t.Errorf("Expected preferred pod affinity")
+	}
+
+	group.Spec.Policy = SERVER_GROUP_POLICY_SOFT_ANTI_AFFINITY
+	affinity = ServerGroupAffinity(group)
+	if affinity == nil || affinity.PodAntiAffinity == nil ||
+		len(affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution) != 1 {
+		t.Errorf("Expected preferred pod anti-affinity")
+	}
+
+	group.Spec.Policy = "random"
+	if ServerGroupAffinity(group) != nil {
+		t.Errorf("Unexpected affinity for invalid policy")
+	}
+}
@@ -0,0 +1,184 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+// The name of the interface and network used for the pod
+// network, which every server is attached to when created.
+// Other networks are Multus network attachment definitions in
+// the project namespace, and are identified by their name.
+const ServerPodNetworkName = "default"
+
+// ServerInterface describes an interface of a server, in the
+// terms of a Nova port attachment
+type ServerInterface struct {
+	// The name of the interface in the instance spec
+	PortID string
+	NetID  string
+	MAC    string
+}
+
+func serverInterfaceNetID(network *kubevirtv1.Network) string {
+	if network.Multus != nil {
+		return network.Multus.NetworkName
+	}
+	return ServerPodNetworkName
+}
+
+// ServerNetworks maps the name of each interface in the instance
+// spec to the network it is attached to, including interfaces
+// which are being detached
+func ServerNetworks(spec *kubevirtv1.VirtualMachineInstanceSpec) map[string]string {
+	networks := map[string]string{}
+	for idx := range spec.Networks {
+		networks[spec.Networks[idx].Name] = serverInterfaceNetID(&spec.Networks[idx])
+	}
+	return networks
+}
+
+// ServerInterfaces lists the interfaces of a server, leaving out
+// those which are being detached
+func ServerInterfaces(spec *kubevirtv1.VirtualMachineInstanceSpec) []ServerInterface {
+	networks := map[string]*kubevirtv1.Network{}
+	for idx := range spec.Networks {
+		networks[spec.Networks[idx].Name] = &spec.Networks[idx]
+	}
+
+	ifaces := []ServerInterface{}
+	for _, iface := range spec.Domain.Devices.Interfaces {
+		if iface.State == kubevirtv1.InterfaceStateAbsent {
+			continue
+		}
+		network, ok := networks[iface.Name]
+		if !ok {
+			continue
+		}
+		ifaces = append(ifaces, ServerInterface{
+			PortID: iface.Name,
+			NetID:  serverInterfaceNetID(network),
+			MAC:    iface.MacAddress,
+		})
+	}
+	return ifaces
+}
+
+// AddServerInterface adds a bridged interface on a Multus
+// network to the instance spec
+func AddServerInterface(spec *kubevirtv1.VirtualMachineInstanceSpec, portID, netID, mac string) {
+	spec.Domain.Devices.Interfaces = append(spec.Domain.Devices.Interfaces, kubevirtv1.Interface{
+		Name:       portID,
+		MacAddress: mac,
+		Bridge:     &kubevirtv1.InterfaceBridge{},
+	})
+	spec.Networks = append(spec.Networks, kubevirtv1.Network{
+		Name: portID,
+		Multus: &kubevirtv1.MultusNetwork{
+			NetworkName: netID,
+		},
+	})
+}
+
+// RemoveServerInterface removes an interface from the instance
+// spec. If unplug is set the instance is running, so instead
+// the interface is marked absent, for KubeVirt to hot-unplug it.
+// It is removed by PruneServerInterfaces once unplugged. Returns
+// false if the interface was not found.
+func RemoveServerInterface(spec *kubevirtv1.VirtualMachineInstanceSpec, portID string, unplug bool) bool {
+	found := false
+	ifaces := []kubevirtv1.Interface{}
+	for _, iface := range spec.Domain.Devices.Interfaces {
+		if iface.Name == portID && iface.State != kubevirtv1.InterfaceStateAbsent {
+			found = true
+			if !unplug {
+				continue
+			}
+			iface.State = kubevirtv1.InterfaceStateAbsent
+		}
+		ifaces = append(ifaces, iface)
+	}
+	if !found {
+		return false
+	}
+	spec.Domain.Devices.Interfaces = ifaces
+
+	if !unplug {
+		networks := []kubevirtv1.Network{}
+		for _, network := range spec.Networks {
+			if network.Name != portID {
+				networks = append(networks, network)
+			}
+		}
+		spec.Networks = networks
+	}
+	return true
+}
+
+// PruneServerInterfaces removes the interfaces marked absent from
+// the instance spec once the instance no longer reports them,
+// which is always the case if vmi is nil as it is not running.
+// Returns true if any interface was removed.
+func PruneServerInterfaces(spec *kubevirtv1.VirtualMachineInstanceSpec, vmi *kubevirtv1.VirtualMachineInstance) bool {
+	reported := map[string]bool{}
+	if vmi != nil {
+		for _, status := range vmi.Status.Interfaces {
+			reported[status.Name] = true
+		}
+	}
+
+	pruned := map[string]bool{}
+	ifaces := []kubevirtv1.Interface{}
+	for _, iface := range spec.Domain.Devices.Interfaces {
+		if iface.State == kubevirtv1.InterfaceStateAbsent && !reported[iface.Name] {
+			pruned[iface.Name] = true
+			continue
+		}
+		ifaces = append(ifaces, iface)
+	}
+	if len(pruned) == 0 {
+		return false
+	}
+	spec.Domain.Devices.Interfaces = ifaces
+
+	networks := []kubevirtv1.Network{}
+	for _, network := range spec.Networks {
+		if !pruned[network.Name] {
+			networks = append(networks, network)
+		}
+	}
+	spec.Networks = networks
+	return true
+}
+
+// GenerateMACAddress gives a random MAC address using the
+// prefix Neutron allocates ports from
+func GenerateMACAddress() (string, error) {
+	buf := make([]byte, 3)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("fa:16:3e:%02x:%02x:%02x", buf[0], buf[1], buf[2]), nil
+}
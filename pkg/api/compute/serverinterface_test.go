@@ -0,0 +1,173 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"regexp"
+	"testing"
+
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+func testInterfaceSpec() *kubevirtv1.VirtualMachineInstanceSpec {
+	return &kubevirtv1.VirtualMachineInstanceSpec{
+		Domain: kubevirtv1.DomainSpec{
+			Devices: kubevirtv1.Devices{
+				Interfaces: []kubevirtv1.Interface{
+					kubevirtv1.Interface{
+						Name:   ServerPodNetworkName,
+						Bridge: &kubevirtv1.InterfaceBridge{},
+					},
+				},
+			},
+		},
+		Networks: []kubevirtv1.Network{
+			kubevirtv1.Network{
+				Name: ServerPodNetworkName,
+				Pod:  &kubevirtv1.PodNetwork{},
+			},
+		},
+	}
+}
+
+func TestServerInterfaces(t *testing.T) {
+	spec := testInterfaceSpec()
+	AddServerInterface(spec, "port1", "storage", "fa:16:3e:00:00:01")
+
+	ifaces := ServerInterfaces(spec)
+	expect := []ServerInterface{
+		ServerInterface{ServerPodNetworkName, ServerPodNetworkName, ""},
+		ServerInterface{"port1", "storage", "fa:16:3e:00:00:01"},
+	}
+	if len(ifaces) != len(expect) {
+		t.Fatalf("Expected %d interfaces got %d", len(expect), len(ifaces))
+	}
+	for idx, iface := range ifaces {
+		if iface != expect[idx] {
+			t.Errorf("Expected interface %v got %v", expect[idx], iface)
+		}
+	}
+}
+
+func TestServerNetworks(t *testing.T) {
+	spec := testInterfaceSpec()
+	AddServerInterface(spec, "port1", "storage", "fa:16:3e:00:00:01")
+	RemoveServerInterface(spec, "port1", true)
+
+	networks := ServerNetworks(spec)
+	expect := map[string]string{
+		ServerPodNetworkName: ServerPodNetworkName,
+		"port1":              "storage",
+	}
+	if len(networks) != len(expect) {
+		t.Fatalf("Expected %d networks got %d", len(expect), len(networks))
+	}
+	for portID, netID := range expect {
+		if networks[portID] != netID {
+			t.Errorf("Expected network '%s' for '%s' got '%s'", netID, portID, networks[portID])
+		}
+	}
+}
+
+func TestRemoveServerInterface(t *testing.T) {
+	spec := testInterfaceSpec()
+	AddServerInterface(spec, "port1", "storage", "fa:16:3e:00:00:01")
+
+	if RemoveServerInterface(spec, "port2", false) {
+		t.Fatalf("Removed interface which does not exist")
+	}
+
+	if !RemoveServerInterface(spec, "port1", false) {
+		t.Fatalf("Interface was not found")
+	}
+	if len(spec.Domain.Devices.Interfaces) != 1 || len(spec.Networks) != 1 {
+		t.Fatalf("Interface was not removed")
+	}
+}
+
+func TestUnplugServerInterface(t *testing.T) {
+	spec := testInterfaceSpec()
+	AddServerInterface(spec, "port1", "storage", "fa:16:3e:00:00:01")
+
+	if !RemoveServerInterface(spec, "port1", true) {
+		t.Fatalf("Interface was not found")
+	}
+	if len(spec.Domain.Devices.Interfaces) != 2 || len(spec.Networks) != 2 {
+		t.Fatalf("Interface was removed before it is unplugged")
+	}
+	if spec.Domain.Devices.Interfaces[1].State != kubevirtv1.InterfaceStateAbsent {
+		t.Errorf("Interface was not marked absent")
+	}
+	if len(ServerInterfaces(spec)) != 1 {
+		t.Errorf("Interface being unplugged was listed")
+	}
+	if RemoveServerInterface(spec, "port1", true) {
+		t.Errorf("Interface being unplugged was removed again")
+	}
+}
+
+func TestPruneServerInterfaces(t *testing.T) {
+	spec := testInterfaceSpec()
+	AddServerInterface(spec, "port1", "storage", "fa:16:3e:00:00:01")
+	AddServerInterface(spec, "port2", "storage", "fa:16:3e:00:00:02")
+	RemoveServerInterface(spec, "port1", true)
+
+	vmi := &kubevirtv1.VirtualMachineInstance{
+		Status: kubevirtv1.VirtualMachineInstanceStatus{
+			Interfaces: []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+				kubevirtv1.VirtualMachineInstanceNetworkInterface{Name: ServerPodNetworkName},
+				kubevirtv1.VirtualMachineInstanceNetworkInterface{Name: "port1"},
+				kubevirtv1.VirtualMachineInstanceNetworkInterface{Name: "port2"},
+			},
+		},
+	}
+	if PruneServerInterfaces(spec, vmi) {
+		t.Fatalf("Interface still reported by the instance was removed")
+	}
+
+	vmi.Status.Interfaces = vmi.Status.Interfaces[:1]
+	if !PruneServerInterfaces(spec, vmi) {
+		t.Fatalf("Unplugged interface was not removed")
+	}
+	if len(spec.Domain.Devices.Interfaces) != 2 || len(spec.Networks) != 2 {
+		t.Fatalf("Expected 2 interfaces got %v", spec.Domain.Devices.Interfaces)
+	}
+	if spec.Domain.Devices.Interfaces[1].Name != "port2" || spec.Networks[1].Name != "port2" {
+		t.Errorf("Interface not marked absent was removed")
+	}
+
+	RemoveServerInterface(spec, "port2", true)
+	if !PruneServerInterfaces(spec, nil) {
+		t.Errorf("Interface of stopped server was not removed")
+	}
+	if len(spec.Domain.Devices.Interfaces) != 1 || len(spec.Networks) != 1 {
+		t.Errorf("Expected only pod interface got %v", spec.Domain.Devices.Interfaces)
+	}
+}
+
+func TestGenerateMACAddress(t *testing.T) {
+	mac, err := GenerateMACAddress()
+	if err != nil {
+		t.Fatalf("Unable to generate MAC address: %s", err)
+	}
+	if !regexp.MustCompile("^fa:16:3e(:[0-9a-f]{2}){3}$").MatchString(mac) {
+		t.Errorf("Malformed MAC address '%s'", mac)
+	}
+}
@@ -0,0 +1,246 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"time"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+)
+
+// NewServerUsage starts accounting the usage of a server, which
+// was launched with the given flavor
+func NewServerUsage(serverID, name, projectID, userID string, flv *v1.Flavor, start time.Time) *v1.ServerUsage {
+	usage := &v1.ServerUsage{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: serverID,
+			Labels: map[string]string{
+				LabelServerID: serverID,
+			},
+		},
+		Spec: v1.ServerUsageSpec{
+			ServerID:  serverID,
+			Name:      name,
+			ProjectID: projectID,
+			UserID:    userID,
+			StartedAt: start.UTC().Format(time.RFC3339),
+			Periods:   []v1.ServerUsagePeriod{},
+		},
+	}
+	SetServerUsageFlavor(usage, flv, start)
+	return usage
+}
+
+// SetServerUsageFlavor starts a new period of usage with the
+// resources of a different flavor
+func SetServerUsageFlavor(usage *v1.ServerUsage, flv *v1.Flavor, start time.Time) {
+	usage.Spec.Periods = append(usage.Spec.Periods, v1.ServerUsagePeriod{
+		StartedAt:  start.UTC().Format(time.RFC3339),
+		FlavorName: flv.ObjectMeta.Name,
+		Resources:  flv.Spec.Resources,
+	})
+}
+
+// EndServerUsage stops accounting the usage of a server which
+// has been deleted
+func EndServerUsage(usage *v1.ServerUsage, end time.Time) {
+	if usage.Spec.EndedAt == "" {
+		usage.Spec.EndedAt = end.UTC().Format(time.RFC3339)
+	}
+}
+
+// ServerUsageTimes returns the time at which a server was
+// launched and, if it has been deleted, when it ended
+func ServerUsageTimes(usage *v1.ServerUsage) (time.Time, *time.Time, error) {
+	started, err := time.Parse(time.RFC3339, usage.Spec.StartedAt)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	if usage.Spec.EndedAt == "" {
+		return started, nil, nil
+	}
+	ended, err := time.Parse(time.RFC3339, usage.Spec.EndedAt)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	return started, &ended, nil
+}
+
+// ServerUsageInWindow reports whether a server existed at any
+// time within a window
+func ServerUsageInWindow(usage *v1.ServerUsage, start, end time.Time) (bool, error) {
+	started, ended, err := ServerUsageTimes(usage)
+	if err != nil {
+		return false, err
+	}
+	if started.After(end) || (ended != nil && ended.Before(start)) {
+		return false, nil
+	}
+	return true, nil
+}
+
+type UsageTotals struct {
+	Hours         float64
+	VCPUHours     float64
+	MemoryMBHours float64
+	LocalGBHours  float64
+}
+
+// ServerUsageTotals sums the usage of a server over a window,
+// with the resources of the flavor it had during each part of
+// the window. As done by Nova, a server which has not been
+// deleted is counted up to the end of the window.
+func ServerUsageTotals(usage *v1.ServerUsage, start, end time.Time) (UsageTotals, error) {
+	totals := UsageTotals{}
+
+	started, ended, err := ServerUsageTimes(usage)
+	if err != nil {
+		return totals, err
+	}
+	if ended != nil && ended.Before(end) {
+		end = *ended
+	}
+	if started.After(start) {
+		start = started
+	}
+
+	for idx := range usage.Spec.Periods {
+		period := &usage.Spec.Periods[idx]
+		periodStart, err := time.Parse(time.RFC3339, period.StartedAt)
+		if err != nil {
+			return totals, err
+		}
+		periodEnd := end
+		if idx < len(usage.Spec.Periods)-1 {
+			periodEnd, err = time.Parse(time.RFC3339, usage.Spec.Periods[idx+1].StartedAt)
+			if err != nil {
+				return totals, err
+			}
+		}
+
+		if periodStart.Before(start) {
+			periodStart = start
+		}
+		if periodEnd.After(end) {
+			periodEnd = end
+		}
+		if !periodEnd.After(periodStart) {
+			continue
+		}
+
+		hours := periodEnd.Sub(periodStart).Hours()
+		res := &period.Resources
+		totals.Hours += hours
+		totals.VCPUHours += float64(res.CPUCount) * hours
+		totals.MemoryMBHours += float64(res.MemoryMB) * hours
+		totals.LocalGBHours += float64(ServerUsageLocalGB(res)) * hours
+	}
+
+	return totals, nil
+}
+
+// ServerUsageLocalGB gives the local disk space used by a
+// server, which is its root and ephemeral disks
+func ServerUsageLocalGB(res *v1.FlavorResources) uint64 {
+	return (res.RootDiskMB + res.EphemeralDiskMB) / 1024
+}
+
+func NewServerUsageClient(cl rest.Interface, namespace string) ServerUsageInterface {
+	return &serverusages{cl: cl, ns: namespace}
+}
+
+type serverusages struct {
+	cl rest.Interface
+	ns string
+}
+
+type ServerUsageGetter interface {
+	ServerUsages(namespace string) ServerUsageInterface
+}
+
+type ServerUsageInterface interface {
+	Create(obj *v1.ServerUsage) (*v1.ServerUsage, error)
+	Update(obj *v1.ServerUsage) (*v1.ServerUsage, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	Get(name string) (*v1.ServerUsage, error)
+	List() (*v1.ServerUsageList, error)
+	NewListWatch() *cache.ListWatch
+}
+
+func (suc *serverusages) Create(obj *v1.ServerUsage) (*v1.ServerUsage, error) {
+	var result v1.ServerUsage
+	err := suc.cl.Post().
+		Namespace(suc.ns).Resource("serverusages").
+		Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (suc *serverusages) Update(obj *v1.ServerUsage) (*v1.ServerUsage, error) {
+	var result v1.ServerUsage
+	name := obj.GetObjectMeta().GetName()
+	err := suc.cl.Put().
+		Namespace(suc.ns).Resource("serverusages").
+		Name(name).Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (suc *serverusages) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return suc.cl.Delete().
+		Namespace(suc.ns).Resource("serverusages").
+		Name(name).Body(options).Do().
+		Error()
+}
+
+func (suc *serverusages) Get(name string) (*v1.ServerUsage, error) {
+	var result v1.ServerUsage
+	err := suc.cl.Get().
+		Namespace(suc.ns).Resource("serverusages").
+		Name(name).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (suc *serverusages) List() (*v1.ServerUsageList, error) {
+	var result v1.ServerUsageList
+	err := suc.cl.Get().
+		Namespace(suc.ns).Resource("serverusages").
+		Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (suc *serverusages) NewListWatch() *cache.ListWatch {
+	return cache.NewListWatchFromClient(suc.cl, "serverusages", suc.ns, fields.Everything())
+}
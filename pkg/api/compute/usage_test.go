@@ -0,0 +1,107 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+)
+
+func newTestUsageFlavor(name string, vcpus, ramMB, diskGB uint64) *v1.Flavor {
+	return &v1.Flavor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1.FlavorSpec{
+			Resources: v1.FlavorResources{
+				CPUCount:   vcpus,
+				MemoryMB:   ramMB,
+				RootDiskMB: diskGB * 1024,
+			},
+		},
+	}
+}
+
+func TestServerUsageTotals(t *testing.T) {
+	base := time.Date(2017, 10, 1, 0, 0, 0, 0, time.UTC)
+	hour := func(n int) time.Time {
+		return base.Add(time.Duration(n) * time.Hour)
+	}
+
+	// Launched at 02:00 with 1 vCPU, resized at 04:00 to
+	// 4 vCPUs and deleted at 10:00
+	usage := NewServerUsage("1234", "test", "proj1", "user1",
+		newTestUsageFlavor("small", 1, 512, 1), hour(2))
+	SetServerUsageFlavor(usage, newTestUsageFlavor("large", 4, 2048, 10), hour(4))
+	EndServerUsage(usage, hour(10))
+	EndServerUsage(usage, hour(12))
+	if usage.Spec.EndedAt != "2017-10-01T10:00:00Z" {
+		t.Errorf("Unexpected end time %s", usage.Spec.EndedAt)
+	}
+
+	tests := []struct {
+		Start    int
+		End      int
+		InWindow bool
+		Totals   UsageTotals
+	}{
+		{0, 1, false, UsageTotals{}},
+		{11, 12, false, UsageTotals{}},
+		{0, 24, true, UsageTotals{8, 2 + 24, 1024 + 12288, 2 + 60}},
+		{3, 5, true, UsageTotals{2, 1 + 4, 512 + 2048, 1 + 10}},
+		{6, 8, true, UsageTotals{2, 8, 4096, 20}},
+	}
+
+	for _, test := range tests {
+		inWindow, err := ServerUsageInWindow(usage, hour(test.Start), hour(test.End))
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+		if inWindow != test.InWindow {
+			t.Errorf("Expected in window %t for %d-%d", test.InWindow, test.Start, test.End)
+		}
+		totals, err := ServerUsageTotals(usage, hour(test.Start), hour(test.End))
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+		if totals != test.Totals {
+			t.Errorf("Expected totals %v for %d-%d got %v", test.Totals, test.Start, test.End, totals)
+		}
+	}
+}
+
+func TestServerUsageRunning(t *testing.T) {
+	start := time.Date(2017, 10, 1, 0, 0, 0, 0, time.UTC)
+	usage := NewServerUsage("1234", "test", "proj1", "user1",
+		newTestUsageFlavor("small", 2, 1024, 0), start)
+
+	totals, err := ServerUsageTotals(usage, start, start.Add(90*time.Minute))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	expect := UsageTotals{1.5, 3, 1536, 0}
+	if totals != expect {
+		t.Errorf("Expected totals %v got %v", expect, totals)
+	}
+}
@@ -49,6 +49,14 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&FlavorList{},
 		&Keypair{},
 		&KeypairList{},
+		&ServerGroup{},
+		&ServerGroupList{},
+		&Aggregate{},
+		&AggregateList{},
+		&InstanceAction{},
+		&InstanceActionList{},
+		&ServerUsage{},
+		&ServerUsageList{},
 	)
 	return nil
 }
@@ -87,6 +95,9 @@ type FlavorSpec struct {
 	Public     bool              `json:"public"`
 	Resources  FlavorResources   `json:"resources"`
 	ExtraSpecs map[string]string `json:"extra_specs"`
+	// IDs of the projects allowed to use a flavor which
+	// is not public
+	Projects []string `json:"projects,omitempty"`
 }
 
 type FlavorResources struct {
@@ -126,8 +137,11 @@ type KeypairList struct {
 	Items           []Keypair       `json:"items"`
 }
 
+// Keypairs are stored in the namespace of the domain of the user
+// who owns them, named by a hash of the user ID and keypair name
 type KeypairSpec struct {
 	ID          uint64 `json:"id"`
+	Name        string `json:"name"`
 	Fingerprint string `json:"fingerprint"`
 	Type        string `json:"type"`
 	PublicKey   string `json:"public_key"`
@@ -150,3 +164,181 @@ func (vl *KeypairList) GetObjectKind() schema.ObjectKind {
 func (vl *KeypairList) GetListMeta() metav1.List {
 	return &vl.ListMeta
 }
+
+type ServerGroup struct {
+	metav1.TypeMeta `json:",inline"`
+	ObjectMeta      metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec            ServerGroupSpec   `json:"spec,omitempty" valid:"required"`
+}
+
+type ServerGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	ListMeta        metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ServerGroup   `json:"items"`
+}
+
+// Server groups are named by their ID. The members are the
+// servers labelled with the group ID.
+type ServerGroupSpec struct {
+	Name      string `json:"name"`
+	Policy    string `json:"policy"`
+	UserID    string `json:"user_id"`
+	ProjectID string `json:"project_id"`
+}
+
+func (v *ServerGroup) GetObjectKind() schema.ObjectKind {
+	return &v.TypeMeta
+}
+
+func (v *ServerGroup) GetObjectMeta() metav1.Object {
+	return &v.ObjectMeta
+}
+
+func (vl *ServerGroupList) GetObjectKind() schema.ObjectKind {
+	return &vl.TypeMeta
+}
+
+func (vl *ServerGroupList) GetListMeta() metav1.List {
+	return &vl.ListMeta
+}
+
+type Aggregate struct {
+	metav1.TypeMeta `json:",inline"`
+	ObjectMeta      metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec            AggregateSpec     `json:"spec,omitempty" valid:"required"`
+}
+
+type AggregateList struct {
+	metav1.TypeMeta `json:",inline"`
+	ListMeta        metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Aggregate     `json:"items"`
+}
+
+// Aggregates are stored in the system namespace, named by their
+// UUID. Hosts are the names of Kubernetes nodes.
+type AggregateSpec struct {
+	ID               uint64            `json:"id"`
+	Name             string            `json:"name"`
+	AvailabilityZone string            `json:"availability_zone"`
+	Hosts            []string          `json:"hosts"`
+	Metadata         map[string]string `json:"metadata"`
+	CreatedAt        string            `json:"created_at"`
+	UpdatedAt        string            `json:"updated_at"`
+}
+
+func (v *Aggregate) GetObjectKind() schema.ObjectKind {
+	return &v.TypeMeta
+}
+
+func (v *Aggregate) GetObjectMeta() metav1.Object {
+	return &v.ObjectMeta
+}
+
+func (vl *AggregateList) GetObjectKind() schema.ObjectKind {
+	return &vl.TypeMeta
+}
+
+func (vl *AggregateList) GetListMeta() metav1.List {
+	return &vl.ListMeta
+}
+
+type InstanceAction struct {
+	metav1.TypeMeta `json:",inline"`
+	ObjectMeta      metav1.ObjectMeta  `json:"metadata,omitempty"`
+	Spec            InstanceActionSpec `json:"spec,omitempty" valid:"required"`
+}
+
+type InstanceActionList struct {
+	metav1.TypeMeta `json:",inline"`
+	ListMeta        metav1.ListMeta  `json:"metadata,omitempty"`
+	Items           []InstanceAction `json:"items"`
+}
+
+// Instance actions are stored in the namespace of the server's
+// project, labelled with the server ID and owned by the server so
+// that they are garbage collected with it. Each is named by the
+// server ID and the ID of the request which performed it.
+type InstanceActionSpec struct {
+	Action    string                `json:"action"`
+	ServerID  string                `json:"server_id"`
+	RequestID string                `json:"request_id"`
+	UserID    string                `json:"user_id"`
+	ProjectID string                `json:"project_id"`
+	Message   string                `json:"message"`
+	StartTime string                `json:"start_time"`
+	UpdatedAt string                `json:"updated_at"`
+	Events    []InstanceActionEvent `json:"events"`
+}
+
+type InstanceActionEvent struct {
+	Event      string `json:"event"`
+	StartTime  string `json:"start_time"`
+	FinishTime string `json:"finish_time"`
+	Result     string `json:"result"`
+	Traceback  string `json:"traceback"`
+}
+
+func (v *InstanceAction) GetObjectKind() schema.ObjectKind {
+	return &v.TypeMeta
+}
+
+func (v *InstanceAction) GetObjectMeta() metav1.Object {
+	return &v.ObjectMeta
+}
+
+func (vl *InstanceActionList) GetObjectKind() schema.ObjectKind {
+	return &vl.TypeMeta
+}
+
+func (vl *InstanceActionList) GetListMeta() metav1.List {
+	return &vl.ListMeta
+}
+
+type ServerUsage struct {
+	metav1.TypeMeta `json:",inline"`
+	ObjectMeta      metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec            ServerUsageSpec   `json:"spec,omitempty" valid:"required"`
+}
+
+type ServerUsageList struct {
+	metav1.TypeMeta `json:",inline"`
+	ListMeta        metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ServerUsage   `json:"items"`
+}
+
+// Server usage records are stored in the namespace of the
+// server's project, named by the server ID. They are not owned
+// by the server, so that the usage is still accounted after it
+// has been deleted. A new period is started each time the
+// server's flavor changes.
+type ServerUsageSpec struct {
+	ServerID  string              `json:"server_id"`
+	Name      string              `json:"name"`
+	ProjectID string              `json:"project_id"`
+	UserID    string              `json:"user_id"`
+	StartedAt string              `json:"started_at"`
+	EndedAt   string              `json:"ended_at"`
+	Periods   []ServerUsagePeriod `json:"periods"`
+}
+
+type ServerUsagePeriod struct {
+	StartedAt  string          `json:"started_at"`
+	FlavorName string          `json:"flavor_name"`
+	Resources  FlavorResources `json:"resources"`
+}
+
+func (v *ServerUsage) GetObjectKind() schema.ObjectKind {
+	return &v.TypeMeta
+}
+
+func (v *ServerUsage) GetObjectMeta() metav1.Object {
+	return &v.ObjectMeta
+}
+
+func (vl *ServerUsageList) GetObjectKind() schema.ObjectKind {
+	return &vl.TypeMeta
+}
+
+func (vl *ServerUsageList) GetListMeta() metav1.List {
+	return &vl.ListMeta
+}
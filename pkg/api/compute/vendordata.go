@@ -0,0 +1,28 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+// Vendor data offered to guests by the metadata service is
+// configured by a ConfigMap in the system namespace. If the
+// ConfigMap does not exist, an empty JSON object is served.
+const (
+	VendorDataConfigMap = "dicot-vendordata"
+	VendorDataKey       = "vendor_data.json"
+)
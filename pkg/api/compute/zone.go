@@ -0,0 +1,129 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+// The availability zone of a node is given by the standard
+// Kubernetes zone label, which is also set on the hosts of an
+// aggregate with an availability zone. Nodes without the label
+// are in the default zone.
+const (
+	LabelAvailabilityZone   = "failure-domain.beta.kubernetes.io/zone"
+	DefaultAvailabilityZone = "nova"
+)
+
+// The node label used to place servers on a specific host
+const LabelHostname = "kubernetes.io/hostname"
+
+func NodeAvailabilityZone(node *k8sv1.Node) string {
+	if zone, ok := node.ObjectMeta.Labels[LabelAvailabilityZone]; ok && zone != "" {
+		return zone
+	}
+	return DefaultAvailabilityZone
+}
+
+// AddNodeSelectorRequirements adds requirements which nodes
+// must satisfy to run the instance
+func AddNodeSelectorRequirements(spec *kubevirtv1.VirtualMachineInstanceSpec, reqs ...k8sv1.NodeSelectorRequirement) {
+	if spec.Affinity == nil {
+		spec.Affinity = &k8sv1.Affinity{}
+	}
+	if spec.Affinity.NodeAffinity == nil {
+		spec.Affinity.NodeAffinity = &k8sv1.NodeAffinity{}
+	}
+	if spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &k8sv1.NodeSelector{
+			NodeSelectorTerms: []k8sv1.NodeSelectorTerm{
+				k8sv1.NodeSelectorTerm{},
+			},
+		}
+	}
+	// Terms are alternatives, so every term must include
+	// the requirements
+	terms := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	for idx := range terms {
+		terms[idx].MatchExpressions = append(terms[idx].MatchExpressions, reqs...)
+	}
+}
+
+// RemoveNodeSelectorRequirements removes the requirements on
+// node labels matched by the function, from both the node
+// selector and node affinity of the instance
+func RemoveNodeSelectorRequirements(spec *kubevirtv1.VirtualMachineInstanceSpec, match func(key string) bool) {
+	for key := range spec.NodeSelector {
+		if match(key) {
+			delete(spec.NodeSelector, key)
+		}
+	}
+
+	if spec.Affinity == nil || spec.Affinity.NodeAffinity == nil ||
+		spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return
+	}
+
+	terms := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	empty := true
+	for idx := range terms {
+		reqs := []k8sv1.NodeSelectorRequirement{}
+		for _, req := range terms[idx].MatchExpressions {
+			if !match(req.Key) {
+				reqs = append(reqs, req)
+			}
+		}
+		terms[idx].MatchExpressions = reqs
+		if len(reqs) != 0 {
+			empty = false
+		}
+	}
+	// An empty term matches no nodes at all
+	if empty {
+		spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = nil
+	}
+}
+
+// SetAvailabilityZone restricts the instance to run on nodes in
+// the zone
+func SetAvailabilityZone(spec *kubevirtv1.VirtualMachineInstanceSpec, zone string) {
+	if zone == DefaultAvailabilityZone {
+		AddNodeSelectorRequirements(spec, k8sv1.NodeSelectorRequirement{
+			Key:      LabelAvailabilityZone,
+			Operator: k8sv1.NodeSelectorOpDoesNotExist,
+		})
+		return
+	}
+
+	if spec.NodeSelector == nil {
+		spec.NodeSelector = map[string]string{}
+	}
+	spec.NodeSelector[LabelAvailabilityZone] = zone
+}
+
+// SetHost restricts the instance to run on the named node
+func SetHost(spec *kubevirtv1.VirtualMachineInstanceSpec, host string) {
+	if spec.NodeSelector == nil {
+		spec.NodeSelector = map[string]string{}
+	}
+	spec.NodeSelector[LabelHostname] = host
+}
@@ -0,0 +1,72 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package compute
+
+import (
+	"testing"
+
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+func TestSetAvailabilityZone(t *testing.T) {
+	spec := &kubevirtv1.VirtualMachineInstanceSpec{}
+	SetAvailabilityZone(spec, "rack1")
+	if spec.NodeSelector[LabelAvailabilityZone] != "rack1" {
+		t.Errorf("Expected node selector for zone rack1")
+	}
+	if spec.Affinity != nil {
+		t.Errorf("Unexpected node affinity")
+	}
+
+	spec = &kubevirtv1.VirtualMachineInstanceSpec{}
+	AddNodeSelectorRequirements(spec, k8sv1.NodeSelectorRequirement{
+		Key:      TraitLabel("CUSTOM_SLOW"),
+		Operator: k8sv1.NodeSelectorOpNotIn,
+		Values:   []string{"true"},
+	})
+	SetAvailabilityZone(spec, DefaultAvailabilityZone)
+	if _, ok := spec.NodeSelector[LabelAvailabilityZone]; ok {
+		t.Errorf("Unexpected node selector for default zone")
+	}
+	terms := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 || len(terms[0].MatchExpressions) != 2 {
+		t.Fatalf("Expected one term with two requirements got %v", terms)
+	}
+	req := terms[0].MatchExpressions[1]
+	if req.Key != LabelAvailabilityZone || req.Operator != k8sv1.NodeSelectorOpDoesNotExist {
+		t.Errorf("Expected requirement for nodes without a zone got %v", req)
+	}
+}
+
+func TestNodeAvailabilityZone(t *testing.T) {
+	node := &k8sv1.Node{}
+	if zone := NodeAvailabilityZone(node); zone != DefaultAvailabilityZone {
+		t.Errorf("Expected default zone got %s", zone)
+	}
+
+	node.ObjectMeta.Labels = map[string]string{
+		LabelAvailabilityZone: "rack2",
+	}
+	if zone := NodeAvailabilityZone(node); zone != "rack2" {
+		t.Errorf("Expected zone rack2 got %s", zone)
+	}
+}
@@ -33,6 +33,7 @@ type Interface interface {
 	GroupGetter
 	ProjectGetter
 	RevokedTokenGetter
+	RoleGetter
 	UserGetter
 }
 
@@ -71,6 +72,10 @@ func (c *identity) RevokedTokens(namespace string) RevokedTokenInterface {
 	return NewRevokedTokenClient(c.cl, namespace)
 }
 
+func (c *identity) Roles(namespace string) RoleInterface {
+	return NewRoleClient(c.cl, namespace)
+}
+
 func (c *identity) Users(namespace string) UserInterface {
 	return NewUserClient(c.cl, namespace)
 }
@@ -21,12 +21,24 @@ package identity
 
 import (
 	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
 )
 
+// LabelID is set on identity objects to allow them to be
+// looked up efficiently by their OpenStack ID
+const LabelID = "identity.dicot.io/id"
+
+func NewID() string {
+	return string(uuid.NewUUID())
+}
+
 var sanitizeNameRE = regexp.MustCompile("[^-.a-z0-9]")
 
+// SanitizeName turns an OpenStack name, which may contain
+// capitals, spaces and other characters, into a valid object name
 func SanitizeName(name string) string {
-	// XXX must not start / end with '-'
-	return string(sanitizeNameRE.ReplaceAllLiteral(
-		[]byte(name), []byte("-")))
+	return strings.Trim(string(sanitizeNameRE.ReplaceAllLiteral(
+		[]byte(strings.ToLower(name)), []byte("-"))), "-.")
 }
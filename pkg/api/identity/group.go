@@ -23,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 
@@ -47,7 +48,7 @@ type GroupInterface interface {
 	Update(obj *v1.Group) (*v1.Group, error)
 	Delete(name string, options *meta_v1.DeleteOptions) error
 	Get(name string) (*v1.Group, error)
-	GetByUID(id string) (*v1.Group, error)
+	GetByID(id string) (*v1.Group, error)
 	Exists(name string) (bool, error)
 	List() (*v1.GroupList, error)
 	NewListWatch() *cache.ListWatch
@@ -55,6 +56,15 @@ type GroupInterface interface {
 
 func (pc *groups) Create(obj *v1.Group) (*v1.Group, error) {
 	var result v1.Group
+	if obj.Spec.ID == "" {
+		obj.Spec.ID = NewID()
+	}
+	if len(validation.IsValidLabelValue(obj.Spec.ID)) == 0 {
+		if obj.ObjectMeta.Labels == nil {
+			obj.ObjectMeta.Labels = make(map[string]string)
+		}
+		obj.ObjectMeta.Labels[LabelID] = obj.Spec.ID
+	}
 	err := pc.cl.Post().
 		Namespace(pc.ns).Resource("groups").
 		Body(obj).Do().Into(&result)
@@ -97,17 +107,32 @@ func (pc *groups) Get(name string) (*v1.Group, error) {
 
 }
 
-func (pc *groups) GetByUID(uid string) (*v1.Group, error) {
+func (pc *groups) GetByID(id string) (*v1.Group, error) {
+	if len(validation.IsValidLabelValue(id)) == 0 {
+		var result v1.GroupList
+		err := pc.cl.Get().
+			Namespace(pc.ns).Resource("groups").
+			Param("labelSelector", LabelID+"="+id).
+			Do().Into(&result)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Items) != 0 {
+			return &result.Items[0], nil
+		}
+	}
+
+	// Fallback for objects created before IDs were recorded in the spec
 	list, err := pc.List()
 	if err != nil {
 		return nil, err
 	}
 	for _, group := range list.Items {
-		if string(group.ObjectMeta.UID) == uid {
+		if group.GetID() == id {
 			return &group, nil
 		}
 	}
-	return nil, errors.NewNotFound(v1.Resource("group"), uid)
+	return nil, errors.NewNotFound(v1.Resource("group"), id)
 }
 
 func (pc *groups) Exists(name string) (bool, error) {
@@ -21,10 +21,12 @@ package identity
 
 import (
 	"fmt"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 
@@ -35,12 +37,18 @@ func NewProjectClient(cl rest.Interface, namespace string) ProjectInterface {
 	return &projects{cl: cl, ns: namespace}
 }
 
+// Namespace names cannot contain the dots which are allowed in
+// object names
+var namespaceNameReplacer = strings.NewReplacer(".", "-")
+
 func FormatProjectNamespace(domainName, projectName string) string {
-	return fmt.Sprintf("dicot-project-%s-%s", domainName, projectName)
+	return namespaceNameReplacer.Replace(
+		fmt.Sprintf("dicot-project-%s-%s", domainName, projectName))
 }
 
 func FormatDomainNamespace(domainName string) string {
-	return fmt.Sprintf("dicot-domain-%s", domainName)
+	return namespaceNameReplacer.Replace(
+		fmt.Sprintf("dicot-domain-%s", domainName))
 }
 
 type projects struct {
@@ -57,7 +65,7 @@ type ProjectInterface interface {
 	Update(obj *v1.Project) (*v1.Project, error)
 	Delete(name string, options *meta_v1.DeleteOptions) error
 	Get(name string) (*v1.Project, error)
-	GetByUID(id string) (*v1.Project, error)
+	GetByID(id string) (*v1.Project, error)
 	Exists(name string) (bool, error)
 	List() (*v1.ProjectList, error)
 	NewListWatch() *cache.ListWatch
@@ -65,6 +73,15 @@ type ProjectInterface interface {
 
 func (pc *projects) Create(obj *v1.Project) (*v1.Project, error) {
 	var result v1.Project
+	if obj.Spec.ID == "" {
+		obj.Spec.ID = NewID()
+	}
+	if len(validation.IsValidLabelValue(obj.Spec.ID)) == 0 {
+		if obj.ObjectMeta.Labels == nil {
+			obj.ObjectMeta.Labels = make(map[string]string)
+		}
+		obj.ObjectMeta.Labels[LabelID] = obj.Spec.ID
+	}
 	err := pc.cl.Post().
 		Namespace(pc.ns).Resource("projects").
 		Body(obj).Do().Into(&result)
@@ -107,17 +124,32 @@ func (pc *projects) Get(name string) (*v1.Project, error) {
 
 }
 
-func (pc *projects) GetByUID(uid string) (*v1.Project, error) {
+func (pc *projects) GetByID(id string) (*v1.Project, error) {
+	if len(validation.IsValidLabelValue(id)) == 0 {
+		var result v1.ProjectList
+		err := pc.cl.Get().
+			Namespace(pc.ns).Resource("projects").
+			Param("labelSelector", LabelID+"="+id).
+			Do().Into(&result)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Items) != 0 {
+			return &result.Items[0], nil
+		}
+	}
+
+	// Fallback for objects created before IDs were recorded in the spec
 	list, err := pc.List()
 	if err != nil {
 		return nil, err
 	}
 	for _, project := range list.Items {
-		if string(project.ObjectMeta.UID) == uid {
+		if project.GetID() == id {
 			return &project, nil
 		}
 	}
-	return nil, errors.NewNotFound(v1.Resource("project"), uid)
+	return nil, errors.NewNotFound(v1.Resource("project"), id)
 }
 
 func (pc *projects) Exists(name string) (bool, error) {
@@ -0,0 +1,243 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package identity
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+)
+
+func NewRoleClient(cl rest.Interface, namespace string) RoleInterface {
+	return &roles{cl: cl, ns: namespace}
+}
+
+type roles struct {
+	cl rest.Interface
+	ns string
+}
+
+type RoleGetter interface {
+	Roles(namespace string) RoleInterface
+}
+
+type RoleInterface interface {
+	Create(obj *v1.Role) (*v1.Role, error)
+	Update(obj *v1.Role) (*v1.Role, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	Get(name string) (*v1.Role, error)
+	GetByID(id string) (*v1.Role, error)
+	Exists(name string) (bool, error)
+	List() (*v1.RoleList, error)
+	NewListWatch() *cache.ListWatch
+}
+
+func (pc *roles) Create(obj *v1.Role) (*v1.Role, error) {
+	var result v1.Role
+	if obj.Spec.ID == "" {
+		obj.Spec.ID = NewID()
+	}
+	if len(validation.IsValidLabelValue(obj.Spec.ID)) == 0 {
+		if obj.ObjectMeta.Labels == nil {
+			obj.ObjectMeta.Labels = make(map[string]string)
+		}
+		obj.ObjectMeta.Labels[LabelID] = obj.Spec.ID
+	}
+	err := pc.cl.Post().
+		Namespace(pc.ns).Resource("roles").
+		Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (pc *roles) Update(obj *v1.Role) (*v1.Role, error) {
+	var result v1.Role
+	name := obj.GetObjectMeta().GetName()
+	err := pc.cl.Put().
+		Namespace(pc.ns).Resource("roles").
+		Name(name).Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (pc *roles) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return pc.cl.Delete().
+		Namespace(pc.ns).Resource("roles").
+		Name(name).Body(options).Do().
+		Error()
+}
+
+func (pc *roles) Get(name string) (*v1.Role, error) {
+	var result v1.Role
+	err := pc.cl.Get().
+		Namespace(pc.ns).Resource("roles").
+		Name(name).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (pc *roles) GetByID(id string) (*v1.Role, error) {
+	if len(validation.IsValidLabelValue(id)) == 0 {
+		var result v1.RoleList
+		err := pc.cl.Get().
+			Namespace(pc.ns).Resource("roles").
+			Param("labelSelector", LabelID+"="+id).
+			Do().Into(&result)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Items) != 0 {
+			return &result.Items[0], nil
+		}
+	}
+
+	// Fallback for objects created before IDs were recorded in the spec
+	list, err := pc.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range list.Items {
+		if role.GetID() == id {
+			return &role, nil
+		}
+	}
+	return nil, errors.NewNotFound(v1.Resource("role"), id)
+}
+
+func (pc *roles) Exists(name string) (bool, error) {
+	_, err := pc.Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (pc *roles) List() (*v1.RoleList, error) {
+	var result v1.RoleList
+	err := pc.cl.Get().
+		Namespace(pc.ns).Resource("roles").
+		Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (pc *roles) NewListWatch() *cache.ListWatch {
+	return cache.NewListWatchFromClient(pc.cl, "roles", pc.ns, fields.Everything())
+}
+
+// Roles which may never appear as the implied side of an
+// inference rule, matching keystone's prohibited_implied_role
+var ProhibitedImpliedRoles = []string{"admin"}
+
+func IsProhibitedImpliedRole(name string) bool {
+	for _, prohibited := range ProhibitedImpliedRoles {
+		if name == prohibited {
+			return true
+		}
+	}
+	return false
+}
+
+// ImpliesRole reports whether the role with ID 'prior' implies
+// the role with ID 'implied', either directly or transitively
+func ImpliesRole(roles []v1.Role, prior, implied string) bool {
+	byID := make(map[string]*v1.Role)
+	for idx := range roles {
+		byID[roles[idx].GetID()] = &roles[idx]
+	}
+
+	seen := make(map[string]bool)
+	todo := []string{prior}
+	for len(todo) > 0 {
+		id := todo[0]
+		todo = todo[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		role, ok := byID[id]
+		if !ok {
+			continue
+		}
+		for _, next := range role.Spec.Implies {
+			if next == implied {
+				return true
+			}
+			todo = append(todo, next)
+		}
+	}
+
+	return false
+}
+
+// ExpandImpliedRoles returns the set of roles granted by the
+// role IDs in 'ids' once all inference rules have been followed.
+// Domain specific roles are only used to find further implied
+// roles, and are not themselves included in the result.
+func ExpandImpliedRoles(roles []v1.Role, ids []string) []v1.Role {
+	byID := make(map[string]*v1.Role)
+	for idx := range roles {
+		byID[roles[idx].GetID()] = &roles[idx]
+	}
+
+	res := []v1.Role{}
+	seen := make(map[string]bool)
+	todo := append([]string{}, ids...)
+	for len(todo) > 0 {
+		id := todo[0]
+		todo = todo[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		role, ok := byID[id]
+		if !ok {
+			continue
+		}
+		if role.Spec.DomainID == "" {
+			res = append(res, *role)
+		}
+		todo = append(todo, role.Spec.Implies...)
+	}
+
+	return res
+}
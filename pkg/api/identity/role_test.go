@@ -0,0 +1,115 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package identity
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+)
+
+func newTestRole(id, name, domainID string, implies ...string) v1.Role {
+	return v1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			UID:  types.UID(id),
+		},
+		Spec: v1.RoleSpec{
+			Name:     name,
+			DomainID: domainID,
+			Implies:  implies,
+		},
+	}
+}
+
+func testRoles() []v1.Role {
+	return []v1.Role{
+		newTestRole("1", "admin", "", "2"),
+		newTestRole("2", "member", "", "3"),
+		newTestRole("3", "reader", ""),
+		newTestRole("4", "operator", "d1", "2"),
+	}
+}
+
+type ImpliesRoleData struct {
+	Prior   string
+	Implied string
+	Result  bool
+}
+
+func TestImpliesRole(t *testing.T) {
+	roles := testRoles()
+	data := []ImpliesRoleData{
+		ImpliesRoleData{"1", "2", true},
+		ImpliesRoleData{"1", "3", true},
+		ImpliesRoleData{"4", "3", true},
+		ImpliesRoleData{"3", "1", false},
+		ImpliesRoleData{"2", "1", false},
+		ImpliesRoleData{"3", "3", false},
+	}
+
+	for _, d := range data {
+		res := ImpliesRole(roles, d.Prior, d.Implied)
+		if res != d.Result {
+			t.Errorf("Expected %s implies %s to be %t", d.Prior, d.Implied, d.Result)
+		}
+	}
+}
+
+type ExpandImpliedRolesData struct {
+	IDs   []string
+	Names []string
+}
+
+func TestExpandImpliedRoles(t *testing.T) {
+	roles := testRoles()
+	data := []ExpandImpliedRolesData{
+		ExpandImpliedRolesData{
+			IDs:   []string{"1"},
+			Names: []string{"admin", "member", "reader"},
+		},
+		ExpandImpliedRolesData{
+			IDs:   []string{"3"},
+			Names: []string{"reader"},
+		},
+		ExpandImpliedRolesData{
+			IDs:   []string{"4"},
+			Names: []string{"member", "reader"},
+		},
+		ExpandImpliedRolesData{
+			IDs:   []string{"2", "3", "99"},
+			Names: []string{"member", "reader"},
+		},
+	}
+
+	for _, d := range data {
+		names := []string{}
+		for _, role := range ExpandImpliedRoles(roles, d.IDs) {
+			names = append(names, role.Spec.Name)
+		}
+		if !reflect.DeepEqual(names, d.Names) {
+			t.Errorf("Expected roles %s for %s but got %s", d.Names, d.IDs, names)
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 
@@ -47,7 +48,7 @@ type UserInterface interface {
 	Update(obj *v1.User) (*v1.User, error)
 	Delete(name string, options *meta_v1.DeleteOptions) error
 	Get(name string) (*v1.User, error)
-	GetByUID(id string) (*v1.User, error)
+	GetByID(id string) (*v1.User, error)
 	Exists(name string) (bool, error)
 	List() (*v1.UserList, error)
 	NewListWatch() *cache.ListWatch
@@ -55,6 +56,15 @@ type UserInterface interface {
 
 func (pc *users) Create(obj *v1.User) (*v1.User, error) {
 	var result v1.User
+	if obj.Spec.ID == "" {
+		obj.Spec.ID = NewID()
+	}
+	if len(validation.IsValidLabelValue(obj.Spec.ID)) == 0 {
+		if obj.ObjectMeta.Labels == nil {
+			obj.ObjectMeta.Labels = make(map[string]string)
+		}
+		obj.ObjectMeta.Labels[LabelID] = obj.Spec.ID
+	}
 	err := pc.cl.Post().
 		Namespace(pc.ns).Resource("users").
 		Body(obj).Do().Into(&result)
@@ -97,17 +107,32 @@ func (pc *users) Get(name string) (*v1.User, error) {
 
 }
 
-func (pc *users) GetByUID(uid string) (*v1.User, error) {
+func (pc *users) GetByID(id string) (*v1.User, error) {
+	if len(validation.IsValidLabelValue(id)) == 0 {
+		var result v1.UserList
+		err := pc.cl.Get().
+			Namespace(pc.ns).Resource("users").
+			Param("labelSelector", LabelID+"="+id).
+			Do().Into(&result)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Items) != 0 {
+			return &result.Items[0], nil
+		}
+	}
+
+	// Fallback for objects created before IDs were recorded in the spec
 	list, err := pc.List()
 	if err != nil {
 		return nil, err
 	}
 	for _, user := range list.Items {
-		if string(user.ObjectMeta.UID) == uid {
+		if user.GetID() == id {
 			return &user, nil
 		}
 	}
-	return nil, errors.NewNotFound(v1.Resource("user"), uid)
+	return nil, errors.NewNotFound(v1.Resource("user"), id)
 }
 
 func (pc *users) Exists(name string) (bool, error) {
@@ -53,6 +53,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&GroupList{},
 		&RevokedToken{},
 		&RevokedTokenList{},
+		&Role{},
+		&RoleList{},
 	)
 	return nil
 }
@@ -86,6 +88,8 @@ type ProjectList struct {
 }
 
 type ProjectSpec struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
 	Parent      string `json:"parent"`
 	Domain      string `json:"domain"`
 	Description string `json:"description"`
@@ -101,6 +105,25 @@ func (v *Project) GetObjectMeta() metav1.Object {
 	return &v.ObjectMeta
 }
 
+// GetID returns the OpenStack ID, which is the UID for objects
+// created before the ID was recorded in the spec
+func (v *Project) GetID() string {
+	if v.Spec.ID != "" {
+		return v.Spec.ID
+	}
+	return string(v.ObjectMeta.UID)
+}
+
+// GetName returns the OpenStack name, which may not be a valid
+// object name, falling back to the object name for objects
+// created before the name was recorded in the spec
+func (v *Project) GetName() string {
+	if v.Spec.Name != "" {
+		return v.Spec.Name
+	}
+	return v.ObjectMeta.Name
+}
+
 func (vl *ProjectList) GetObjectKind() schema.ObjectKind {
 	return &vl.TypeMeta
 }
@@ -122,6 +145,7 @@ type UserList struct {
 }
 
 type UserSpec struct {
+	ID               string       `json:"id"`
 	Name             string       `json:"name"`
 	DomainID         string       `json:"domain_id"`
 	Enabled          bool         `json:"enabled"`
@@ -144,6 +168,15 @@ func (v *User) GetObjectMeta() metav1.Object {
 	return &v.ObjectMeta
 }
 
+// GetID returns the OpenStack ID, which is the UID for objects
+// created before the ID was recorded in the spec
+func (v *User) GetID() string {
+	if v.Spec.ID != "" {
+		return v.Spec.ID
+	}
+	return string(v.ObjectMeta.UID)
+}
+
 func (vl *UserList) GetObjectKind() schema.ObjectKind {
 	return &vl.TypeMeta
 }
@@ -165,6 +198,7 @@ type GroupList struct {
 }
 
 type GroupSpec struct {
+	ID          string   `json:"id"`
 	Name        string   `json:"name"`
 	DomainID    string   `json:"domain_id"`
 	Description string   `json:"description"`
@@ -179,6 +213,15 @@ func (v *Group) GetObjectMeta() metav1.Object {
 	return &v.ObjectMeta
 }
 
+// GetID returns the OpenStack ID, which is the UID for objects
+// created before the ID was recorded in the spec
+func (v *Group) GetID() string {
+	if v.Spec.ID != "" {
+		return v.Spec.ID
+	}
+	return string(v.ObjectMeta.UID)
+}
+
 func (vl *GroupList) GetObjectKind() schema.ObjectKind {
 	return &vl.TypeMeta
 }
@@ -214,3 +257,48 @@ func (vl *RevokedTokenList) GetObjectKind() schema.ObjectKind {
 func (vl *RevokedTokenList) GetListMeta() metav1.List {
 	return &vl.ListMeta
 }
+
+type Role struct {
+	metav1.TypeMeta `json:",inline"`
+	ObjectMeta      metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec            RoleSpec          `json:"spec,omitempty" valid:"required"`
+}
+
+type RoleList struct {
+	metav1.TypeMeta `json:",inline"`
+	ListMeta        metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Role          `json:"items"`
+}
+
+type RoleSpec struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	DomainID    string   `json:"domain_id"`
+	Description string   `json:"description"`
+	Implies     []string `json:"implies"`
+}
+
+func (v *Role) GetObjectKind() schema.ObjectKind {
+	return &v.TypeMeta
+}
+
+func (v *Role) GetObjectMeta() metav1.Object {
+	return &v.ObjectMeta
+}
+
+// GetID returns the OpenStack ID, which is the UID for objects
+// created before the ID was recorded in the spec
+func (v *Role) GetID() string {
+	if v.Spec.ID != "" {
+		return v.Spec.ID
+	}
+	return string(v.ObjectMeta.UID)
+}
+
+func (vl *RoleList) GetObjectKind() schema.ObjectKind {
+	return &vl.TypeMeta
+}
+
+func (vl *RoleList) GetListMeta() metav1.List {
+	return &vl.ListMeta
+}
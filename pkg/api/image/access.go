@@ -0,0 +1,45 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package image
+
+import (
+	identityv1 "github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+	"github.com/dicot-project/dicot-api/pkg/api/image/v1"
+)
+
+func ImageAccessible(img *v1.Image, proj *identityv1.Project) bool {
+	if img.ObjectMeta.Namespace == proj.Spec.Namespace {
+		return true
+	}
+
+	switch img.Spec.Visibility {
+	case IMAGE_VISIBILITY_PUBLIC:
+		return true
+	case IMAGE_VISIBILITY_COMMUNITY:
+		return true
+	case IMAGE_VISIBILITY_SHARED:
+		// XXX validate sharing rules
+		return false
+	case IMAGE_VISIBILITY_PRIVATE:
+		return false
+	}
+
+	panic("Unexpected visibility")
+}
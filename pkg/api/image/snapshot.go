@@ -0,0 +1,84 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package image
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/dicot-project/dicot-api/pkg/api/cdi"
+	cdiv1 "github.com/dicot-project/dicot-api/pkg/api/cdi/v1"
+	"github.com/dicot-project/dicot-api/pkg/api/image/v1"
+)
+
+// Properties recorded on images saved from servers
+const (
+	IMAGE_PROPERTY_IMAGE_TYPE     = "image_type"
+	IMAGE_PROPERTY_BASE_IMAGE_REF = "base_image_ref"
+	IMAGE_PROPERTY_INSTANCE_UUID  = "instance_uuid"
+	IMAGE_PROPERTY_USER_ID        = "user_id"
+
+	IMAGE_TYPE_SNAPSHOT = "snapshot"
+)
+
+// ImageVolumeName gives the name of the DataVolume holding a
+// saved image
+func ImageVolumeName(id string) string {
+	return "image-" + id
+}
+
+// ImageVolumeStatus maps the phase of the DataVolume holding an
+// image to the image status
+func ImageVolumeStatus(dv *cdiv1.DataVolume) string {
+	switch dv.Status.Phase {
+	case cdiv1.DataVolumeSucceeded:
+		return IMAGE_STATUS_ACTIVE
+	case cdiv1.DataVolumeFailed:
+		return IMAGE_STATUS_KILLED
+	}
+	return IMAGE_STATUS_SAVING
+}
+
+// SyncImageVolume updates the status of an image which is being
+// saved to a volume, once copying the data has finished
+func SyncImageVolume(imgs Interface, dvs cdi.Interface, img *v1.Image) (*v1.Image, error) {
+	if img.Spec.Volume == nil || img.Spec.Status != IMAGE_STATUS_SAVING {
+		return img, nil
+	}
+
+	status := IMAGE_STATUS_KILLED
+	dv, err := dvs.DataVolumes(img.Spec.Volume.Namespace).Get(img.Spec.Volume.Name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	} else {
+		status = ImageVolumeStatus(dv)
+	}
+
+	if status == img.Spec.Status {
+		return img, nil
+	}
+
+	img.Spec.Status = status
+	img.Spec.UpdatedAt = time.Now().Format(time.RFC3339)
+	return imgs.Images(img.ObjectMeta.Namespace).Update(img)
+}
@@ -96,7 +96,14 @@ type ImageSpec struct {
 	Tags            []string          `json:"tags"`
 	Metadata        map[string]string `json:"metadata"`
 
-	/* XXX some how reference where it is stored - PVC ? */
+	// Images saved from servers are stored in a volume rather
+	// than the image repository
+	Volume *ImageVolume `json:"volume,omitempty"`
+}
+
+type ImageVolume struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
 }
 
 func (v *Image) GetObjectKind() schema.ObjectKind {
@@ -0,0 +1,73 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package kubevirt
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+
+	"github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+type Interface interface {
+	RESTClient() rest.Interface
+	VirtualMachineGetter
+	VirtualMachineInstanceGetter
+	VirtualMachineInstanceMigrationGetter
+}
+
+type kubevirt struct {
+	cl  rest.Interface
+	cfg *rest.Config
+}
+
+func New(c *rest.Config) (Interface, error) {
+	cfg := *c
+	cCopy := *c
+	cCopy.GroupVersion = &v1.GroupVersion
+	cCopy.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: scheme.Codecs}
+	cCopy.APIPath = "/apis"
+	cCopy.ContentType = runtime.ContentTypeJSON
+
+	cl, err := rest.RESTClientFor(&cCopy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kubevirt{cl, &cfg}, err
+}
+
+func (c *kubevirt) RESTClient() rest.Interface {
+	return c.cl
+}
+
+func (c *kubevirt) VirtualMachines(namespace string) VirtualMachineInterface {
+	return NewVirtualMachineClient(c.cl, namespace)
+}
+
+func (c *kubevirt) VirtualMachineInstances(namespace string) VirtualMachineInstanceInterface {
+	return NewVirtualMachineInstanceClient(c.cl, c.cfg, namespace)
+}
+
+func (c *kubevirt) VirtualMachineInstanceMigrations(namespace string) VirtualMachineInstanceMigrationInterface {
+	return NewVirtualMachineInstanceMigrationClient(c.cl, namespace)
+}
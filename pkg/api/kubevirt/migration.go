@@ -0,0 +1,87 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package kubevirt
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+func NewVirtualMachineInstanceMigrationClient(cl rest.Interface, namespace string) VirtualMachineInstanceMigrationInterface {
+	return &virtualMachineInstanceMigrations{cl: cl, ns: namespace}
+}
+
+type virtualMachineInstanceMigrations struct {
+	cl rest.Interface
+	ns string
+}
+
+type VirtualMachineInstanceMigrationGetter interface {
+	VirtualMachineInstanceMigrations(namespace string) VirtualMachineInstanceMigrationInterface
+}
+
+type VirtualMachineInstanceMigrationInterface interface {
+	Create(obj *v1.VirtualMachineInstanceMigration) (*v1.VirtualMachineInstanceMigration, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	Get(name string) (*v1.VirtualMachineInstanceMigration, error)
+	List() (*v1.VirtualMachineInstanceMigrationList, error)
+}
+
+func (vmimc *virtualMachineInstanceMigrations) Create(obj *v1.VirtualMachineInstanceMigration) (*v1.VirtualMachineInstanceMigration, error) {
+	var result v1.VirtualMachineInstanceMigration
+	err := vmimc.cl.Post().
+		Namespace(vmimc.ns).Resource("virtualmachineinstancemigrations").
+		Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (vmimc *virtualMachineInstanceMigrations) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return vmimc.cl.Delete().
+		Namespace(vmimc.ns).Resource("virtualmachineinstancemigrations").
+		Name(name).Body(options).Do().
+		Error()
+}
+
+func (vmimc *virtualMachineInstanceMigrations) Get(name string) (*v1.VirtualMachineInstanceMigration, error) {
+	var result v1.VirtualMachineInstanceMigration
+	err := vmimc.cl.Get().
+		Namespace(vmimc.ns).Resource("virtualmachineinstancemigrations").
+		Name(name).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (vmimc *virtualMachineInstanceMigrations) List() (*v1.VirtualMachineInstanceMigrationList, error) {
+	var result v1.VirtualMachineInstanceMigrationList
+	err := vmimc.cl.Get().
+		Namespace(vmimc.ns).Resource("virtualmachineinstancemigrations").
+		Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
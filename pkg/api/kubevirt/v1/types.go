@@ -0,0 +1,462 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apimachinery/announced"
+	"k8s.io/apimachinery/pkg/apimachinery/registered"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	cdiv1 "github.com/dicot-project/dicot-api/pkg/api/cdi/v1"
+)
+
+// The subset of the KubeVirt API used to run servers. Only
+// the fields Dicot sets or reads are declared.
+
+const GroupName = "kubevirt.io"
+
+var GroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha3"}
+
+// Operations such as pausing an instance are exposed by the
+// KubeVirt API server as subresources in a separate group
+const SubresourceGroupName = "subresources.kubevirt.io"
+
+var SubresourceGroupVersion = schema.GroupVersion{Group: SubresourceGroupName, Version: "v1alpha3"}
+
+func Resource(resource string) schema.GroupResource {
+	return GroupVersion.WithResource(resource).GroupResource()
+}
+
+var (
+	groupFactoryRegistry = make(announced.APIGroupFactoryRegistry)
+	registry             = registered.NewOrDie(GroupVersion.String())
+)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(GroupVersion,
+		&VirtualMachine{},
+		&VirtualMachineList{},
+		&VirtualMachineInstance{},
+		&VirtualMachineInstanceList{},
+		&VirtualMachineInstanceMigration{},
+		&VirtualMachineInstanceMigrationList{},
+	)
+	return nil
+}
+
+func init() {
+	SchemeBuilder := runtime.NewSchemeBuilder(addKnownTypes)
+	if err := announced.NewGroupMetaFactory(
+		&announced.GroupMetaFactoryArgs{
+			GroupName:              GroupName,
+			VersionPreferenceOrder: []string{GroupVersion.Version},
+			ImportPrefix:           "dicot.io/dicot/pkg/api/kubevirt/v1",
+		},
+		announced.VersionToSchemeFunc{
+			GroupVersion.Version: SchemeBuilder.AddToScheme,
+		},
+	).Announce(groupFactoryRegistry).RegisterAndEnable(registry, scheme.Scheme); err != nil {
+		panic(err)
+	}
+}
+
+type VirtualMachine struct {
+	metav1.TypeMeta `json:",inline"`
+	ObjectMeta      metav1.ObjectMeta    `json:"metadata,omitempty"`
+	Spec            VirtualMachineSpec   `json:"spec"`
+	Status          VirtualMachineStatus `json:"status,omitempty"`
+}
+
+type VirtualMachineList struct {
+	metav1.TypeMeta `json:",inline"`
+	ListMeta        metav1.ListMeta  `json:"metadata,omitempty"`
+	Items           []VirtualMachine `json:"items"`
+}
+
+type VirtualMachineSpec struct {
+	Running             *bool                               `json:"running,omitempty"`
+	Template            *VirtualMachineInstanceTemplateSpec `json:"template"`
+	DataVolumeTemplates []cdiv1.DataVolume                  `json:"dataVolumeTemplates,omitempty"`
+}
+
+type VirtualMachineStatus struct {
+	Created bool `json:"created,omitempty"`
+	Ready   bool `json:"ready,omitempty"`
+}
+
+type VirtualMachineInstanceTemplateSpec struct {
+	ObjectMeta metav1.ObjectMeta          `json:"metadata,omitempty"`
+	Spec       VirtualMachineInstanceSpec `json:"spec,omitempty"`
+}
+
+type VirtualMachineInstance struct {
+	metav1.TypeMeta `json:",inline"`
+	ObjectMeta      metav1.ObjectMeta            `json:"metadata,omitempty"`
+	Spec            VirtualMachineInstanceSpec   `json:"spec"`
+	Status          VirtualMachineInstanceStatus `json:"status,omitempty"`
+}
+
+type VirtualMachineInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	ListMeta        metav1.ListMeta          `json:"metadata,omitempty"`
+	Items           []VirtualMachineInstance `json:"items"`
+}
+
+type VirtualMachineInstanceSpec struct {
+	Domain                        DomainSpec         `json:"domain"`
+	NodeSelector                  map[string]string  `json:"nodeSelector,omitempty"`
+	Affinity                      *k8sv1.Affinity    `json:"affinity,omitempty"`
+	TerminationGracePeriodSeconds *int64             `json:"terminationGracePeriodSeconds,omitempty"`
+	Volumes                       []Volume           `json:"volumes,omitempty"`
+	Networks                      []Network          `json:"networks,omitempty"`
+	Hostname                      string             `json:"hostname,omitempty"`
+	AccessCredentials             []AccessCredential `json:"accessCredentials,omitempty"`
+}
+
+type DomainSpec struct {
+	Resources ResourceRequirements `json:"resources,omitempty"`
+	CPU       *CPU                 `json:"cpu,omitempty"`
+	Memory    *Memory              `json:"memory,omitempty"`
+	Devices   Devices              `json:"devices"`
+}
+
+type ResourceRequirements struct {
+	Requests k8sv1.ResourceList `json:"requests,omitempty"`
+	Limits   k8sv1.ResourceList `json:"limits,omitempty"`
+}
+
+type CPU struct {
+	Cores                 uint32 `json:"cores,omitempty"`
+	Sockets               uint32 `json:"sockets,omitempty"`
+	Threads               uint32 `json:"threads,omitempty"`
+	Model                 string `json:"model,omitempty"`
+	DedicatedCPUPlacement bool   `json:"dedicatedCpuPlacement,omitempty"`
+}
+
+type Memory struct {
+	Hugepages *Hugepages         `json:"hugepages,omitempty"`
+	Guest     *resource.Quantity `json:"guest,omitempty"`
+}
+
+type Hugepages struct {
+	PageSize string `json:"pageSize,omitempty"`
+}
+
+type Devices struct {
+	Disks      []Disk      `json:"disks,omitempty"`
+	Interfaces []Interface `json:"interfaces,omitempty"`
+	Watchdog   *Watchdog   `json:"watchdog,omitempty"`
+	Rng        *Rng        `json:"rng,omitempty"`
+}
+
+type Watchdog struct {
+	Name     string            `json:"name"`
+	I6300ESB *I6300ESBWatchdog `json:"i6300esb,omitempty"`
+}
+
+type WatchdogAction string
+
+const (
+	WatchdogActionPoweroff WatchdogAction = "poweroff"
+	WatchdogActionReset    WatchdogAction = "reset"
+	WatchdogActionShutdown WatchdogAction = "shutdown"
+)
+
+type I6300ESBWatchdog struct {
+	Action WatchdogAction `json:"action,omitempty"`
+}
+
+type Rng struct {
+}
+
+type Disk struct {
+	Name      string       `json:"name"`
+	BootOrder *uint        `json:"bootOrder,omitempty"`
+	Serial    string       `json:"serial,omitempty"`
+	Disk      *DiskTarget  `json:"disk,omitempty"`
+	CDRom     *CDRomTarget `json:"cdrom,omitempty"`
+}
+
+type DiskTarget struct {
+	Bus      string `json:"bus,omitempty"`
+	ReadOnly bool   `json:"readonly,omitempty"`
+}
+
+type CDRomTarget struct {
+	Bus      string `json:"bus,omitempty"`
+	ReadOnly *bool  `json:"readonly,omitempty"`
+}
+
+type Interface struct {
+	Name       string               `json:"name"`
+	Model      string               `json:"model,omitempty"`
+	MacAddress string               `json:"macAddress,omitempty"`
+	Bridge     *InterfaceBridge     `json:"bridge,omitempty"`
+	Masquerade *InterfaceMasquerade `json:"masquerade,omitempty"`
+	State      InterfaceState       `json:"state,omitempty"`
+}
+
+type InterfaceState string
+
+// Setting an interface of a running instance absent requests
+// that it is hot-unplugged
+const InterfaceStateAbsent InterfaceState = "absent"
+
+type InterfaceBridge struct {
+}
+
+type InterfaceMasquerade struct {
+}
+
+type Network struct {
+	Name   string         `json:"name"`
+	Pod    *PodNetwork    `json:"pod,omitempty"`
+	Multus *MultusNetwork `json:"multus,omitempty"`
+}
+
+type PodNetwork struct {
+}
+
+type MultusNetwork struct {
+	NetworkName string `json:"networkName"`
+}
+
+type AccessCredential struct {
+	UserPassword *UserPasswordAccessCredential `json:"userPassword,omitempty"`
+}
+
+type UserPasswordAccessCredential struct {
+	Source            UserPasswordAccessCredentialSource            `json:"source"`
+	PropagationMethod UserPasswordAccessCredentialPropagationMethod `json:"propagationMethod"`
+}
+
+type UserPasswordAccessCredentialSource struct {
+	Secret *AccessCredentialSecretSource `json:"secret,omitempty"`
+}
+
+type AccessCredentialSecretSource struct {
+	SecretName string `json:"secretName"`
+}
+
+type UserPasswordAccessCredentialPropagationMethod struct {
+	QemuGuestAgent *QemuGuestAgentUserPasswordAccessCredentialPropagation `json:"qemuGuestAgent,omitempty"`
+}
+
+type QemuGuestAgentUserPasswordAccessCredentialPropagation struct {
+}
+
+type Volume struct {
+	Name                  string                                   `json:"name"`
+	ContainerDisk         *ContainerDiskSource                     `json:"containerDisk,omitempty"`
+	CloudInitNoCloud      *CloudInitNoCloudSource                  `json:"cloudInitNoCloud,omitempty"`
+	CloudInitConfigDrive  *CloudInitConfigDriveSource              `json:"cloudInitConfigDrive,omitempty"`
+	DataVolume            *DataVolumeSource                        `json:"dataVolume,omitempty"`
+	PersistentVolumeClaim *k8sv1.PersistentVolumeClaimVolumeSource `json:"persistentVolumeClaim,omitempty"`
+	EmptyDisk             *EmptyDiskSource                         `json:"emptyDisk,omitempty"`
+}
+
+type ContainerDiskSource struct {
+	Image string `json:"image"`
+}
+
+type CloudInitNoCloudSource struct {
+	UserDataSecretRef *k8sv1.LocalObjectReference `json:"secretRef,omitempty"`
+	UserDataBase64    string                      `json:"userDataBase64,omitempty"`
+	UserData          string                      `json:"userData,omitempty"`
+	NetworkData       string                      `json:"networkData,omitempty"`
+}
+
+type CloudInitConfigDriveSource struct {
+	UserDataSecretRef *k8sv1.LocalObjectReference `json:"secretRef,omitempty"`
+	UserDataBase64    string                      `json:"userDataBase64,omitempty"`
+	UserData          string                      `json:"userData,omitempty"`
+	NetworkData       string                      `json:"networkData,omitempty"`
+}
+
+type DataVolumeSource struct {
+	Name string `json:"name"`
+}
+
+type EmptyDiskSource struct {
+	Capacity resource.Quantity `json:"capacity"`
+}
+
+type VirtualMachineInstancePhase string
+
+const (
+	VmPhaseUnset VirtualMachineInstancePhase = ""
+	Pending      VirtualMachineInstancePhase = "Pending"
+	Scheduling   VirtualMachineInstancePhase = "Scheduling"
+	Scheduled    VirtualMachineInstancePhase = "Scheduled"
+	Running      VirtualMachineInstancePhase = "Running"
+	Succeeded    VirtualMachineInstancePhase = "Succeeded"
+	Failed       VirtualMachineInstancePhase = "Failed"
+	Unknown      VirtualMachineInstancePhase = "Unknown"
+)
+
+type VirtualMachineInstanceStatus struct {
+	NodeName       string                                   `json:"nodeName,omitempty"`
+	Phase          VirtualMachineInstancePhase              `json:"phase,omitempty"`
+	Interfaces     []VirtualMachineInstanceNetworkInterface `json:"interfaces,omitempty"`
+	Conditions     []VirtualMachineInstanceCondition        `json:"conditions,omitempty"`
+	MigrationState *VirtualMachineInstanceMigrationState    `json:"migrationState,omitempty"`
+}
+
+// The state of the most recent migration of an instance
+type VirtualMachineInstanceMigrationState struct {
+	StartTimestamp *metav1.Time `json:"startTimestamp,omitempty"`
+	EndTimestamp   *metav1.Time `json:"endTimestamp,omitempty"`
+	TargetNode     string       `json:"targetNode,omitempty"`
+	SourceNode     string       `json:"sourceNode,omitempty"`
+	Completed      bool         `json:"completed,omitempty"`
+	Failed         bool         `json:"failed,omitempty"`
+	MigrationUID   types.UID    `json:"migrationUid,omitempty"`
+}
+
+type VirtualMachineInstanceConditionType string
+
+const (
+	VirtualMachineInstanceReady  VirtualMachineInstanceConditionType = "Ready"
+	VirtualMachineInstancePaused VirtualMachineInstanceConditionType = "Paused"
+)
+
+type VirtualMachineInstanceCondition struct {
+	Type    VirtualMachineInstanceConditionType `json:"type"`
+	Status  k8sv1.ConditionStatus               `json:"status"`
+	Reason  string                              `json:"reason,omitempty"`
+	Message string                              `json:"message,omitempty"`
+}
+
+type VirtualMachineInstanceMigration struct {
+	metav1.TypeMeta `json:",inline"`
+	ObjectMeta      metav1.ObjectMeta                     `json:"metadata,omitempty"`
+	Spec            VirtualMachineInstanceMigrationSpec   `json:"spec"`
+	Status          VirtualMachineInstanceMigrationStatus `json:"status,omitempty"`
+}
+
+type VirtualMachineInstanceMigrationList struct {
+	metav1.TypeMeta `json:",inline"`
+	ListMeta        metav1.ListMeta                   `json:"metadata,omitempty"`
+	Items           []VirtualMachineInstanceMigration `json:"items"`
+}
+
+type VirtualMachineInstanceMigrationSpec struct {
+	VMIName string `json:"vmiName,omitempty"`
+	// Further restricts the nodes the instance may move to,
+	// without changing the placement of the instance itself
+	AddedNodeSelector map[string]string `json:"addedNodeSelector,omitempty"`
+}
+
+type VirtualMachineInstanceMigrationPhase string
+
+const (
+	MigrationPhaseUnset      VirtualMachineInstanceMigrationPhase = ""
+	MigrationPending         VirtualMachineInstanceMigrationPhase = "Pending"
+	MigrationScheduling      VirtualMachineInstanceMigrationPhase = "Scheduling"
+	MigrationScheduled       VirtualMachineInstanceMigrationPhase = "Scheduled"
+	MigrationPreparingTarget VirtualMachineInstanceMigrationPhase = "PreparingTarget"
+	MigrationTargetReady     VirtualMachineInstanceMigrationPhase = "TargetReady"
+	MigrationRunning         VirtualMachineInstanceMigrationPhase = "Running"
+	MigrationSucceeded       VirtualMachineInstanceMigrationPhase = "Succeeded"
+	MigrationFailed          VirtualMachineInstanceMigrationPhase = "Failed"
+)
+
+type VirtualMachineInstanceMigrationStatus struct {
+	Phase VirtualMachineInstanceMigrationPhase `json:"phase,omitempty"`
+}
+
+type VirtualMachineInstanceNetworkInterface struct {
+	Name string   `json:"name,omitempty"`
+	IP   string   `json:"ipAddress,omitempty"`
+	IPs  []string `json:"ipAddresses,omitempty"`
+	MAC  string   `json:"mac,omitempty"`
+}
+
+// IsPaused reports whether the guest CPUs of the instance are
+// currently paused
+func (v *VirtualMachineInstance) IsPaused() bool {
+	for _, cond := range v.Status.Conditions {
+		if cond.Type == VirtualMachineInstancePaused {
+			return cond.Status == k8sv1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// IsMigrating reports whether the instance is currently being
+// migrated to another node
+func (v *VirtualMachineInstance) IsMigrating() bool {
+	state := v.Status.MigrationState
+	return state != nil && !state.Completed && !state.Failed
+}
+
+func (v *VirtualMachine) GetObjectKind() schema.ObjectKind {
+	return &v.TypeMeta
+}
+
+func (v *VirtualMachine) GetObjectMeta() metav1.Object {
+	return &v.ObjectMeta
+}
+
+func (vl *VirtualMachineList) GetObjectKind() schema.ObjectKind {
+	return &vl.TypeMeta
+}
+
+func (vl *VirtualMachineList) GetListMeta() metav1.List {
+	return &vl.ListMeta
+}
+
+func (v *VirtualMachineInstance) GetObjectKind() schema.ObjectKind {
+	return &v.TypeMeta
+}
+
+func (v *VirtualMachineInstance) GetObjectMeta() metav1.Object {
+	return &v.ObjectMeta
+}
+
+func (vl *VirtualMachineInstanceList) GetObjectKind() schema.ObjectKind {
+	return &vl.TypeMeta
+}
+
+func (vl *VirtualMachineInstanceList) GetListMeta() metav1.List {
+	return &vl.ListMeta
+}
+
+func (v *VirtualMachineInstanceMigration) GetObjectKind() schema.ObjectKind {
+	return &v.TypeMeta
+}
+
+func (v *VirtualMachineInstanceMigration) GetObjectMeta() metav1.Object {
+	return &v.ObjectMeta
+}
+
+func (vl *VirtualMachineInstanceMigrationList) GetObjectKind() schema.ObjectKind {
+	return &vl.TypeMeta
+}
+
+func (vl *VirtualMachineInstanceMigrationList) GetListMeta() metav1.List {
+	return &vl.ListMeta
+}
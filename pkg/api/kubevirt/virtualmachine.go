@@ -0,0 +1,131 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package kubevirt
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+func NewVirtualMachineClient(cl rest.Interface, namespace string) VirtualMachineInterface {
+	return &virtualMachines{cl: cl, ns: namespace}
+}
+
+type virtualMachines struct {
+	cl rest.Interface
+	ns string
+}
+
+type VirtualMachineGetter interface {
+	VirtualMachines(namespace string) VirtualMachineInterface
+}
+
+type VirtualMachineInterface interface {
+	Create(obj *v1.VirtualMachine) (*v1.VirtualMachine, error)
+	Update(obj *v1.VirtualMachine) (*v1.VirtualMachine, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	Get(name string) (*v1.VirtualMachine, error)
+	Exists(name string) (bool, error)
+	List() (*v1.VirtualMachineList, error)
+	NewListWatch() *cache.ListWatch
+	Restart(name string) error
+}
+
+func (vmc *virtualMachines) Create(obj *v1.VirtualMachine) (*v1.VirtualMachine, error) {
+	var result v1.VirtualMachine
+	err := vmc.cl.Post().
+		Namespace(vmc.ns).Resource("virtualmachines").
+		Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (vmc *virtualMachines) Update(obj *v1.VirtualMachine) (*v1.VirtualMachine, error) {
+	var result v1.VirtualMachine
+	name := obj.GetObjectMeta().GetName()
+	err := vmc.cl.Put().
+		Namespace(vmc.ns).Resource("virtualmachines").
+		Name(name).Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (vmc *virtualMachines) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return vmc.cl.Delete().
+		Namespace(vmc.ns).Resource("virtualmachines").
+		Name(name).Body(options).Do().
+		Error()
+}
+
+func (vmc *virtualMachines) Get(name string) (*v1.VirtualMachine, error) {
+	var result v1.VirtualMachine
+	err := vmc.cl.Get().
+		Namespace(vmc.ns).Resource("virtualmachines").
+		Name(name).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (vmc *virtualMachines) Exists(name string) (bool, error) {
+	_, err := vmc.Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (vmc *virtualMachines) List() (*v1.VirtualMachineList, error) {
+	var result v1.VirtualMachineList
+	err := vmc.cl.Get().
+		Namespace(vmc.ns).Resource("virtualmachines").
+		Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (vmc *virtualMachines) NewListWatch() *cache.ListWatch {
+	return cache.NewListWatchFromClient(vmc.cl, "virtualmachines", vmc.ns, fields.Everything())
+}
+
+func (vmc *virtualMachines) Restart(name string) error {
+	return vmc.cl.Put().
+		AbsPath("/apis", v1.SubresourceGroupVersion.Group, v1.SubresourceGroupVersion.Version,
+			"namespaces", vmc.ns, "virtualmachines", name, "restart").
+		Do().Error()
+}
@@ -0,0 +1,216 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package kubevirt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+
+	"golang.org/x/net/websocket"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+func NewVirtualMachineInstanceClient(cl rest.Interface, cfg *rest.Config, namespace string) VirtualMachineInstanceInterface {
+	return &virtualMachineInstances{cl: cl, cfg: cfg, ns: namespace}
+}
+
+type virtualMachineInstances struct {
+	cl  rest.Interface
+	cfg *rest.Config
+	ns  string
+}
+
+type VirtualMachineInstanceGetter interface {
+	VirtualMachineInstances(namespace string) VirtualMachineInstanceInterface
+}
+
+type VirtualMachineInstanceInterface interface {
+	Create(obj *v1.VirtualMachineInstance) (*v1.VirtualMachineInstance, error)
+	Update(obj *v1.VirtualMachineInstance) (*v1.VirtualMachineInstance, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	Get(name string) (*v1.VirtualMachineInstance, error)
+	Exists(name string) (bool, error)
+	List() (*v1.VirtualMachineInstanceList, error)
+	NewListWatch() *cache.ListWatch
+	Pause(name string) error
+	Unpause(name string) error
+	VNC(name string) (io.ReadWriteCloser, error)
+	SerialConsole(name string) (io.ReadWriteCloser, error)
+}
+
+func (vmic *virtualMachineInstances) Create(obj *v1.VirtualMachineInstance) (*v1.VirtualMachineInstance, error) {
+	var result v1.VirtualMachineInstance
+	err := vmic.cl.Post().
+		Namespace(vmic.ns).Resource("virtualmachineinstances").
+		Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (vmic *virtualMachineInstances) Update(obj *v1.VirtualMachineInstance) (*v1.VirtualMachineInstance, error) {
+	var result v1.VirtualMachineInstance
+	name := obj.GetObjectMeta().GetName()
+	err := vmic.cl.Put().
+		Namespace(vmic.ns).Resource("virtualmachineinstances").
+		Name(name).Body(obj).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (vmic *virtualMachineInstances) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return vmic.cl.Delete().
+		Namespace(vmic.ns).Resource("virtualmachineinstances").
+		Name(name).Body(options).Do().
+		Error()
+}
+
+func (vmic *virtualMachineInstances) Get(name string) (*v1.VirtualMachineInstance, error) {
+	var result v1.VirtualMachineInstance
+	err := vmic.cl.Get().
+		Namespace(vmic.ns).Resource("virtualmachineinstances").
+		Name(name).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (vmic *virtualMachineInstances) Exists(name string) (bool, error) {
+	_, err := vmic.Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (vmic *virtualMachineInstances) List() (*v1.VirtualMachineInstanceList, error) {
+	var result v1.VirtualMachineInstanceList
+	err := vmic.cl.Get().
+		Namespace(vmic.ns).Resource("virtualmachineinstances").
+		Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+
+}
+
+func (vmic *virtualMachineInstances) NewListWatch() *cache.ListWatch {
+	return cache.NewListWatchFromClient(vmic.cl, "virtualmachineinstances", vmic.ns, fields.Everything())
+}
+
+func (vmic *virtualMachineInstances) subresource(name, subresource string) error {
+	return vmic.cl.Put().
+		AbsPath("/apis", v1.SubresourceGroupVersion.Group, v1.SubresourceGroupVersion.Version,
+			"namespaces", vmic.ns, "virtualmachineinstances", name, subresource).
+		Do().Error()
+}
+
+func (vmic *virtualMachineInstances) Pause(name string) error {
+	return vmic.subresource(name, "pause")
+}
+
+func (vmic *virtualMachineInstances) Unpause(name string) error {
+	return vmic.subresource(name, "unpause")
+}
+
+// stream connects to a websocket subresource of an instance,
+// which carries the raw console data in binary frames
+func (vmic *virtualMachineInstances) stream(name, subresource string) (io.ReadWriteCloser, error) {
+	host := vmic.cfg.Host
+	if host == "" {
+		return nil, fmt.Errorf("No API server host configured")
+	}
+	base, err := url.Parse(host)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		base, err = url.Parse("https://" + host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	origin := *base
+	target := *base
+	switch base.Scheme {
+	case "https":
+		target.Scheme = "wss"
+	case "http":
+		target.Scheme = "ws"
+	default:
+		return nil, fmt.Errorf("Unexpected API server scheme %s", base.Scheme)
+	}
+	target.Path = fmt.Sprintf("/apis/%s/%s/namespaces/%s/virtualmachineinstances/%s/%s",
+		v1.SubresourceGroupVersion.Group, v1.SubresourceGroupVersion.Version,
+		vmic.ns, name, subresource)
+
+	wscfg, err := websocket.NewConfig(target.String(), origin.String())
+	if err != nil {
+		return nil, err
+	}
+	wscfg.Protocol = []string{"plain.kubevirt.io"}
+
+	if target.Scheme == "wss" {
+		wscfg.TlsConfig, err = rest.TLSConfigFor(vmic.cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if vmic.cfg.BearerToken != "" {
+		wscfg.Header.Set("Authorization", "Bearer "+vmic.cfg.BearerToken)
+	} else if vmic.cfg.Username != "" {
+		creds := base64.StdEncoding.EncodeToString(
+			[]byte(vmic.cfg.Username + ":" + vmic.cfg.Password))
+		wscfg.Header.Set("Authorization", "Basic "+creds)
+	}
+
+	conn, err := websocket.DialConfig(wscfg)
+	if err != nil {
+		return nil, err
+	}
+	conn.PayloadType = websocket.BinaryFrame
+
+	return conn, nil
+}
+
+func (vmic *virtualMachineInstances) VNC(name string) (io.ReadWriteCloser, error) {
+	return vmic.stream(name, "vnc")
+}
+
+func (vmic *virtualMachineInstances) SerialConsole(name string) (io.ReadWriteCloser, error) {
+	return vmic.stream(name, "console")
+}
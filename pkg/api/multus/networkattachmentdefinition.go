@@ -0,0 +1,68 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package multus
+
+import (
+	"k8s.io/client-go/rest"
+
+	"github.com/dicot-project/dicot-api/pkg/api/multus/v1"
+)
+
+func NewNetworkAttachmentDefinitionClient(cl rest.Interface, namespace string) NetworkAttachmentDefinitionInterface {
+	return &networkAttachmentDefinitions{cl: cl, ns: namespace}
+}
+
+type networkAttachmentDefinitions struct {
+	cl rest.Interface
+	ns string
+}
+
+type NetworkAttachmentDefinitionGetter interface {
+	NetworkAttachmentDefinitions(namespace string) NetworkAttachmentDefinitionInterface
+}
+
+// Networks are defined by the cluster administrator, so only
+// read access is needed
+type NetworkAttachmentDefinitionInterface interface {
+	Get(name string) (*v1.NetworkAttachmentDefinition, error)
+	List() (*v1.NetworkAttachmentDefinitionList, error)
+}
+
+func (nc *networkAttachmentDefinitions) Get(name string) (*v1.NetworkAttachmentDefinition, error) {
+	var result v1.NetworkAttachmentDefinition
+	err := nc.cl.Get().
+		Namespace(nc.ns).Resource("network-attachment-definitions").
+		Name(name).Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (nc *networkAttachmentDefinitions) List() (*v1.NetworkAttachmentDefinitionList, error) {
+	var result v1.NetworkAttachmentDefinitionList
+	err := nc.cl.Get().
+		Namespace(nc.ns).Resource("network-attachment-definitions").
+		Do().Into(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,103 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/apimachinery/announced"
+	"k8s.io/apimachinery/pkg/apimachinery/registered"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// The subset of the Kubernetes Network Plumbing Working Group
+// API, implemented by Multus, which describes the networks
+// servers can be attached to in addition to the pod network
+
+const GroupName = "k8s.cni.cncf.io"
+
+var GroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1"}
+
+func Resource(resource string) schema.GroupResource {
+	return GroupVersion.WithResource(resource).GroupResource()
+}
+
+var (
+	groupFactoryRegistry = make(announced.APIGroupFactoryRegistry)
+	registry             = registered.NewOrDie(GroupVersion.String())
+)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(GroupVersion,
+		&NetworkAttachmentDefinition{},
+		&NetworkAttachmentDefinitionList{},
+	)
+	return nil
+}
+
+func init() {
+	SchemeBuilder := runtime.NewSchemeBuilder(addKnownTypes)
+	if err := announced.NewGroupMetaFactory(
+		&announced.GroupMetaFactoryArgs{
+			GroupName:              GroupName,
+			VersionPreferenceOrder: []string{GroupVersion.Version},
+			ImportPrefix:           "dicot.io/dicot/pkg/api/multus/v1",
+		},
+		announced.VersionToSchemeFunc{
+			GroupVersion.Version: SchemeBuilder.AddToScheme,
+		},
+	).Announce(groupFactoryRegistry).RegisterAndEnable(registry, scheme.Scheme); err != nil {
+		panic(err)
+	}
+}
+
+type NetworkAttachmentDefinition struct {
+	metav1.TypeMeta `json:",inline"`
+	ObjectMeta      metav1.ObjectMeta               `json:"metadata,omitempty"`
+	Spec            NetworkAttachmentDefinitionSpec `json:"spec"`
+}
+
+type NetworkAttachmentDefinitionList struct {
+	metav1.TypeMeta `json:",inline"`
+	ListMeta        metav1.ListMeta               `json:"metadata,omitempty"`
+	Items           []NetworkAttachmentDefinition `json:"items"`
+}
+
+type NetworkAttachmentDefinitionSpec struct {
+	// The CNI plugin configuration, as JSON
+	Config string `json:"config,omitempty"`
+}
+
+func (v *NetworkAttachmentDefinition) GetObjectKind() schema.ObjectKind {
+	return &v.TypeMeta
+}
+
+func (v *NetworkAttachmentDefinition) GetObjectMeta() metav1.Object {
+	return &v.ObjectMeta
+}
+
+func (vl *NetworkAttachmentDefinitionList) GetObjectKind() schema.ObjectKind {
+	return &vl.TypeMeta
+}
+
+func (vl *NetworkAttachmentDefinitionList) GetListMeta() metav1.List {
+	return &vl.ListMeta
+}
@@ -0,0 +1,56 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package audit
+
+import (
+	"github.com/golang/glog"
+)
+
+type Auditor interface {
+	Emit(ev *Event)
+}
+
+type auditor struct {
+	observer Resource
+	sinks    []Sink
+}
+
+// NewAuditor creates an auditor which sends events to all
+// of the sinks. It is valid to pass no sinks at all, in
+// which case events are discarded.
+func NewAuditor(observer Resource, sinks ...Sink) Auditor {
+	return &auditor{
+		observer: observer,
+		sinks:    sinks,
+	}
+}
+
+// Emit delivers the event to each sink. A sink failing
+// must not cause the audited operation to fail, so errors
+// are only logged
+func (a *auditor) Emit(ev *Event) {
+	ev.Observer = a.observer
+	for _, sink := range a.sinks {
+		err := sink.Emit(ev)
+		if err != nil {
+			glog.Errorf("Unable to emit audit event %s: %s", ev.ID, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package audit
+
+import (
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// Events are formatted according to the DMTF Cloud Auditing Data
+// Federation (CADF) specification, as used by OpenStack Keystone
+const (
+	EventTypeURI = "http://schemas.dmtf.org/cloud/audit/1.0/event"
+
+	EventTypeActivity = "activity"
+
+	ActionAuthenticate = "authenticate"
+	ActionCreate       = "create"
+	ActionUpdate       = "update"
+	ActionDelete       = "delete"
+	ActionRead         = "read"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+
+	TypeURIUser    = "data/security/account/user"
+	TypeURIProject = "data/security/project"
+	TypeURIDomain  = "data/security/domain"
+	TypeURIGroup   = "data/security/group"
+	TypeURIRole    = "data/security/role"
+	TypeURIToken   = "data/security/credential/token"
+	TypeURIService = "service/security"
+	TypeURIServer  = "compute/machine"
+)
+
+type Host struct {
+	Address string `json:"address,omitempty"`
+	Agent   string `json:"agent,omitempty"`
+}
+
+type Resource struct {
+	TypeURI   string `json:"typeURI"`
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	DomainID  string `json:"domain_id,omitempty"`
+	ProjectID string `json:"project_id,omitempty"`
+	Host      *Host  `json:"host,omitempty"`
+}
+
+type Reason struct {
+	ReasonType string `json:"reasonType"`
+	ReasonCode string `json:"reasonCode"`
+}
+
+type Event struct {
+	TypeURI   string   `json:"typeURI"`
+	ID        string   `json:"id"`
+	EventType string   `json:"eventType"`
+	EventTime string   `json:"eventTime"`
+	Action    string   `json:"action"`
+	Outcome   string   `json:"outcome"`
+	Initiator Resource `json:"initiator"`
+	Target    Resource `json:"target"`
+	Observer  Resource `json:"observer"`
+	Reason    *Reason  `json:"reason,omitempty"`
+	RequestID string   `json:"request_id,omitempty"`
+}
+
+func NewEvent(action, outcome string, initiator, target Resource) *Event {
+	return &Event{
+		TypeURI:   EventTypeURI,
+		ID:        string(uuid.NewUUID()),
+		EventType: EventTypeActivity,
+		EventTime: time.Now().UTC().Format(time.RFC3339Nano),
+		Action:    action,
+		Outcome:   outcome,
+		Initiator: initiator,
+		Target:    target,
+	}
+}
+
+// SetReason records the HTTP status code that determined the
+// outcome of the action
+func (ev *Event) SetReason(status int) {
+	ev.Reason = &Reason{
+		ReasonType: "HTTP",
+		ReasonCode: strconv.Itoa(status),
+	}
+}
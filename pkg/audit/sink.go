@@ -0,0 +1,191 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+)
+
+type Sink interface {
+	Emit(ev *Event) error
+}
+
+// NewSink creates a sink from a specification string, which
+// is one of
+//
+//	stdout
+//	file:/path/to/audit.log
+//	webhook:http://example.com/audit
+//	kube:namespace
+func NewSink(spec string, k8sClient k8s.Interface) (Sink, error) {
+	bits := strings.SplitN(spec, ":", 2)
+	arg := ""
+	if len(bits) == 2 {
+		arg = bits[1]
+	}
+
+	switch bits[0] {
+	case "stdout":
+		return NewWriterSink(os.Stdout), nil
+	case "file":
+		if arg == "" {
+			return nil, fmt.Errorf("Missing path for audit file sink")
+		}
+		return NewFileSink(arg)
+	case "webhook":
+		if arg == "" {
+			return nil, fmt.Errorf("Missing URL for audit webhook sink")
+		}
+		return NewWebhookSink(arg), nil
+	case "kube":
+		if arg == "" {
+			return nil, fmt.Errorf("Missing namespace for audit kube sink")
+		}
+		return NewKubeSink(k8sClient, arg), nil
+	default:
+		return nil, fmt.Errorf("Unknown audit sink type '%s'", bits[0])
+	}
+}
+
+type writerSink struct {
+	lock   sync.Mutex
+	writer io.Writer
+}
+
+// NewWriterSink emits each event as a single line of JSON
+func NewWriterSink(writer io.Writer) Sink {
+	return &writerSink{
+		writer: writer,
+	}
+}
+
+func NewFileSink(path string) (Sink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterSink(file), nil
+}
+
+func (s *writerSink) Emit(ev *Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err = s.writer.Write(data)
+	return err
+}
+
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink POSTs each event as a JSON document to a URL
+func NewWebhookSink(url string) Sink {
+	return &webhookSink{
+		url: url,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (s *webhookSink) Emit(ev *Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.client.Post(s.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("Audit webhook returned status %d", res.StatusCode)
+	}
+	return nil
+}
+
+type kubeSink struct {
+	client    k8s.Interface
+	namespace string
+}
+
+// NewKubeSink records each event as a Kubernetes Event object
+// in a namespace, with the CADF document as the message
+func NewKubeSink(client k8s.Interface, namespace string) Sink {
+	return &kubeSink{
+		client:    client,
+		namespace: namespace,
+	}
+}
+
+func (s *kubeSink) Emit(ev *Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	evtype := k8sv1.EventTypeNormal
+	if ev.Outcome != OutcomeSuccess {
+		evtype = k8sv1.EventTypeWarning
+	}
+
+	now := metav1.Now()
+	kev := &k8sv1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "audit-" + ev.ID,
+		},
+		InvolvedObject: k8sv1.ObjectReference{
+			Kind:      ev.Target.TypeURI,
+			Namespace: s.namespace,
+			Name:      ev.Target.ID,
+		},
+		Reason:         ev.Action,
+		Message:        string(data),
+		Source:         k8sv1.EventSource{Component: "dicot-api"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           evtype,
+	}
+
+	_, err = s.client.CoreV1().Events(s.namespace).Create(kev)
+	return err
+}
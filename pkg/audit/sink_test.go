@@ -0,0 +1,78 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+
+	ev := NewEvent(ActionCreate, OutcomeSuccess,
+		Resource{TypeURIUser, "user1", "admin", "", "", nil},
+		Resource{TypeURIProject, "proj1", "demo", "", "", nil})
+	ev.RequestID = "req-1"
+
+	for i := 0; i < 2; i++ {
+		err := sink.Emit(ev)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines of output, got %d", len(lines))
+	}
+
+	var got Event
+	err := json.Unmarshal([]byte(lines[0]), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.TypeURI != EventTypeURI || got.Action != ActionCreate ||
+		got.Outcome != OutcomeSuccess || got.RequestID != "req-1" ||
+		got.Initiator.ID != "user1" || got.Target.ID != "proj1" {
+		t.Errorf("Unexpected event content %s", lines[0])
+	}
+}
+
+func TestNewSinkInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"syslog",
+		"file",
+		"webhook:",
+		"kube",
+	}
+
+	for _, spec := range specs {
+		_, err := NewSink(spec, nil)
+		if err == nil {
+			t.Errorf("Expected error for sink spec '%s'", spec)
+		}
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/uuid"
 
 	"github.com/dicot-project/dicot-api/pkg/api/identity"
@@ -44,6 +45,8 @@ const (
 
 	ClaimScopeDomain  = "github.com/dicot-project/scope/domain"
 	ClaimScopeProject = "github.com/dicot-project/scope/project"
+	ClaimRoles        = "github.com/dicot-project/roles"
+	ClaimConsole      = "github.com/dicot-project/console"
 
 	Issuer = "github.com/dicot-project/api"
 )
@@ -52,6 +55,7 @@ type TokenManager interface {
 	NewToken() *Token
 	SignToken(tok *Token) (string, error)
 	ValidateToken(toksig string) (*Token, error)
+	RevokeToken(tok *Token) error
 }
 
 type Token struct {
@@ -60,6 +64,16 @@ type Token struct {
 	Expiry  time.Time
 	Subject TokenSubject
 	Scope   TokenScope
+	Roles   []string
+	Console *TokenConsole
+}
+
+// TokenConsole restricts a token to granting access to a single
+// server console. Such tokens are not valid for API requests.
+type TokenConsole struct {
+	Type      string
+	Namespace string
+	Server    string
 }
 
 type TokenSubject struct {
@@ -116,6 +130,14 @@ func (tm *tokenManager) SignToken(tok *Token) (string, error) {
 		ClaimSubject:      tok.Subject.DomainName + "/" + tok.Subject.UserName,
 		ClaimScopeDomain:  tok.Scope.DomainName,
 		ClaimScopeProject: tok.Scope.ProjectName,
+		ClaimRoles:        tok.Roles,
+	}
+	if tok.Console != nil {
+		claims[ClaimConsole] = map[string]string{
+			"type":      tok.Console.Type,
+			"namespace": tok.Console.Namespace,
+			"server":    tok.Console.Server,
+		}
 	}
 
 	var jtok *jwt.Token
@@ -174,6 +196,16 @@ func validateTokenKey(toksig string, key interface{}) (*Token, error) {
 		return nil, fmt.Errorf("Unexpected id claim type")
 	}
 
+	issued, ok := claims[ClaimIssued].(float64)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected issued claim type")
+	}
+
+	expiry, ok := claims[ClaimExpiry].(float64)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected expiry claim type")
+	}
+
 	subject, ok := claims[ClaimSubject].(string)
 	if !ok {
 		return nil, fmt.Errorf("Unexpected subject claim type")
@@ -189,13 +221,51 @@ func validateTokenKey(toksig string, key interface{}) (*Token, error) {
 		return nil, fmt.Errorf("Unexpected project claim type")
 	}
 
+	roles := []string{}
+	if claim, ok := claims[ClaimRoles]; ok && claim != nil {
+		vals, ok := claim.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Unexpected roles claim type")
+		}
+		for _, val := range vals {
+			role, ok := val.(string)
+			if !ok {
+				return nil, fmt.Errorf("Unexpected role claim type")
+			}
+			roles = append(roles, role)
+		}
+	}
+
+	var console *TokenConsole
+	if claim, ok := claims[ClaimConsole]; ok && claim != nil {
+		vals, ok := claim.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Unexpected console claim type")
+		}
+		console = &TokenConsole{}
+		fields := map[string]*string{
+			"type":      &console.Type,
+			"namespace": &console.Namespace,
+			"server":    &console.Server,
+		}
+		for name, field := range fields {
+			val, ok := vals[name].(string)
+			if !ok {
+				return nil, fmt.Errorf("Unexpected console %s claim type", name)
+			}
+			*field = val
+		}
+	}
+
 	subjectBits := strings.Split(subject, "/")
 	if len(subjectBits) != 2 {
 		return nil, fmt.Errorf("Unexpected subject format %s", subject)
 	}
 
 	return &Token{
-		ID: id,
+		ID:     id,
+		Issued: time.Unix(int64(issued), 0),
+		Expiry: time.Unix(int64(expiry), 0),
 		Subject: TokenSubject{
 			DomainName: subjectBits[0],
 			UserName:   subjectBits[1],
@@ -204,6 +274,8 @@ func validateTokenKey(toksig string, key interface{}) (*Token, error) {
 			DomainName:  domain,
 			ProjectName: project,
 		},
+		Roles:   roles,
+		Console: console,
 	}, nil
 }
 
@@ -226,3 +298,15 @@ func (tm *tokenManager) ValidateToken(toksig string) (*Token, error) {
 
 	return nil, firstErr
 }
+
+func (tm *tokenManager) RevokeToken(tok *Token) error {
+	revoked := &v1.RevokedToken{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: tok.ID,
+		},
+		Expiry: tok.Expiry.Format(time.RFC3339),
+	}
+
+	_, err := tm.tokenClient.Create(revoked)
+	return err
+}
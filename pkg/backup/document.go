@@ -0,0 +1,89 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package backup
+
+// DocumentVersion is incremented whenever an incompatible
+// change is made to the document format
+const DocumentVersion = 1
+
+// Document is a portable representation of all identity
+// objects. Objects refer to each other using the IDs they
+// had at the time of export.
+type Document struct {
+	Version       int            `json:"version"`
+	Domains       []Domain       `json:"domains"`
+	Projects      []Project      `json:"projects"`
+	Users         []User         `json:"users"`
+	Groups        []Group        `json:"groups"`
+	Roles         []Role         `json:"roles"`
+	RevokedTokens []RevokedToken `json:"revoked_tokens"`
+}
+
+type Domain struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type Project struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DomainID    string `json:"domain_id"`
+	ParentID    string `json:"parent_id"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type User struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	DomainID         string `json:"domain_id"`
+	DefaultProjectID string `json:"default_project_id"`
+	Description      string `json:"description"`
+	EMail            string `json:"email"`
+	Enabled          bool   `json:"enabled"`
+	PasswordHash     string `json:"password_hash,omitempty"`
+}
+
+type Group struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	DomainID    string   `json:"domain_id"`
+	Description string   `json:"description"`
+	UserIDs     []string `json:"user_ids"`
+}
+
+type Role struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	DomainID    string   `json:"domain_id"`
+	Description string   `json:"description"`
+	Implies     []string `json:"implies"`
+}
+
+type RevokedToken struct {
+	ID     string `json:"id"`
+	Expiry string `json:"expiry"`
+}
+
+// IDMap records the ID each object was given on import,
+// indexed by the ID in the document
+type IDMap map[string]string
@@ -0,0 +1,155 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package backup
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+
+	"github.com/dicot-project/dicot-api/pkg/api"
+	"github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+)
+
+func exportRoles(doc *Document, client api.Interface, namespace string) error {
+	roles, err := client.Identity().Roles(namespace).List()
+	if err != nil {
+		return err
+	}
+	for _, role := range roles.Items {
+		doc.Roles = append(doc.Roles, Role{
+			ID:          role.GetID(),
+			Name:        role.Spec.Name,
+			DomainID:    role.Spec.DomainID,
+			Description: role.Spec.Description,
+			Implies:     role.Spec.Implies,
+		})
+	}
+	return nil
+}
+
+func exportDomain(doc *Document, client api.Interface, k8sClient k8s.Interface, domain *v1.Project) error {
+	doc.Domains = append(doc.Domains, Domain{
+		ID:          domain.GetID(),
+		Name:        domain.GetName(),
+		Description: domain.Spec.Description,
+		Enabled:     domain.Spec.Enabled,
+	})
+
+	namespace := domain.Spec.Namespace
+
+	projects, err := client.Identity().Projects(namespace).List()
+	if err != nil {
+		return err
+	}
+	for _, project := range projects.Items {
+		doc.Projects = append(doc.Projects, Project{
+			ID:          project.GetID(),
+			Name:        project.GetName(),
+			DomainID:    project.Spec.Domain,
+			ParentID:    project.Spec.Parent,
+			Description: project.Spec.Description,
+			Enabled:     project.Spec.Enabled,
+		})
+	}
+
+	users, err := client.Identity().Users(namespace).List()
+	if err != nil {
+		return err
+	}
+	for _, user := range users.Items {
+		info := User{
+			ID:               user.GetID(),
+			Name:             user.Spec.Name,
+			DomainID:         user.Spec.DomainID,
+			DefaultProjectID: user.Spec.DefaultProjectID,
+			Description:      user.Spec.Description,
+			EMail:            user.Spec.EMail,
+			Enabled:          user.Spec.Enabled,
+		}
+		if user.Spec.Password.SecretRef != "" {
+			secret, err := k8sClient.CoreV1().Secrets(namespace).Get(
+				user.Spec.Password.SecretRef, metav1.GetOptions{})
+			if err == nil {
+				info.PasswordHash = string(secret.Data["password"])
+			}
+		}
+		doc.Users = append(doc.Users, info)
+	}
+
+	groups, err := client.Identity().Groups(namespace).List()
+	if err != nil {
+		return err
+	}
+	for _, group := range groups.Items {
+		doc.Groups = append(doc.Groups, Group{
+			ID:          group.GetID(),
+			Name:        group.Spec.Name,
+			DomainID:    group.Spec.DomainID,
+			Description: group.Spec.Description,
+			UserIDs:     group.Spec.UserIDs,
+		})
+	}
+
+	return exportRoles(doc, client, namespace)
+}
+
+// Export collects all identity objects into a document,
+// including the password hashes of users. The document is
+// thus sensitive and must be stored accordingly.
+func Export(client api.Interface, k8sClient k8s.Interface) (*Document, error) {
+	doc := &Document{
+		Version:       DocumentVersion,
+		Domains:       []Domain{},
+		Projects:      []Project{},
+		Users:         []User{},
+		Groups:        []Group{},
+		Roles:         []Role{},
+		RevokedTokens: []RevokedToken{},
+	}
+
+	domains, err := client.Identity().Projects(v1.NamespaceSystem).List()
+	if err != nil {
+		return nil, err
+	}
+	for idx := range domains.Items {
+		err = exportDomain(doc, client, k8sClient, &domains.Items[idx])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = exportRoles(doc, client, v1.NamespaceSystem)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := client.Identity().RevokedTokens(v1.NamespaceSystem).List()
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens.Items {
+		doc.RevokedTokens = append(doc.RevokedTokens, RevokedToken{
+			ID:     token.ObjectMeta.Name,
+			Expiry: token.Expiry,
+		})
+	}
+
+	return doc, nil
+}
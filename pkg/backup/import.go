@@ -0,0 +1,441 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package backup
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api"
+	"github.com/dicot-project/dicot-api/pkg/api/identity"
+	"github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+)
+
+type importer struct {
+	client    api.Interface
+	k8sClient k8s.Interface
+	ids       IDMap
+	domains   map[string]*v1.Project
+}
+
+// Import creates the objects described by the document, keeping
+// the IDs they had at export. Objects which already exist, as
+// identified by name, are left as they are, so may have different
+// IDs. References between objects are rewritten to use the IDs of
+// the objects actually used, and the mapping from old to new IDs
+// returned.
+func Import(client api.Interface, k8sClient k8s.Interface, doc *Document) (IDMap, error) {
+	if doc.Version != DocumentVersion {
+		return nil, fmt.Errorf("Unsupported document version %d", doc.Version)
+	}
+
+	imp := &importer{
+		client:    client,
+		k8sClient: k8sClient,
+		ids:       make(IDMap),
+		domains:   make(map[string]*v1.Project),
+	}
+
+	err := imp.ensureNamespace(v1.NamespaceSystem)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, domain := range doc.Domains {
+		err = imp.importDomain(&domain)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = imp.importProjects(doc.Projects)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range doc.Users {
+		err = imp.importUser(&user)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, group := range doc.Groups {
+		err = imp.importGroup(&group)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = imp.importRoles(doc.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range doc.RevokedTokens {
+		err = imp.importRevokedToken(&token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return imp.ids, nil
+}
+
+func (imp *importer) mapID(id string) string {
+	if id == "" {
+		return ""
+	}
+	newID, ok := imp.ids[id]
+	if !ok {
+		return ""
+	}
+	return newID
+}
+
+func (imp *importer) mapIDs(ids []string) []string {
+	res := []string{}
+	for _, id := range ids {
+		if newID := imp.mapID(id); newID != "" {
+			res = append(res, newID)
+		}
+	}
+	return res
+}
+
+func (imp *importer) getDomain(id string) (*v1.Project, error) {
+	domain, ok := imp.domains[id]
+	if !ok {
+		return nil, fmt.Errorf("Unknown domain %s", id)
+	}
+	return domain, nil
+}
+
+func (imp *importer) ensureNamespace(name string) error {
+	ns := &k8sv1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	_, err := imp.k8sClient.CoreV1().Namespaces().Create(ns)
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+func (imp *importer) importDomain(info *Domain) error {
+	clnt := imp.client.Identity().Projects(v1.NamespaceSystem)
+
+	name := identity.SanitizeName(info.Name)
+	domain, err := clnt.Get(name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+
+		domain = &v1.Project{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: v1.ProjectSpec{
+				ID:          info.ID,
+				Name:        info.Name,
+				Enabled:     info.Enabled,
+				Description: info.Description,
+				Namespace:   identity.FormatDomainNamespace(name),
+			},
+		}
+		domain, err = clnt.Create(domain)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = imp.ensureNamespace(domain.Spec.Namespace)
+	if err != nil {
+		return err
+	}
+
+	imp.ids[info.ID] = domain.GetID()
+	imp.domains[info.ID] = domain
+	return nil
+}
+
+// importProjects creates projects in an order which ensures
+// that each project's parent exists before the project itself
+func (imp *importer) importProjects(projects []Project) error {
+	todo := projects
+	for len(todo) > 0 {
+		pending := []Project{}
+		for _, info := range todo {
+			if _, ok := imp.ids[info.ParentID]; info.ParentID != "" && !ok {
+				pending = append(pending, info)
+				continue
+			}
+
+			err := imp.importProject(&info)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(pending) == len(todo) {
+			return fmt.Errorf("Unable to resolve parent of project %s", pending[0].Name)
+		}
+		todo = pending
+	}
+	return nil
+}
+
+func (imp *importer) importProject(info *Project) error {
+	domain, err := imp.getDomain(info.DomainID)
+	if err != nil {
+		return err
+	}
+
+	clnt := imp.client.Identity().Projects(domain.Spec.Namespace)
+
+	name := identity.SanitizeName(info.Name)
+	project, err := clnt.Get(name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+
+		parentID := imp.mapID(info.ParentID)
+		if parentID == "" {
+			parentID = domain.GetID()
+		}
+
+		project = &v1.Project{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: v1.ProjectSpec{
+				ID:          info.ID,
+				Name:        info.Name,
+				Enabled:     info.Enabled,
+				Description: info.Description,
+				Parent:      parentID,
+				Domain:      domain.GetID(),
+				Namespace:   identity.FormatProjectNamespace(domain.ObjectMeta.Name, name),
+			},
+		}
+		project, err = clnt.Create(project)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = imp.ensureNamespace(project.Spec.Namespace)
+	if err != nil {
+		return err
+	}
+
+	imp.ids[info.ID] = project.GetID()
+	return nil
+}
+
+func (imp *importer) importUser(info *User) error {
+	domain, err := imp.getDomain(info.DomainID)
+	if err != nil {
+		return err
+	}
+
+	clnt := imp.client.Identity().Users(domain.Spec.Namespace)
+
+	name := identity.SanitizeName(info.Name)
+	user, err := clnt.Get(name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+
+		user = &v1.User{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: v1.UserSpec{
+				ID:               info.ID,
+				Name:             info.Name,
+				Enabled:          info.Enabled,
+				DomainID:         domain.GetID(),
+				DefaultProjectID: imp.mapID(info.DefaultProjectID),
+				Description:      info.Description,
+				EMail:            info.EMail,
+				Password: v1.UserPassword{
+					SecretRef: "user-password-" + name,
+				},
+			},
+		}
+		user, err = clnt.Create(user)
+		if err != nil {
+			return err
+		}
+	}
+
+	imp.ids[info.ID] = user.GetID()
+
+	// Users without a password hash (eg from Keystone, whose hash
+	// formats are not supported) must have their password reset
+	if info.PasswordHash == "" {
+		return nil
+	}
+
+	secret := &k8sv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: user.Spec.Password.SecretRef,
+		},
+		Type: "identity.dicot.io/user-password",
+		Data: map[string][]byte{
+			"password": []byte(info.PasswordHash),
+		},
+	}
+	_, err = imp.k8sClient.CoreV1().Secrets(domain.Spec.Namespace).Create(secret)
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+func (imp *importer) importGroup(info *Group) error {
+	domain, err := imp.getDomain(info.DomainID)
+	if err != nil {
+		return err
+	}
+
+	clnt := imp.client.Identity().Groups(domain.Spec.Namespace)
+
+	name := identity.SanitizeName(info.Name)
+	group, err := clnt.Get(name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+
+		group = &v1.Group{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: v1.GroupSpec{
+				ID:          info.ID,
+				Name:        info.Name,
+				DomainID:    domain.GetID(),
+				Description: info.Description,
+				UserIDs:     imp.mapIDs(info.UserIDs),
+			},
+		}
+		group, err = clnt.Create(group)
+		if err != nil {
+			return err
+		}
+	}
+
+	imp.ids[info.ID] = group.GetID()
+	return nil
+}
+
+// importRoles creates all roles before setting up inference
+// rules, since the rules may refer to roles in any order
+func (imp *importer) importRoles(roles []Role) error {
+	created := make(map[string]*v1.Role)
+	for _, info := range roles {
+		namespace := v1.NamespaceSystem
+		domainID := ""
+		if info.DomainID != "" {
+			domain, err := imp.getDomain(info.DomainID)
+			if err != nil {
+				return err
+			}
+			namespace = domain.Spec.Namespace
+			domainID = domain.GetID()
+		}
+
+		clnt := imp.client.Identity().Roles(namespace)
+
+		name := identity.SanitizeName(info.Name)
+		role, err := clnt.Get(name)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return err
+			}
+
+			role = &v1.Role{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+				},
+				Spec: v1.RoleSpec{
+					ID:          info.ID,
+					Name:        info.Name,
+					DomainID:    domainID,
+					Description: info.Description,
+					Implies:     []string{},
+				},
+			}
+			role, err = clnt.Create(role)
+			if err != nil {
+				return err
+			}
+			created[info.ID] = role
+		}
+
+		imp.ids[info.ID] = role.GetID()
+	}
+
+	for _, info := range roles {
+		role, ok := created[info.ID]
+		if !ok || len(info.Implies) == 0 {
+			continue
+		}
+
+		role.Spec.Implies = imp.mapIDs(info.Implies)
+		_, err := imp.client.Identity().Roles(role.ObjectMeta.Namespace).Update(role)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (imp *importer) importRevokedToken(info *RevokedToken) error {
+	expiry, err := time.Parse(time.RFC3339, info.Expiry)
+	if err == nil && expiry.Before(time.Now()) {
+		return nil
+	}
+
+	token := &v1.RevokedToken{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: info.ID,
+		},
+		Expiry: info.Expiry,
+	}
+	_, err = imp.client.Identity().RevokedTokens(v1.NamespaceSystem).Create(token)
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package backup
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/dicot-project/dicot-api/pkg/api"
+	"github.com/dicot-project/dicot-api/pkg/api/identity"
+	"github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+)
+
+// fakeProjects stores projects in memory, implementing only the
+// methods used by the importer
+type fakeProjects struct {
+	identity.ProjectInterface
+	ns    string
+	store map[string]map[string]*v1.Project
+}
+
+func (fp *fakeProjects) Get(name string) (*v1.Project, error) {
+	project, ok := fp.store[fp.ns][name]
+	if !ok {
+		return nil, errors.NewNotFound(schema.GroupResource{Resource: "projects"}, name)
+	}
+	return project, nil
+}
+
+func (fp *fakeProjects) Create(obj *v1.Project) (*v1.Project, error) {
+	if _, ok := fp.store[fp.ns]; !ok {
+		fp.store[fp.ns] = map[string]*v1.Project{}
+	}
+	if _, ok := fp.store[fp.ns][obj.ObjectMeta.Name]; ok {
+		return nil, errors.NewAlreadyExists(schema.GroupResource{Resource: "projects"}, obj.ObjectMeta.Name)
+	}
+	project := *obj
+	project.ObjectMeta.Namespace = fp.ns
+	fp.store[fp.ns][obj.ObjectMeta.Name] = &project
+	return &project, nil
+}
+
+type fakeIdentity struct {
+	identity.Interface
+	projects map[string]map[string]*v1.Project
+}
+
+func (fi *fakeIdentity) Projects(namespace string) identity.ProjectInterface {
+	return &fakeProjects{ns: namespace, store: fi.projects}
+}
+
+type fakeClientset struct {
+	api.Interface
+	identity *fakeIdentity
+}
+
+func (fc *fakeClientset) Identity() identity.Interface {
+	return fc.identity
+}
+
+func TestImportKeystoneNames(t *testing.T) {
+	client := &fakeClientset{
+		identity: &fakeIdentity{
+			projects: map[string]map[string]*v1.Project{},
+		},
+	}
+	k8sClient := fake.NewSimpleClientset()
+
+	doc := &Document{
+		Version: DocumentVersion,
+		Domains: []Domain{
+			Domain{ID: "default", Name: "Default", Enabled: true},
+		},
+		Projects: []Project{
+			Project{ID: "p1", Name: "Demo Project", DomainID: "default", Enabled: true},
+		},
+	}
+
+	ids, err := Import(client, k8sClient, doc)
+	if err != nil {
+		t.Fatalf("Unable to import: %s", err)
+	}
+	if ids["default"] != "default" || ids["p1"] != "p1" {
+		t.Errorf("Unexpected IDs %v", ids)
+	}
+
+	tests := []struct {
+		Namespace string
+		Object    string
+		Name      string
+	}{
+		{v1.NamespaceSystem, "default", "Default"},
+		{"dicot-domain-default", "demo-project", "Demo Project"},
+	}
+	for _, test := range tests {
+		project, ok := client.identity.projects[test.Namespace][test.Object]
+		if !ok {
+			t.Errorf("Expected %s in namespace %s got %v", test.Object, test.Namespace, client.identity.projects)
+			continue
+		}
+		if errs := validation.IsDNS1123Subdomain(project.ObjectMeta.Name); len(errs) != 0 {
+			t.Errorf("Invalid object name %s: %v", project.ObjectMeta.Name, errs)
+		}
+		if project.Spec.Name != test.Name || project.GetName() != test.Name {
+			t.Errorf("Expected name '%s' got '%s'", test.Name, project.Spec.Name)
+		}
+
+		ns := project.Spec.Namespace
+		if errs := validation.IsDNS1123Label(ns); len(errs) != 0 {
+			t.Errorf("Invalid namespace name %s: %v", ns, errs)
+		}
+		_, err = k8sClient.CoreV1().Namespaces().Get(ns, metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("Namespace %s was not created: %s", ns, err)
+		}
+	}
+
+	// Importing again finds the objects by their sanitized names
+	_, err = Import(client, k8sClient, doc)
+	if err != nil {
+		t.Errorf("Unable to import again: %s", err)
+	}
+}
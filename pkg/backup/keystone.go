@@ -0,0 +1,186 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package backup
+
+import (
+	"encoding/json"
+)
+
+// KeystoneDump describes identity data extracted from Keystone,
+// with each list in the format returned by the corresponding
+// Keystone v3 API call (eg GET /v3/projects). Group membership
+// is given by the IDs of the users in each group.
+type KeystoneDump struct {
+	Domains        []KeystoneDomain        `json:"domains"`
+	Projects       []KeystoneProject       `json:"projects"`
+	Users          []KeystoneUser          `json:"users"`
+	Groups         []KeystoneGroup         `json:"groups"`
+	Roles          []KeystoneRole          `json:"roles"`
+	RoleInferences []KeystoneRoleInference `json:"role_inferences"`
+}
+
+type KeystoneDomain struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type KeystoneProject struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DomainID    string `json:"domain_id"`
+	ParentID    string `json:"parent_id"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	IsDomain    bool   `json:"is_domain"`
+}
+
+type KeystoneUser struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	DomainID         string `json:"domain_id"`
+	DefaultProjectID string `json:"default_project_id"`
+	Description      string `json:"description"`
+	EMail            string `json:"email"`
+	Enabled          bool   `json:"enabled"`
+}
+
+type KeystoneGroup struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	DomainID    string   `json:"domain_id"`
+	Description string   `json:"description"`
+	Users       []string `json:"users"`
+}
+
+type KeystoneRole struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	DomainID    *string `json:"domain_id"`
+	Description string  `json:"description"`
+}
+
+type KeystoneRoleRef struct {
+	ID string `json:"id"`
+}
+
+type KeystoneRoleInference struct {
+	PriorRole KeystoneRoleRef   `json:"prior_role"`
+	Implies   []KeystoneRoleRef `json:"implies"`
+}
+
+// ParseKeystone converts a Keystone dump into a document which
+// can be imported. Keystone password hashes are not compatible
+// with Dicot, so users are imported without passwords.
+func ParseKeystone(data []byte) (*Document, error) {
+	var dump KeystoneDump
+	err := json.Unmarshal(data, &dump)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{
+		Version:       DocumentVersion,
+		Domains:       []Domain{},
+		Projects:      []Project{},
+		Users:         []User{},
+		Groups:        []Group{},
+		Roles:         []Role{},
+		RevokedTokens: []RevokedToken{},
+	}
+
+	for _, domain := range dump.Domains {
+		doc.Domains = append(doc.Domains, Domain{
+			ID:          domain.ID,
+			Name:        domain.Name,
+			Description: domain.Description,
+			Enabled:     domain.Enabled,
+		})
+	}
+
+	for _, project := range dump.Projects {
+		// Keystone represents domains as projects too, but
+		// these duplicate the entries in the domain list
+		if project.IsDomain {
+			continue
+		}
+		doc.Projects = append(doc.Projects, Project{
+			ID:          project.ID,
+			Name:        project.Name,
+			DomainID:    project.DomainID,
+			ParentID:    project.ParentID,
+			Description: project.Description,
+			Enabled:     project.Enabled,
+		})
+	}
+
+	for _, user := range dump.Users {
+		doc.Users = append(doc.Users, User{
+			ID:               user.ID,
+			Name:             user.Name,
+			DomainID:         user.DomainID,
+			DefaultProjectID: user.DefaultProjectID,
+			Description:      user.Description,
+			EMail:            user.EMail,
+			Enabled:          user.Enabled,
+		})
+	}
+
+	for _, group := range dump.Groups {
+		userIDs := group.Users
+		if userIDs == nil {
+			userIDs = []string{}
+		}
+		doc.Groups = append(doc.Groups, Group{
+			ID:          group.ID,
+			Name:        group.Name,
+			DomainID:    group.DomainID,
+			Description: group.Description,
+			UserIDs:     userIDs,
+		})
+	}
+
+	implies := make(map[string][]string)
+	for _, inference := range dump.RoleInferences {
+		for _, implied := range inference.Implies {
+			implies[inference.PriorRole.ID] = append(
+				implies[inference.PriorRole.ID], implied.ID)
+		}
+	}
+
+	for _, role := range dump.Roles {
+		info := Role{
+			ID:          role.ID,
+			Name:        role.Name,
+			Description: role.Description,
+			Implies:     implies[role.ID],
+		}
+		if role.DomainID != nil {
+			info.DomainID = *role.DomainID
+		}
+		if info.Implies == nil {
+			info.Implies = []string{}
+		}
+		doc.Roles = append(doc.Roles, info)
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,104 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package backup
+
+import (
+	"reflect"
+	"testing"
+)
+
+const keystoneDump = `{
+  "domains": [
+    {"id": "default", "name": "Default", "description": "The default domain", "enabled": true}
+  ],
+  "projects": [
+    {"id": "default", "name": "Default", "domain_id": null, "is_domain": true, "enabled": true},
+    {"id": "p1", "name": "admin", "domain_id": "default", "parent_id": "default", "enabled": true},
+    {"id": "p2", "name": "demo", "domain_id": "default", "parent_id": "p1", "enabled": false}
+  ],
+  "users": [
+    {"id": "u1", "name": "admin", "domain_id": "default", "default_project_id": "p1", "enabled": true}
+  ],
+  "groups": [
+    {"id": "g1", "name": "admins", "domain_id": "default", "users": ["u1"]},
+    {"id": "g2", "name": "empty", "domain_id": "default"}
+  ],
+  "roles": [
+    {"id": "r1", "name": "admin", "domain_id": null},
+    {"id": "r2", "name": "member", "domain_id": null},
+    {"id": "r3", "name": "operator", "domain_id": "default"}
+  ],
+  "role_inferences": [
+    {"prior_role": {"id": "r1"}, "implies": [{"id": "r2"}]},
+    {"prior_role": {"id": "r3"}, "implies": [{"id": "r2"}]}
+  ]
+}`
+
+func TestParseKeystone(t *testing.T) {
+	doc, err := ParseKeystone([]byte(keystoneDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Version != DocumentVersion {
+		t.Errorf("Unexpected version %d", doc.Version)
+	}
+
+	if len(doc.Domains) != 1 || doc.Domains[0].Name != "Default" {
+		t.Errorf("Unexpected domains %v", doc.Domains)
+	}
+
+	expectProjects := []Project{
+		Project{ID: "p1", Name: "admin", DomainID: "default", ParentID: "default", Enabled: true},
+		Project{ID: "p2", Name: "demo", DomainID: "default", ParentID: "p1", Enabled: false},
+	}
+	if !reflect.DeepEqual(doc.Projects, expectProjects) {
+		t.Errorf("Expected projects %v got %v", expectProjects, doc.Projects)
+	}
+
+	if len(doc.Users) != 1 || doc.Users[0].DefaultProjectID != "p1" ||
+		doc.Users[0].PasswordHash != "" {
+		t.Errorf("Unexpected users %v", doc.Users)
+	}
+
+	expectGroups := []Group{
+		Group{ID: "g1", Name: "admins", DomainID: "default", UserIDs: []string{"u1"}},
+		Group{ID: "g2", Name: "empty", DomainID: "default", UserIDs: []string{}},
+	}
+	if !reflect.DeepEqual(doc.Groups, expectGroups) {
+		t.Errorf("Expected groups %v got %v", expectGroups, doc.Groups)
+	}
+
+	expectRoles := []Role{
+		Role{ID: "r1", Name: "admin", Implies: []string{"r2"}},
+		Role{ID: "r2", Name: "member", Implies: []string{}},
+		Role{ID: "r3", Name: "operator", DomainID: "default", Implies: []string{"r2"}},
+	}
+	if !reflect.DeepEqual(doc.Roles, expectRoles) {
+		t.Errorf("Expected roles %v got %v", expectRoles, doc.Roles)
+	}
+}
+
+func TestParseKeystoneInvalid(t *testing.T) {
+	_, err := ParseKeystone([]byte("{\"domains\": 1}"))
+	if err == nil {
+		t.Errorf("Expected error parsing invalid dump")
+	}
+}
@@ -0,0 +1,208 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package cloudinit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// Config describes the data to be provided to a guest through
+// cloud-init. The settings Dicot manages itself are rendered as
+// a cloud-config document, and any user data supplied by the
+// user is attached alongside it, so that neither replaces the
+// other.
+type Config struct {
+	Hostname          string
+	SSHAuthorizedKeys []string
+	Password          string
+	Files             []File
+	UserData          []byte
+}
+
+type File struct {
+	Path    string
+	Content []byte
+}
+
+type cloudConfig struct {
+	Hostname          string           `json:"hostname,omitempty"`
+	SSHAuthorizedKeys []string         `json:"ssh_authorized_keys,omitempty"`
+	Password          string           `json:"password,omitempty"`
+	Chpasswd          *cloudChpasswd   `json:"chpasswd,omitempty"`
+	SSHPwauth         *bool            `json:"ssh_pwauth,omitempty"`
+	WriteFiles        []cloudWriteFile `json:"write_files,omitempty"`
+}
+
+type cloudChpasswd struct {
+	Expire bool `json:"expire"`
+}
+
+type cloudWriteFile struct {
+	Path        string `json:"path"`
+	Encoding    string `json:"encoding"`
+	Content     string `json:"content"`
+	Permissions string `json:"permissions"`
+}
+
+const (
+	cloudConfigHeader = "#cloud-config\n"
+
+	ContentTypeCloudConfig = "text/cloud-config"
+	ContentTypeShellScript = "text/x-shellscript"
+	ContentTypeIncludeURL  = "text/x-include-url"
+	ContentTypeBoothook    = "text/cloud-boothook"
+	ContentTypePartHandler = "text/part-handler"
+	ContentTypeUpstartJob  = "text/upstart-job"
+	ContentTypeMultipart   = "multipart/mixed"
+	ContentTypePlain       = "text/plain"
+)
+
+var contentTypePrefixes = []struct {
+	Prefix      string
+	ContentType string
+}{
+	{"#cloud-config", ContentTypeCloudConfig},
+	{"#!", ContentTypeShellScript},
+	{"#include", ContentTypeIncludeURL},
+	{"#cloud-boothook", ContentTypeBoothook},
+	{"#part-handler", ContentTypePartHandler},
+	{"#upstart-job", ContentTypeUpstartJob},
+}
+
+// UserDataContentType identifies the type of a user data
+// document from its leading characters, in the same way that
+// cloud-init itself does
+func UserDataContentType(data []byte) string {
+	for _, prefix := range contentTypePrefixes {
+		if bytes.HasPrefix(data, []byte(prefix.Prefix)) {
+			return prefix.ContentType
+		}
+	}
+	return ContentTypePlain
+}
+
+// CloudConfig renders the settings as a cloud-config document.
+// JSON is a subset of YAML, so is used to avoid any need for
+// quoting of user provided values.
+func (cfg *Config) CloudConfig() ([]byte, error) {
+	cc := cloudConfig{
+		Hostname:          cfg.Hostname,
+		SSHAuthorizedKeys: cfg.SSHAuthorizedKeys,
+	}
+
+	if cfg.Password != "" {
+		pwauth := true
+		cc.Password = cfg.Password
+		cc.Chpasswd = &cloudChpasswd{
+			Expire: false,
+		}
+		cc.SSHPwauth = &pwauth
+	}
+
+	for _, file := range cfg.Files {
+		cc.WriteFiles = append(cc.WriteFiles, cloudWriteFile{
+			Path:        file.Path,
+			Encoding:    "b64",
+			Content:     base64.StdEncoding.EncodeToString(file.Content),
+			Permissions: "0644",
+		})
+	}
+
+	data, err := json.MarshalIndent(cc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(cloudConfigHeader), append(data, '\n')...), nil
+}
+
+// Build renders the complete user data for the guest. Without
+// any user supplied data, this is just the cloud-config
+// document, otherwise a MIME multipart archive holding both.
+func (cfg *Config) Build() ([]byte, error) {
+	cc, err := cfg.CloudConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.UserData) == 0 {
+		return cc, nil
+	}
+
+	var body bytes.Buffer
+	mpw := multipart.NewWriter(&body)
+
+	err = writePart(mpw, ContentTypeCloudConfig, "dicot-cloud-config", cc)
+	if err != nil {
+		return nil, err
+	}
+	err = writePart(mpw, UserDataContentType(cfg.UserData), "user-data", cfg.UserData)
+	if err != nil {
+		return nil, err
+	}
+
+	err = mpw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var res bytes.Buffer
+	res.WriteString("Content-Type: " + ContentTypeMultipart + "; boundary=\"" + mpw.Boundary() + "\"\r\n")
+	res.WriteString("MIME-Version: 1.0\r\n")
+	res.WriteString("\r\n")
+	res.Write(body.Bytes())
+
+	return res.Bytes(), nil
+}
+
+func writePart(mpw *multipart.Writer, contentType, filename string, data []byte) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+"; charset=\"utf-8\"")
+	header.Set("MIME-Version", "1.0")
+	header.Set("Content-Transfer-Encoding", "base64")
+	header.Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+
+	part, err := mpw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		_, err = part.Write([]byte(encoded[:76] + "\r\n"))
+		if err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = part.Write([]byte(encoded + "\r\n"))
+	return err
+}
+
+// IsValidFilePath checks that a file to be injected into the
+// guest has an absolute path
+func IsValidFilePath(path string) bool {
+	return strings.HasPrefix(path, "/") && !strings.Contains(path, "\x00")
+}
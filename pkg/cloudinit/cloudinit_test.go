@@ -0,0 +1,209 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package cloudinit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"testing"
+)
+
+func TestUserDataContentType(t *testing.T) {
+	tests := []struct {
+		Data        string
+		ContentType string
+	}{
+		{"#cloud-config\nhostname: foo\n", ContentTypeCloudConfig},
+		{"#!/bin/sh\necho hello\n", ContentTypeShellScript},
+		{"#include\nhttp://example.com/\n", ContentTypeIncludeURL},
+		{"#cloud-boothook\n", ContentTypeBoothook},
+		{"hello world\n", ContentTypePlain},
+		{"", ContentTypePlain},
+	}
+
+	for _, test := range tests {
+		got := UserDataContentType([]byte(test.Data))
+		if got != test.ContentType {
+			t.Errorf("Expected content type %s for '%s' but got %s",
+				test.ContentType, test.Data, got)
+		}
+	}
+}
+
+func TestCloudConfig(t *testing.T) {
+	cfg := &Config{
+		Hostname:          "vm1",
+		SSHAuthorizedKeys: []string{"ssh-rsa AAAA fred@example.com"},
+		Password:          "s3cret",
+		Files: []File{
+			File{
+				Path:    "/etc/motd",
+				Content: []byte("Welcome \"user\"\n"),
+			},
+		},
+	}
+
+	data, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Cannot build user data %s", err)
+	}
+
+	if !bytes.HasPrefix(data, []byte("#cloud-config\n")) {
+		t.Fatalf("Expected cloud-config header in '%s'", string(data))
+	}
+
+	var cc cloudConfig
+	err = json.Unmarshal(data[len(cloudConfigHeader):], &cc)
+	if err != nil {
+		t.Fatalf("Cannot parse cloud config %s", err)
+	}
+
+	if cc.Hostname != "vm1" {
+		t.Errorf("Expected hostname vm1 but got %s", cc.Hostname)
+	}
+	if len(cc.SSHAuthorizedKeys) != 1 || cc.SSHAuthorizedKeys[0] != cfg.SSHAuthorizedKeys[0] {
+		t.Errorf("Expected SSH keys %s but got %s", cfg.SSHAuthorizedKeys, cc.SSHAuthorizedKeys)
+	}
+	if cc.Password != "s3cret" || cc.Chpasswd == nil || cc.Chpasswd.Expire {
+		t.Errorf("Expected non-expiring password to be set")
+	}
+	if len(cc.WriteFiles) != 1 {
+		t.Fatalf("Expected 1 file but got %d", len(cc.WriteFiles))
+	}
+	if cc.WriteFiles[0].Path != "/etc/motd" || cc.WriteFiles[0].Encoding != "b64" {
+		t.Errorf("Unexpected file %s", cc.WriteFiles[0])
+	}
+	content, err := base64.StdEncoding.DecodeString(cc.WriteFiles[0].Content)
+	if err != nil {
+		t.Fatalf("Cannot decode file content %s", err)
+	}
+	if string(content) != "Welcome \"user\"\n" {
+		t.Errorf("Unexpected file content '%s'", string(content))
+	}
+}
+
+func TestCloudConfigNoPassword(t *testing.T) {
+	cfg := &Config{}
+
+	data, err := cfg.CloudConfig()
+	if err != nil {
+		t.Fatalf("Cannot build cloud config %s", err)
+	}
+
+	var cc map[string]interface{}
+	err = json.Unmarshal(data[len(cloudConfigHeader):], &cc)
+	if err != nil {
+		t.Fatalf("Cannot parse cloud config %s", err)
+	}
+
+	if len(cc) != 0 {
+		t.Errorf("Expected empty cloud config but got %s", cc)
+	}
+}
+
+func TestMultipart(t *testing.T) {
+	script := []byte("#!/bin/sh\necho hello\n")
+	cfg := &Config{
+		SSHAuthorizedKeys: []string{"ssh-rsa AAAA fred@example.com"},
+		UserData:          script,
+	}
+
+	data, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Cannot build user data %s", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Cannot parse MIME message %s", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("Cannot parse content type %s", err)
+	}
+	if mediaType != ContentTypeMultipart {
+		t.Fatalf("Expected %s but got %s", ContentTypeMultipart, mediaType)
+	}
+
+	expect := []struct {
+		ContentType string
+		Prefix      string
+	}{
+		{ContentTypeCloudConfig, "#cloud-config\n"},
+		{ContentTypeShellScript, string(script)},
+	}
+
+	mpr := multipart.NewReader(msg.Body, params["boundary"])
+	for idx, want := range expect {
+		part, err := mpr.NextPart()
+		if err != nil {
+			t.Fatalf("Cannot read part %d %s", idx, err)
+		}
+
+		partType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			t.Fatalf("Cannot parse part content type %s", err)
+		}
+		if partType != want.ContentType {
+			t.Errorf("Expected part %d type %s but got %s", idx, want.ContentType, partType)
+		}
+
+		encoded, err := ioutil.ReadAll(part)
+		if err != nil {
+			t.Fatalf("Cannot read part %d %s", idx, err)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.Replace(encoded, []byte("\r\n"), []byte{}, -1)))
+		if err != nil {
+			t.Fatalf("Cannot decode part %d %s", idx, err)
+		}
+		if !bytes.HasPrefix(decoded, []byte(want.Prefix)) {
+			t.Errorf("Expected part %d to start with '%s' but got '%s'", idx, want.Prefix, string(decoded))
+		}
+	}
+
+	_, err = mpr.NextPart()
+	if err == nil {
+		t.Errorf("Expected only 2 parts")
+	}
+}
+
+func TestIsValidFilePath(t *testing.T) {
+	tests := []struct {
+		Path  string
+		Valid bool
+	}{
+		{"/etc/motd", true},
+		{"etc/motd", false},
+		{"", false},
+		{"/etc/\x00", false},
+	}
+
+	for _, test := range tests {
+		if IsValidFilePath(test.Path) != test.Valid {
+			t.Errorf("Expected path '%s' valid %t", test.Path, test.Valid)
+		}
+	}
+}
@@ -26,4 +26,5 @@ const (
 type KeyManager interface {
 	FingerPrint(pubkey string) (string, error)
 	CreateKeyPair(algorith string, bits int) (string, string, error)
+	Encrypt(pubkey string, data []byte) ([]byte, error)
 }
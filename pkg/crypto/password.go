@@ -37,6 +37,10 @@ const (
 	SCRYPT_SALT_SIZE       = 32
 )
 
+// Characters used in generated passwords, omitting those which
+// are easily confused with each other
+const passwordChars = "23456789abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+
 func makeHash(password []byte, salt []byte) ([]byte, error) {
 	return scrypt.Key(
 		password, salt,
@@ -46,6 +50,20 @@ func makeHash(password []byte, salt []byte) ([]byte, error) {
 		SCRYPT_OUTPUT_SIZE)
 }
 
+func GeneratePassword(length int) (string, error) {
+	buf := make([]byte, length)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	for i, b := range buf {
+		buf[i] = passwordChars[int(b)%len(passwordChars)]
+	}
+
+	return string(buf), nil
+}
+
 func HashPassword(password string) (string, error) {
 
 	salt := make([]byte, SCRYPT_SALT_SIZE)
@@ -20,6 +20,7 @@
 package crypto
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -92,3 +93,29 @@ func TestPasswordHash(t *testing.T) {
 		return
 	}
 }
+
+func TestGeneratePassword(t *testing.T) {
+	passwd, err := GeneratePassword(12)
+	if err != nil {
+		t.Fatalf("Cannot generate password %s", err)
+	}
+
+	if len(passwd) != 12 {
+		t.Errorf("Expected 12 characters but got %d", len(passwd))
+	}
+
+	for _, c := range passwd {
+		if !strings.ContainsRune(passwordChars, c) {
+			t.Errorf("Unexpected character '%c' in password", c)
+		}
+	}
+
+	other, err := GeneratePassword(12)
+	if err != nil {
+		t.Fatalf("Cannot generate password %s", err)
+	}
+
+	if passwd == other {
+		t.Errorf("Expected different passwords but got %s twice", passwd)
+	}
+}
@@ -24,10 +24,13 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/pem"
 	"fmt"
 	"golang.org/x/crypto/ssh"
+	"math/big"
 	"strings"
 )
 
@@ -61,16 +64,124 @@ func (k *sshKeyManager) CreateKeyPair(algorithm string, length int) (string, str
 	return string(privKeyPEM), string(pubKeyHex), nil
 }
 
+// The types of SSH public key which may be imported
+var sshKeyTypes = map[string]bool{
+	"ssh-rsa":             true,
+	"ssh-dss":             true,
+	"ecdsa-sha2-nistp256": true,
+	"ecdsa-sha2-nistp384": true,
+	"ecdsa-sha2-nistp521": true,
+	"ssh-ed25519":         true,
+}
+
+// readSSHString reads a length prefixed string from the wire
+// format of an SSH public key
+func readSSHString(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("Truncated SSH public key")
+	}
+	length := binary.BigEndian.Uint32(data)
+	data = data[4:]
+	if uint32(len(data)) < length {
+		return nil, nil, fmt.Errorf("Truncated SSH public key")
+	}
+	return data[:length], data[length:], nil
+}
+
+// parseSSHPublicKey extracts the wire format of the public key
+// from a line in OpenSSH authorized_keys format, which may start
+// with options. The ssh package cannot parse ed25519 keys, so
+// they are checked here.
+func parseSSHPublicKey(pubkey string) ([]byte, error) {
+	fields := strings.Fields(pubkey)
+	for idx := 0; idx < len(fields)-1; idx++ {
+		keyType := fields[idx]
+		if !sshKeyTypes[keyType] {
+			continue
+		}
+
+		blob, err := base64.StdEncoding.DecodeString(fields[idx+1])
+		if err != nil {
+			return nil, err
+		}
+
+		blobType, rest, err := readSSHString(blob)
+		if err != nil {
+			return nil, err
+		}
+		if string(blobType) != keyType {
+			return nil, fmt.Errorf("SSH public key type '%s' does not match '%s'", blobType, keyType)
+		}
+
+		if keyType == "ssh-ed25519" {
+			key, rest, err := readSSHString(rest)
+			if err != nil {
+				return nil, err
+			}
+			if len(key) != 32 || len(rest) != 0 {
+				return nil, fmt.Errorf("Malformed ed25519 public key")
+			}
+		} else {
+			_, err = ssh.ParsePublicKey(blob)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return blob, nil
+	}
+
+	return nil, fmt.Errorf("No supported SSH public key found")
+}
+
 func (k *sshKeyManager) FingerPrint(pubkey string) (string, error) {
-	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pubkey))
+	blob, err := parseSSHPublicKey(pubkey)
 	if err != nil {
 		return "", err
 	}
 
-	md5sum := md5.Sum(key.Marshal())
+	md5sum := md5.Sum(blob)
 	hexarray := make([]string, len(md5sum))
 	for i, c := range md5sum {
 		hexarray[i] = hex.EncodeToString([]byte{c})
 	}
 	return strings.Join(hexarray, ":"), nil
 }
+
+// Encrypt encrypts data with the RSA public key using PKCS#1
+// v1.5 padding, as expected by tools which decrypt server
+// passwords with the private key. Other key types cannot be
+// used for encryption.
+func (k *sshKeyManager) Encrypt(pubkey string, data []byte) ([]byte, error) {
+	blob, err := parseSSHPublicKey(pubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyType, rest, err := readSSHString(blob)
+	if err != nil {
+		return nil, err
+	}
+	if string(keyType) != "ssh-rsa" {
+		return nil, fmt.Errorf("Unable to encrypt with SSH public key type '%s'", keyType)
+	}
+
+	e, rest, err := readSSHString(rest)
+	if err != nil {
+		return nil, err
+	}
+	n, rest, err := readSSHString(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() > (1<<31-1) {
+		return nil, fmt.Errorf("Malformed RSA public key exponent")
+	}
+	key := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exp.Int64()),
+	}
+
+	return rsa.EncryptPKCS1v15(rand.Reader, key, data)
+}
@@ -20,6 +20,10 @@
 package crypto
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"strings"
 	"testing"
 )
@@ -68,3 +72,107 @@ func TestSSHKeyFingerprint(t *testing.T) {
 		return
 	}
 }
+
+func TestSSHKeyFingerprintTypes(t *testing.T) {
+	mgr := NewSSHKeyManager()
+
+	tests := []struct {
+		PublicKey   string
+		Fingerprint string
+	}{
+		{
+			"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMeGTaIPN+dyaRwMFSg0KvuSfFj03pmlQar4tXSIr9eI test@example",
+			"2c:cc:f7:8a:ee:db:82:ce:5f:8b:08:20:83:cc:a6:8c",
+		},
+		{
+			"ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBJkCxU+RzlRYbX6F" +
+				"aeGGCcv6Eucc+CYxYTqu4h6xxaNEyQdshEbmet41fib6lakWGFYKlMe88s5JnYFxMOxBXXY=",
+			"cd:64:32:0d:ee:ce:1a:ec:ae:27:cb:44:98:e4:7e:aa",
+		},
+		{
+			"no-pty ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMeGTaIPN+dyaRwMFSg0KvuSfFj03pmlQar4tXSIr9eI",
+			"2c:cc:f7:8a:ee:db:82:ce:5f:8b:08:20:83:cc:a6:8c",
+		},
+	}
+
+	for _, test := range tests {
+		fpr, err := mgr.FingerPrint(test.PublicKey)
+		if err != nil {
+			t.Errorf("Unable to create key fingerprint for %s: %s", test.PublicKey, err)
+			continue
+		}
+		if fpr != test.Fingerprint {
+			t.Errorf("Incorrect fingerprint '%s' expected '%s'", fpr, test.Fingerprint)
+		}
+	}
+}
+
+func TestSSHKeyFingerprintInvalid(t *testing.T) {
+	mgr := NewSSHKeyManager()
+
+	keys := []string{
+		"",
+		"ssh-foo AAAAB3NzaC1lZDI1NTE5AAAAIMeGTaIPN+dyaRwMFSg0KvuSfFj03pmlQar4tXSIr9eI",
+		// Key type does not match the encoded key
+		"ssh-rsa AAAAC3NzaC1lZDI1NTE5AAAAIMeGTaIPN+dyaRwMFSg0KvuSfFj03pmlQar4tXSIr9eI",
+		// Truncated key
+		"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMeGTaIPN+dyaRwMFSg0KvuSfFj03pmlQar4",
+		"ssh-ed25519 !!!!",
+	}
+
+	for _, key := range keys {
+		_, err := mgr.FingerPrint(key)
+		if err == nil {
+			t.Errorf("Expected error for key '%s'", key)
+		}
+	}
+}
+
+func TestSSHKeyEncrypt(t *testing.T) {
+	mgr := NewSSHKeyManager()
+
+	priv, pub, err := mgr.CreateKeyPair(AlgRSA, 2048)
+	if err != nil {
+		t.Fatalf("Unable to create RSA-2048 keypair: %s", err)
+	}
+
+	data, err := mgr.Encrypt(pub, []byte("Passw0rd"))
+	if err != nil {
+		t.Fatalf("Unable to encrypt data: %s", err)
+	}
+
+	block, _ := pem.Decode([]byte(priv))
+	if block == nil {
+		t.Fatalf("Unable to decode private key")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("Unable to parse private key: %s", err)
+	}
+
+	clear, err := rsa.DecryptPKCS1v15(rand.Reader, key, data)
+	if err != nil {
+		t.Fatalf("Unable to decrypt data: %s", err)
+	}
+	if string(clear) != "Passw0rd" {
+		t.Errorf("Incorrect decrypted data '%s'", clear)
+	}
+}
+
+func TestSSHKeyEncryptUnsupported(t *testing.T) {
+	mgr := NewSSHKeyManager()
+
+	keys := []string{
+		"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMeGTaIPN+dyaRwMFSg0KvuSfFj03pmlQar4tXSIr9eI",
+		"ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBJkCxU+RzlRYbX6F" +
+			"aeGGCcv6Eucc+CYxYTqu4h6xxaNEyQdshEbmet41fib6lakWGFYKlMe88s5JnYFxMOxBXXY=",
+		"ssh-foo AAAAB3NzaC1lZDI1NTE5AAAAIMeGTaIPN+dyaRwMFSg0KvuSfFj03pmlQar4tXSIr9eI",
+	}
+
+	for _, key := range keys {
+		_, err := mgr.Encrypt(key, []byte("Passw0rd"))
+		if err == nil {
+			t.Errorf("Expected error encrypting with key '%s'", key)
+		}
+	}
+}
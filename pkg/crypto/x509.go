@@ -20,6 +20,8 @@
 package crypto
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha1"
 	"crypto/x509"
 	"encoding/hex"
@@ -39,17 +41,21 @@ func (k *x509KeyManager) CreateKeyPair(algorithm string, length int) (string, st
 	return "", "", fmt.Errorf("Unable to generate certificates")
 }
 
-func (k *x509KeyManager) FingerPrint(pubkey string) (string, error) {
+func parseCertificate(pubkey string) (*x509.Certificate, error) {
 	block, _ := pem.Decode([]byte(pubkey))
 	if block == nil {
-		return "", fmt.Errorf("Unable to decode PEM file")
+		return nil, fmt.Errorf("Unable to decode PEM file")
 	}
 
 	if block.Type != "CERTIFICATE" {
-		return "", fmt.Errorf("Unexpected PEM file type '%s'", block.Type)
+		return nil, fmt.Errorf("Unexpected PEM file type '%s'", block.Type)
 	}
 
-	cert, err := x509.ParseCertificate(block.Bytes)
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func (k *x509KeyManager) FingerPrint(pubkey string) (string, error) {
+	cert, err := parseCertificate(pubkey)
 	if err != nil {
 		return "", err
 	}
@@ -57,3 +63,17 @@ func (k *x509KeyManager) FingerPrint(pubkey string) (string, error) {
 	hash := sha1.New()
 	return hex.EncodeToString(hash.Sum(cert.Raw)), nil
 }
+
+func (k *x509KeyManager) Encrypt(pubkey string, data []byte) ([]byte, error) {
+	cert, err := parseCertificate(pubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("Unable to encrypt with non-RSA certificate")
+	}
+
+	return rsa.EncryptPKCS1v15(rand.Reader, key, data)
+}
@@ -0,0 +1,425 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package metadata
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Instance holds everything about a server which is exposed
+// to the guest through the metadata service
+type Instance struct {
+	UUID             string
+	Name             string
+	Hostname         string
+	ProjectID        string
+	AvailabilityZone string
+	InstanceType     string
+	LocalIPv4        string
+	MACAddress       string
+	Keys             []Key
+	Meta             map[string]string
+	UserData         []byte
+	VendorData       []byte
+	Password         string
+}
+
+type Key struct {
+	Name string
+	Type string
+	Data string
+}
+
+type Response struct {
+	ContentType string
+	Body        []byte
+}
+
+const (
+	ContentTypeText = "text/plain; charset=utf-8"
+	ContentTypeJSON = "application/json"
+)
+
+// The versions of the OpenStack metadata format that are
+// advertised. The content is the same for all of them.
+var openstackVersions = []string{
+	"2012-08-10",
+	"2013-04-04",
+	"2013-10-17",
+	"2015-10-15",
+	"2016-06-30",
+	"2016-10-06",
+	"2017-02-22",
+	"latest",
+}
+
+// The first OpenStack metadata version in which guests can
+// store their encrypted password
+const openstackPasswordVersion = "2013-04-04"
+
+func hasPassword(version string) bool {
+	return version == "latest" || version >= openstackPasswordVersion
+}
+
+var ec2Versions = []string{
+	"1.0",
+	"2007-01-19",
+	"2007-03-01",
+	"2007-08-29",
+	"2007-10-10",
+	"2007-12-15",
+	"2008-02-01",
+	"2008-09-01",
+	"2009-04-04",
+	"latest",
+}
+
+type openstackMetaData struct {
+	UUID             string            `json:"uuid"`
+	Name             string            `json:"name"`
+	Hostname         string            `json:"hostname"`
+	LaunchIndex      int               `json:"launch_index"`
+	AvailabilityZone string            `json:"availability_zone"`
+	ProjectID        string            `json:"project_id"`
+	PublicKeys       map[string]string `json:"public_keys,omitempty"`
+	Keys             []openstackKey    `json:"keys,omitempty"`
+	Meta             map[string]string `json:"meta,omitempty"`
+	Devices          []interface{}     `json:"devices"`
+	RandomSeed       string            `json:"random_seed"`
+}
+
+type openstackKey struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+type networkData struct {
+	Links    []networkLink    `json:"links"`
+	Networks []networkNetwork `json:"networks"`
+	Services []interface{}    `json:"services"`
+}
+
+type networkLink struct {
+	ID                 string `json:"id"`
+	Type               string `json:"type"`
+	EthernetMACAddress string `json:"ethernet_mac_address"`
+	MTU                *int   `json:"mtu"`
+	VifID              string `json:"vif_id"`
+}
+
+type networkNetwork struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Link      string `json:"link"`
+	NetworkID string `json:"network_id"`
+}
+
+func textResponse(lines ...string) *Response {
+	return &Response{
+		ContentType: ContentTypeText,
+		Body:        []byte(strings.Join(lines, "\n")),
+	}
+}
+
+func jsonResponse(obj interface{}) (*Response, error) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		ContentType: ContentTypeJSON,
+		Body:        body,
+	}, nil
+}
+
+func isVersion(versions []string, version string) bool {
+	for _, val := range versions {
+		if val == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Serve produces the content for a metadata path, in either the
+// OpenStack or EC2 format. A nil response is returned if the
+// path does not exist.
+func (inst *Instance) Serve(path string) (*Response, error) {
+	path = strings.Trim(path, "/")
+	bits := []string{}
+	if path != "" {
+		bits = strings.Split(path, "/")
+	}
+
+	if len(bits) == 0 {
+		return textResponse(append(ec2Versions, "openstack")...), nil
+	}
+
+	if bits[0] == "openstack" {
+		return inst.serveOpenStack(bits[1:])
+	}
+
+	return inst.serveEC2(bits)
+}
+
+func (inst *Instance) serveOpenStack(bits []string) (*Response, error) {
+	if len(bits) == 0 {
+		return textResponse(openstackVersions...), nil
+	}
+
+	if !isVersion(openstackVersions, bits[0]) {
+		return nil, nil
+	}
+
+	if len(bits) == 1 {
+		files := []string{
+			"meta_data.json",
+			"vendor_data.json",
+			"network_data.json",
+		}
+		if hasPassword(bits[0]) {
+			files = append(files, "password")
+		}
+		if len(inst.UserData) != 0 {
+			files = append(files, "user_data")
+		}
+		return textResponse(files...), nil
+	}
+
+	if len(bits) != 2 {
+		return nil, nil
+	}
+
+	switch bits[1] {
+	case "meta_data.json":
+		return inst.openstackMetaData()
+	case "vendor_data.json":
+		body := inst.VendorData
+		if len(body) == 0 {
+			body = []byte("{}")
+		}
+		return &Response{
+			ContentType: ContentTypeJSON,
+			Body:        body,
+		}, nil
+	case "network_data.json":
+		return inst.openstackNetworkData()
+	case "password":
+		if !hasPassword(bits[0]) {
+			return nil, nil
+		}
+		return textResponse(inst.Password), nil
+	case "user_data":
+		if len(inst.UserData) == 0 {
+			return nil, nil
+		}
+		return &Response{
+			ContentType: ContentTypeText,
+			Body:        inst.UserData,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// IsPasswordPath reports whether a path is one where the guest
+// may store its encrypted password
+func IsPasswordPath(path string) bool {
+	bits := strings.Split(strings.Trim(path, "/"), "/")
+	return len(bits) == 3 && bits[0] == "openstack" &&
+		isVersion(openstackVersions, bits[1]) && hasPassword(bits[1]) &&
+		bits[2] == "password"
+}
+
+func (inst *Instance) openstackMetaData() (*Response, error) {
+	seed := make([]byte, 512)
+	_, err := rand.Read(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	md := openstackMetaData{
+		UUID:             inst.UUID,
+		Name:             inst.Name,
+		Hostname:         inst.Hostname,
+		AvailabilityZone: inst.AvailabilityZone,
+		ProjectID:        inst.ProjectID,
+		Meta:             inst.Meta,
+		Devices:          []interface{}{},
+		RandomSeed:       base64.StdEncoding.EncodeToString(seed),
+	}
+
+	if len(inst.Keys) != 0 {
+		md.PublicKeys = make(map[string]string)
+	}
+	for _, key := range inst.Keys {
+		md.PublicKeys[key.Name] = key.Data
+		md.Keys = append(md.Keys, openstackKey{
+			Name: key.Name,
+			Type: key.Type,
+			Data: key.Data,
+		})
+	}
+
+	return jsonResponse(md)
+}
+
+func (inst *Instance) openstackNetworkData() (*Response, error) {
+	nd := networkData{
+		Links:    []networkLink{},
+		Networks: []networkNetwork{},
+		Services: []interface{}{},
+	}
+
+	if inst.MACAddress != "" {
+		nd.Links = append(nd.Links, networkLink{
+			ID:                 "tap0",
+			Type:               "phy",
+			EthernetMACAddress: inst.MACAddress,
+			VifID:              "tap0",
+		})
+		// Addresses on the pod network are handed out by the
+		// DHCP server KubeVirt runs for the guest
+		nd.Networks = append(nd.Networks, networkNetwork{
+			ID:        "network0",
+			Type:      "ipv4_dhcp",
+			Link:      "tap0",
+			NetworkID: "default",
+		})
+	}
+
+	return jsonResponse(nd)
+}
+
+func (inst *Instance) serveEC2(bits []string) (*Response, error) {
+	if !isVersion(ec2Versions, bits[0]) {
+		return nil, nil
+	}
+
+	if len(bits) == 1 {
+		entries := []string{"meta-data/"}
+		if len(inst.UserData) != 0 {
+			entries = append(entries, "user-data")
+		}
+		return textResponse(entries...), nil
+	}
+
+	switch bits[1] {
+	case "user-data":
+		if len(bits) != 2 || len(inst.UserData) == 0 {
+			return nil, nil
+		}
+		return &Response{
+			ContentType: ContentTypeText,
+			Body:        inst.UserData,
+		}, nil
+	case "meta-data":
+		return inst.ec2MetaData(bits[2:]), nil
+	}
+
+	return nil, nil
+}
+
+func (inst *Instance) ec2MetaDataTree() map[string]interface{} {
+	tree := map[string]interface{}{
+		"ami-id":            "ami-00000001",
+		"ami-launch-index":  "0",
+		"ami-manifest-path": "FIXME",
+		"hostname":          inst.Hostname,
+		"instance-action":   "none",
+		"instance-id":       inst.UUID,
+		"instance-type":     inst.InstanceType,
+		"local-hostname":    inst.Hostname,
+		"public-hostname":   inst.Hostname,
+		"reservation-id":    "r-" + inst.UUID,
+		"security-groups":   "default",
+		"block-device-mapping": map[string]interface{}{
+			"ami":  "vda",
+			"root": "/dev/vda",
+		},
+		"placement": map[string]interface{}{
+			"availability-zone": inst.AvailabilityZone,
+		},
+	}
+
+	if inst.LocalIPv4 != "" {
+		tree["local-ipv4"] = inst.LocalIPv4
+	}
+
+	if len(inst.Keys) != 0 {
+		keys := map[string]interface{}{}
+		for idx, key := range inst.Keys {
+			keys[fmt.Sprintf("%d", idx)] = map[string]interface{}{
+				"openssh-key": key.Data,
+			}
+		}
+		tree["public-keys"] = keys
+	}
+
+	return tree
+}
+
+func (inst *Instance) ec2MetaData(bits []string) *Response {
+	var node interface{} = inst.ec2MetaDataTree()
+
+	for _, bit := range bits {
+		dir, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node, ok = dir[bit]
+		if !ok {
+			return nil
+		}
+	}
+
+	switch val := node.(type) {
+	case string:
+		return textResponse(val)
+	case map[string]interface{}:
+		// The public keys directory lists the key names
+		// alongside their indexes
+		if len(bits) == 1 && bits[0] == "public-keys" {
+			entries := []string{}
+			for idx, key := range inst.Keys {
+				entries = append(entries, fmt.Sprintf("%d=%s", idx, key.Name))
+			}
+			return textResponse(entries...)
+		}
+
+		entries := []string{}
+		for name, child := range val {
+			if _, ok := child.(map[string]interface{}); ok {
+				name = name + "/"
+			}
+			entries = append(entries, name)
+		}
+		sort.Strings(entries)
+		return textResponse(entries...)
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package metadata
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func testInstance() *Instance {
+	return &Instance{
+		UUID:             "3c4ac7a1-1c4b-4a4e-9e8f-0ff4c5d1e3a2",
+		Name:             "web 1",
+		Hostname:         "web-1",
+		ProjectID:        "2d8b5b0e-2bb4-4b6e-9f0e-1a3c8f0b6d2a",
+		AvailabilityZone: "nova",
+		InstanceType:     "m1.small",
+		LocalIPv4:        "10.244.0.12",
+		MACAddress:       "0a:58:0a:f4:00:0c",
+		Keys: []Key{
+			Key{
+				Name: "fred",
+				Type: "ssh",
+				Data: "ssh-rsa AAAA fred@example.com",
+			},
+		},
+		Meta: map[string]string{
+			"role": "webserver",
+		},
+		UserData: []byte("#!/bin/sh\necho hello\n"),
+		Password: "c2VjcmV0",
+	}
+}
+
+func TestServeText(t *testing.T) {
+	inst := testInstance()
+
+	tests := []struct {
+		Path string
+		Body string
+	}{
+		{"/openstack", "2012-08-10\n2013-04-04\n2013-10-17\n2015-10-15\n2016-06-30\n2016-10-06\n2017-02-22\nlatest"},
+		{"/openstack/latest/", "meta_data.json\nvendor_data.json\nnetwork_data.json\npassword\nuser_data"},
+		{"/openstack/2012-08-10/", "meta_data.json\nvendor_data.json\nnetwork_data.json\nuser_data"},
+		{"/openstack/latest/password", "c2VjcmV0"},
+		{"/openstack/2013-04-04/password", "c2VjcmV0"},
+		{"/openstack/latest/user_data", "#!/bin/sh\necho hello\n"},
+		{"/openstack/latest/vendor_data.json", "{}"},
+		{"/latest", "meta-data/\nuser-data"},
+		{"/2009-04-04/user-data", "#!/bin/sh\necho hello\n"},
+		{"/latest/meta-data/instance-id", "3c4ac7a1-1c4b-4a4e-9e8f-0ff4c5d1e3a2"},
+		{"/latest/meta-data/hostname", "web-1"},
+		{"/latest/meta-data/instance-type", "m1.small"},
+		{"/latest/meta-data/local-ipv4", "10.244.0.12"},
+		{"/latest/meta-data/placement/", "availability-zone"},
+		{"/latest/meta-data/placement/availability-zone", "nova"},
+		{"/latest/meta-data/public-keys/", "0=fred"},
+		{"/latest/meta-data/public-keys/0/", "openssh-key"},
+		{"/latest/meta-data/public-keys/0/openssh-key", "ssh-rsa AAAA fred@example.com"},
+		{"/latest/meta-data/block-device-mapping/", "ami\nroot"},
+	}
+
+	for _, test := range tests {
+		res, err := inst.Serve(test.Path)
+		if err != nil {
+			t.Fatalf("Cannot serve %s: %s", test.Path, err)
+		}
+		if res == nil {
+			t.Errorf("Expected content for %s", test.Path)
+			continue
+		}
+		if string(res.Body) != test.Body {
+			t.Errorf("Expected '%s' for %s but got '%s'", test.Body, test.Path, string(res.Body))
+		}
+	}
+}
+
+func TestServeNotFound(t *testing.T) {
+	inst := testInstance()
+	inst.UserData = []byte{}
+
+	paths := []string{
+		"/openstack/1999-01-01/meta_data.json",
+		"/openstack/latest/bogus",
+		"/openstack/latest/user_data",
+		"/openstack/2012-08-10/password",
+		"/1999-01-01/meta-data/",
+		"/latest/user-data",
+		"/latest/meta-data/bogus",
+		"/latest/meta-data/hostname/bogus",
+	}
+
+	for _, path := range paths {
+		res, err := inst.Serve(path)
+		if err != nil {
+			t.Fatalf("Cannot serve %s: %s", path, err)
+		}
+		if res != nil {
+			t.Errorf("Expected no content for %s but got '%s'", path, string(res.Body))
+		}
+	}
+}
+
+func TestPasswordPath(t *testing.T) {
+	tests := map[string]bool{
+		"/openstack/latest/password":       true,
+		"/openstack/2013-04-04/password":   true,
+		"openstack/2017-02-22/password/":   true,
+		"/openstack/2012-08-10/password":   false,
+		"/openstack/1999-01-01/password":   false,
+		"/openstack/latest/meta_data.json": false,
+		"/latest/password":                 false,
+	}
+
+	for path, want := range tests {
+		if IsPasswordPath(path) != want {
+			t.Errorf("Expected %t for password path %s", want, path)
+		}
+	}
+}
+
+func TestServeEC2Listing(t *testing.T) {
+	inst := testInstance()
+
+	res, err := inst.Serve("/latest/meta-data/")
+	if err != nil {
+		t.Fatalf("Cannot serve meta-data: %s", err)
+	}
+
+	want := "ami-id\nami-launch-index\nami-manifest-path\nblock-device-mapping/\n" +
+		"hostname\ninstance-action\ninstance-id\ninstance-type\nlocal-hostname\n" +
+		"local-ipv4\nplacement/\npublic-hostname\npublic-keys/\nreservation-id\n" +
+		"security-groups"
+	if string(res.Body) != want {
+		t.Errorf("Expected '%s' but got '%s'", want, string(res.Body))
+	}
+}
+
+func TestServeOpenStackMetaData(t *testing.T) {
+	inst := testInstance()
+
+	res, err := inst.Serve("/openstack/latest/meta_data.json")
+	if err != nil {
+		t.Fatalf("Cannot serve meta_data.json: %s", err)
+	}
+	if res.ContentType != ContentTypeJSON {
+		t.Errorf("Expected JSON content but got %s", res.ContentType)
+	}
+
+	var md openstackMetaData
+	err = json.Unmarshal(res.Body, &md)
+	if err != nil {
+		t.Fatalf("Cannot parse meta_data.json: %s", err)
+	}
+
+	if md.UUID != inst.UUID || md.Hostname != "web-1" || md.Name != "web 1" {
+		t.Errorf("Unexpected identity in %s", string(res.Body))
+	}
+	if md.ProjectID != inst.ProjectID {
+		t.Errorf("Expected project %s but got %s", inst.ProjectID, md.ProjectID)
+	}
+	if md.PublicKeys["fred"] != "ssh-rsa AAAA fred@example.com" {
+		t.Errorf("Expected key fred in %s", md.PublicKeys)
+	}
+	if len(md.Keys) != 1 || md.Keys[0].Type != "ssh" {
+		t.Errorf("Expected 1 ssh key but got %s", md.Keys)
+	}
+	if md.Meta["role"] != "webserver" {
+		t.Errorf("Expected role metadata in %s", md.Meta)
+	}
+	if md.RandomSeed == "" {
+		t.Errorf("Expected a random seed")
+	}
+}
+
+func TestServeOpenStackNetworkData(t *testing.T) {
+	inst := testInstance()
+
+	res, err := inst.Serve("/openstack/latest/network_data.json")
+	if err != nil {
+		t.Fatalf("Cannot serve network_data.json: %s", err)
+	}
+
+	var nd networkData
+	err = json.Unmarshal(res.Body, &nd)
+	if err != nil {
+		t.Fatalf("Cannot parse network_data.json: %s", err)
+	}
+
+	if len(nd.Links) != 1 || nd.Links[0].EthernetMACAddress != inst.MACAddress {
+		t.Errorf("Expected link with MAC %s in %s", inst.MACAddress, string(res.Body))
+	}
+	if len(nd.Networks) != 1 || nd.Networks[0].Link != nd.Links[0].ID {
+		t.Errorf("Expected network on link in %s", string(res.Body))
+	}
+}
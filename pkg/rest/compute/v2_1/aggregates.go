@@ -0,0 +1,595 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+type AggregateReq struct {
+	Aggregate AggregateUpdateInfo `json:"aggregate"`
+}
+
+type AggregateUpdateInfo struct {
+	Name             *string `json:"name"`
+	AvailabilityZone *string `json:"availability_zone"`
+}
+
+type AggregateListRes struct {
+	Aggregates []AggregateInfo `json:"aggregates"`
+}
+
+type AggregateRes struct {
+	Aggregate AggregateInfo `json:"aggregate"`
+}
+
+type AggregateInfo struct {
+	ID               uint64            `json:"id"`
+	UUID             string            `json:"uuid"`
+	Name             string            `json:"name"`
+	AvailabilityZone *string           `json:"availability_zone"`
+	Hosts            []string          `json:"hosts"`
+	Metadata         map[string]string `json:"metadata"`
+	CreatedAt        string            `json:"created_at"`
+	UpdatedAt        *string           `json:"updated_at"`
+	Deleted          bool              `json:"deleted"`
+	DeletedAt        *string           `json:"deleted_at"`
+}
+
+type AggregateHostReq struct {
+	Host string `json:"host"`
+}
+
+type AggregateMetadataReq struct {
+	Metadata map[string]*string `json:"metadata"`
+}
+
+// The aggregate metadata key which holds the availability zone
+const aggregateMetadataZone = "availability_zone"
+
+func aggregateInfo(agg *v1.Aggregate) AggregateInfo {
+	info := AggregateInfo{
+		ID:        agg.Spec.ID,
+		UUID:      agg.ObjectMeta.Name,
+		Name:      agg.Spec.Name,
+		Hosts:     agg.Spec.Hosts,
+		Metadata:  map[string]string{},
+		CreatedAt: agg.Spec.CreatedAt,
+	}
+	if info.Hosts == nil {
+		info.Hosts = []string{}
+	}
+	for key, val := range agg.Spec.Metadata {
+		info.Metadata[key] = val
+	}
+	if agg.Spec.AvailabilityZone != "" {
+		zone := agg.Spec.AvailabilityZone
+		info.AvailabilityZone = &zone
+		info.Metadata[aggregateMetadataZone] = zone
+	}
+	if agg.Spec.UpdatedAt != "" {
+		updated := agg.Spec.UpdatedAt
+		info.UpdatedAt = &updated
+	}
+	return info
+}
+
+func aggregateHasHost(agg *v1.Aggregate, host string) bool {
+	for _, name := range agg.Spec.Hosts {
+		if name == host {
+			return true
+		}
+	}
+	return false
+}
+
+// hostAggregateZone returns the availability zone given to the
+// host by aggregates other than the one named
+func hostAggregateZone(aggs []v1.Aggregate, host, exclude string) string {
+	for idx := range aggs {
+		agg := &aggs[idx]
+		if agg.ObjectMeta.Name == exclude || agg.Spec.AvailabilityZone == "" {
+			continue
+		}
+		if aggregateHasHost(agg, host) {
+			return agg.Spec.AvailabilityZone
+		}
+	}
+	return ""
+}
+
+func isValidAvailabilityZone(zone string) bool {
+	return zone != compute.DefaultAvailabilityZone &&
+		len(validation.IsValidLabelValue(zone)) == 0
+}
+
+// setHostZone labels the node with the availability zone. If
+// zone is empty, the label is removed if it still has the value
+// oldZone.
+func (svc *service) setHostZone(host, zone, oldZone string) error {
+	clnt := svc.K8SClient.CoreV1().Nodes()
+	node, err := clnt.Get(host, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	current := node.ObjectMeta.Labels[compute.LabelAvailabilityZone]
+	if zone != "" {
+		if current == zone {
+			return nil
+		}
+		if node.ObjectMeta.Labels == nil {
+			node.ObjectMeta.Labels = map[string]string{}
+		}
+		node.ObjectMeta.Labels[compute.LabelAvailabilityZone] = zone
+	} else {
+		if oldZone == "" || current != oldZone {
+			return nil
+		}
+		delete(node.ObjectMeta.Labels, compute.LabelAvailabilityZone)
+	}
+
+	_, err = clnt.Update(node)
+	return err
+}
+
+// checkHostsZone verifies that the hosts of the aggregate can be
+// moved into the availability zone. It aborts the request and
+// returns false if any host is already in another zone.
+func (svc *service) checkHostsZone(c *gin.Context, agg *v1.Aggregate, aggs []v1.Aggregate, zone string) bool {
+	for _, host := range agg.Spec.Hosts {
+		other := hostAggregateZone(aggs, host, agg.ObjectMeta.Name)
+		if other != "" && other != zone {
+			c.AbortWithError(http.StatusConflict,
+				fmt.Errorf("Host %s is already in availability zone %s", host, other))
+			return false
+		}
+
+		// Zones set outside of any aggregate, for example by a
+		// cloud provider, are not overridden
+		node, err := svc.K8SClient.CoreV1().Nodes().Get(host, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return false
+		}
+		current := node.ObjectMeta.Labels[compute.LabelAvailabilityZone]
+		if current != "" && current != zone && current != agg.Spec.AvailabilityZone {
+			c.AbortWithError(http.StatusConflict,
+				fmt.Errorf("Host %s is already in availability zone %s", host, current))
+			return false
+		}
+	}
+
+	return true
+}
+
+// changeAggregateZone moves the hosts of the aggregate into the
+// new availability zone, or back to the zone given by their
+// other aggregates if zone is empty. It aborts the request and
+// returns false on failure.
+func (svc *service) changeAggregateZone(c *gin.Context, agg *v1.Aggregate, aggs []v1.Aggregate, zone string) bool {
+	if zone != "" && !isValidAvailabilityZone(zone) {
+		c.AbortWithError(http.StatusBadRequest,
+			fmt.Errorf("Invalid availability zone '%s'", zone))
+		return false
+	}
+
+	// A host can only be in one zone
+	if zone != "" && !svc.checkHostsZone(c, agg, aggs, zone) {
+		return false
+	}
+
+	for _, host := range agg.Spec.Hosts {
+		newZone := zone
+		if newZone == "" {
+			newZone = hostAggregateZone(aggs, host, agg.ObjectMeta.Name)
+		}
+		err := svc.setHostZone(host, newZone, agg.Spec.AvailabilityZone)
+		if err != nil && !errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return false
+		}
+	}
+
+	agg.Spec.AvailabilityZone = zone
+	return true
+}
+
+// getAggregate returns the aggregate with the ID given in the
+// request, along with all aggregates. It aborts the request and
+// returns nil on failure.
+func (svc *service) getAggregate(c *gin.Context) (*v1.Aggregate, []v1.Aggregate) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return nil, nil
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithError(http.StatusNotFound, err)
+		return nil, nil
+	}
+
+	aggs, err := svc.Client.Compute().Aggregates(v1.NamespaceSystem).List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return nil, nil
+	}
+
+	for idx := range aggs.Items {
+		if aggs.Items[idx].Spec.ID == id {
+			return &aggs.Items[idx], aggs.Items
+		}
+	}
+
+	c.AbortWithStatus(http.StatusNotFound)
+	return nil, nil
+}
+
+func (svc *service) updateAggregate(c *gin.Context, agg *v1.Aggregate) {
+	agg.Spec.UpdatedAt = time.Now().Format(time.RFC3339)
+	agg, err := svc.Client.Compute().Aggregates(v1.NamespaceSystem).Update(agg)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := AggregateRes{
+		Aggregate: aggregateInfo(agg),
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) AggregateList(c *gin.Context) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	aggs, err := svc.Client.Compute().Aggregates(v1.NamespaceSystem).List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := AggregateListRes{
+		Aggregates: []AggregateInfo{},
+	}
+	for idx := range aggs.Items {
+		res.Aggregates = append(res.Aggregates, aggregateInfo(&aggs.Items[idx]))
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) AggregateCreate(c *gin.Context) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	req := AggregateReq{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Aggregate.Name == nil || *req.Aggregate.Name == "" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	zone := ""
+	if req.Aggregate.AvailabilityZone != nil {
+		zone = *req.Aggregate.AvailabilityZone
+		if !isValidAvailabilityZone(zone) {
+			c.AbortWithError(http.StatusBadRequest,
+				fmt.Errorf("Invalid availability zone '%s'", zone))
+			return
+		}
+	}
+
+	clnt := svc.Client.Compute().Aggregates(v1.NamespaceSystem)
+
+	aggs, err := clnt.List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	id := uint64(1)
+	for _, agg := range aggs.Items {
+		if agg.Spec.Name == *req.Aggregate.Name {
+			c.AbortWithStatus(http.StatusConflict)
+			return
+		}
+		if agg.Spec.ID >= id {
+			id = agg.Spec.ID + 1
+		}
+	}
+
+	agg := &v1.Aggregate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: string(uuid.NewUUID()),
+		},
+		Spec: v1.AggregateSpec{
+			ID:               id,
+			Name:             *req.Aggregate.Name,
+			AvailabilityZone: zone,
+			Hosts:            []string{},
+			Metadata:         map[string]string{},
+			CreatedAt:        time.Now().Format(time.RFC3339),
+		},
+	}
+
+	agg, err = clnt.Create(agg)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := AggregateRes{
+		Aggregate: aggregateInfo(agg),
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) AggregateShow(c *gin.Context) {
+	agg, _ := svc.getAggregate(c)
+	if agg == nil {
+		return
+	}
+
+	res := AggregateRes{
+		Aggregate: aggregateInfo(agg),
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) AggregateUpdate(c *gin.Context) {
+	req := AggregateReq{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	agg, aggs := svc.getAggregate(c)
+	if agg == nil {
+		return
+	}
+
+	if req.Aggregate.Name != nil {
+		if *req.Aggregate.Name == "" {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		for _, other := range aggs {
+			if other.ObjectMeta.Name != agg.ObjectMeta.Name &&
+				other.Spec.Name == *req.Aggregate.Name {
+				c.AbortWithStatus(http.StatusConflict)
+				return
+			}
+		}
+		agg.Spec.Name = *req.Aggregate.Name
+	}
+
+	if req.Aggregate.AvailabilityZone != nil {
+		if !svc.changeAggregateZone(c, agg, aggs, *req.Aggregate.AvailabilityZone) {
+			return
+		}
+	}
+
+	svc.updateAggregate(c, agg)
+}
+
+func (svc *service) AggregateDelete(c *gin.Context) {
+	agg, _ := svc.getAggregate(c)
+	if agg == nil {
+		return
+	}
+
+	if len(agg.Spec.Hosts) != 0 {
+		c.AbortWithError(http.StatusBadRequest,
+			fmt.Errorf("Aggregate %d still has hosts", agg.Spec.ID))
+		return
+	}
+
+	err := svc.Client.Compute().Aggregates(v1.NamespaceSystem).Delete(agg.ObjectMeta.Name, nil)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.String(http.StatusOK, "")
+}
+
+func (svc *service) AggregateAction(c *gin.Context) {
+	reqs := map[string]json.RawMessage{}
+	err := c.BindJSON(&reqs)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if len(reqs) != 1 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var action string
+	var body json.RawMessage
+	for action, body = range reqs {
+	}
+
+	switch action {
+	case "add_host":
+		svc.aggregateAddHost(c, body)
+	case "remove_host":
+		svc.aggregateRemoveHost(c, body)
+	case "set_metadata":
+		svc.aggregateSetMetadata(c, body)
+	default:
+		c.AbortWithStatus(http.StatusBadRequest)
+	}
+}
+
+func (svc *service) aggregateAddHost(c *gin.Context, body json.RawMessage) {
+	req := AggregateHostReq{}
+	err := json.Unmarshal(body, &req)
+	if err != nil || req.Host == "" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	agg, _ := svc.getAggregate(c)
+	if agg == nil {
+		return
+	}
+
+	node, err := svc.K8SClient.CoreV1().Nodes().Get(req.Host, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if aggregateHasHost(agg, req.Host) {
+		c.AbortWithStatus(http.StatusConflict)
+		return
+	}
+
+	if agg.Spec.AvailabilityZone != "" {
+		// Zones set outside of any aggregate, for example by a
+		// cloud provider, are not overridden
+		current := node.ObjectMeta.Labels[compute.LabelAvailabilityZone]
+		if current != "" && current != agg.Spec.AvailabilityZone {
+			c.AbortWithError(http.StatusConflict,
+				fmt.Errorf("Host %s is already in availability zone %s", req.Host, current))
+			return
+		}
+
+		err = svc.setHostZone(req.Host, agg.Spec.AvailabilityZone, "")
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	agg.Spec.Hosts = append(agg.Spec.Hosts, req.Host)
+	svc.updateAggregate(c, agg)
+}
+
+func (svc *service) aggregateRemoveHost(c *gin.Context, body json.RawMessage) {
+	req := AggregateHostReq{}
+	err := json.Unmarshal(body, &req)
+	if err != nil || req.Host == "" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	agg, aggs := svc.getAggregate(c)
+	if agg == nil {
+		return
+	}
+
+	if !aggregateHasHost(agg, req.Host) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	if agg.Spec.AvailabilityZone != "" {
+		zone := hostAggregateZone(aggs, req.Host, agg.ObjectMeta.Name)
+		err = svc.setHostZone(req.Host, zone, agg.Spec.AvailabilityZone)
+		if err != nil && !errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	hosts := []string{}
+	for _, host := range agg.Spec.Hosts {
+		if host != req.Host {
+			hosts = append(hosts, host)
+		}
+	}
+	agg.Spec.Hosts = hosts
+	svc.updateAggregate(c, agg)
+}
+
+func (svc *service) aggregateSetMetadata(c *gin.Context, body json.RawMessage) {
+	req := AggregateMetadataReq{}
+	err := json.Unmarshal(body, &req)
+	if err != nil || req.Metadata == nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	agg, aggs := svc.getAggregate(c)
+	if agg == nil {
+		return
+	}
+
+	if agg.Spec.Metadata == nil {
+		agg.Spec.Metadata = map[string]string{}
+	}
+	for key, val := range req.Metadata {
+		if key == aggregateMetadataZone {
+			zone := ""
+			if val != nil {
+				zone = *val
+			}
+			if !svc.changeAggregateZone(c, agg, aggs, zone) {
+				return
+			}
+			continue
+		}
+
+		// XXX metadata is not yet matched against the
+		// aggregate_instance_extra_specs of flavors
+		if val == nil {
+			delete(agg.Spec.Metadata, key)
+		} else {
+			agg.Spec.Metadata[key] = *val
+		}
+	}
+
+	svc.updateAggregate(c, agg)
+}
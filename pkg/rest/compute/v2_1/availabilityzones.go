@@ -0,0 +1,143 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+type AvailabilityZoneListRes struct {
+	AvailabilityZoneInfo []AvailabilityZoneInfo `json:"availabilityZoneInfo"`
+}
+
+type AvailabilityZoneInfo struct {
+	ZoneName  string                                        `json:"zoneName"`
+	ZoneState AvailabilityZoneState                         `json:"zoneState"`
+	Hosts     map[string]map[string]AvailabilityZoneService `json:"hosts"`
+}
+
+type AvailabilityZoneState struct {
+	Available bool `json:"available"`
+}
+
+type AvailabilityZoneService struct {
+	Available bool    `json:"available"`
+	Active    bool    `json:"active"`
+	UpdatedAt *string `json:"updated_at"`
+}
+
+func nodeReady(node *k8sv1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == k8sv1.NodeReady {
+			return cond.Status == k8sv1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// getNodeZones returns the availability zones of all nodes,
+// indexed by node name
+func (svc *service) getNodeZones() (map[string]string, error) {
+	nodes, err := svc.K8SClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]string)
+	for idx := range nodes.Items {
+		node := &nodes.Items[idx]
+		res[node.ObjectMeta.Name] = compute.NodeAvailabilityZone(node)
+	}
+	return res, nil
+}
+
+func (svc *service) commonAvailabilityZoneList(c *gin.Context, detail bool) {
+	nodes, err := svc.K8SClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	zones := make(map[string]*AvailabilityZoneInfo)
+	for idx := range nodes.Items {
+		node := &nodes.Items[idx]
+		name := compute.NodeAvailabilityZone(node)
+		zone, ok := zones[name]
+		if !ok {
+			zone = &AvailabilityZoneInfo{
+				ZoneName: name,
+			}
+			zones[name] = zone
+		}
+
+		ready := nodeReady(node)
+		if ready {
+			zone.ZoneState.Available = true
+		}
+
+		if detail {
+			if zone.Hosts == nil {
+				zone.Hosts = make(map[string]map[string]AvailabilityZoneService)
+			}
+			zone.Hosts[node.ObjectMeta.Name] = map[string]AvailabilityZoneService{
+				"nova-compute": AvailabilityZoneService{
+					Available: ready && !compute.NodeForcedDown(node),
+					Active:    !node.Spec.Unschedulable,
+				},
+			}
+		}
+	}
+
+	names := []string{}
+	for name := range zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := AvailabilityZoneListRes{
+		AvailabilityZoneInfo: []AvailabilityZoneInfo{},
+	}
+	for _, name := range names {
+		res.AvailabilityZoneInfo = append(res.AvailabilityZoneInfo, *zones[name])
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) AvailabilityZoneList(c *gin.Context) {
+	svc.commonAvailabilityZoneList(c, false)
+}
+
+func (svc *service) AvailabilityZoneListDetail(c *gin.Context) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	svc.commonAvailabilityZoneList(c, true)
+}
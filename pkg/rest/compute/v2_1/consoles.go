@@ -0,0 +1,306 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+	"github.com/dicot-project/dicot-api/pkg/auth"
+	"github.com/dicot-project/dicot-api/pkg/rest/console"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+// How long a console URL may be used for
+const consoleTokenLifetime = 10 * time.Minute
+
+// The container in the virt-launcher pod which logs the output
+// of the guest serial console
+const consoleLogContainer = "guest-console-log"
+
+type RemoteConsoleReq struct {
+	RemoteConsole RemoteConsoleInfo `json:"remote_console"`
+}
+
+type RemoteConsoleRes struct {
+	RemoteConsole RemoteConsoleInfo `json:"remote_console"`
+}
+
+type RemoteConsoleInfo struct {
+	Protocol string `json:"protocol"`
+	Type     string `json:"type"`
+	URL      string `json:"url,omitempty"`
+}
+
+type ConsoleReq struct {
+	Type string `json:"type"`
+}
+
+type ConsoleRes struct {
+	Console ConsoleInfo `json:"console"`
+}
+
+type ConsoleInfo struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type ConsoleOutputReq struct {
+	Length interface{} `json:"length"`
+}
+
+type ConsoleOutputRes struct {
+	Output string `json:"output"`
+}
+
+// consoleScheme gives the scheme of websocket URLs for the
+// proxy, which uses TLS if the request did, either directly or
+// at a reverse proxy setting X-Forwarded-Proto
+func consoleScheme(c *gin.Context) string {
+	if c.Request.TLS != nil || c.Request.Header.Get("X-Forwarded-Proto") == "https" {
+		return "wss"
+	}
+	return "ws"
+}
+
+// consoleURL creates a one-time token granting access to the
+// console of a running server, returning the URL of the proxy
+// with the token. It aborts the request and returns "" on
+// failure.
+func (svc *service) consoleURL(c *gin.Context, id, consoleType string) string {
+	proj := middleware.RequiredTokenScopeProject(c)
+
+	vm, vmi := svc.getServer(c, id)
+	if vm == nil {
+		return ""
+	}
+
+	if vmi == nil || vmi.Status.Phase != kubevirtv1.Running {
+		status, _, _ := compute.ServerStatus(vm, vmi)
+		c.AbortWithStatusJSON(http.StatusConflict, ConflictingRequestRes{
+			ConflictingRequest: ErrorInfo{
+				Code: http.StatusConflict,
+				Message: fmt.Sprintf("Instance %s is not ready, it is in vm_state %s",
+					id, serverVMStates[status]),
+			},
+		})
+		return ""
+	}
+
+	// The console token acts on behalf of the same user and
+	// project as the token used to request it
+	parent := middleware.GetToken(c)
+	if parent == nil {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return ""
+	}
+
+	tok := svc.TokenManager.NewToken()
+	tok.Expiry = tok.Issued.Add(consoleTokenLifetime)
+	tok.Subject = parent.Subject
+	tok.Scope = parent.Scope
+	tok.Roles = parent.Roles
+	tok.Console = &auth.TokenConsole{
+		Type:      consoleType,
+		Namespace: proj.Spec.Namespace,
+		Server:    vm.ObjectMeta.Name,
+	}
+
+	toksig, err := svc.TokenManager.SignToken(tok)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return ""
+	}
+
+	return consoleScheme(c) + "://" + c.Request.Host + console.DefaultPrefix + console.ProxyPath +
+		"?token=" + url.QueryEscape(toksig)
+}
+
+func (svc *service) ServerRemoteConsoleCreate(c *gin.Context) {
+	id := c.Param("id")
+
+	ver := middleware.GetMicroVersion(c)
+	if ver == nil || !ver.AtLeast(2, 6) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	req := RemoteConsoleReq{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var consoleType string
+	switch {
+	case req.RemoteConsole.Protocol == "vnc" && req.RemoteConsole.Type == "novnc":
+		consoleType = console.TypeVNC
+	case req.RemoteConsole.Protocol == "serial" && req.RemoteConsole.Type == "serial":
+		consoleType = console.TypeSerial
+	default:
+		// XXX SPICE, RDP and MKS consoles are not available
+		// with KubeVirt
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	consoleURL := svc.consoleURL(c, id, consoleType)
+	if consoleURL == "" {
+		return
+	}
+
+	res := RemoteConsoleRes{
+		RemoteConsole: RemoteConsoleInfo{
+			Protocol: req.RemoteConsole.Protocol,
+			Type:     req.RemoteConsole.Type,
+			URL:      consoleURL,
+		},
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// serverActionConsole handles the os-getVNCConsole and
+// os-getSerialConsole actions used before the remote consoles
+// API was added
+func (svc *service) serverActionConsole(c *gin.Context, id string, body json.RawMessage, wantType string) {
+	req := ConsoleReq{}
+	err := json.Unmarshal(body, &req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if req.Type != wantType {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	consoleType := console.TypeVNC
+	if wantType == "serial" {
+		consoleType = console.TypeSerial
+	}
+
+	consoleURL := svc.consoleURL(c, id, consoleType)
+	if consoleURL == "" {
+		return
+	}
+
+	res := ConsoleRes{
+		Console: ConsoleInfo{
+			Type: req.Type,
+			URL:  consoleURL,
+		},
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) serverActionConsoleOutput(c *gin.Context, id string, body json.RawMessage) {
+	proj := middleware.RequiredTokenScopeProject(c)
+
+	req := ConsoleOutputReq{}
+	if len(body) != 0 && string(body) != "null" {
+		err := json.Unmarshal(body, &req)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	// Nova accepts the length as either a number or a string,
+	// with a missing or negative length meaning everything
+	var tailLines *int64
+	switch length := req.Length.(type) {
+	case nil:
+	case float64:
+		if length >= 0 {
+			lines := int64(length)
+			tailLines = &lines
+		}
+	case string:
+		lines, err := strconv.ParseInt(length, 10, 64)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		if lines >= 0 {
+			tailLines = &lines
+		}
+	default:
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	vm, vmi := svc.getServer(c, id)
+	if vm == nil {
+		return
+	}
+	if vmi == nil {
+		status, _, _ := compute.ServerStatus(vm, vmi)
+		serverActionConflict(c, "os-getConsoleOutput", id, status)
+		return
+	}
+
+	pods, err := svc.K8SClient.CoreV1().Pods(proj.Spec.Namespace).List(metav1.ListOptions{
+		LabelSelector: compute.LabelServerID + "=" + vm.ObjectMeta.Name,
+	})
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var pod *k8sv1.Pod
+	for idx := range pods.Items {
+		if pods.Items[idx].Status.Phase == k8sv1.PodRunning {
+			pod = &pods.Items[idx]
+			break
+		}
+	}
+	if pod == nil {
+		status, _, _ := compute.ServerStatus(vm, vmi)
+		serverActionConflict(c, "os-getConsoleOutput", id, status)
+		return
+	}
+
+	output, err := svc.K8SClient.CoreV1().Pods(proj.Spec.Namespace).GetLogs(pod.ObjectMeta.Name, &k8sv1.PodLogOptions{
+		Container: consoleLogContainer,
+		TailLines: tailLines,
+	}).Do().Raw()
+	if err != nil {
+		// XXX older KubeVirt releases don't log the serial
+		// console
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := ConsoleOutputRes{
+		Output: string(output),
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,60 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestConsoleScheme(t *testing.T) {
+	tests := []struct {
+		TLS    bool
+		Proto  string
+		Scheme string
+	}{
+		{false, "", "ws"},
+		{true, "", "wss"},
+		{false, "https", "wss"},
+		{false, "http", "ws"},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("POST", "http://dicot.example.com/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.TLS {
+			req.TLS = &tls.ConnectionState{}
+		}
+		if test.Proto != "" {
+			req.Header.Set("X-Forwarded-Proto", test.Proto)
+		}
+
+		scheme := consoleScheme(&gin.Context{Request: req})
+		if scheme != test.Scheme {
+			t.Errorf("Expected scheme '%s' for TLS %t proto '%s' got '%s'",
+				test.Scheme, test.TLS, test.Proto, scheme)
+		}
+	}
+}
@@ -21,12 +21,14 @@ package v2_1
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/uuid"
 
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
 	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
 	"github.com/dicot-project/dicot-api/pkg/rest"
 	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
@@ -56,6 +58,10 @@ type FlavorExtraSpecsListRes struct {
 	ExtraSpecs map[string]string `json:"extra_specs"`
 }
 
+type BadRequestRes struct {
+	BadRequest ErrorInfo `json:"badRequest"`
+}
+
 type FlavorInfo struct {
 	ID    string          `json:"id"`
 	Name  string          `json:"name"`
@@ -74,8 +80,20 @@ type FlavorInfoDetail struct {
 	Public     bool    `json:"os-flavor-access:is_public"`
 }
 
+// badRequest aborts the request, reporting why it was invalid
+func badRequest(c *gin.Context, err error) {
+	c.Error(err)
+	c.AbortWithStatusJSON(http.StatusBadRequest, BadRequestRes{
+		BadRequest: ErrorInfo{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		},
+	})
+}
+
 func (svc *service) commonFlavorList(c *gin.Context) ([]v1.Flavor, bool) {
 	dom := middleware.RequiredTokenScopeDomain(c)
+	proj := middleware.RequiredTokenScopeProject(c)
 	//sortKeys := c.QueryArray("sort_key")
 	//sortDirs := c.QueryArray("sort_dir")
 	marker := c.Query("marker")
@@ -84,8 +102,21 @@ func (svc *service) commonFlavorList(c *gin.Context) ([]v1.Flavor, bool) {
 	filterMinDisk, minDisk := GetFilterUInt(c, "minDisk")
 	minDisk = minDisk * 1024 // GB -> MB
 	filterLimit, limit := GetFilterUInt(c, "limit")
-	// XXX Disallow unless user == admin
-	filterPublic, public := GetFilterBool(c, "isPublic")
+
+	// Projects see the public flavors and the private flavors
+	// they have been given access to. Admins may instead ask
+	// for only private flavors, or all flavors with is_public=None
+	filterPublic, public := true, true
+	if middleware.TokenHasRole(c, "admin") {
+		val := c.Query("is_public")
+		if val != "" {
+			filterPublic, public = GetFilterBool(c, "is_public")
+			if !filterPublic && !strings.EqualFold(val, "none") {
+				c.AbortWithStatus(http.StatusBadRequest)
+				return []v1.Flavor{}, true
+			}
+		}
+	}
 
 	clnt := svc.Client.Compute().Flavors(dom.Spec.Namespace)
 
@@ -114,8 +145,13 @@ func (svc *service) commonFlavorList(c *gin.Context) ([]v1.Flavor, bool) {
 		if !seenMarker {
 			continue
 		}
-		if filterPublic && flv.Spec.Public != public {
-			continue
+		if filterPublic {
+			if public && !compute.FlavorAccessible(&flv, proj) {
+				continue
+			}
+			if !public && flv.Spec.Public {
+				continue
+			}
 		}
 		if filterMinRam && flv.Spec.Resources.MemoryMB < minRam {
 			continue
@@ -185,6 +221,7 @@ func (svc *service) FlavorListDetail(c *gin.Context) {
 
 func (svc *service) FlavorShow(c *gin.Context) {
 	dom := middleware.RequiredTokenScopeDomain(c)
+	proj := middleware.RequiredTokenScopeProject(c)
 	id := c.Param("id")
 
 	if id == "detail" {
@@ -204,6 +241,11 @@ func (svc *service) FlavorShow(c *gin.Context) {
 		return
 	}
 
+	if !compute.FlavorAccessible(flavor, proj) && !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
 	// XXX Links field
 	res := FlavorShowRes{
 		Flavor: FlavorInfoDetail{
@@ -392,6 +434,12 @@ func (svc *service) FlavorCreateExtraSpecs(c *gin.Context) {
 		flavor.Spec.ExtraSpecs[key] = val
 	}
 
+	err = compute.ValidateFlavorExtraSpecs(flavor)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
 	flavor, err = clnt.Update(flavor)
 
 	if err != nil {
@@ -465,8 +513,17 @@ func (svc *service) FlavorCreateExtraSpec(c *gin.Context) {
 		return
 	}
 
+	if flavor.Spec.ExtraSpecs == nil {
+		flavor.Spec.ExtraSpecs = make(map[string]string)
+	}
 	flavor.Spec.ExtraSpecs[key] = val
 
+	err = compute.ValidateFlavorExtraSpecs(flavor)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
 	flavor, err = clnt.Update(flavor)
 
 	if err != nil {
@@ -0,0 +1,177 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+type FlavorAccessListRes struct {
+	FlavorAccess []FlavorAccessInfo `json:"flavor_access"`
+}
+
+type FlavorAccessInfo struct {
+	FlavorID string `json:"flavor_id"`
+	TenantID string `json:"tenant_id"`
+}
+
+type FlavorAccessReq struct {
+	Tenant string `json:"tenant"`
+}
+
+func flavorAccessList(flavor *v1.Flavor) FlavorAccessListRes {
+	res := FlavorAccessListRes{
+		FlavorAccess: []FlavorAccessInfo{},
+	}
+	for _, id := range flavor.Spec.Projects {
+		res.FlavorAccess = append(res.FlavorAccess, FlavorAccessInfo{
+			FlavorID: flavor.Spec.ID,
+			TenantID: id,
+		})
+	}
+	return res
+}
+
+// getFlavorForAccess returns the flavor whose access list is
+// being managed, which must not be public. It aborts the
+// request and returns nil on failure.
+func (svc *service) getFlavorForAccess(c *gin.Context, id string) *v1.Flavor {
+	dom := middleware.RequiredTokenScopeDomain(c)
+
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return nil
+	}
+
+	flavor, err := svc.Client.Compute().Flavors(dom.Spec.Namespace).GetByID(id)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return nil
+	}
+
+	return flavor
+}
+
+func (svc *service) FlavorAccessList(c *gin.Context) {
+	id := c.Param("id")
+
+	flavor := svc.getFlavorForAccess(c, id)
+	if flavor == nil {
+		return
+	}
+
+	// Access lists are not available for public flavors
+	if flavor.Spec.Public {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, flavorAccessList(flavor))
+}
+
+func (svc *service) FlavorAction(c *gin.Context) {
+	dom := middleware.RequiredTokenScopeDomain(c)
+	id := c.Param("id")
+
+	reqs := map[string]json.RawMessage{}
+	err := c.BindJSON(&reqs)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if len(reqs) != 1 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var action string
+	var body json.RawMessage
+	for action, body = range reqs {
+	}
+
+	if action != "addTenantAccess" && action != "removeTenantAccess" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	req := FlavorAccessReq{}
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if req.Tenant == "" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	flavor := svc.getFlavorForAccess(c, id)
+	if flavor == nil {
+		return
+	}
+
+	if flavor.Spec.Public {
+		c.AbortWithStatus(http.StatusConflict)
+		return
+	}
+
+	if action == "addTenantAccess" {
+		_, err = svc.Client.Identity().Projects(k8sv1.NamespaceAll).GetByID(req.Tenant)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				c.AbortWithError(http.StatusBadRequest, err)
+			} else {
+				c.AbortWithError(http.StatusInternalServerError, err)
+			}
+			return
+		}
+
+		if !compute.FlavorAddAccess(flavor, req.Tenant) {
+			c.AbortWithStatus(http.StatusConflict)
+			return
+		}
+	} else {
+		if !compute.FlavorRemoveAccess(flavor, req.Tenant) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+	}
+
+	flavor, err = svc.Client.Compute().Flavors(dom.Spec.Namespace).Update(flavor)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, flavorAccessList(flavor))
+}
@@ -21,26 +21,48 @@ package v2_1
 
 import (
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	k8sv1 "k8s.io/client-go/pkg/api/v1"
 
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
 	"github.com/dicot-project/dicot-api/pkg/rest"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
 )
 
+// Node labels set by KubeVirt's node labeller describing the
+// host CPU
+const (
+	labelPrefixCPUModel     = "cpu-model.node.kubevirt.io/"
+	labelPrefixCPUFeature   = "cpu-feature.node.kubevirt.io/"
+	labelPrefixHostCPUModel = "host-model-cpu.node.kubevirt.io/"
+	labelPrefixCPUVendor    = "cpu-vendor.node.kubevirt.io/"
+)
+
+// Nodes report their local storage under this resource name
+const resourceEphemeralStorage k8sv1.ResourceName = "ephemeral-storage"
+
 type HypervisorListRes struct {
 	Hypervisors []HypervisorInfo `json:"hypervisors"`
 	Links       []rest.LinkInfo  `json:"hypervisor_links"`
 }
 
 type HypervisorInfo struct {
-	ID       string `json:"id"`
-	Hostname string `json:"hypervisor_hostname"`
-	State    string `json:"state"`
-	Status   string `json:"status"`
+	ID       string                 `json:"id"`
+	Hostname string                 `json:"hypervisor_hostname"`
+	State    string                 `json:"state"`
+	Status   string                 `json:"status"`
+	Servers  []HypervisorServerInfo `json:"servers,omitempty"`
+}
+
+type HypervisorServerInfo struct {
+	Name string `json:"name"`
+	UUID string `json:"uuid"`
 }
 
 type HypervisorListDetailRes struct {
@@ -53,26 +75,27 @@ type HypervisorShowRes struct {
 }
 
 type HypervisorInfoDetail struct {
-	ID                 string      `json:"id"`
-	Hostname           string      `json:"hypervisor_hostname"`
-	Type               string      `json:"hypervisor_type"`
-	Version            string      `json:"hypervisor_version"`
-	State              string      `json:"state"`
-	Status             string      `json:"status"`
-	CPUInfo            CPUInfo     `json:"cpu_info"`
-	CurrentWorkload    uint64      `json:"current_workload"`
-	DiskAvailableLeast uint64      `json:"disk_available_least"`
-	HostIP             string      `json:"host_ip"`
-	FreeDiskGB         uint64      `json:"free_disk_gb"`
-	FreeRamMB          uint64      `json:"free_ram_mb"`
-	LocalGB            uint64      `json:"local_gb"`
-	LocalGBUsed        uint64      `json:"local_gb_used"`
-	MemoryMB           uint64      `json:"memory_mb"`
-	MemoryMBUsed       uint64      `json:"memory_mb_used"`
-	RunningVMs         uint64      `json:"running_vm"`
-	VCPUs              uint64      `json:"vcpus"`
-	VCPUsUsed          uint64      `json:"vcpus_used"`
-	Service            ServiceInfo `json:"service"`
+	ID                 string                 `json:"id"`
+	Hostname           string                 `json:"hypervisor_hostname"`
+	Type               string                 `json:"hypervisor_type"`
+	Version            string                 `json:"hypervisor_version"`
+	State              string                 `json:"state"`
+	Status             string                 `json:"status"`
+	CPUInfo            CPUInfo                `json:"cpu_info"`
+	CurrentWorkload    uint64                 `json:"current_workload"`
+	DiskAvailableLeast uint64                 `json:"disk_available_least"`
+	HostIP             string                 `json:"host_ip"`
+	FreeDiskGB         uint64                 `json:"free_disk_gb"`
+	FreeRamMB          uint64                 `json:"free_ram_mb"`
+	LocalGB            uint64                 `json:"local_gb"`
+	LocalGBUsed        uint64                 `json:"local_gb_used"`
+	MemoryMB           uint64                 `json:"memory_mb"`
+	MemoryMBUsed       uint64                 `json:"memory_mb_used"`
+	RunningVMs         uint64                 `json:"running_vms"`
+	VCPUs              uint64                 `json:"vcpus"`
+	VCPUsUsed          uint64                 `json:"vcpus_used"`
+	Service            ServiceInfo            `json:"service"`
+	Servers            []HypervisorServerInfo `json:"servers,omitempty"`
 }
 
 type ServiceInfo struct {
@@ -95,23 +118,97 @@ type CPUInfoTopology struct {
 	Sockets uint `json:"sockets"`
 }
 
-func (svc *service) getHypervisorList(c *gin.Context) ([]k8sv1.Pod, error) {
+type HypervisorStatisticsRes struct {
+	HypervisorStatistics HypervisorStatisticsInfo `json:"hypervisor_statistics"`
+}
+
+type HypervisorStatisticsInfo struct {
+	Count              uint64 `json:"count"`
+	CurrentWorkload    uint64 `json:"current_workload"`
+	DiskAvailableLeast uint64 `json:"disk_available_least"`
+	FreeDiskGB         uint64 `json:"free_disk_gb"`
+	FreeRamMB          uint64 `json:"free_ram_mb"`
+	LocalGB            uint64 `json:"local_gb"`
+	LocalGBUsed        uint64 `json:"local_gb_used"`
+	MemoryMB           uint64 `json:"memory_mb"`
+	MemoryMBUsed       uint64 `json:"memory_mb_used"`
+	RunningVMs         uint64 `json:"running_vms"`
+	VCPUs              uint64 `json:"vcpus"`
+	VCPUsUsed          uint64 `json:"vcpus_used"`
+}
+
+// A hypervisor is the virt-handler pod on a node
+type hypervisor struct {
+	Pod  k8sv1.Pod
+	Node *k8sv1.Node
+	VMIs []*kubevirtv1.VirtualMachineInstance
+}
+
+func (hv *hypervisor) ID() string {
+	return string(hv.Pod.ObjectMeta.UID)
+}
+
+func (hv *hypervisor) Hostname() string {
+	return hv.Pod.Spec.NodeName
+}
+
+func (hv *hypervisor) State() string {
+	if hv.Node != nil && compute.NodeForcedDown(hv.Node) {
+		return "down"
+	}
+	if hv.Pod.Status.Phase == k8sv1.PodRunning {
+		return "up"
+	}
+	return "down"
+}
+
+func (hv *hypervisor) Status() string {
+	if hv.Node != nil && hv.Node.Spec.Unschedulable {
+		return "disabled"
+	}
+	return "enabled"
+}
+
+func (svc *service) getHypervisorList(c *gin.Context) ([]*hypervisor, error) {
 	marker := c.Query("marker")
 	filterLimit, limit := GetFilterUInt(c, "limit")
+	pattern := c.Query("hypervisor_hostname_pattern")
 
 	selector, err := labels.Parse("daemon in (virt-handler)")
 	if err != nil {
-		return []k8sv1.Pod{}, err
+		return []*hypervisor{}, err
 	}
 
 	pods, err := svc.K8SClient.CoreV1().Pods(k8smetav1.NamespaceAll).List(
 		k8smetav1.ListOptions{
 			LabelSelector: selector.String()})
 	if err != nil {
-		return []k8sv1.Pod{}, err
+		return []*hypervisor{}, err
+	}
+
+	nodes, err := svc.K8SClient.CoreV1().Nodes().List(k8smetav1.ListOptions{})
+	if err != nil {
+		return []*hypervisor{}, err
+	}
+	nodeMap := make(map[string]*k8sv1.Node)
+	for idx := range nodes.Items {
+		nodeMap[nodes.Items[idx].ObjectMeta.Name] = &nodes.Items[idx]
+	}
+
+	vmis, err := svc.Client.Kubevirt().VirtualMachineInstances(k8sv1.NamespaceAll).List()
+	if err != nil {
+		return []*hypervisor{}, err
+	}
+	vmiMap := make(map[string][]*kubevirtv1.VirtualMachineInstance)
+	for idx := range vmis.Items {
+		vmi := &vmis.Items[idx]
+		if vmi.Status.NodeName == "" {
+			continue
+		}
+		vmiMap[vmi.Status.NodeName] = append(vmiMap[vmi.Status.NodeName], vmi)
 	}
 
-	res := []k8sv1.Pod{}
+	res := []*hypervisor{}
 
 	count := uint64(0)
 	seenMarker := false
@@ -130,8 +227,15 @@ func (svc *service) getHypervisorList(c *gin.Context) ([]k8sv1.Pod, error) {
 		if !seenMarker {
 			continue
 		}
+		if pattern != "" && !strings.Contains(pod.Spec.NodeName, pattern) {
+			continue
+		}
 
-		res = append(res, pod)
+		res = append(res, &hypervisor{
+			Pod:  pod,
+			Node: nodeMap[pod.Spec.NodeName],
+			VMIs: vmiMap[pod.Spec.NodeName],
+		})
 
 		count = count + 1
 		if filterLimit && count >= limit {
@@ -142,53 +246,245 @@ func (svc *service) getHypervisorList(c *gin.Context) ([]k8sv1.Pod, error) {
 	return res, nil
 }
 
-func (svc *service) HypervisorList(c *gin.Context) {
+// getHypervisor finds the hypervisor with the ID or hostname
+// given in the request. It aborts the request and returns nil
+// if it is not found.
+func (svc *service) getHypervisor(c *gin.Context) *hypervisor {
+	name := c.Param("name")
 
-	pods, err := svc.getHypervisorList(c)
+	hvs, err := svc.getHypervisorList(c)
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
+		return nil
+	}
+
+	for _, hv := range hvs {
+		if hv.ID() == name || hv.Hostname() == name {
+			return hv
+		}
+	}
+
+	c.AbortWithStatus(http.StatusNotFound)
+	return nil
+}
+
+func hypervisorCPUInfo(node *k8sv1.Node, vcpus uint64) CPUInfo {
+	info := CPUInfo{
+		Arch:     node.Status.NodeInfo.Architecture,
+		Features: []string{},
+		Topology: CPUInfoTopology{
+			Sockets: 1,
+			Cores:   uint(vcpus),
+			Threads: 1,
+		},
+	}
+
+	models := []string{}
+	for key := range node.ObjectMeta.Labels {
+		switch {
+		case strings.HasPrefix(key, labelPrefixHostCPUModel):
+			info.Model = strings.TrimPrefix(key, labelPrefixHostCPUModel)
+		case strings.HasPrefix(key, labelPrefixCPUModel):
+			models = append(models, strings.TrimPrefix(key, labelPrefixCPUModel))
+		case strings.HasPrefix(key, labelPrefixCPUFeature):
+			info.Features = append(info.Features, strings.TrimPrefix(key, labelPrefixCPUFeature))
+		case strings.HasPrefix(key, labelPrefixCPUVendor):
+			info.Vendor = strings.TrimPrefix(key, labelPrefixCPUVendor)
+		}
+	}
+	sort.Strings(info.Features)
+
+	// Without the host model, report the first of the models
+	// the host can provide
+	if info.Model == "" && len(models) != 0 {
+		sort.Strings(models)
+		info.Model = models[0]
 	}
 
-	res := HypervisorListRes{}
+	return info
+}
 
-	for _, pod := range pods {
-		state := "down"
-		if pod.Status.Phase == "Running" {
-			state = "up"
+func hypervisorHostIP(node *k8sv1.Node) string {
+	for _, addrType := range []k8sv1.NodeAddressType{k8sv1.NodeInternalIP, k8sv1.NodeExternalIP} {
+		for _, addr := range node.Status.Addresses {
+			if addr.Type == addrType {
+				return addr.Address
+			}
 		}
+	}
+	return ""
+}
+
+func quantityMiB(list k8sv1.ResourceList, name k8sv1.ResourceName) uint64 {
+	if qty, ok := list[name]; ok && qty.Value() > 0 {
+		return uint64(qty.Value() / (1024 * 1024))
+	}
+	return 0
+}
 
-		res.Hypervisors = append(res.Hypervisors, HypervisorInfo{
-			Hostname: pod.Spec.NodeName,
-			ID:       string(pod.ObjectMeta.UID),
-			Status:   "enabled",
-			State:    state,
+func hypervisorInfoDetail(hv *hypervisor) HypervisorInfoDetail {
+	info := HypervisorInfoDetail{
+		ID:       hv.ID(),
+		Hostname: hv.Hostname(),
+		Status:   hv.Status(),
+		State:    hv.State(),
+		Type:     "QEMU",
+		Service: ServiceInfo{
+			Host: hv.Hostname(),
+		},
+	}
+
+	for _, vmi := range hv.VMIs {
+		if vmi.Status.Phase != kubevirtv1.Running {
+			continue
+		}
+		cores, ram := compute.ServerResources(&kubevirtv1.VirtualMachine{
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: vmi.Spec,
+				},
+			},
 		})
+		info.RunningVMs++
+		info.VCPUsUsed += uint64(cores)
+		info.MemoryMBUsed += uint64(ram)
+	}
+
+	if hv.Node == nil {
+		return info
+	}
+
+	node := hv.Node
+	if cpu, ok := node.Status.Capacity[k8sv1.ResourceCPU]; ok {
+		info.VCPUs = uint64(cpu.Value())
+	}
+	info.MemoryMB = quantityMiB(node.Status.Capacity, k8sv1.ResourceMemory)
+	if free := quantityMiB(node.Status.Allocatable, k8sv1.ResourceMemory); free > info.MemoryMBUsed {
+		info.FreeRamMB = free - info.MemoryMBUsed
+	}
+	info.LocalGB = quantityMiB(node.Status.Capacity, resourceEphemeralStorage) / 1024
+	// XXX root disks are on persistent volumes, which may or may
+	// not be local to the node
+	info.FreeDiskGB = quantityMiB(node.Status.Allocatable, resourceEphemeralStorage) / 1024
+	info.DiskAvailableLeast = info.FreeDiskGB
+	if info.LocalGB > info.FreeDiskGB {
+		info.LocalGBUsed = info.LocalGB - info.FreeDiskGB
+	}
+
+	info.HostIP = hypervisorHostIP(node)
+	info.CPUInfo = hypervisorCPUInfo(node, info.VCPUs)
+	info.Service.ID = string(node.ObjectMeta.UID)
+	info.Service.DisabledReason = compute.NodeDisabledReason(node)
+
+	return info
+}
+
+func hypervisorServers(hv *hypervisor) []HypervisorServerInfo {
+	servers := []HypervisorServerInfo{}
+	for _, vmi := range hv.VMIs {
+		id, ok := vmi.ObjectMeta.Labels[compute.LabelServerID]
+		if !ok {
+			continue
+		}
+		// The name of the libvirt domain
+		servers = append(servers, HypervisorServerInfo{
+			Name: vmi.ObjectMeta.Namespace + "_" + vmi.ObjectMeta.Name,
+			UUID: id,
+		})
+	}
+	return servers
+}
+
+func (svc *service) HypervisorList(c *gin.Context) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	_, withServers := GetFilterBool(c, "with_servers")
+
+	hvs, err := svc.getHypervisorList(c)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := HypervisorListRes{
+		Hypervisors: []HypervisorInfo{},
+	}
+
+	for _, hv := range hvs {
+		info := HypervisorInfo{
+			Hostname: hv.Hostname(),
+			ID:       hv.ID(),
+			Status:   hv.Status(),
+			State:    hv.State(),
+		}
+		if withServers {
+			info.Servers = hypervisorServers(hv)
+		}
+		res.Hypervisors = append(res.Hypervisors, info)
 	}
 	c.JSON(http.StatusOK, res)
 }
 
 func (svc *service) HypervisorListDetails(c *gin.Context) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	_, withServers := GetFilterBool(c, "with_servers")
 
-	pods, err := svc.getHypervisorList(c)
+	hvs, err := svc.getHypervisorList(c)
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
+		return
 	}
 
-	res := HypervisorListDetailRes{}
+	res := HypervisorListDetailRes{
+		Hypervisors: []HypervisorInfoDetail{},
+	}
 
-	for _, pod := range pods {
-		state := "down"
-		if pod.Status.Phase == "Running" {
-			state = "up"
+	for _, hv := range hvs {
+		info := hypervisorInfoDetail(hv)
+		if withServers {
+			info.Servers = hypervisorServers(hv)
 		}
+		res.Hypervisors = append(res.Hypervisors, info)
+	}
+	c.JSON(http.StatusOK, res)
+}
 
-		res.Hypervisors = append(res.Hypervisors, HypervisorInfoDetail{
-			Hostname: pod.Spec.NodeName,
-			ID:       string(pod.ObjectMeta.UID),
-			Status:   "enabled",
-			State:    state,
-			Type:     "QEMU",
-		})
+func (svc *service) HypervisorStatistics(c *gin.Context) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	hvs, err := svc.getHypervisorList(c)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	stats := HypervisorStatisticsInfo{}
+	for _, hv := range hvs {
+		info := hypervisorInfoDetail(hv)
+		stats.Count++
+		stats.CurrentWorkload += info.CurrentWorkload
+		stats.DiskAvailableLeast += info.DiskAvailableLeast
+		stats.FreeDiskGB += info.FreeDiskGB
+		stats.FreeRamMB += info.FreeRamMB
+		stats.LocalGB += info.LocalGB
+		stats.LocalGBUsed += info.LocalGBUsed
+		stats.MemoryMB += info.MemoryMB
+		stats.MemoryMBUsed += info.MemoryMBUsed
+		stats.RunningVMs += info.RunningVMs
+		stats.VCPUs += info.VCPUs
+		stats.VCPUsUsed += info.VCPUsUsed
+	}
+
+	res := HypervisorStatisticsRes{
+		HypervisorStatistics: stats,
 	}
 	c.JSON(http.StatusOK, res)
 }
@@ -200,55 +496,49 @@ func (svc *service) HypervisorShow(c *gin.Context) {
 		svc.HypervisorListDetails(c)
 		return
 	}
-
-	fieldSelector, err := fields.ParseSelector("spec.nodeName=" + name)
-	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+	if name == "statistics" {
+		svc.HypervisorStatistics(c)
 		return
 	}
 
-	labelSelector, err := labels.Parse("daemon in (virt-handler)")
-	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
 		return
 	}
 
-	pods, err := svc.K8SClient.CoreV1().Pods(k8smetav1.NamespaceAll).List(
-		k8smetav1.ListOptions{
-			LabelSelector: labelSelector.String(),
-			FieldSelector: fieldSelector.String(),
-		})
-	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+	hv := svc.getHypervisor(c)
+	if hv == nil {
 		return
 	}
 
-	if len(pods.Items) == 0 {
-		c.AbortWithStatus(http.StatusNotFound)
-		return
+	res := HypervisorShowRes{
+		Hypervisor: hypervisorInfoDetail(hv),
 	}
 
-	if len(pods.Items) > 1 {
-		c.AbortWithStatus(http.StatusInternalServerError)
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) HypervisorServers(c *gin.Context) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
 		return
 	}
 
-	pod := pods.Items[0]
-
-	state := "down"
-	if pod.Status.Phase == "Running" {
-		state = "up"
+	hv := svc.getHypervisor(c)
+	if hv == nil {
+		return
 	}
 
-	res := HypervisorShowRes{
-		Hypervisor: HypervisorInfoDetail{
-			Hostname: pod.Spec.NodeName,
-			ID:       string(pod.ObjectMeta.UID),
-			Status:   "enabled",
-			State:    state,
-			Type:     "QEMU",
+	res := HypervisorListRes{
+		Hypervisors: []HypervisorInfo{
+			HypervisorInfo{
+				ID:       hv.ID(),
+				Hostname: hv.Hostname(),
+				State:    hv.State(),
+				Status:   hv.Status(),
+				Servers:  hypervisorServers(hv),
+			},
 		},
 	}
-
 	c.JSON(http.StatusOK, res)
 }
@@ -0,0 +1,290 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+func TestHypervisorCPUInfo(t *testing.T) {
+	tests := []struct {
+		Labels   map[string]string
+		Model    string
+		Vendor   string
+		Features []string
+	}{
+		{
+			Labels:   nil,
+			Model:    "",
+			Vendor:   "",
+			Features: []string{},
+		},
+		{
+			Labels: map[string]string{
+				labelPrefixHostCPUModel + "Skylake-Client": "true",
+				labelPrefixCPUModel + "Haswell":            "true",
+				labelPrefixCPUModel + "Broadwell":          "true",
+				labelPrefixCPUFeature + "vmx":              "true",
+				labelPrefixCPUFeature + "avx2":             "true",
+				labelPrefixCPUVendor + "Intel":             "true",
+				"kubernetes.io/hostname":                   "node1",
+			},
+			Model:    "Skylake-Client",
+			Vendor:   "Intel",
+			Features: []string{"avx2", "vmx"},
+		},
+		{
+			Labels: map[string]string{
+				labelPrefixCPUModel + "Haswell":   "true",
+				labelPrefixCPUModel + "Broadwell": "true",
+				labelPrefixCPUFeature + "svm":     "true",
+			},
+			Model:    "Broadwell",
+			Vendor:   "",
+			Features: []string{"svm"},
+		},
+	}
+
+	for _, test := range tests {
+		node := &k8sv1.Node{
+			ObjectMeta: k8smetav1.ObjectMeta{
+				Labels: test.Labels,
+			},
+			Status: k8sv1.NodeStatus{
+				NodeInfo: k8sv1.NodeSystemInfo{
+					Architecture: "amd64",
+				},
+			},
+		}
+
+		info := hypervisorCPUInfo(node, 4)
+		if info.Arch != "amd64" {
+			t.Errorf("Expected arch amd64 got %s", info.Arch)
+		}
+		if info.Model != test.Model {
+			t.Errorf("Expected model '%s' for %v got '%s'", test.Model, test.Labels, info.Model)
+		}
+		if info.Vendor != test.Vendor {
+			t.Errorf("Expected vendor '%s' for %v got '%s'", test.Vendor, test.Labels, info.Vendor)
+		}
+		if !reflect.DeepEqual(info.Features, test.Features) {
+			t.Errorf("Expected features %v for %v got %v", test.Features, test.Labels, info.Features)
+		}
+		expect := CPUInfoTopology{Sockets: 1, Cores: 4, Threads: 1}
+		if info.Topology != expect {
+			t.Errorf("Expected topology %v got %v", expect, info.Topology)
+		}
+	}
+}
+
+func TestHypervisorHostIP(t *testing.T) {
+	tests := []struct {
+		Addresses []k8sv1.NodeAddress
+		IP        string
+	}{
+		{
+			Addresses: nil,
+			IP:        "",
+		},
+		{
+			Addresses: []k8sv1.NodeAddress{
+				k8sv1.NodeAddress{Type: k8sv1.NodeHostName, Address: "node1"},
+			},
+			IP: "",
+		},
+		{
+			Addresses: []k8sv1.NodeAddress{
+				k8sv1.NodeAddress{Type: k8sv1.NodeHostName, Address: "node1"},
+				k8sv1.NodeAddress{Type: k8sv1.NodeExternalIP, Address: "203.0.113.10"},
+			},
+			IP: "203.0.113.10",
+		},
+		{
+			Addresses: []k8sv1.NodeAddress{
+				k8sv1.NodeAddress{Type: k8sv1.NodeExternalIP, Address: "203.0.113.10"},
+				k8sv1.NodeAddress{Type: k8sv1.NodeInternalIP, Address: "192.168.122.10"},
+			},
+			IP: "192.168.122.10",
+		},
+	}
+
+	for _, test := range tests {
+		node := &k8sv1.Node{
+			Status: k8sv1.NodeStatus{
+				Addresses: test.Addresses,
+			},
+		}
+		if ip := hypervisorHostIP(node); ip != test.IP {
+			t.Errorf("Expected host IP '%s' for %v got '%s'", test.IP, test.Addresses, ip)
+		}
+	}
+}
+
+func TestQuantityMiB(t *testing.T) {
+	tests := []struct {
+		Quantity string
+		MiB      uint64
+	}{
+		{"", 0},
+		{"0", 0},
+		{"1500Ki", 1},
+		{"512Mi", 512},
+		{"16Gi", 16 * 1024},
+		{"1G", 953},
+	}
+
+	for _, test := range tests {
+		list := k8sv1.ResourceList{}
+		if test.Quantity != "" {
+			list[k8sv1.ResourceMemory] = resource.MustParse(test.Quantity)
+		}
+		if mib := quantityMiB(list, k8sv1.ResourceMemory); mib != test.MiB {
+			t.Errorf("Expected %d MiB for '%s' got %d", test.MiB, test.Quantity, mib)
+		}
+	}
+}
+
+func newTestHypervisorVMI(phase kubevirtv1.VirtualMachineInstancePhase, cores uint32, memory string) *kubevirtv1.VirtualMachineInstance {
+	return &kubevirtv1.VirtualMachineInstance{
+		Spec: kubevirtv1.VirtualMachineInstanceSpec{
+			Domain: kubevirtv1.DomainSpec{
+				CPU: &kubevirtv1.CPU{
+					Cores: cores,
+				},
+				Resources: kubevirtv1.ResourceRequirements{
+					Requests: k8sv1.ResourceList{
+						k8sv1.ResourceMemory: resource.MustParse(memory),
+					},
+				},
+			},
+		},
+		Status: kubevirtv1.VirtualMachineInstanceStatus{
+			Phase: phase,
+		},
+	}
+}
+
+func TestHypervisorInfoDetail(t *testing.T) {
+	hv := &hypervisor{
+		Pod: k8sv1.Pod{
+			ObjectMeta: k8smetav1.ObjectMeta{
+				UID: "virt-handler-1",
+			},
+			Spec: k8sv1.PodSpec{
+				NodeName: "node1",
+			},
+			Status: k8sv1.PodStatus{
+				Phase: k8sv1.PodRunning,
+			},
+		},
+		VMIs: []*kubevirtv1.VirtualMachineInstance{
+			newTestHypervisorVMI(kubevirtv1.Running, 2, "2Gi"),
+			newTestHypervisorVMI(kubevirtv1.Running, 1, "512Mi"),
+			newTestHypervisorVMI(kubevirtv1.Pending, 4, "4Gi"),
+		},
+	}
+
+	// Without the node only the running instances are known
+	info := hypervisorInfoDetail(hv)
+	if info.ID != "virt-handler-1" || info.Hostname != "node1" ||
+		info.State != "up" || info.Status != "enabled" {
+		t.Errorf("Unexpected hypervisor %v", info)
+	}
+	if info.RunningVMs != 2 || info.VCPUsUsed != 3 || info.MemoryMBUsed != 2560 {
+		t.Errorf("Expected 2 running VMs using 3 vCPUs and 2560 MiB got %v", info)
+	}
+	if info.VCPUs != 0 || info.MemoryMB != 0 || info.HostIP != "" {
+		t.Errorf("Unexpected node resources %v", info)
+	}
+
+	hv.Node = &k8sv1.Node{
+		ObjectMeta: k8smetav1.ObjectMeta{
+			UID: "node-1",
+			Labels: map[string]string{
+				labelPrefixHostCPUModel + "Skylake-Client": "true",
+			},
+		},
+		Status: k8sv1.NodeStatus{
+			Capacity: k8sv1.ResourceList{
+				k8sv1.ResourceCPU:        resource.MustParse("8"),
+				k8sv1.ResourceMemory:     resource.MustParse("16Gi"),
+				resourceEphemeralStorage: resource.MustParse("100Gi"),
+			},
+			Allocatable: k8sv1.ResourceList{
+				k8sv1.ResourceCPU:        resource.MustParse("8"),
+				k8sv1.ResourceMemory:     resource.MustParse("15Gi"),
+				resourceEphemeralStorage: resource.MustParse("80Gi"),
+			},
+			Addresses: []k8sv1.NodeAddress{
+				k8sv1.NodeAddress{Type: k8sv1.NodeInternalIP, Address: "192.168.122.10"},
+			},
+		},
+	}
+
+	info = hypervisorInfoDetail(hv)
+	expect := HypervisorInfoDetail{
+		ID:                 "virt-handler-1",
+		Hostname:           "node1",
+		Type:               "QEMU",
+		State:              "up",
+		Status:             "enabled",
+		DiskAvailableLeast: 80,
+		HostIP:             "192.168.122.10",
+		FreeDiskGB:         80,
+		FreeRamMB:          15*1024 - 2560,
+		LocalGB:            100,
+		LocalGBUsed:        20,
+		MemoryMB:           16 * 1024,
+		MemoryMBUsed:       2560,
+		RunningVMs:         2,
+		VCPUs:              8,
+		VCPUsUsed:          3,
+		CPUInfo: CPUInfo{
+			Model:    "Skylake-Client",
+			Features: []string{},
+			Topology: CPUInfoTopology{
+				Sockets: 1,
+				Cores:   8,
+				Threads: 1,
+			},
+		},
+		Service: ServiceInfo{
+			ID:   "node-1",
+			Host: "node1",
+		},
+	}
+	if !reflect.DeepEqual(info, expect) {
+		t.Errorf("Expected hypervisor %v got %v", expect, info)
+	}
+
+	// Memory used beyond what is allocatable leaves none free
+	hv.Node.Status.Allocatable[k8sv1.ResourceMemory] = resource.MustParse("2Gi")
+	info = hypervisorInfoDetail(hv)
+	if info.FreeRamMB != 0 {
+		t.Errorf("Expected no free memory got %d", info.FreeRamMB)
+	}
+}
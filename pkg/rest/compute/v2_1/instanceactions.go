@@ -0,0 +1,199 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+type InstanceActionListRes struct {
+	InstanceActions []InstanceActionInfo `json:"instanceActions"`
+}
+
+type InstanceActionShowRes struct {
+	InstanceAction InstanceActionInfo `json:"instanceAction"`
+}
+
+type InstanceActionInfo struct {
+	Action       string                    `json:"action"`
+	InstanceUUID string                    `json:"instance_uuid"`
+	Message      *string                   `json:"message"`
+	ProjectID    string                    `json:"project_id"`
+	RequestID    string                    `json:"request_id"`
+	StartTime    string                    `json:"start_time"`
+	UserID       string                    `json:"user_id"`
+	Events       []InstanceActionEventInfo `json:"events,omitempty"`
+}
+
+type InstanceActionEventInfo struct {
+	Event      string  `json:"event"`
+	StartTime  string  `json:"start_time"`
+	FinishTime string  `json:"finish_time"`
+	Result     string  `json:"result"`
+	Traceback  *string `json:"traceback,omitempty"`
+}
+
+// recordInstanceAction records an action performed on a server
+// by the request, with a single event for its outcome. It is
+// deferred by the handlers performing actions, so that the
+// outcome can be taken from the response status.
+//
+// XXX changes of state which are not requested through the API,
+// such as the guest shutting itself down, are not recorded
+func (svc *service) recordInstanceAction(c *gin.Context, vm *kubevirtv1.VirtualMachine, action string, start time.Time) {
+	var err error
+	if status := c.Writer.Status(); status >= http.StatusBadRequest {
+		if last := c.Errors.Last(); last != nil {
+			err = last.Err
+		} else {
+			err = fmt.Errorf("%s", http.StatusText(status))
+		}
+	}
+
+	userID := ""
+	if user := middleware.GetTokenSubjectUser(c); user != nil {
+		userID = user.GetID()
+	}
+	projectID := ""
+	if proj := middleware.GetTokenScopeProject(c); proj != nil {
+		projectID = proj.GetID()
+	}
+
+	act := compute.NewInstanceAction(vm.ObjectMeta.Name, action, middleware.GetRequestID(c), userID, projectID, start)
+	compute.AddInstanceActionEvent(act, compute.InstanceActionEvent(action), start, time.Now(), err)
+
+	// The response has already been sent, so failing to record
+	// the action cannot be reported to the client
+	_, err = svc.Client.Compute().InstanceActions(vm.ObjectMeta.Namespace).Create(act)
+	if err != nil {
+		glog.Errorf("Unable to record action %s on server %s: %s", action, vm.ObjectMeta.Name, err)
+	}
+}
+
+func InstanceActionInfoFromRecord(act *v1.InstanceAction) InstanceActionInfo {
+	info := InstanceActionInfo{
+		Action:       act.Spec.Action,
+		InstanceUUID: act.Spec.ServerID,
+		ProjectID:    act.Spec.ProjectID,
+		RequestID:    act.Spec.RequestID,
+		StartTime:    act.Spec.StartTime,
+		UserID:       act.Spec.UserID,
+	}
+	if act.Spec.Message != "" {
+		info.Message = &act.Spec.Message
+	}
+	return info
+}
+
+// The actions are not looked up through the server, since they
+// remain listable after it is deleted, as allowed by Nova since
+// microversion 2.21 which is below the minimum supported
+func (svc *service) ServerInstanceActionList(c *gin.Context) {
+	proj := middleware.RequiredTokenScopeProject(c)
+	id := c.Param("id")
+
+	acts, err := svc.Client.Compute().InstanceActions(proj.Spec.Namespace).List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := InstanceActionListRes{
+		InstanceActions: []InstanceActionInfo{},
+	}
+	for idx := range acts.Items {
+		act := &acts.Items[idx]
+		if act.Spec.ServerID != id {
+			continue
+		}
+		res.InstanceActions = append(res.InstanceActions, InstanceActionInfoFromRecord(act))
+	}
+
+	// Every server has at least its create action recorded, so
+	// a server without any is only reported if it still exists
+	if len(res.InstanceActions) == 0 {
+		vm, _ := svc.getServer(c, id)
+		if vm == nil {
+			return
+		}
+	}
+
+	// Most recent first, as done by Nova. The timestamps are
+	// all in UTC so they sort as strings.
+	sort.SliceStable(res.InstanceActions, func(i, j int) bool {
+		return res.InstanceActions[i].StartTime > res.InstanceActions[j].StartTime
+	})
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerInstanceActionShow(c *gin.Context) {
+	proj := middleware.RequiredTokenScopeProject(c)
+
+	act, err := svc.Client.Compute().InstanceActions(proj.Spec.Namespace).Get(
+		compute.InstanceActionName(c.Param("id"), c.Param("rid")))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	// Before microversion 2.51 only admins can see the events,
+	// and the tracebacks are always restricted to admins
+	admin := middleware.TokenHasRole(c, "admin")
+	ver := middleware.GetMicroVersion(c)
+	info := InstanceActionInfoFromRecord(act)
+	if admin || (ver != nil && ver.AtLeast(2, 51)) {
+		info.Events = []InstanceActionEventInfo{}
+		for idx := range act.Spec.Events {
+			ev := &act.Spec.Events[idx]
+			evinfo := InstanceActionEventInfo{
+				Event:      ev.Event,
+				StartTime:  ev.StartTime,
+				FinishTime: ev.FinishTime,
+				Result:     ev.Result,
+			}
+			if admin && ev.Traceback != "" {
+				evinfo.Traceback = &ev.Traceback
+			}
+			info.Events = append(info.Events, evinfo)
+		}
+	}
+
+	res := InstanceActionShowRes{
+		InstanceAction: info,
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -20,14 +20,18 @@
 package v2_1
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
 
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
 	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	identityv1 "github.com/dicot-project/dicot-api/pkg/api/identity/v1"
 	"github.com/dicot-project/dicot-api/pkg/crypto"
 	"github.com/dicot-project/dicot-api/pkg/rest"
 	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
@@ -73,15 +77,66 @@ type KeypairInfo struct {
 	ID          uint64  `json:"id"`
 }
 
+// getKeypairUser returns the user whose keypairs are being
+// accessed. This is the subject of the token, unless an admin
+// gives the ID of another user. It aborts the request and
+// returns nil on failure.
+func (svc *service) getKeypairUser(c *gin.Context, id string) *identityv1.User {
+	user := middleware.RequiredTokenSubjectUser(c)
+
+	if id == "" || id == user.GetID() {
+		return user
+	}
+
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return nil
+	}
+
+	user, err := svc.Client.Identity().Users(k8sv1.NamespaceAll).GetByID(id)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return nil
+	}
+	return user
+}
+
+// getUserKeypair fetches a keypair owned by a user
+func (svc *service) getUserKeypair(user *identityv1.User, name string) (*v1.Keypair, error) {
+	return svc.Client.Compute().Keypairs(user.ObjectMeta.Namespace).Get(
+		compute.KeypairObjectName(user.GetID(), name))
+}
+
+// listUserKeypairs returns the keypairs owned by a user, which
+// share the namespace with those of other users in the domain
+func (svc *service) listUserKeypairs(user *identityv1.User) ([]v1.Keypair, error) {
+	keypairs, err := svc.Client.Compute().Keypairs(user.ObjectMeta.Namespace).List()
+	if err != nil {
+		return nil, err
+	}
+
+	res := []v1.Keypair{}
+	for _, keypair := range keypairs.Items {
+		if keypair.Spec.UserID == user.GetID() {
+			res = append(res, keypair)
+		}
+	}
+	return res, nil
+}
+
 func (svc *service) KeypairList(c *gin.Context) {
-	proj := middleware.RequiredTokenScopeProject(c)
-	// XXX user id
+	user := svc.getKeypairUser(c, c.Query("user_id"))
+	if user == nil {
+		return
+	}
 	marker := c.Query("marker")
 	filterLimit, limit := GetFilterUInt(c, "limit")
 
-	clnt := svc.Client.Compute().Keypairs(proj.Spec.Namespace)
-
-	keypairs, err := clnt.List()
+	keypairs, err := svc.listUserKeypairs(user)
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
@@ -97,9 +152,9 @@ func (svc *service) KeypairList(c *gin.Context) {
 		seenMarker = true
 	}
 	// XXX Links field
-	for _, keypair := range keypairs.Items {
+	for _, keypair := range keypairs {
 		if marker != "" {
-			if marker == keypair.ObjectMeta.Name {
+			if marker == keypair.Spec.Name {
 				seenMarker = true
 				marker = ""
 				continue
@@ -110,7 +165,7 @@ func (svc *service) KeypairList(c *gin.Context) {
 		}
 
 		res.Keypairs = append(res.Keypairs, KeypairInfo{
-			Name:        keypair.ObjectMeta.Name,
+			Name:        keypair.Spec.Name,
 			Fingerprint: keypair.Spec.Fingerprint,
 			Type:        keypair.Spec.Type,
 			PublicKey:   keypair.Spec.PublicKey,
@@ -129,8 +184,7 @@ func (svc *service) KeypairCreate(c *gin.Context) {
 	proj := middleware.RequiredTokenScopeProject(c)
 	req := KeypairCreateReq{
 		Keypair: KeypairInfo{
-			Type:   "ssh",
-			UserID: "admin",
+			Type: compute.KEYPAIR_TYPE_SSH,
 		},
 	}
 	err := c.BindJSON(&req)
@@ -139,9 +193,19 @@ func (svc *service) KeypairCreate(c *gin.Context) {
 		return
 	}
 
-	clnt := svc.Client.Compute().Keypairs(proj.Spec.Namespace)
+	if !compute.IsValidKeypairName(req.Keypair.Name) {
+		badRequest(c, fmt.Errorf("Keypair data is invalid: The keypair name contains unsafe characters or is too long"))
+		return
+	}
 
-	exists, err := clnt.Exists(req.Keypair.Name)
+	user := svc.getKeypairUser(c, req.Keypair.UserID)
+	if user == nil {
+		return
+	}
+
+	clnt := svc.Client.Compute().Keypairs(user.ObjectMeta.Namespace)
+
+	exists, err := clnt.Exists(compute.KeypairObjectName(user.GetID(), req.Keypair.Name))
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
@@ -152,17 +216,39 @@ func (svc *service) KeypairCreate(c *gin.Context) {
 		return
 	}
 
+	// The limit on keypairs applies to each user, rather than
+	// the project as a whole
+	quota, err := svc.getQuota(proj.Spec.Namespace)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	keypairs, err := svc.listUserKeypairs(user)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if quotaExceeded(c, "key_pairs", 1, int64(len(keypairs)), quota.KeyPairs) {
+		return
+	}
+
 	var keyManager crypto.KeyManager
-	if req.Keypair.Type == "ssh" || req.Keypair.Type == "" {
+	if req.Keypair.Type == compute.KEYPAIR_TYPE_SSH || req.Keypair.Type == "" {
 		keyManager = crypto.NewSSHKeyManager()
-	} else if req.Keypair.Type == "x509" {
+		req.Keypair.Type = compute.KEYPAIR_TYPE_SSH
+	} else if req.Keypair.Type == compute.KEYPAIR_TYPE_X509 {
 		keyManager = crypto.NewX509KeyManager()
 	} else {
-		c.AbortWithStatus(http.StatusBadRequest)
+		badRequest(c, fmt.Errorf("Keypair type '%s' is not supported", req.Keypair.Type))
 		return
 	}
 	var privKey string
 	if req.Keypair.PublicKey == "" {
+		// XXX certificates cannot be generated, only imported
+		if req.Keypair.Type == compute.KEYPAIR_TYPE_X509 {
+			badRequest(c, fmt.Errorf("Generating x509 keypairs is not supported"))
+			return
+		}
 		privKey, req.Keypair.PublicKey, err = keyManager.CreateKeyPair(crypto.AlgRSA, 2048)
 		if err != nil {
 			c.AbortWithError(http.StatusInternalServerError, err)
@@ -172,32 +258,37 @@ func (svc *service) KeypairCreate(c *gin.Context) {
 	}
 	fingerprint, err := keyManager.FingerPrint(req.Keypair.PublicKey)
 	if err != nil {
-		c.AbortWithError(http.StatusBadRequest, err)
+		badRequest(c, fmt.Errorf("Keypair data is invalid: failed to generate fingerprint: %s", err))
 		return
 	}
 
 	keypair := &v1.Keypair{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: req.Keypair.Name,
+			Name: compute.KeypairObjectName(user.GetID(), req.Keypair.Name),
 		},
 		Spec: v1.KeypairSpec{
+			Name:        req.Keypair.Name,
 			Fingerprint: fingerprint,
 			Type:        req.Keypair.Type,
 			PublicKey:   req.Keypair.PublicKey,
-			UserID:      req.Keypair.UserID,
+			UserID:      user.GetID(),
 			CreatedAt:   time.Now().Format(time.RFC3339),
 		},
 	}
 
 	keypair, err = clnt.Create(keypair)
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+		if errors.IsForbidden(err) {
+			c.AbortWithError(http.StatusForbidden, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
 		return
 	}
 
 	res := KeypairCreateRes{
 		Keypair: KeypairNewInfo{
-			Name:        keypair.ObjectMeta.Name,
+			Name:        keypair.Spec.Name,
 			Fingerprint: keypair.Spec.Fingerprint,
 			Type:        keypair.Spec.Type,
 			PublicKey:   keypair.Spec.PublicKey,
@@ -210,12 +301,12 @@ func (svc *service) KeypairCreate(c *gin.Context) {
 }
 
 func (svc *service) KeypairShow(c *gin.Context) {
-	proj := middleware.RequiredTokenScopeProject(c)
-	name := c.Param("name")
-
-	clnt := svc.Client.Compute().Keypairs(proj.Spec.Namespace)
+	user := svc.getKeypairUser(c, c.Query("user_id"))
+	if user == nil {
+		return
+	}
 
-	keypair, err := clnt.Get(name)
+	keypair, err := svc.getUserKeypair(user, c.Param("name"))
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -228,7 +319,7 @@ func (svc *service) KeypairShow(c *gin.Context) {
 	res := KeypairShowRes{
 		Keypair: KeypairInfo{
 			ID:          keypair.Spec.ID,
-			Name:        keypair.ObjectMeta.Name,
+			Name:        keypair.Spec.Name,
 			Fingerprint: keypair.Spec.Fingerprint,
 			Type:        keypair.Spec.Type,
 			PublicKey:   keypair.Spec.PublicKey,
@@ -242,12 +333,12 @@ func (svc *service) KeypairShow(c *gin.Context) {
 }
 
 func (svc *service) KeypairDelete(c *gin.Context) {
-	proj := middleware.RequiredTokenScopeProject(c)
-	name := c.Param("name")
-
-	clnt := svc.Client.Compute().Keypairs(proj.Spec.Namespace)
+	user := svc.getKeypairUser(c, c.Query("user_id"))
+	if user == nil {
+		return
+	}
 
-	keypair, err := clnt.Get(name)
+	keypair, err := svc.getUserKeypair(user, c.Param("name"))
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -257,7 +348,7 @@ func (svc *service) KeypairDelete(c *gin.Context) {
 		return
 	}
 
-	err = clnt.Delete(keypair.ObjectMeta.Name, nil)
+	err = svc.Client.Compute().Keypairs(keypair.ObjectMeta.Namespace).Delete(keypair.ObjectMeta.Name, nil)
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
@@ -0,0 +1,406 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+	"github.com/dicot-project/dicot-api/pkg/rest"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+type ServerLiveMigrateInfo struct {
+	Host *string `json:"host"`
+	// Block migration and forcing the host are irrelevant
+	// since root disks are on shared storage and placement is
+	// always checked by the Kubernetes scheduler
+	BlockMigration json.RawMessage `json:"block_migration"`
+	Force          *bool           `json:"force"`
+}
+
+type MigrationListRes struct {
+	Migrations []MigrationInfo `json:"migrations"`
+}
+
+type MigrationInfo struct {
+	ID                uint64          `json:"id"`
+	InstanceUUID      string          `json:"instance_uuid"`
+	MigrationType     string          `json:"migration_type"`
+	Status            string          `json:"status"`
+	SourceCompute     string          `json:"source_compute"`
+	SourceNode        string          `json:"source_node"`
+	SourceRegion      *string         `json:"source_region"`
+	DestCompute       string          `json:"dest_compute"`
+	DestNode          string          `json:"dest_node"`
+	DestHost          string          `json:"dest_host"`
+	OldInstanceTypeID *uint64         `json:"old_instance_type_id"`
+	NewInstanceTypeID *uint64         `json:"new_instance_type_id"`
+	CreatedAt         string          `json:"created_at"`
+	UpdatedAt         *string         `json:"updated_at"`
+	Links             []rest.LinkInfo `json:"links,omitempty"`
+}
+
+type ServerMigrationListRes struct {
+	Migrations []ServerMigrationInfo `json:"migrations"`
+}
+
+type ServerMigrationShowRes struct {
+	Migration ServerMigrationInfo `json:"migration"`
+}
+
+type ServerMigrationInfo struct {
+	ID                   uint64  `json:"id"`
+	ServerUUID           string  `json:"server_uuid"`
+	Status               string  `json:"status"`
+	SourceCompute        string  `json:"source_compute"`
+	SourceNode           string  `json:"source_node"`
+	DestCompute          string  `json:"dest_compute"`
+	DestNode             string  `json:"dest_node"`
+	DestHost             string  `json:"dest_host"`
+	MemoryTotalBytes     *uint64 `json:"memory_total_bytes"`
+	MemoryProcessedBytes *uint64 `json:"memory_processed_bytes"`
+	MemoryRemainingBytes *uint64 `json:"memory_remaining_bytes"`
+	DiskTotalBytes       *uint64 `json:"disk_total_bytes"`
+	DiskProcessedBytes   *uint64 `json:"disk_processed_bytes"`
+	DiskRemainingBytes   *uint64 `json:"disk_remaining_bytes"`
+	CreatedAt            string  `json:"created_at"`
+	UpdatedAt            *string `json:"updated_at"`
+}
+
+// migrationNodes returns the source and destination nodes of
+// the migration. The destination chosen by the scheduler is
+// only known from the instance until it migrates again.
+func migrationNodes(mig *kubevirtv1.VirtualMachineInstanceMigration, vmi *kubevirtv1.VirtualMachineInstance) (string, string) {
+	src := mig.ObjectMeta.Annotations[compute.AnnotationMigrationSource]
+	dst := mig.ObjectMeta.Annotations[compute.AnnotationMigrationDest]
+	if vmi != nil && vmi.Status.MigrationState != nil &&
+		vmi.Status.MigrationState.MigrationUID == mig.ObjectMeta.UID &&
+		vmi.Status.MigrationState.TargetNode != "" {
+		dst = vmi.Status.MigrationState.TargetNode
+	}
+	return src, dst
+}
+
+func migrationUpdatedAt(mig *kubevirtv1.VirtualMachineInstanceMigration, vmi *kubevirtv1.VirtualMachineInstance) *string {
+	if vmi == nil || vmi.Status.MigrationState == nil ||
+		vmi.Status.MigrationState.MigrationUID != mig.ObjectMeta.UID {
+		return nil
+	}
+	state := vmi.Status.MigrationState
+	ts := state.EndTimestamp
+	if ts == nil {
+		ts = state.StartTimestamp
+	}
+	if ts == nil {
+		return nil
+	}
+	updated := ts.Format(time.RFC3339)
+	return &updated
+}
+
+func (svc *service) migrationInfo(c *gin.Context, mig *kubevirtv1.VirtualMachineInstanceMigration, vmi *kubevirtv1.VirtualMachineInstance) MigrationInfo {
+	src, dst := migrationNodes(mig, vmi)
+	id := mig.ObjectMeta.Labels[compute.LabelServerID]
+	info := MigrationInfo{
+		ID:            compute.MigrationID(mig),
+		InstanceUUID:  id,
+		MigrationType: mig.ObjectMeta.Annotations[compute.AnnotationMigrationType],
+		Status:        compute.MigrationStatus(mig),
+		SourceCompute: src,
+		SourceNode:    src,
+		DestCompute:   dst,
+		DestNode:      dst,
+		CreatedAt:     mig.ObjectMeta.CreationTimestamp.Format(time.RFC3339),
+		UpdatedAt:     migrationUpdatedAt(mig, vmi),
+	}
+
+	if info.MigrationType == compute.MIGRATION_TYPE_LIVE && compute.MigrationInProgress(mig) {
+		mid := strconv.FormatUint(info.ID, 10)
+		info.Links = []rest.LinkInfo{
+			rest.LinkInfo{
+				Rel:  "self",
+				HRef: "http://" + c.Request.Host + svc.Prefix + "/servers/" + id + "/migrations/" + mid,
+			},
+			rest.LinkInfo{
+				Rel:  "bookmark",
+				HRef: "http://" + c.Request.Host + "/servers/" + id + "/migrations/" + mid,
+			},
+		}
+	}
+
+	return info
+}
+
+func serverMigrationInfo(mig *kubevirtv1.VirtualMachineInstanceMigration, vmi *kubevirtv1.VirtualMachineInstance) ServerMigrationInfo {
+	src, dst := migrationNodes(mig, vmi)
+	// XXX KubeVirt does not report the progress of copying
+	// guest memory
+	return ServerMigrationInfo{
+		ID:            compute.MigrationID(mig),
+		ServerUUID:    mig.ObjectMeta.Labels[compute.LabelServerID],
+		Status:        compute.MigrationStatus(mig),
+		SourceCompute: src,
+		SourceNode:    src,
+		DestCompute:   dst,
+		DestNode:      dst,
+		CreatedAt:     mig.ObjectMeta.CreationTimestamp.Format(time.RFC3339),
+		UpdatedAt:     migrationUpdatedAt(mig, vmi),
+	}
+}
+
+// validateMigrationServer checks the server is not restricted
+// to its current host, which the migration could not change. It
+// aborts the request and returns false if it is.
+func validateMigrationServer(c *gin.Context, vmi *kubevirtv1.VirtualMachineInstance) bool {
+	if host := compute.InstanceHost(&vmi.Spec); host != "" {
+		badRequest(c, fmt.Errorf("Server %s is restricted to host %s and cannot be migrated.",
+			vmi.ObjectMeta.Name, host))
+		return false
+	}
+	return true
+}
+
+// validateMigrationHost checks the requested target of a
+// migration. It aborts the request and returns false if the
+// host does not exist.
+func (svc *service) validateMigrationHost(c *gin.Context, host string) bool {
+	_, err := svc.K8SClient.CoreV1().Nodes().Get(host, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			badRequest(c, fmt.Errorf("Compute host %s could not be found.", host))
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return false
+	}
+	return true
+}
+
+// migrateServer starts moving the running instance of the server
+// to another node, optionally a specific host
+func (svc *service) migrateServer(vm *kubevirtv1.VirtualMachine, vmi *kubevirtv1.VirtualMachineInstance, migType, host string) error {
+	namespace := vm.ObjectMeta.Namespace
+
+	migs, err := svc.Client.Kubevirt().VirtualMachineInstanceMigrations(namespace).List()
+	if err != nil {
+		return err
+	}
+	for idx := range migs.Items {
+		mig := &migs.Items[idx]
+		if mig.Spec.VMIName == vmi.ObjectMeta.Name && compute.MigrationInProgress(mig) {
+			return errors.NewConflict(kubevirtv1.Resource("virtualmachineinstancemigrations"),
+				mig.ObjectMeta.Name, fmt.Errorf("Server %s is already migrating", vm.ObjectMeta.Name))
+		}
+	}
+
+	mig := compute.NewMigration(vm, vmi, migType, host)
+	_, err = svc.Client.Kubevirt().VirtualMachineInstanceMigrations(namespace).Create(mig)
+	return err
+}
+
+func (svc *service) MigrationList(c *gin.Context) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	host := c.Query("host")
+	status := c.Query("status")
+	server := c.Query("instance_uuid")
+	migType := c.Query("migration_type")
+
+	migs, err := svc.Client.Kubevirt().VirtualMachineInstanceMigrations(k8sv1.NamespaceAll).List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	vmis, err := svc.Client.Kubevirt().VirtualMachineInstances(k8sv1.NamespaceAll).List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	vmiMap := make(map[string]*kubevirtv1.VirtualMachineInstance)
+	for idx := range vmis.Items {
+		vmi := &vmis.Items[idx]
+		vmiMap[vmi.ObjectMeta.Namespace+"/"+vmi.ObjectMeta.Name] = vmi
+	}
+
+	res := MigrationListRes{
+		Migrations: []MigrationInfo{},
+	}
+
+	for idx := range migs.Items {
+		mig := &migs.Items[idx]
+		if _, ok := mig.ObjectMeta.Annotations[compute.AnnotationMigrationType]; !ok {
+			continue
+		}
+
+		vmi := vmiMap[mig.ObjectMeta.Namespace+"/"+mig.Spec.VMIName]
+		info := svc.migrationInfo(c, mig, vmi)
+
+		if host != "" && info.SourceCompute != host && info.DestCompute != host {
+			continue
+		}
+		if status != "" && info.Status != status {
+			continue
+		}
+		if server != "" && info.InstanceUUID != server {
+			continue
+		}
+		if migType != "" && info.MigrationType != migType {
+			continue
+		}
+
+		res.Migrations = append(res.Migrations, info)
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// getServerMigrations returns the in progress live migrations of
+// the server. It aborts the request and returns nil on failure.
+func (svc *service) getServerMigrations(c *gin.Context) ([]*kubevirtv1.VirtualMachineInstanceMigration, *kubevirtv1.VirtualMachineInstance) {
+	id := c.Param("id")
+
+	vm, vmi := svc.getServer(c, id)
+	if vm == nil {
+		return nil, nil
+	}
+
+	migs, err := svc.Client.Kubevirt().VirtualMachineInstanceMigrations(vm.ObjectMeta.Namespace).List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return nil, nil
+	}
+
+	res := []*kubevirtv1.VirtualMachineInstanceMigration{}
+	for idx := range migs.Items {
+		mig := &migs.Items[idx]
+		if mig.ObjectMeta.Labels[compute.LabelServerID] != id ||
+			mig.ObjectMeta.Annotations[compute.AnnotationMigrationType] != compute.MIGRATION_TYPE_LIVE ||
+			!compute.MigrationInProgress(mig) {
+			continue
+		}
+		res = append(res, mig)
+	}
+
+	return res, vmi
+}
+
+// getServerMigration finds the in progress live migration of the
+// server given in the request. It aborts the request and returns
+// nil if not found.
+func (svc *service) getServerMigration(c *gin.Context) (*kubevirtv1.VirtualMachineInstanceMigration, *kubevirtv1.VirtualMachineInstance) {
+	mid, err := strconv.ParseUint(c.Param("mid"), 10, 64)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return nil, nil
+	}
+
+	migs, vmi := svc.getServerMigrations(c)
+	if migs == nil {
+		return nil, nil
+	}
+
+	for _, mig := range migs {
+		if compute.MigrationID(mig) == mid {
+			return mig, vmi
+		}
+	}
+
+	c.AbortWithStatus(http.StatusNotFound)
+	return nil, nil
+}
+
+func (svc *service) ServerMigrationList(c *gin.Context) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	migs, vmi := svc.getServerMigrations(c)
+	if migs == nil {
+		return
+	}
+
+	res := ServerMigrationListRes{
+		Migrations: []ServerMigrationInfo{},
+	}
+	for _, mig := range migs {
+		res.Migrations = append(res.Migrations, serverMigrationInfo(mig, vmi))
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerMigrationShow(c *gin.Context) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	mig, vmi := svc.getServerMigration(c)
+	if mig == nil {
+		return
+	}
+
+	res := ServerMigrationShowRes{
+		Migration: serverMigrationInfo(mig, vmi),
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerMigrationDelete(c *gin.Context) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	mig, _ := svc.getServerMigration(c)
+	if mig == nil {
+		return
+	}
+
+	// KubeVirt aborts a migration when it is deleted
+	err := svc.Client.Kubevirt().VirtualMachineInstanceMigrations(mig.ObjectMeta.Namespace).Delete(
+		mig.ObjectMeta.Name, &metav1.DeleteOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.String(http.StatusAccepted, "")
+}
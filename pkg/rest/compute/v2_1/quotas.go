@@ -0,0 +1,480 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	identityv1 "github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+type ForbiddenRes struct {
+	Forbidden ErrorInfo `json:"forbidden"`
+}
+
+type QuotaSetRes struct {
+	QuotaSet QuotaSetInfo `json:"quota_set"`
+}
+
+type QuotaSetInfo struct {
+	ID                       string `json:"id,omitempty"`
+	Instances                int64  `json:"instances"`
+	Cores                    int64  `json:"cores"`
+	RAM                      int64  `json:"ram"`
+	KeyPairs                 int64  `json:"key_pairs"`
+	ServerGroups             int64  `json:"server_groups"`
+	ServerGroupMembers       int64  `json:"server_group_members"`
+	MetadataItems            int64  `json:"metadata_items"`
+	InjectedFiles            int64  `json:"injected_files"`
+	InjectedFileContentBytes int64  `json:"injected_file_content_bytes"`
+	InjectedFilePathBytes    int64  `json:"injected_file_path_bytes"`
+}
+
+type QuotaSetDetailRes struct {
+	QuotaSet QuotaSetDetailInfo `json:"quota_set"`
+}
+
+type QuotaSetDetailInfo struct {
+	ID                       string          `json:"id"`
+	Instances                QuotaDetailInfo `json:"instances"`
+	Cores                    QuotaDetailInfo `json:"cores"`
+	RAM                      QuotaDetailInfo `json:"ram"`
+	KeyPairs                 QuotaDetailInfo `json:"key_pairs"`
+	ServerGroups             QuotaDetailInfo `json:"server_groups"`
+	ServerGroupMembers       QuotaDetailInfo `json:"server_group_members"`
+	MetadataItems            QuotaDetailInfo `json:"metadata_items"`
+	InjectedFiles            QuotaDetailInfo `json:"injected_files"`
+	InjectedFileContentBytes QuotaDetailInfo `json:"injected_file_content_bytes"`
+	InjectedFilePathBytes    QuotaDetailInfo `json:"injected_file_path_bytes"`
+}
+
+type QuotaDetailInfo struct {
+	InUse    int64 `json:"in_use"`
+	Limit    int64 `json:"limit"`
+	Reserved int64 `json:"reserved"`
+}
+
+type QuotaSetUpdateReq struct {
+	QuotaSet QuotaSetUpdateInfo `json:"quota_set"`
+}
+
+type QuotaSetUpdateInfo struct {
+	Force                    bool   `json:"force"`
+	Instances                *int64 `json:"instances"`
+	Cores                    *int64 `json:"cores"`
+	RAM                      *int64 `json:"ram"`
+	KeyPairs                 *int64 `json:"key_pairs"`
+	ServerGroups             *int64 `json:"server_groups"`
+	ServerGroupMembers       *int64 `json:"server_group_members"`
+	MetadataItems            *int64 `json:"metadata_items"`
+	InjectedFiles            *int64 `json:"injected_files"`
+	InjectedFileContentBytes *int64 `json:"injected_file_content_bytes"`
+	InjectedFilePathBytes    *int64 `json:"injected_file_path_bytes"`
+}
+
+type LimitsRes struct {
+	Limits LimitsInfo `json:"limits"`
+}
+
+type LimitsInfo struct {
+	Rate     []interface{}      `json:"rate"`
+	Absolute AbsoluteLimitsInfo `json:"absolute"`
+}
+
+type AbsoluteLimitsInfo struct {
+	MaxPersonality        int64 `json:"maxPersonality"`
+	MaxPersonalitySize    int64 `json:"maxPersonalitySize"`
+	MaxServerGroupMembers int64 `json:"maxServerGroupMembers"`
+	MaxServerGroups       int64 `json:"maxServerGroups"`
+	MaxServerMeta         int64 `json:"maxServerMeta"`
+	MaxTotalCores         int64 `json:"maxTotalCores"`
+	MaxTotalInstances     int64 `json:"maxTotalInstances"`
+	MaxTotalKeypairs      int64 `json:"maxTotalKeypairs"`
+	MaxTotalRAMSize       int64 `json:"maxTotalRAMSize"`
+	TotalCoresUsed        int64 `json:"totalCoresUsed"`
+	TotalInstancesUsed    int64 `json:"totalInstancesUsed"`
+	TotalRAMUsed          int64 `json:"totalRAMUsed"`
+	TotalServerGroupsUsed int64 `json:"totalServerGroupsUsed"`
+}
+
+func quotaSetInfo(id string, quota compute.Quota) QuotaSetInfo {
+	return QuotaSetInfo{
+		ID:                       id,
+		Instances:                quota.Instances,
+		Cores:                    quota.Cores,
+		RAM:                      quota.RAM,
+		KeyPairs:                 quota.KeyPairs,
+		ServerGroups:             quota.ServerGroups,
+		ServerGroupMembers:       quota.ServerGroupMembers,
+		MetadataItems:            compute.SERVER_METADATA_MAX_ITEMS,
+		InjectedFiles:            serverMaxInjectedFiles,
+		InjectedFileContentBytes: serverMaxInjectedFileContent,
+		InjectedFilePathBytes:    serverMaxInjectedFilePath,
+	}
+}
+
+// getQuota returns the limits of the project whose resources
+// are in the namespace ns
+func (svc *service) getQuota(ns string) (compute.Quota, error) {
+	rq, err := svc.K8SClient.CoreV1().ResourceQuotas(ns).Get(compute.QuotaName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return compute.QuotaFromResourceQuota(nil), nil
+		}
+		return compute.Quota{}, err
+	}
+	return compute.QuotaFromResourceQuota(rq), nil
+}
+
+// getQuotaUsage counts the resources currently used by the
+// project whose resources are in the namespace ns
+func (svc *service) getQuotaUsage(ns string) (compute.Quota, error) {
+	usage := compute.Quota{}
+
+	vms, err := svc.Client.Kubevirt().VirtualMachines(ns).List()
+	if err != nil {
+		return usage, err
+	}
+	for idx := range vms.Items {
+		cores, ram := compute.ServerResources(&vms.Items[idx])
+		usage.Instances++
+		usage.Cores += cores
+		usage.RAM += ram
+	}
+
+	// Keypairs belong to users rather than projects, so the
+	// project has no usage of them
+	groups, err := svc.Client.Compute().ServerGroups(ns).List()
+	if err != nil {
+		return usage, err
+	}
+	usage.ServerGroups = int64(len(groups.Items))
+
+	return usage, nil
+}
+
+// quotaExceeded aborts the request and returns true if adding
+// the requested amount of a resource to that already used
+// would exceed the limit
+func quotaExceeded(c *gin.Context, name string, requested, used, limit int64) bool {
+	if limit < 0 || used+requested <= limit {
+		return false
+	}
+
+	c.AbortWithStatusJSON(http.StatusForbidden, ForbiddenRes{
+		Forbidden: ErrorInfo{
+			Code: http.StatusForbidden,
+			Message: fmt.Sprintf("Quota exceeded for %s: Requested %d, but already used %d of %d %s",
+				name, requested, used, limit, name),
+		},
+	})
+	return true
+}
+
+// getQuotaProject returns the project whose quota or usage is
+// being accessed, checking that it is the project of the token, or
+// that the token has the admin role. It aborts the request and
+// returns nil on failure.
+func (svc *service) getQuotaProject(c *gin.Context, id string) *identityv1.Project {
+	tokenProj := middleware.RequiredTokenScopeProject(c)
+
+	if id == tokenProj.GetID() {
+		return tokenProj
+	}
+
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return nil
+	}
+
+	proj, err := svc.Client.Identity().Projects(k8sv1.NamespaceAll).GetByID(id)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return nil
+	}
+
+	// Domains are also projects, but don't have any compute
+	// resources of their own
+	if proj.ObjectMeta.Namespace == identityv1.NamespaceSystem {
+		c.AbortWithStatus(http.StatusNotFound)
+		return nil
+	}
+
+	return proj
+}
+
+func (svc *service) QuotaSetShow(c *gin.Context) {
+	id := c.Param("id")
+
+	proj := svc.getQuotaProject(c, id)
+	if proj == nil {
+		return
+	}
+
+	quota, err := svc.getQuota(proj.Spec.Namespace)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := QuotaSetRes{
+		QuotaSet: quotaSetInfo(proj.GetID(), quota),
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func quotaDetailInfo(used, limit int64) QuotaDetailInfo {
+	return QuotaDetailInfo{
+		InUse: used,
+		Limit: limit,
+	}
+}
+
+func (svc *service) QuotaSetShowDetail(c *gin.Context) {
+	id := c.Param("id")
+
+	proj := svc.getQuotaProject(c, id)
+	if proj == nil {
+		return
+	}
+
+	quota, err := svc.getQuota(proj.Spec.Namespace)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	usage, err := svc.getQuotaUsage(proj.Spec.Namespace)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := QuotaSetDetailRes{
+		QuotaSet: QuotaSetDetailInfo{
+			ID:                       proj.GetID(),
+			Instances:                quotaDetailInfo(usage.Instances, quota.Instances),
+			Cores:                    quotaDetailInfo(usage.Cores, quota.Cores),
+			RAM:                      quotaDetailInfo(usage.RAM, quota.RAM),
+			KeyPairs:                 quotaDetailInfo(usage.KeyPairs, quota.KeyPairs),
+			ServerGroups:             quotaDetailInfo(usage.ServerGroups, quota.ServerGroups),
+			ServerGroupMembers:       quotaDetailInfo(0, quota.ServerGroupMembers),
+			MetadataItems:            quotaDetailInfo(0, compute.SERVER_METADATA_MAX_ITEMS),
+			InjectedFiles:            quotaDetailInfo(0, serverMaxInjectedFiles),
+			InjectedFileContentBytes: quotaDetailInfo(0, serverMaxInjectedFileContent),
+			InjectedFilePathBytes:    quotaDetailInfo(0, serverMaxInjectedFilePath),
+		},
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) QuotaSetShowDefaults(c *gin.Context) {
+	id := c.Param("id")
+
+	res := QuotaSetRes{
+		QuotaSet: quotaSetInfo(id, compute.DefaultQuota()),
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) QuotaSetUpdate(c *gin.Context) {
+	id := c.Param("id")
+
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	proj := svc.getQuotaProject(c, id)
+	if proj == nil {
+		return
+	}
+
+	req := QuotaSetUpdateReq{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	// The limits enforced by the server metadata and personality
+	// validation are fixed
+	fixed := []struct {
+		Value *int64
+		Limit int64
+	}{
+		{req.QuotaSet.MetadataItems, compute.SERVER_METADATA_MAX_ITEMS},
+		{req.QuotaSet.InjectedFiles, serverMaxInjectedFiles},
+		{req.QuotaSet.InjectedFileContentBytes, serverMaxInjectedFileContent},
+		{req.QuotaSet.InjectedFilePathBytes, serverMaxInjectedFilePath},
+	}
+	for _, field := range fixed {
+		if field.Value != nil && *field.Value != field.Limit {
+			c.AbortWithError(http.StatusBadRequest,
+				fmt.Errorf("Quota limit %d cannot be changed", field.Limit))
+			return
+		}
+	}
+
+	clnt := svc.K8SClient.CoreV1().ResourceQuotas(proj.Spec.Namespace)
+
+	rq, err := clnt.Get(compute.QuotaName, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		rq = nil
+	}
+
+	quota := compute.QuotaFromResourceQuota(rq)
+
+	usage, err := svc.getQuotaUsage(proj.Spec.Namespace)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	updates := []struct {
+		Value *int64
+		Limit *int64
+		Used  int64
+	}{
+		{req.QuotaSet.Instances, &quota.Instances, usage.Instances},
+		{req.QuotaSet.Cores, &quota.Cores, usage.Cores},
+		{req.QuotaSet.RAM, &quota.RAM, usage.RAM},
+		{req.QuotaSet.KeyPairs, &quota.KeyPairs, usage.KeyPairs},
+		{req.QuotaSet.ServerGroups, &quota.ServerGroups, usage.ServerGroups},
+		{req.QuotaSet.ServerGroupMembers, &quota.ServerGroupMembers, 0},
+	}
+	for _, field := range updates {
+		if field.Value == nil {
+			continue
+		}
+		if *field.Value < compute.QUOTA_UNLIMITED {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		// Unless forced, a limit cannot be set below the
+		// current usage
+		if !req.QuotaSet.Force && *field.Value >= 0 && *field.Value < field.Used {
+			c.AbortWithError(http.StatusBadRequest,
+				fmt.Errorf("Quota limit %d is less than the %d in use", *field.Value, field.Used))
+			return
+		}
+		*field.Limit = *field.Value
+	}
+
+	if rq == nil {
+		rq = &k8sv1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: compute.QuotaName,
+			},
+			Spec: k8sv1.ResourceQuotaSpec{
+				Hard: quota.ResourceList(),
+			},
+		}
+		_, err = clnt.Create(rq)
+	} else {
+		rq.Spec.Hard = quota.ResourceList()
+		_, err = clnt.Update(rq)
+	}
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := QuotaSetRes{
+		QuotaSet: quotaSetInfo("", quota),
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) QuotaSetDelete(c *gin.Context) {
+	id := c.Param("id")
+
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	proj := svc.getQuotaProject(c, id)
+	if proj == nil {
+		return
+	}
+
+	err := svc.K8SClient.CoreV1().ResourceQuotas(proj.Spec.Namespace).Delete(compute.QuotaName, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.String(http.StatusAccepted, "")
+}
+
+func (svc *service) LimitsShow(c *gin.Context) {
+	proj := middleware.RequiredTokenScopeProject(c)
+
+	quota, err := svc.getQuota(proj.Spec.Namespace)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	usage, err := svc.getQuotaUsage(proj.Spec.Namespace)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := LimitsRes{
+		Limits: LimitsInfo{
+			Rate: []interface{}{},
+			Absolute: AbsoluteLimitsInfo{
+				MaxPersonality:        serverMaxInjectedFiles,
+				MaxPersonalitySize:    serverMaxInjectedFileContent,
+				MaxServerGroupMembers: quota.ServerGroupMembers,
+				MaxServerGroups:       quota.ServerGroups,
+				MaxServerMeta:         compute.SERVER_METADATA_MAX_ITEMS,
+				MaxTotalCores:         quota.Cores,
+				MaxTotalInstances:     quota.Instances,
+				MaxTotalKeypairs:      quota.KeyPairs,
+				MaxTotalRAMSize:       quota.RAM,
+				TotalCoresUsed:        usage.Cores,
+				TotalInstancesUsed:    usage.Instances,
+				TotalRAMUsed:          usage.RAM,
+				TotalServerGroupsUsed: usage.ServerGroups,
+			},
+		},
+	}
+	c.JSON(http.StatusOK, res)
+}
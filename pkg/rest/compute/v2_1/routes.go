@@ -24,6 +24,7 @@ import (
 	k8s "k8s.io/client-go/kubernetes"
 
 	"github.com/dicot-project/dicot-api/pkg/api"
+	"github.com/dicot-project/dicot-api/pkg/audit"
 	"github.com/dicot-project/dicot-api/pkg/auth"
 	"github.com/dicot-project/dicot-api/pkg/rest"
 	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
@@ -35,9 +36,11 @@ type service struct {
 	Prefix       string
 	ServerID     string
 	TokenManager auth.TokenManager
+	Auditor      audit.Auditor
+	ImageRepoURL string
 }
 
-func NewService(client api.Interface, k8sClient k8s.Interface, tm auth.TokenManager, serverID string, prefix string) rest.Service {
+func NewService(client api.Interface, k8sClient k8s.Interface, tm auth.TokenManager, auditor audit.Auditor, imageRepoURL string, serverID string, prefix string) rest.Service {
 	if prefix == "" {
 		prefix = "/compute/v2.1"
 	}
@@ -47,6 +50,8 @@ func NewService(client api.Interface, k8sClient k8s.Interface, tm auth.TokenMana
 		Prefix:       prefix,
 		ServerID:     serverID,
 		TokenManager: tm,
+		Auditor:      auditor,
+		ImageRepoURL: imageRepoURL,
 	}
 }
 
@@ -77,7 +82,7 @@ func (svc *service) RegisterRoutes(router *gin.RouterGroup) {
 	}
 	router.Use(middleware.NewMicroVersionHandler("compute", "X-OpenStack-Nova-API-Version", min, max).Handler())
 
-	router.Use(middleware.NewTokenHandler(svc.TokenManager, svc.Client.Identity()).Handler())
+	router.Use(middleware.NewTokenHandler(svc.TokenManager, svc.Client.Identity(), svc.Auditor).Handler())
 
 	//router.GET("/", svc.IndexShow)
 	router.GET("/", svc.VersionIndexShow)
@@ -87,6 +92,8 @@ func (svc *service) RegisterRoutes(router *gin.RouterGroup) {
 	router.DELETE("/flavors/:id", svc.FlavorDelete)
 	//router.GET("/flavors/detail", svc.FlavorListDetail)
 	router.GET("/flavors/:id", svc.FlavorShow)
+	router.POST("/flavors/:id/action", svc.FlavorAction)
+	router.GET("/flavors/:id/os-flavor-access", svc.FlavorAccessList)
 	router.GET("/flavors/:id/os-extra_specs", svc.FlavorShowExtraSpecs)
 	router.POST("/flavors/:id/os-extra_specs", svc.FlavorCreateExtraSpecs)
 	router.GET("/flavors/:id/os-extra_specs/:key", svc.FlavorShowExtraSpec)
@@ -98,8 +105,73 @@ func (svc *service) RegisterRoutes(router *gin.RouterGroup) {
 	router.GET("/os-keypairs/:name", svc.KeypairShow)
 	router.DELETE("/os-keypairs/:name", svc.KeypairDelete)
 
+	router.GET("/servers", svc.ServerList)
+	router.POST("/servers", svc.ServerCreate)
+	router.GET("/servers/:id", svc.ServerShow)
+	router.DELETE("/servers/:id", svc.ServerDelete)
+	router.POST("/servers/:id/action", svc.ServerAction)
+	router.POST("/servers/:id/remote-consoles", svc.ServerRemoteConsoleCreate)
+	router.GET("/servers/:id/metadata", svc.ServerMetadataList)
+	router.POST("/servers/:id/metadata", svc.ServerMetadataUpdate)
+	router.PUT("/servers/:id/metadata", svc.ServerMetadataReplace)
+	router.GET("/servers/:id/metadata/:key", svc.ServerMetadataShow)
+	router.PUT("/servers/:id/metadata/:key", svc.ServerMetadataSet)
+	router.DELETE("/servers/:id/metadata/:key", svc.ServerMetadataDelete)
+	router.GET("/servers/:id/tags", svc.ServerTagList)
+	router.PUT("/servers/:id/tags", svc.ServerTagReplace)
+	router.DELETE("/servers/:id/tags", svc.ServerTagDeleteAll)
+	router.GET("/servers/:id/tags/:tag", svc.ServerTagShow)
+	router.PUT("/servers/:id/tags/:tag", svc.ServerTagAdd)
+	router.DELETE("/servers/:id/tags/:tag", svc.ServerTagDelete)
+	router.GET("/servers/:id/migrations", svc.ServerMigrationList)
+	router.GET("/servers/:id/migrations/:mid", svc.ServerMigrationShow)
+	router.DELETE("/servers/:id/migrations/:mid", svc.ServerMigrationDelete)
+	router.GET("/servers/:id/os-instance-actions", svc.ServerInstanceActionList)
+	router.GET("/servers/:id/os-instance-actions/:rid", svc.ServerInstanceActionShow)
+	router.GET("/servers/:id/os-server-password", svc.ServerPasswordShow)
+	router.DELETE("/servers/:id/os-server-password", svc.ServerPasswordDelete)
+	router.GET("/servers/:id/os-interface", svc.ServerInterfaceList)
+	router.POST("/servers/:id/os-interface", svc.ServerInterfaceAttach)
+	router.GET("/servers/:id/os-interface/:pid", svc.ServerInterfaceShow)
+	router.DELETE("/servers/:id/os-interface/:pid", svc.ServerInterfaceDetach)
+
+	router.GET("/os-server-groups", svc.ServerGroupList)
+	router.POST("/os-server-groups", svc.ServerGroupCreate)
+	router.GET("/os-server-groups/:id", svc.ServerGroupShow)
+	router.DELETE("/os-server-groups/:id", svc.ServerGroupDelete)
+
+	router.GET("/os-availability-zone", svc.AvailabilityZoneList)
+	router.GET("/os-availability-zone/detail", svc.AvailabilityZoneListDetail)
+
+	router.GET("/os-aggregates", svc.AggregateList)
+	router.POST("/os-aggregates", svc.AggregateCreate)
+	router.GET("/os-aggregates/:id", svc.AggregateShow)
+	router.PUT("/os-aggregates/:id", svc.AggregateUpdate)
+	router.DELETE("/os-aggregates/:id", svc.AggregateDelete)
+	router.POST("/os-aggregates/:id/action", svc.AggregateAction)
+
+	router.GET("/os-quota-sets/:id", svc.QuotaSetShow)
+	router.PUT("/os-quota-sets/:id", svc.QuotaSetUpdate)
+	router.DELETE("/os-quota-sets/:id", svc.QuotaSetDelete)
+	router.GET("/os-quota-sets/:id/defaults", svc.QuotaSetShowDefaults)
+	router.GET("/os-quota-sets/:id/detail", svc.QuotaSetShowDetail)
+
+	router.GET("/limits", svc.LimitsShow)
+
+	router.GET("/os-simple-tenant-usage", svc.TenantUsageList)
+	router.GET("/os-simple-tenant-usage/:id", svc.TenantUsageShow)
+
 	router.GET("/os-hypervisors", svc.HypervisorList)
 	//router.GET("/os-hypervisors/detail", svc.HypervisorList)
 	router.GET("/os-hypervisors/:name", svc.HypervisorShow)
+	router.GET("/os-hypervisors/:name/servers", svc.HypervisorServers)
+
+	router.GET("/os-services", svc.ServiceList)
+	// XXX the enable, disable, disable-log-reason and force-down
+	// actions replaced by the ID in microversion 2.53 are not
+	// supported, since older microversions are not
+	router.PUT("/os-services/:id", svc.ServiceUpdate)
+
+	router.GET("/os-migrations", svc.MigrationList)
 
 }
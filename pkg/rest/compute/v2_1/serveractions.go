@@ -0,0 +1,309 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+type ServerRebootInfo struct {
+	Type string `json:"type"`
+}
+
+type ConflictingRequestRes struct {
+	ConflictingRequest ErrorInfo `json:"conflictingRequest"`
+}
+
+type ErrorInfo struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func serverActionConflict(c *gin.Context, action, id, status string) {
+	res := ConflictingRequestRes{
+		ConflictingRequest: ErrorInfo{
+			Code: http.StatusConflict,
+			Message: fmt.Sprintf("Cannot '%s' instance %s while it is in vm_state %s",
+				action, id, serverVMStates[status]),
+		},
+	}
+	c.AbortWithStatusJSON(http.StatusConflict, res)
+}
+
+func (svc *service) ServerAction(c *gin.Context) {
+	proj := middleware.RequiredTokenScopeProject(c)
+	id := c.Param("id")
+
+	req := map[string]json.RawMessage{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if len(req) != 1 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var action string
+	var body json.RawMessage
+	for action, body = range req {
+	}
+
+	// Actions which retrieve information rather than change
+	// the server state
+	switch action {
+	case "os-getVNCConsole":
+		svc.serverActionConsole(c, id, body, "novnc")
+		return
+	case "os-getSerialConsole":
+		svc.serverActionConsole(c, id, body, "serial")
+		return
+	case "os-getConsoleOutput":
+		svc.serverActionConsoleOutput(c, id, body)
+		return
+	}
+
+	rule := action
+	host := ""
+	createImage := ServerCreateImageInfo{}
+	resize := ServerResizeInfo{}
+	rebuild := ServerRebuildInfo{}
+	changePassword := ServerChangePasswordInfo{}
+	switch action {
+	case compute.SERVER_ACTION_START, compute.SERVER_ACTION_STOP,
+		compute.SERVER_ACTION_PAUSE, compute.SERVER_ACTION_UNPAUSE,
+		compute.SERVER_ACTION_SUSPEND, compute.SERVER_ACTION_RESUME,
+		compute.SERVER_ACTION_SHELVE, compute.SERVER_ACTION_UNSHELVE:
+	case compute.SERVER_ACTION_REBOOT:
+		reboot := ServerRebootInfo{}
+		err = json.Unmarshal(body, &reboot)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		if reboot.Type != compute.SERVER_REBOOT_TYPE_SOFT &&
+			reboot.Type != compute.SERVER_REBOOT_TYPE_HARD {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		rule = action + ":" + reboot.Type
+	case compute.SERVER_ACTION_MIGRATE, compute.SERVER_ACTION_LIVE_MIGRATE:
+		if !middleware.TokenHasRole(c, "admin") {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		if action == compute.SERVER_ACTION_LIVE_MIGRATE {
+			migrate := ServerLiveMigrateInfo{}
+			err = json.Unmarshal(body, &migrate)
+			if err != nil {
+				c.AbortWithError(http.StatusBadRequest, err)
+				return
+			}
+			if migrate.Host != nil {
+				host = *migrate.Host
+			}
+		}
+		if host != "" && !svc.validateMigrationHost(c, host) {
+			return
+		}
+	case compute.SERVER_ACTION_CREATE_IMAGE:
+		err = json.Unmarshal(body, &createImage)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		if createImage.Name == "" {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+	case compute.SERVER_ACTION_RESIZE:
+		err = json.Unmarshal(body, &resize)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		if resize.FlavorRef == "" {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+	case compute.SERVER_ACTION_CONFIRM_RESIZE, compute.SERVER_ACTION_REVERT_RESIZE:
+	case compute.SERVER_ACTION_REBUILD:
+		err = json.Unmarshal(body, &rebuild)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		if rebuild.ImageRef == "" {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+	case compute.SERVER_ACTION_CHANGE_PASSWORD:
+		err = json.Unmarshal(body, &changePassword)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		if changePassword.AdminPass == nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+	default:
+		// XXX other actions are not implemented
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	vm, vmi := svc.getServer(c, id)
+	if vm == nil {
+		return
+	}
+
+	status, taskState, _ := compute.ServerStatus(vm, vmi)
+	if !compute.ServerActionAllowed(rule, status, taskState) {
+		serverActionConflict(c, action, id, status)
+		return
+	}
+	defer svc.recordInstanceAction(c, vm, compute.ServerInstanceAction(action), time.Now())
+
+	// Actions which need further validation of their body, or
+	// have a response body
+	switch rule {
+	case compute.SERVER_ACTION_CREATE_IMAGE:
+		svc.serverActionCreateImage(c, vm, &createImage)
+		return
+	case compute.SERVER_ACTION_RESIZE:
+		svc.serverActionResize(c, vm, vmi, &resize)
+		return
+	case compute.SERVER_ACTION_REBUILD:
+		svc.serverActionRebuild(c, vm, vmi, &rebuild)
+		return
+	case compute.SERVER_ACTION_CHANGE_PASSWORD:
+		svc.serverActionChangePassword(c, vm, &changePassword)
+		return
+	}
+
+	vmClnt := svc.Client.Kubevirt().VirtualMachines(proj.Spec.Namespace)
+	vmiClnt := svc.Client.Kubevirt().VirtualMachineInstances(proj.Spec.Namespace)
+
+	switch rule {
+	case compute.SERVER_ACTION_START:
+		err = svc.setServerRunning(vm, true)
+	case compute.SERVER_ACTION_STOP:
+		err = svc.setServerRunning(vm, false)
+	case compute.SERVER_ACTION_REBOOT + ":" + compute.SERVER_REBOOT_TYPE_SOFT:
+		err = vmClnt.Restart(vm.ObjectMeta.Name)
+	case compute.SERVER_ACTION_REBOOT + ":" + compute.SERVER_REBOOT_TYPE_HARD:
+		// Deleting the instance without a grace period is the
+		// equivalent of pulling the power, and the VM controller
+		// will immediately start a new one
+		if vmi != nil {
+			grace := int64(0)
+			err = vmiClnt.Delete(vmi.ObjectMeta.Name, &metav1.DeleteOptions{
+				GracePeriodSeconds: &grace,
+			})
+			if errors.IsNotFound(err) {
+				err = nil
+			}
+		} else {
+			err = svc.setServerRunning(vm, true)
+		}
+	case compute.SERVER_ACTION_PAUSE:
+		err = vmiClnt.Pause(vmi.ObjectMeta.Name)
+	case compute.SERVER_ACTION_UNPAUSE:
+		err = vmiClnt.Unpause(vmi.ObjectMeta.Name)
+	case compute.SERVER_ACTION_SUSPEND:
+		// XXX KubeVirt cannot save guest memory to disk, so
+		// suspending is a pause which is distinguished by an
+		// annotation
+		vm.ObjectMeta.Annotations[compute.AnnotationSuspended] = "true"
+		_, err = vmClnt.Update(vm)
+		if err == nil {
+			err = vmiClnt.Pause(vmi.ObjectMeta.Name)
+		}
+	case compute.SERVER_ACTION_RESUME:
+		err = vmiClnt.Unpause(vmi.ObjectMeta.Name)
+		if err == nil {
+			delete(vm.ObjectMeta.Annotations, compute.AnnotationSuspended)
+			_, err = vmClnt.Update(vm)
+		}
+	case compute.SERVER_ACTION_SHELVE:
+		// Stopping the VM releases its compute resources, which
+		// is equivalent to an immediately offloaded server. The
+		// root disk is retained for unshelving.
+		delete(vm.ObjectMeta.Annotations, compute.AnnotationSuspended)
+		vm.ObjectMeta.Annotations[compute.AnnotationShelved] = "true"
+		err = svc.setServerRunning(vm, false)
+	case compute.SERVER_ACTION_UNSHELVE:
+		delete(vm.ObjectMeta.Annotations, compute.AnnotationShelved)
+		err = svc.setServerRunning(vm, true)
+	case compute.SERVER_ACTION_LIVE_MIGRATE:
+		if host == vmi.Status.NodeName {
+			badRequest(c, fmt.Errorf("The target host can't be the same one."))
+			return
+		}
+		if !validateMigrationServer(c, vmi) {
+			return
+		}
+		err = svc.migrateServer(vm, vmi, compute.MIGRATION_TYPE_LIVE, host)
+	case compute.SERVER_ACTION_MIGRATE:
+		if !validateMigrationServer(c, vmi) {
+			return
+		}
+		// XXX KubeVirt can only migrate live, so the server is
+		// not stopped and there is no resize to confirm
+		err = svc.migrateServer(vm, vmi, compute.MIGRATION_TYPE_COLD, host)
+	case compute.SERVER_ACTION_CONFIRM_RESIZE:
+		compute.SetServerResize(vm, nil)
+		_, err = vmClnt.Update(vm)
+	case compute.SERVER_ACTION_REVERT_RESIZE:
+		err = svc.revertServerResize(vm, vmi)
+	}
+
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else if errors.IsConflict(err) {
+			serverActionConflict(c, action, id, status)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.String(http.StatusAccepted, "")
+}
+
+func (svc *service) setServerRunning(vm *kubevirtv1.VirtualMachine, running bool) error {
+	vm.Spec.Running = &running
+	_, err := svc.Client.Kubevirt().VirtualMachines(vm.ObjectMeta.Namespace).Update(vm)
+	return err
+}
@@ -0,0 +1,253 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+type ServerGroupCreateReq struct {
+	ServerGroup ServerGroupCreateInfo `json:"server_group"`
+}
+
+type ServerGroupCreateInfo struct {
+	Name     string   `json:"name"`
+	Policies []string `json:"policies"`
+}
+
+type ServerGroupListRes struct {
+	ServerGroups []ServerGroupInfo `json:"server_groups"`
+}
+
+type ServerGroupShowRes struct {
+	ServerGroup ServerGroupInfo `json:"server_group"`
+}
+
+type ServerGroupInfo struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Policies  []string          `json:"policies"`
+	Members   []string          `json:"members"`
+	Metadata  map[string]string `json:"metadata"`
+	ProjectID string            `json:"project_id"`
+	UserID    string            `json:"user_id"`
+}
+
+func serverGroupInfo(group *v1.ServerGroup, members []string) ServerGroupInfo {
+	if members == nil {
+		members = []string{}
+	}
+	return ServerGroupInfo{
+		ID:        group.ObjectMeta.Name,
+		Name:      group.Spec.Name,
+		Policies:  []string{group.Spec.Policy},
+		Members:   members,
+		Metadata:  map[string]string{},
+		ProjectID: group.Spec.ProjectID,
+		UserID:    group.Spec.UserID,
+	}
+}
+
+// getServerGroupMembers returns the IDs of the servers in the
+// namespace, indexed by the ID of the server group they are in
+func (svc *service) getServerGroupMembers(ns string) (map[string][]string, error) {
+	vms, err := svc.Client.Kubevirt().VirtualMachines(ns).List()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string][]string)
+	for _, vm := range vms.Items {
+		group, ok := vm.ObjectMeta.Labels[compute.LabelServerGroup]
+		if !ok {
+			continue
+		}
+		res[group] = append(res[group], vm.ObjectMeta.Name)
+	}
+	return res, nil
+}
+
+func (svc *service) ServerGroupList(c *gin.Context) {
+	proj := middleware.RequiredTokenScopeProject(c)
+	_, allProjects := GetFilterBool(c, "all_projects")
+	filterOffset, offset := GetFilterUInt(c, "offset")
+	filterLimit, limit := GetFilterUInt(c, "limit")
+
+	ns := proj.Spec.Namespace
+	if allProjects && middleware.TokenHasRole(c, "admin") {
+		ns = k8sv1.NamespaceAll
+	}
+
+	groups, err := svc.Client.Compute().ServerGroups(ns).List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	members, err := svc.getServerGroupMembers(ns)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := ServerGroupListRes{
+		ServerGroups: []ServerGroupInfo{},
+	}
+
+	count := uint64(0)
+	for idx := range groups.Items {
+		if filterOffset && uint64(idx) < offset {
+			continue
+		}
+
+		group := &groups.Items[idx]
+		res.ServerGroups = append(res.ServerGroups,
+			serverGroupInfo(group, members[group.ObjectMeta.Name]))
+
+		count = count + 1
+		if filterLimit && count >= limit {
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerGroupShow(c *gin.Context) {
+	proj := middleware.RequiredTokenScopeProject(c)
+	id := c.Param("id")
+
+	group, err := svc.Client.Compute().ServerGroups(proj.Spec.Namespace).Get(id)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	members, err := svc.getServerGroupMembers(proj.Spec.Namespace)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := ServerGroupShowRes{
+		ServerGroup: serverGroupInfo(group, members[group.ObjectMeta.Name]),
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerGroupCreate(c *gin.Context) {
+	proj := middleware.RequiredTokenScopeProject(c)
+	user := middleware.RequiredTokenSubjectUser(c)
+
+	req := ServerGroupCreateReq{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if req.ServerGroup.Name == "" || len(req.ServerGroup.Policies) != 1 ||
+		!compute.IsValidServerGroupPolicy(req.ServerGroup.Policies[0]) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	quota, err := svc.getQuota(proj.Spec.Namespace)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	usage, err := svc.getQuotaUsage(proj.Spec.Namespace)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if quotaExceeded(c, "server_groups", 1, usage.ServerGroups, quota.ServerGroups) {
+		return
+	}
+
+	group := &v1.ServerGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: string(uuid.NewUUID()),
+		},
+		Spec: v1.ServerGroupSpec{
+			Name:      req.ServerGroup.Name,
+			Policy:    req.ServerGroup.Policies[0],
+			UserID:    user.GetID(),
+			ProjectID: proj.GetID(),
+		},
+	}
+
+	group, err = svc.Client.Compute().ServerGroups(proj.Spec.Namespace).Create(group)
+	if err != nil {
+		if errors.IsForbidden(err) {
+			c.AbortWithError(http.StatusForbidden, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	res := ServerGroupShowRes{
+		ServerGroup: serverGroupInfo(group, nil),
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerGroupDelete(c *gin.Context) {
+	proj := middleware.RequiredTokenScopeProject(c)
+	id := c.Param("id")
+
+	clnt := svc.Client.Compute().ServerGroups(proj.Spec.Namespace)
+
+	group, err := clnt.Get(id)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	// Members keep their placement rules, but are no longer
+	// considered when scheduling new servers
+	err = clnt.Delete(group.ObjectMeta.Name, nil)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
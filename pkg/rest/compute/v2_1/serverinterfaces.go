@@ -0,0 +1,276 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+type InterfaceAttachmentListRes struct {
+	InterfaceAttachments []InterfaceAttachmentInfo `json:"interfaceAttachments"`
+}
+
+type InterfaceAttachmentRes struct {
+	InterfaceAttachment InterfaceAttachmentInfo `json:"interfaceAttachment"`
+}
+
+type InterfaceAttachmentInfo struct {
+	PortID    string             `json:"port_id"`
+	NetID     string             `json:"net_id"`
+	MACAddr   string             `json:"mac_addr"`
+	PortState string             `json:"port_state"`
+	FixedIPs  []InterfaceFixedIP `json:"fixed_ips"`
+}
+
+type InterfaceFixedIP struct {
+	SubnetID  string `json:"subnet_id"`
+	IPAddress string `json:"ip_address"`
+}
+
+type InterfaceAttachReq struct {
+	InterfaceAttachment InterfaceAttachInfo `json:"interfaceAttachment"`
+}
+
+type InterfaceAttachInfo struct {
+	NetID    string             `json:"net_id"`
+	PortID   string             `json:"port_id"`
+	FixedIPs []InterfaceFixedIP `json:"fixed_ips"`
+}
+
+const (
+	INTERFACE_PORT_STATE_ACTIVE = "ACTIVE"
+	INTERFACE_PORT_STATE_DOWN   = "DOWN"
+)
+
+// InterfaceAttachmentInfoFromServer describes an interface of a
+// server, with the addresses reported for it by the running
+// instance, if any. Interfaces which have not yet been plugged
+// into the instance are down.
+func InterfaceAttachmentInfoFromServer(iface *compute.ServerInterface, vmi *kubevirtv1.VirtualMachineInstance) InterfaceAttachmentInfo {
+	info := InterfaceAttachmentInfo{
+		PortID:    iface.PortID,
+		NetID:     iface.NetID,
+		MACAddr:   iface.MAC,
+		PortState: INTERFACE_PORT_STATE_DOWN,
+		FixedIPs:  []InterfaceFixedIP{},
+	}
+	if vmi == nil {
+		return info
+	}
+
+	for idx := range vmi.Status.Interfaces {
+		status := &vmi.Status.Interfaces[idx]
+		if interfaceStatusPortID(status) != iface.PortID {
+			continue
+		}
+
+		info.PortState = INTERFACE_PORT_STATE_ACTIVE
+		if status.MAC != "" {
+			info.MACAddr = status.MAC
+		}
+		for _, ip := range interfaceStatusIPs(status) {
+			info.FixedIPs = append(info.FixedIPs, InterfaceFixedIP{
+				IPAddress: ip,
+			})
+		}
+	}
+	return info
+}
+
+func (svc *service) ServerInterfaceList(c *gin.Context) {
+	vm, vmi := svc.getServer(c, c.Param("id"))
+	if vm == nil {
+		return
+	}
+
+	res := InterfaceAttachmentListRes{
+		InterfaceAttachments: []InterfaceAttachmentInfo{},
+	}
+	for _, iface := range compute.ServerInterfaces(&vm.Spec.Template.Spec) {
+		res.InterfaceAttachments = append(res.InterfaceAttachments,
+			InterfaceAttachmentInfoFromServer(&iface, vmi))
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerInterfaceShow(c *gin.Context) {
+	vm, vmi := svc.getServer(c, c.Param("id"))
+	if vm == nil {
+		return
+	}
+
+	pid := c.Param("pid")
+	for _, iface := range compute.ServerInterfaces(&vm.Spec.Template.Spec) {
+		if iface.PortID == pid {
+			res := InterfaceAttachmentRes{
+				InterfaceAttachment: InterfaceAttachmentInfoFromServer(&iface, vmi),
+			}
+			c.JSON(http.StatusOK, res)
+			return
+		}
+	}
+
+	c.AbortWithStatus(http.StatusNotFound)
+}
+
+// ServerInterfaceAttach adds an interface on a Multus network
+// to the server.
+// XXX the interface is only hot-plugged into a running instance
+// if KubeVirt is configured to apply changes to VMs live,
+// otherwise it is added when the instance is next restarted
+func (svc *service) ServerInterfaceAttach(c *gin.Context) {
+	proj := middleware.RequiredTokenScopeProject(c)
+	id := c.Param("id")
+
+	req := InterfaceAttachReq{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	// XXX there are no Neutron ports, so interfaces can only
+	// be created on a network, with addresses it assigns
+	if req.InterfaceAttachment.PortID != "" || len(req.InterfaceAttachment.FixedIPs) != 0 {
+		badRequest(c, fmt.Errorf("Attaching ports or fixed IPs is not supported"))
+		return
+	}
+	netID := req.InterfaceAttachment.NetID
+	if netID == "" {
+		badRequest(c, fmt.Errorf("A network must be given"))
+		return
+	}
+	if netID == compute.ServerPodNetworkName {
+		badRequest(c, fmt.Errorf("Network %s can only be attached when the server is created", netID))
+		return
+	}
+
+	_, err = svc.Client.Multus().NetworkAttachmentDefinitions(proj.Spec.Namespace).Get(netID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	vm, vmi := svc.getServer(c, id)
+	if vm == nil {
+		return
+	}
+
+	status, taskState, _ := compute.ServerStatus(vm, vmi)
+	if !compute.ServerActionAllowed(compute.SERVER_ACTION_ATTACH_INTERFACE, status, taskState) {
+		serverActionConflict(c, compute.SERVER_ACTION_ATTACH_INTERFACE, id, status)
+		return
+	}
+	defer svc.recordInstanceAction(c, vm, compute.INSTANCE_ACTION_ATTACH_INTERFACE, time.Now())
+
+	mac, err := compute.GenerateMACAddress()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	iface := compute.ServerInterface{
+		PortID: string(uuid.NewUUID()),
+		NetID:  netID,
+		MAC:    mac,
+	}
+	compute.PruneServerInterfaces(&vm.Spec.Template.Spec, vmi)
+	compute.AddServerInterface(&vm.Spec.Template.Spec, iface.PortID, iface.NetID, iface.MAC)
+
+	_, err = svc.Client.Kubevirt().VirtualMachines(proj.Spec.Namespace).Update(vm)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else if errors.IsConflict(err) {
+			serverActionConflict(c, compute.SERVER_ACTION_ATTACH_INTERFACE, id, status)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	res := InterfaceAttachmentRes{
+		InterfaceAttachment: InterfaceAttachmentInfoFromServer(&iface, nil),
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ServerInterfaceDetach removes an interface on a Multus network
+// from the server. Interfaces of a running instance are marked
+// for KubeVirt to hot-unplug.
+func (svc *service) ServerInterfaceDetach(c *gin.Context) {
+	proj := middleware.RequiredTokenScopeProject(c)
+	id := c.Param("id")
+	pid := c.Param("pid")
+
+	vm, vmi := svc.getServer(c, id)
+	if vm == nil {
+		return
+	}
+
+	// KubeVirt can only hot-unplug interfaces on Multus networks
+	if pid == compute.ServerPodNetworkName {
+		badRequest(c, fmt.Errorf("Port %s cannot be detached", pid))
+		return
+	}
+
+	status, taskState, _ := compute.ServerStatus(vm, vmi)
+	if !compute.ServerActionAllowed(compute.SERVER_ACTION_DETACH_INTERFACE, status, taskState) {
+		serverActionConflict(c, compute.SERVER_ACTION_DETACH_INTERFACE, id, status)
+		return
+	}
+
+	// Interfaces unplugged by earlier detaches are removed for
+	// good along with this one
+	compute.PruneServerInterfaces(&vm.Spec.Template.Spec, vmi)
+	if !compute.RemoveServerInterface(&vm.Spec.Template.Spec, pid, vmi != nil) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	defer svc.recordInstanceAction(c, vm, compute.INSTANCE_ACTION_DETACH_INTERFACE, time.Now())
+
+	_, err := svc.Client.Kubevirt().VirtualMachines(proj.Spec.Namespace).Update(vm)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else if errors.IsConflict(err) {
+			serverActionConflict(c, compute.SERVER_ACTION_DETACH_INTERFACE, id, status)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.String(http.StatusAccepted, "")
+}
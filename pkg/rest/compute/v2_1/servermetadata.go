@@ -0,0 +1,243 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+type ServerMetadataReq struct {
+	Metadata map[string]string `json:"metadata"`
+}
+
+type ServerMetadataRes struct {
+	Metadata map[string]string `json:"metadata"`
+}
+
+type ServerMetaReq struct {
+	Meta map[string]string `json:"meta"`
+}
+
+type ServerMetaRes struct {
+	Meta map[string]string `json:"meta"`
+}
+
+// getServerMetadata fetches a server and its metadata, aborting
+// the request and returning nil on failure
+func (svc *service) getServerMetadata(c *gin.Context) (*kubevirtv1.VirtualMachine, map[string]string) {
+	vm, _ := svc.getServer(c, c.Param("id"))
+	if vm == nil {
+		return nil, nil
+	}
+
+	md, err := compute.GetServerMetadata(vm)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return nil, nil
+	}
+
+	return vm, md
+}
+
+// updateServer saves changes to the VM backing a server,
+// aborting the request and returning false on failure
+func (svc *service) updateServer(c *gin.Context, vm *kubevirtv1.VirtualMachine) bool {
+	proj := middleware.RequiredTokenScopeProject(c)
+
+	_, err := svc.Client.Kubevirt().VirtualMachines(proj.Spec.Namespace).Update(vm)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else if errors.IsConflict(err) {
+			c.AbortWithError(http.StatusConflict, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return false
+	}
+	return true
+}
+
+func (svc *service) saveServerMetadata(c *gin.Context, vm *kubevirtv1.VirtualMachine, md map[string]string) bool {
+	if !compute.IsValidServerMetadata(md) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return false
+	}
+
+	err := compute.SetServerMetadata(vm, md)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return false
+	}
+
+	return svc.updateServer(c, vm)
+}
+
+func (svc *service) ServerMetadataList(c *gin.Context) {
+	_, md := svc.getServerMetadata(c)
+	if md == nil {
+		return
+	}
+
+	res := ServerMetadataRes{
+		Metadata: md,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerMetadataUpdate(c *gin.Context) {
+	req := ServerMetadataReq{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if req.Metadata == nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	vm, md := svc.getServerMetadata(c)
+	if vm == nil {
+		return
+	}
+
+	for key, val := range req.Metadata {
+		md[key] = val
+	}
+
+	if !svc.saveServerMetadata(c, vm, md) {
+		return
+	}
+
+	res := ServerMetadataRes{
+		Metadata: md,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerMetadataReplace(c *gin.Context) {
+	req := ServerMetadataReq{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if req.Metadata == nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	vm, _ := svc.getServerMetadata(c)
+	if vm == nil {
+		return
+	}
+
+	if !svc.saveServerMetadata(c, vm, req.Metadata) {
+		return
+	}
+
+	res := ServerMetadataRes{
+		Metadata: req.Metadata,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerMetadataShow(c *gin.Context) {
+	key := c.Param("key")
+
+	_, md := svc.getServerMetadata(c)
+	if md == nil {
+		return
+	}
+
+	val, ok := md[key]
+	if !ok {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	res := ServerMetaRes{
+		Meta: map[string]string{
+			key: val,
+		},
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerMetadataSet(c *gin.Context) {
+	key := c.Param("key")
+
+	req := ServerMetaReq{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	val, ok := req.Meta[key]
+	if len(req.Meta) != 1 || !ok {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	vm, md := svc.getServerMetadata(c)
+	if vm == nil {
+		return
+	}
+
+	md[key] = val
+
+	if !svc.saveServerMetadata(c, vm, md) {
+		return
+	}
+
+	res := ServerMetaRes{
+		Meta: req.Meta,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerMetadataDelete(c *gin.Context) {
+	key := c.Param("key")
+
+	vm, md := svc.getServerMetadata(c)
+	if vm == nil {
+		return
+	}
+
+	if _, ok := md[key]; !ok {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	delete(md, key)
+
+	if !svc.saveServerMetadata(c, vm, md) {
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
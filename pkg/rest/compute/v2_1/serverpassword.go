@@ -0,0 +1,156 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+type ServerPasswordRes struct {
+	Password string `json:"password"`
+}
+
+type ServerChangePasswordInfo struct {
+	AdminPass *string `json:"adminPass"`
+}
+
+func (svc *service) ServerPasswordShow(c *gin.Context) {
+	vm, _ := svc.getServer(c, c.Param("id"))
+	if vm == nil {
+		return
+	}
+
+	res := ServerPasswordRes{
+		Password: compute.GetServerPassword(vm),
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ServerPasswordDelete clears the stored password, without
+// changing the password in the guest, so that the guest may
+// store a new one through the metadata service
+func (svc *service) ServerPasswordDelete(c *gin.Context) {
+	vm, _ := svc.getServer(c, c.Param("id"))
+	if vm == nil {
+		return
+	}
+
+	compute.SetServerPassword(vm, "")
+	_, err := svc.Client.Kubevirt().VirtualMachines(vm.ObjectMeta.Namespace).Update(vm)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+// encryptServerPassword encrypts the password with the public
+// key of the keypair the server was created with. An empty
+// string is returned if the server has no keypair, or its key
+// cannot be used for encryption.
+func (svc *service) encryptServerPassword(vm *kubevirtv1.VirtualMachine, password string) (string, error) {
+	keyName, ok := vm.ObjectMeta.Annotations[compute.AnnotationKeyName]
+	if !ok {
+		return "", nil
+	}
+
+	user, err := svc.Client.Identity().Users(k8sv1.NamespaceAll).GetByID(
+		vm.ObjectMeta.Annotations[compute.AnnotationUserID])
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	keypair, err := svc.getUserKeypair(user, keyName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	enc, err := compute.EncryptServerPassword(keypair, password)
+	if err != nil {
+		glog.V(1).Infof("Cannot encrypt password for server %s: %s", vm.ObjectMeta.Name, err)
+		return "", nil
+	}
+	return enc, nil
+}
+
+// serverActionChangePassword updates the server's credentials
+// Secret, which KubeVirt applies to the guest through the QEMU
+// guest agent, so the agent must be running in the guest
+func (svc *service) serverActionChangePassword(c *gin.Context, vm *kubevirtv1.VirtualMachine, req *ServerChangePasswordInfo) {
+	if !compute.HasServerAccessCredentials(vm) {
+		c.AbortWithError(http.StatusConflict,
+			fmt.Errorf("Setting the admin password is not supported for instance %s", vm.ObjectMeta.Name))
+		return
+	}
+
+	clnt := svc.K8SClient.CoreV1().Secrets(vm.ObjectMeta.Namespace)
+	secret, err := clnt.Get(compute.ServerCredentialsSecretName(vm.ObjectMeta.Name), metav1.GetOptions{})
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	for user := range secret.Data {
+		secret.Data[user] = []byte(*req.AdminPass)
+	}
+	_, err = clnt.Update(secret)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// Any password stored previously is no longer valid
+	enc, err := svc.encryptServerPassword(vm, *req.AdminPass)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	compute.SetServerPassword(vm, enc)
+	_, err = svc.Client.Kubevirt().VirtualMachines(vm.ObjectMeta.Namespace).Update(vm)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.String(http.StatusAccepted, "")
+}
@@ -0,0 +1,369 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	"github.com/dicot-project/dicot-api/pkg/api/image"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+type ServerResizeInfo struct {
+	FlavorRef string `json:"flavorRef"`
+}
+
+type ServerRebuildInfo struct {
+	ImageRef string             `json:"imageRef"`
+	Name     *string            `json:"name"`
+	Metadata *map[string]string `json:"metadata"`
+	// XXX the cloud-init data is not regenerated, so the
+	// password is unchanged, and the ephemeral disk is
+	// always recreated empty
+	AdminPass         string `json:"adminPass"`
+	PreserveEphemeral bool   `json:"preserve_ephemeral"`
+}
+
+// restartServerInstance stops the running instance of the
+// server, if any, so that the VM controller starts a new one
+// from the updated template
+func (svc *service) restartServerInstance(vmi *kubevirtv1.VirtualMachineInstance) error {
+	if vmi == nil {
+		return nil
+	}
+	err := svc.Client.Kubevirt().VirtualMachineInstances(vmi.ObjectMeta.Namespace).Delete(
+		vmi.ObjectMeta.Name, &metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// growServerRootDisk expands the volume holding the server's
+// root disk to the size given by the flavor, if that is larger.
+// The storage class must allow volume expansion.
+func (svc *service) growServerRootDisk(vm *kubevirtv1.VirtualMachine, sizeMB uint64) error {
+	rootDisk := serverRootDisk(vm)
+	if rootDisk == nil || rootDisk.Spec.PVC == nil || sizeMB == 0 {
+		return nil
+	}
+
+	size := resource.MustParse(fmt.Sprintf("%dMi", sizeMB))
+	current := rootDisk.Spec.PVC.Resources.Requests[k8sv1.ResourceStorage]
+	if size.Cmp(current) <= 0 {
+		return nil
+	}
+
+	clnt := svc.K8SClient.CoreV1().PersistentVolumeClaims(vm.ObjectMeta.Namespace)
+	pvc, err := clnt.Get(rootDisk.ObjectMeta.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	pvc.Spec.Resources.Requests[k8sv1.ResourceStorage] = size
+	_, err = clnt.Update(pvc)
+	if err != nil {
+		return err
+	}
+
+	rootDisk.Spec.PVC.Resources.Requests[k8sv1.ResourceStorage] = size
+	return nil
+}
+
+func (svc *service) serverActionResize(c *gin.Context, vm *kubevirtv1.VirtualMachine, vmi *kubevirtv1.VirtualMachineInstance, req *ServerResizeInfo) {
+	proj := middleware.RequiredTokenScopeProject(c)
+	dom := middleware.RequiredTokenScopeDomain(c)
+
+	clnt := svc.Client.Compute().Flavors(dom.Spec.Namespace)
+	flavor, err := clnt.GetByID(refID(req.FlavorRef))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusBadRequest, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+	if !compute.FlavorAccessible(flavor, proj) || flavor.Spec.Disabled {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	oldID := vm.ObjectMeta.Annotations[compute.AnnotationFlavorID]
+	if flavor.Spec.ID == oldID {
+		badRequest(c, fmt.Errorf("When resizing, instances must change flavor!"))
+		return
+	}
+
+	// The flavor the server was created with may have been
+	// deleted, in which case its size is taken from the server
+	resize := &compute.ServerResize{
+		FlavorID:   oldID,
+		FlavorName: vm.ObjectMeta.Annotations[compute.AnnotationFlavorName],
+	}
+	oldFlavor, err := clnt.GetByID(oldID)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		resize.Resources = compute.ServerFlavorResources(vm)
+	} else {
+		resize.Resources = oldFlavor.Spec.Resources
+		resize.ExtraSpecs = oldFlavor.Spec.ExtraSpecs
+	}
+
+	if flavor.Spec.Resources.RootDiskMB != 0 &&
+		flavor.Spec.Resources.RootDiskMB < resize.Resources.RootDiskMB {
+		badRequest(c, fmt.Errorf("Resizing to a smaller disk is not allowed"))
+		return
+	}
+
+	quota, err := svc.getQuota(proj.Spec.Namespace)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	usage, err := svc.getQuotaUsage(proj.Spec.Namespace)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	cores := int64(flavor.Spec.Resources.CPUCount) - int64(resize.Resources.CPUCount)
+	ram := int64(flavor.Spec.Resources.MemoryMB) - int64(resize.Resources.MemoryMB)
+	if (cores > 0 && quotaExceeded(c, "cores", cores, usage.Cores, quota.Cores)) ||
+		(ram > 0 && quotaExceeded(c, "ram", ram, usage.RAM, quota.RAM)) {
+		return
+	}
+
+	err = compute.SetServerFlavor(flavor, &vm.Spec.Template.Spec)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+	err = compute.SetServerResize(vm, resize)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	vm.ObjectMeta.Annotations[compute.AnnotationFlavorID] = flavor.Spec.ID
+	vm.ObjectMeta.Annotations[compute.AnnotationFlavorName] = flavor.ObjectMeta.Name
+
+	// XXX the root disk is not shrunk again if the resize is
+	// reverted
+	err = svc.growServerRootDisk(vm, flavor.Spec.Resources.RootDiskMB)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	_, err = svc.Client.Kubevirt().VirtualMachines(vm.ObjectMeta.Namespace).Update(vm)
+	if err == nil {
+		err = svc.restartServerInstance(vmi)
+	}
+	if err != nil {
+		if errors.IsConflict(err) {
+			serverActionConflict(c, compute.SERVER_ACTION_RESIZE, vm.ObjectMeta.Name, compute.SERVER_STATUS_ACTIVE)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	err = svc.updateServerUsage(vm, func(usage *v1.ServerUsage) {
+		compute.SetServerUsageFlavor(usage, flavor, time.Now())
+	})
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.String(http.StatusAccepted, "")
+}
+
+// revertServerResize restores the flavor the server had before
+// it was resized
+func (svc *service) revertServerResize(vm *kubevirtv1.VirtualMachine, vmi *kubevirtv1.VirtualMachineInstance) error {
+	resize, err := compute.GetServerResize(vm)
+	if err != nil {
+		return err
+	}
+
+	flavor := resize.Flavor()
+	err = compute.SetServerFlavor(flavor, &vm.Spec.Template.Spec)
+	if err != nil {
+		return err
+	}
+	vm.ObjectMeta.Annotations[compute.AnnotationFlavorID] = flavor.Spec.ID
+	vm.ObjectMeta.Annotations[compute.AnnotationFlavorName] = flavor.ObjectMeta.Name
+	compute.SetServerResize(vm, nil)
+
+	_, err = svc.Client.Kubevirt().VirtualMachines(vm.ObjectMeta.Namespace).Update(vm)
+	if err != nil {
+		return err
+	}
+	err = svc.restartServerInstance(vmi)
+	if err != nil {
+		return err
+	}
+
+	return svc.updateServerUsage(vm, func(usage *v1.ServerUsage) {
+		compute.SetServerUsageFlavor(usage, flavor, time.Now())
+	})
+}
+
+// serverActionRebuild replaces the root disk of the server with
+// a fresh copy of an image. The new disk gets a new name, since
+// the old one may take some time to be deleted.
+func (svc *service) serverActionRebuild(c *gin.Context, vm *kubevirtv1.VirtualMachine, vmi *kubevirtv1.VirtualMachineInstance, req *ServerRebuildInfo) {
+	proj := middleware.RequiredTokenScopeProject(c)
+	dom := middleware.RequiredTokenScopeDomain(c)
+	id := vm.ObjectMeta.Name
+
+	img, err := svc.Client.Image().Images(k8sv1.NamespaceAll).GetByID(refID(req.ImageRef))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusBadRequest, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+	img, err = image.SyncImageVolume(svc.Client.Image(), svc.Client.CDI(), img)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if !image.ImageAccessible(img, proj) || img.Spec.Status != image.IMAGE_STATUS_ACTIVE {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	rootDisk := serverRootDisk(vm)
+	if rootDisk == nil || rootDisk.Spec.PVC == nil {
+		c.AbortWithStatus(http.StatusConflict)
+		return
+	}
+	oldRootDisk := rootDisk.ObjectMeta.Name
+
+	flavor, err := svc.Client.Compute().Flavors(dom.Spec.Namespace).GetByID(
+		vm.ObjectMeta.Annotations[compute.AnnotationFlavorID])
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		flavor = nil
+	}
+	if flavor != nil {
+		if flavor.Spec.Resources.MemoryMB < img.Spec.MinRam ||
+			(flavor.Spec.Resources.RootDiskMB != 0 &&
+				flavor.Spec.Resources.RootDiskMB/1024 < img.Spec.MinDisk) {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		rootDisk.Spec.PVC.Resources.Requests[k8sv1.ResourceStorage] = serverRootDiskSize(flavor, img)
+	}
+
+	if req.Metadata != nil {
+		if !compute.IsValidServerMetadata(*req.Metadata) {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		err = compute.SetServerMetadata(vm, *req.Metadata)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if req.Name != nil {
+		if *req.Name == "" {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		vm.ObjectMeta.Annotations[compute.AnnotationServerName] = *req.Name
+	}
+
+	rootDisk.Spec.Source, err = svc.serverRootDiskSource(img)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	rootDisk.ObjectMeta.Name = serverRootDiskName(id) + "-" + string(uuid.NewUUID())[:8]
+	for idx := range vm.Spec.Template.Spec.Volumes {
+		volume := &vm.Spec.Template.Spec.Volumes[idx]
+		if volume.Name == "root" && volume.DataVolume != nil {
+			volume.DataVolume.Name = rootDisk.ObjectMeta.Name
+		}
+	}
+	vm.ObjectMeta.Annotations[compute.AnnotationImageID] = img.Spec.ID
+
+	vm, err = svc.Client.Kubevirt().VirtualMachines(vm.ObjectMeta.Namespace).Update(vm)
+	if err != nil {
+		if errors.IsConflict(err) {
+			serverActionConflict(c, compute.SERVER_ACTION_REBUILD, id, compute.SERVER_STATUS_ACTIVE)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	err = svc.restartServerInstance(vmi)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// The old disk is no longer in the templates, so it must
+	// be deleted explicitly
+	err = svc.Client.CDI().DataVolumes(vm.ObjectMeta.Namespace).Delete(oldRootDisk, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	flavors, err := svc.serverFlavors(dom)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	zones, err := svc.getNodeZones()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// The instance is being replaced, so it is not reported
+	res := ServerShowRes{
+		Server: svc.serverInfoDetail(c, vm, nil, flavors, zones),
+	}
+	c.JSON(http.StatusAccepted, res)
+}
@@ -0,0 +1,1271 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	cdiv1 "github.com/dicot-project/dicot-api/pkg/api/cdi/v1"
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	"github.com/dicot-project/dicot-api/pkg/api/identity"
+	identityv1 "github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+	"github.com/dicot-project/dicot-api/pkg/api/image"
+	imagev1 "github.com/dicot-project/dicot-api/pkg/api/image/v1"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+	"github.com/dicot-project/dicot-api/pkg/cloudinit"
+	"github.com/dicot-project/dicot-api/pkg/crypto"
+	"github.com/dicot-project/dicot-api/pkg/rest"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+type ServerCreateReq struct {
+	Server         ServerCreateInfo     `json:"server"`
+	SchedulerHints ServerSchedulerHints `json:"os:scheduler_hints"`
+	// Alias used by older clients
+	SchedulerHintsAlias ServerSchedulerHints `json:"OS-SCH-HNT:scheduler_hints"`
+}
+
+type ServerSchedulerHints struct {
+	Group string `json:"group"`
+}
+
+type ServerCreateInfo struct {
+	Name        string              `json:"name"`
+	ImageRef    string              `json:"imageRef"`
+	FlavorRef   string              `json:"flavorRef"`
+	KeyName     string              `json:"key_name"`
+	UserData    string              `json:"user_data"`
+	AdminPass   string              `json:"adminPass"`
+	Metadata    map[string]string   `json:"metadata"`
+	Personality []ServerPersonality `json:"personality"`
+	ConfigDrive bool                `json:"config_drive"`
+	Tags        []string            `json:"tags"`
+	// The zone, optionally followed by ":host"
+	AvailabilityZone string `json:"availability_zone"`
+}
+
+type ServerPersonality struct {
+	Path     string `json:"path"`
+	Contents string `json:"contents"`
+}
+
+type ServerCreateRes struct {
+	Server ServerNewInfo `json:"server"`
+}
+
+type ServerNewInfo struct {
+	ID             string              `json:"id"`
+	Links          []rest.LinkInfo     `json:"links"`
+	DiskConfig     string              `json:"OS-DCF:diskConfig"`
+	SecurityGroups []SecurityGroupInfo `json:"security_groups"`
+	AdminPass      string              `json:"adminPass"`
+}
+
+type SecurityGroupInfo struct {
+	Name string `json:"name"`
+}
+
+type ServerListRes struct {
+	Servers []ServerInfo    `json:"servers"`
+	Links   []rest.LinkInfo `json:"servers_links,omitempty"`
+}
+
+type ServerListDetailRes struct {
+	Servers []ServerInfoDetail `json:"servers"`
+	Links   []rest.LinkInfo    `json:"servers_links,omitempty"`
+}
+
+type ServerShowRes struct {
+	Server ServerInfoDetail `json:"server"`
+}
+
+type ServerInfo struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Links []rest.LinkInfo `json:"links"`
+}
+
+type ServerInfoDetail struct {
+	ServerInfo       `json:",inline"`
+	Status           string                       `json:"status"`
+	TenantID         string                       `json:"tenant_id"`
+	UserID           string                       `json:"user_id"`
+	Created          string                       `json:"created"`
+	Updated          string                       `json:"updated"`
+	HostID           string                       `json:"hostId"`
+	Image            ServerImageRef               `json:"image"`
+	Flavor           ServerFlavorInfo             `json:"flavor"`
+	Addresses        map[string][]ServerAddress   `json:"addresses"`
+	KeyName          *string                      `json:"key_name"`
+	Metadata         map[string]string            `json:"metadata"`
+	AccessIPv4       string                       `json:"accessIPv4"`
+	AccessIPv6       string                       `json:"accessIPv6"`
+	Progress         uint                         `json:"progress"`
+	ConfigDrive      string                       `json:"config_drive"`
+	Locked           bool                         `json:"locked"`
+	Description      *string                      `json:"description"`
+	Tags             []string                     `json:"tags"`
+	DiskConfig       string                       `json:"OS-DCF:diskConfig"`
+	AvailabilityZone string                       `json:"OS-EXT-AZ:availability_zone"`
+	Host             *string                      `json:"OS-EXT-SRV-ATTR:host,omitempty"`
+	InstanceName     *string                      `json:"OS-EXT-SRV-ATTR:instance_name,omitempty"`
+	TaskState        *string                      `json:"OS-EXT-STS:task_state"`
+	VMState          string                       `json:"OS-EXT-STS:vm_state"`
+	PowerState       int                          `json:"OS-EXT-STS:power_state"`
+	LaunchedAt       *string                      `json:"OS-SRV-USG:launched_at"`
+	TerminatedAt     *string                      `json:"OS-SRV-USG:terminated_at"`
+	SecurityGroups   []SecurityGroupInfo          `json:"security_groups"`
+	VolumesAttached  []ServerVolumeAttachmentInfo `json:"os-extended-volumes:volumes_attached"`
+}
+
+type ServerImageRef struct {
+	ID    string          `json:"id"`
+	Links []rest.LinkInfo `json:"links"`
+}
+
+type ServerFlavorInfo struct {
+	OriginalName string            `json:"original_name"`
+	RAM          uint64            `json:"ram"`
+	Disk         uint64            `json:"disk"`
+	VCpus        uint64            `json:"vcpus"`
+	Ephemeral    uint64            `json:"ephemeral"`
+	Swap         uint64            `json:"swap"`
+	ExtraSpecs   map[string]string `json:"extra_specs"`
+}
+
+type ServerAddress struct {
+	Version int    `json:"version"`
+	Addr    string `json:"addr"`
+	Type    string `json:"OS-EXT-IPS:type"`
+	MACAddr string `json:"OS-EXT-IPS-MAC:mac_addr"`
+}
+
+type ServerVolumeAttachmentInfo struct {
+	ID                  string `json:"id"`
+	DeleteOnTermination bool   `json:"delete_on_termination"`
+}
+
+// Nova's default quotas on file injection
+const (
+	serverMaxInjectedFiles       = 5
+	serverMaxInjectedFileContent = 10240
+	serverMaxInjectedFilePath    = 255
+)
+
+var serverVMStates = map[string]string{
+	compute.SERVER_STATUS_ACTIVE:            "active",
+	compute.SERVER_STATUS_BUILD:             "building",
+	compute.SERVER_STATUS_SHUTOFF:           "stopped",
+	compute.SERVER_STATUS_ERROR:             "error",
+	compute.SERVER_STATUS_PAUSED:            "paused",
+	compute.SERVER_STATUS_SUSPENDED:         "suspended",
+	compute.SERVER_STATUS_SHELVED_OFFLOADED: "shelved_offloaded",
+	compute.SERVER_STATUS_MIGRATING:         "active",
+	compute.SERVER_STATUS_RESIZE:            "active",
+	compute.SERVER_STATUS_VERIFY_RESIZE:     "resized",
+}
+
+func (svc *service) serverLinks(c *gin.Context, id string) []rest.LinkInfo {
+	return []rest.LinkInfo{
+		rest.LinkInfo{
+			Rel:  "self",
+			HRef: "http://" + c.Request.Host + svc.Prefix + "/servers/" + id,
+		},
+		rest.LinkInfo{
+			Rel:  "bookmark",
+			HRef: "http://" + c.Request.Host + "/servers/" + id,
+		},
+	}
+}
+
+// refID extracts the ID from a reference which may either be
+// a plain ID or the URL of the resource
+func refID(ref string) string {
+	bits := strings.Split(strings.TrimSuffix(ref, "/"), "/")
+	return bits[len(bits)-1]
+}
+
+func serverHostname(name string) string {
+	return strings.Trim(strings.Replace(
+		identity.SanitizeName(strings.ToLower(name)), ".", "-", -1), "-")
+}
+
+func serverHostID(projectID, nodeName string) string {
+	if nodeName == "" {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum224([]byte(projectID+nodeName)))
+}
+
+// interfaceStatusIPs gives the addresses reported for an
+// interface of an instance. Older KubeVirt only reports one.
+func interfaceStatusIPs(iface *kubevirtv1.VirtualMachineInstanceNetworkInterface) []string {
+	if len(iface.IPs) == 0 && iface.IP != "" {
+		return []string{iface.IP}
+	}
+	return iface.IPs
+}
+
+// interfaceStatusPortID gives the name in the instance spec of
+// an interface reported in the status. Older KubeVirt does not
+// name the interfaces in the status, which only happens with the
+// pod network.
+func interfaceStatusPortID(iface *kubevirtv1.VirtualMachineInstanceNetworkInterface) string {
+	if iface.Name == "" {
+		return compute.ServerPodNetworkName
+	}
+	return iface.Name
+}
+
+// serverAddresses reports the addresses of the running instance
+// under the name of the network each interface is attached to
+func serverAddresses(vmi *kubevirtv1.VirtualMachineInstance) map[string][]ServerAddress {
+	addrs := map[string][]ServerAddress{}
+	if vmi == nil {
+		return addrs
+	}
+
+	networks := compute.ServerNetworks(&vmi.Spec)
+	for _, iface := range vmi.Status.Interfaces {
+		network, ok := networks[interfaceStatusPortID(&iface)]
+		if !ok {
+			continue
+		}
+		for _, ip := range interfaceStatusIPs(&iface) {
+			parsed := net.ParseIP(ip)
+			if parsed == nil {
+				continue
+			}
+			version := 6
+			if parsed.To4() != nil {
+				version = 4
+			}
+			addrs[network] = append(addrs[network], ServerAddress{
+				Version: version,
+				Addr:    ip,
+				Type:    "fixed",
+				MACAddr: iface.MAC,
+			})
+		}
+	}
+
+	return addrs
+}
+
+// serverAvailabilityZone returns the zone requested for the
+// server, or the zone of the node it is running on
+func serverAvailabilityZone(vm *kubevirtv1.VirtualMachine, vmi *kubevirtv1.VirtualMachineInstance, zones map[string]string) string {
+	if zone, ok := vm.ObjectMeta.Annotations[compute.AnnotationAvailabilityZone]; ok {
+		return zone
+	}
+	if vmi != nil && vmi.Status.NodeName != "" {
+		return zones[vmi.Status.NodeName]
+	}
+	return ""
+}
+
+func (svc *service) serverInfoDetail(c *gin.Context, vm *kubevirtv1.VirtualMachine, vmi *kubevirtv1.VirtualMachineInstance, flavors map[string]*v1.Flavor, zones map[string]string) ServerInfoDetail {
+	annotations := vm.ObjectMeta.Annotations
+	status, taskState, powerState := compute.ServerStatus(vm, vmi)
+
+	info := ServerInfoDetail{
+		ServerInfo: ServerInfo{
+			ID:    vm.ObjectMeta.Name,
+			Name:  annotations[compute.AnnotationServerName],
+			Links: svc.serverLinks(c, vm.ObjectMeta.Name),
+		},
+		Status:   status,
+		TenantID: annotations[compute.AnnotationProjectID],
+		UserID:   annotations[compute.AnnotationUserID],
+		Created:  annotations[compute.AnnotationCreated],
+		Updated:  annotations[compute.AnnotationCreated],
+		Image: ServerImageRef{
+			ID: annotations[compute.AnnotationImageID],
+			Links: []rest.LinkInfo{
+				rest.LinkInfo{
+					Rel:  "bookmark",
+					HRef: "http://" + c.Request.Host + "/images/" + annotations[compute.AnnotationImageID],
+				},
+			},
+		},
+		Addresses:        serverAddresses(vmi),
+		Metadata:         map[string]string{},
+		Tags:             []string{},
+		ConfigDrive:      annotations[compute.AnnotationConfigDrive],
+		DiskConfig:       "AUTO",
+		AvailabilityZone: serverAvailabilityZone(vm, vmi, zones),
+		VMState:          serverVMStates[status],
+		PowerState:       powerState,
+		SecurityGroups: []SecurityGroupInfo{
+			SecurityGroupInfo{
+				Name: "default",
+			},
+		},
+		VolumesAttached: []ServerVolumeAttachmentInfo{},
+	}
+
+	if taskState != "" {
+		info.TaskState = &taskState
+	}
+
+	if md, err := compute.GetServerMetadata(vm); err == nil {
+		info.Metadata = md
+	}
+	if tags, err := compute.GetServerTags(vm); err == nil {
+		info.Tags = tags
+	}
+
+	if keyName, ok := annotations[compute.AnnotationKeyName]; ok {
+		info.KeyName = &keyName
+	}
+
+	if flavor, ok := flavors[annotations[compute.AnnotationFlavorID]]; ok {
+		info.Flavor = ServerFlavorInfo{
+			OriginalName: flavor.ObjectMeta.Name,
+			RAM:          flavor.Spec.Resources.MemoryMB,
+			Disk:         flavor.Spec.Resources.RootDiskMB / 1024,
+			VCpus:        flavor.Spec.Resources.CPUCount,
+			Ephemeral:    flavor.Spec.Resources.EphemeralDiskMB / 1024,
+			Swap:         flavor.Spec.Resources.SwapDiskMB,
+			ExtraSpecs:   flavor.Spec.ExtraSpecs,
+		}
+	}
+
+	if vmi != nil {
+		info.HostID = serverHostID(info.TenantID, vmi.Status.NodeName)
+		if middleware.TokenHasRole(c, "admin") {
+			info.Host = &vmi.Status.NodeName
+			info.InstanceName = &vmi.ObjectMeta.Name
+		}
+		if vmi.Status.Phase == kubevirtv1.Running {
+			launched := vmi.ObjectMeta.CreationTimestamp.Format(time.RFC3339)
+			info.LaunchedAt = &launched
+		}
+	}
+
+	return info
+}
+
+// serverFlavors returns the flavors of the domain indexed by ID,
+// for filling in the flavor details of servers
+func (svc *service) serverFlavors(dom *identityv1.Project) (map[string]*v1.Flavor, error) {
+	flavors, err := svc.Client.Compute().Flavors(dom.Spec.Namespace).List()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]*v1.Flavor)
+	for idx := range flavors.Items {
+		res[flavors.Items[idx].Spec.ID] = &flavors.Items[idx]
+	}
+	return res, nil
+}
+
+type serverRecord struct {
+	VM  *kubevirtv1.VirtualMachine
+	VMI *kubevirtv1.VirtualMachineInstance
+}
+
+func (svc *service) commonServerList(c *gin.Context) ([]serverRecord, bool) {
+	proj := middleware.RequiredTokenScopeProject(c)
+	marker := c.Query("marker")
+	filterLimit, limit := GetFilterUInt(c, "limit")
+	filterStatus := c.Query("status")
+
+	var filterName *regexp.Regexp
+	if name := c.Query("name"); name != "" {
+		var err error
+		filterName, err = regexp.Compile(name)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return []serverRecord{}, true
+		}
+	}
+
+	namespace := proj.Spec.Namespace
+	_, allTenants := GetFilterBool(c, "all_tenants")
+	if allTenants {
+		if !middleware.TokenHasRole(c, "admin") {
+			c.AbortWithStatus(http.StatusForbidden)
+			return []serverRecord{}, true
+		}
+		namespace = k8sv1.NamespaceAll
+	}
+
+	vms, err := svc.Client.Kubevirt().VirtualMachines(namespace).List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return []serverRecord{}, true
+	}
+
+	vmis, err := svc.Client.Kubevirt().VirtualMachineInstances(namespace).List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return []serverRecord{}, true
+	}
+
+	instances := make(map[string]*kubevirtv1.VirtualMachineInstance)
+	for idx := range vmis.Items {
+		vmi := &vmis.Items[idx]
+		instances[vmi.ObjectMeta.Namespace+"/"+vmi.ObjectMeta.Name] = vmi
+	}
+
+	res := []serverRecord{}
+
+	count := uint64(0)
+	seenMarker := false
+	if marker == "" {
+		seenMarker = true
+	}
+	for idx := range vms.Items {
+		vm := &vms.Items[idx]
+		// Ignore VMs which were not created through the
+		// servers API
+		if _, ok := vm.ObjectMeta.Labels[compute.LabelServerID]; !ok {
+			continue
+		}
+		if marker != "" {
+			if marker == vm.ObjectMeta.Name {
+				seenMarker = true
+				marker = ""
+				continue
+			}
+		}
+		if !seenMarker {
+			continue
+		}
+
+		vmi := instances[vm.ObjectMeta.Namespace+"/"+vm.ObjectMeta.Name]
+
+		if filterName != nil && !filterName.MatchString(vm.ObjectMeta.Annotations[compute.AnnotationServerName]) {
+			continue
+		}
+		if filterStatus != "" {
+			status, _, _ := compute.ServerStatus(vm, vmi)
+			if status != filterStatus {
+				continue
+			}
+		}
+
+		res = append(res, serverRecord{
+			VM:  vm,
+			VMI: vmi,
+		})
+		count = count + 1
+		if filterLimit && count >= limit {
+			break
+		}
+	}
+	return res, false
+}
+
+func (svc *service) ServerList(c *gin.Context) {
+	servers, failed := svc.commonServerList(c)
+	if failed {
+		return
+	}
+
+	res := ServerListRes{
+		Servers: []ServerInfo{},
+	}
+
+	for _, server := range servers {
+		res.Servers = append(res.Servers, ServerInfo{
+			ID:    server.VM.ObjectMeta.Name,
+			Name:  server.VM.ObjectMeta.Annotations[compute.AnnotationServerName],
+			Links: svc.serverLinks(c, server.VM.ObjectMeta.Name),
+		})
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerListDetail(c *gin.Context) {
+	dom := middleware.RequiredTokenScopeDomain(c)
+
+	servers, failed := svc.commonServerList(c)
+	if failed {
+		return
+	}
+
+	flavors, err := svc.serverFlavors(dom)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	zones, err := svc.getNodeZones()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := ServerListDetailRes{
+		Servers: []ServerInfoDetail{},
+	}
+
+	for _, server := range servers {
+		res.Servers = append(res.Servers, svc.serverInfoDetail(c, server.VM, server.VMI, flavors, zones))
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// getServer fetches the VM backing a server and its running
+// instance, if any. It aborts the request and returns nil if
+// the server can't be fetched.
+func (svc *service) getServer(c *gin.Context, id string) (*kubevirtv1.VirtualMachine, *kubevirtv1.VirtualMachineInstance) {
+	proj := middleware.RequiredTokenScopeProject(c)
+
+	vm, err := svc.Client.Kubevirt().VirtualMachines(proj.Spec.Namespace).Get(id)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return nil, nil
+	}
+
+	if _, ok := vm.ObjectMeta.Labels[compute.LabelServerID]; !ok {
+		c.AbortWithStatus(http.StatusNotFound)
+		return nil, nil
+	}
+
+	vmi, err := svc.Client.Kubevirt().VirtualMachineInstances(proj.Spec.Namespace).Get(id)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return nil, nil
+		}
+		vmi = nil
+	}
+
+	return vm, vmi
+}
+
+func (svc *service) ServerShow(c *gin.Context) {
+	dom := middleware.RequiredTokenScopeDomain(c)
+	id := c.Param("id")
+
+	if id == "detail" {
+		svc.ServerListDetail(c)
+		return
+	}
+
+	vm, vmi := svc.getServer(c, id)
+	if vm == nil {
+		return
+	}
+
+	flavors, err := svc.serverFlavors(dom)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	zones, err := svc.getNodeZones()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := ServerShowRes{
+		Server: svc.serverInfoDetail(c, vm, vmi, flavors, zones),
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerDelete(c *gin.Context) {
+	proj := middleware.RequiredTokenScopeProject(c)
+	id := c.Param("id")
+
+	vm, _ := svc.getServer(c, id)
+	if vm == nil {
+		return
+	}
+	defer svc.recordInstanceAction(c, vm, compute.INSTANCE_ACTION_DELETE, time.Now())
+
+	// The VM owns its instance and the data volumes created
+	// from its templates, so they are garbage collected too
+	propagation := metav1.DeletePropagationBackground
+	err := svc.Client.Kubevirt().VirtualMachines(proj.Spec.Namespace).Delete(vm.ObjectMeta.Name, &metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	// The server is already gone, so failing to account for
+	// its end is not reported to the client
+	err = svc.updateServerUsage(vm, func(usage *v1.ServerUsage) {
+		compute.EndServerUsage(usage, time.Now())
+	})
+	if err != nil {
+		glog.Errorf("Unable to end usage of server %s: %s", vm.ObjectMeta.Name, err)
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+// serverRootDiskName gives the name of the DataVolume holding
+// the server's root disk
+func serverRootDiskName(id string) string {
+	return id + "-root"
+}
+
+// serverRootDisk returns the template of the DataVolume holding
+// the server's root disk, which is replaced when rebuilding
+func serverRootDisk(vm *kubevirtv1.VirtualMachine) *cdiv1.DataVolume {
+	name := ""
+	for _, volume := range vm.Spec.Template.Spec.Volumes {
+		if volume.Name == "root" && volume.DataVolume != nil {
+			name = volume.DataVolume.Name
+		}
+	}
+	if name == "" {
+		return nil
+	}
+
+	for idx := range vm.Spec.DataVolumeTemplates {
+		if vm.Spec.DataVolumeTemplates[idx].ObjectMeta.Name == name {
+			return &vm.Spec.DataVolumeTemplates[idx]
+		}
+	}
+	return nil
+}
+
+// serverRootDiskSource gives the source of the data for a root
+// disk from the image. Images saved from servers are cloned from
+// their volume, others are imported from the image repository.
+func (svc *service) serverRootDiskSource(img *imagev1.Image) (cdiv1.DataVolumeSource, error) {
+	if img.Spec.Volume != nil {
+		return cdiv1.DataVolumeSource{
+			PVC: &cdiv1.DataVolumeSourcePVC{
+				Namespace: img.Spec.Volume.Namespace,
+				Name:      img.Spec.Volume.Name,
+			},
+		}, nil
+	}
+
+	if svc.ImageRepoURL == "" {
+		return cdiv1.DataVolumeSource{}, fmt.Errorf("No image repository URL configured")
+	}
+	return cdiv1.DataVolumeSource{
+		HTTP: &cdiv1.DataVolumeSourceHTTP{
+			URL: strings.TrimSuffix(svc.ImageRepoURL, "/") + "/" + img.Spec.ID,
+		},
+	}, nil
+}
+
+// serverRootDiskSize picks the size of the root disk, which is
+// the flavor's disk size if set, otherwise large enough to hold
+// the image
+func serverRootDiskSize(flavor *v1.Flavor, img *imagev1.Image) resource.Quantity {
+	if flavor.Spec.Resources.RootDiskMB != 0 {
+		return resource.MustParse(fmt.Sprintf("%dMi", flavor.Spec.Resources.RootDiskMB))
+	}
+
+	size := uint64(0)
+	if img.Spec.VirtualSize != nil {
+		size = *img.Spec.VirtualSize
+	} else if img.Spec.Size != nil {
+		size = *img.Spec.Size
+	}
+	gib := uint64(1024 * 1024 * 1024)
+	sizeGiB := (size + gib - 1) / gib
+	if sizeGiB < img.Spec.MinDisk {
+		sizeGiB = img.Spec.MinDisk
+	}
+	if sizeGiB == 0 {
+		sizeGiB = 1
+	}
+	return resource.MustParse(fmt.Sprintf("%dGi", sizeGiB))
+}
+
+// checkServerQuota aborts the request and returns false if
+// creating a server with the flavor would exceed the quota of
+// the project
+func (svc *service) checkServerQuota(c *gin.Context, ns string, flavor *v1.Flavor) bool {
+	quota, err := svc.getQuota(ns)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return false
+	}
+	usage, err := svc.getQuotaUsage(ns)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return false
+	}
+
+	if quotaExceeded(c, "instances", 1, usage.Instances, quota.Instances) ||
+		quotaExceeded(c, "cores", int64(flavor.Spec.Resources.CPUCount), usage.Cores, quota.Cores) ||
+		quotaExceeded(c, "ram", int64(flavor.Spec.Resources.MemoryMB), usage.RAM, quota.RAM) {
+		return false
+	}
+	return true
+}
+
+// getServerAvailabilityZone parses the availability zone
+// requested for a new server, which may also name a host for
+// admins. It aborts the request and returns false on failure.
+func (svc *service) getServerAvailabilityZone(c *gin.Context, request string) (string, string, bool) {
+	if request == "" {
+		return "", "", true
+	}
+
+	// XXX the optional node after the host is ignored, since
+	// hosts and nodes are the same
+	parts := strings.SplitN(request, ":", 3)
+	zone := parts[0]
+	host := ""
+	if len(parts) > 1 {
+		host = parts[1]
+		if !middleware.TokenHasRole(c, "admin") {
+			c.AbortWithStatus(http.StatusForbidden)
+			return "", "", false
+		}
+	}
+
+	zones, err := svc.getNodeZones()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return "", "", false
+	}
+
+	if host != "" {
+		nodeZone, ok := zones[host]
+		if !ok || (zone != "" && zone != nodeZone) {
+			c.AbortWithError(http.StatusBadRequest,
+				fmt.Errorf("No host %s in availability zone %s", host, zone))
+			return "", "", false
+		}
+		return zone, host, true
+	}
+
+	for _, nodeZone := range zones {
+		if nodeZone == zone {
+			return zone, "", true
+		}
+	}
+	c.AbortWithError(http.StatusBadRequest,
+		fmt.Errorf("Unknown availability zone %s", zone))
+	return "", "", false
+}
+
+// getServerGroupForServer returns the server group a new server
+// is to be placed in, checking it has room for another member.
+// It aborts the request and returns nil on failure.
+func (svc *service) getServerGroupForServer(c *gin.Context, ns string, id string) *v1.ServerGroup {
+	group, err := svc.Client.Compute().ServerGroups(ns).Get(id)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusBadRequest, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return nil
+	}
+
+	quota, err := svc.getQuota(ns)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return nil
+	}
+	members, err := svc.getServerGroupMembers(ns)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return nil
+	}
+	if quotaExceeded(c, "server_group_members", 1,
+		int64(len(members[group.ObjectMeta.Name])), quota.ServerGroupMembers) {
+		return nil
+	}
+
+	return group
+}
+
+// serverSecret creates a Secret for a server, which is owned
+// by the VM so that it is garbage collected with it
+func serverSecret(vm *kubevirtv1.VirtualMachine, name string, data map[string][]byte) *k8sv1.Secret {
+	controller := true
+	return &k8sv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				compute.LabelServerID: vm.ObjectMeta.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				metav1.OwnerReference{
+					APIVersion: kubevirtv1.GroupVersion.String(),
+					Kind:       "VirtualMachine",
+					Name:       vm.ObjectMeta.Name,
+					UID:        vm.ObjectMeta.UID,
+					Controller: &controller,
+				},
+			},
+		},
+		Data: data,
+	}
+}
+
+// abortServerCreate fails the creation of a server after its VM
+// was created, deleting the VM along with the objects it owns so
+// that no server is left which cannot start
+func (svc *service) abortServerCreate(c *gin.Context, vm *kubevirtv1.VirtualMachine, err error) {
+	c.AbortWithError(http.StatusInternalServerError, err)
+
+	propagation := metav1.DeletePropagationBackground
+	err = svc.Client.Kubevirt().VirtualMachines(vm.ObjectMeta.Namespace).Delete(vm.ObjectMeta.Name, &metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		glog.Errorf("Unable to delete server %s after failing to create it: %s", vm.ObjectMeta.Name, err)
+	}
+}
+
+func (svc *service) ServerCreate(c *gin.Context) {
+	proj := middleware.RequiredTokenScopeProject(c)
+	dom := middleware.RequiredTokenScopeDomain(c)
+	user := middleware.RequiredTokenSubjectUser(c)
+
+	req := ServerCreateReq{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Server.Name == "" || req.Server.FlavorRef == "" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	// XXX booting from volumes is not supported
+	if req.Server.ImageRef == "" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	flavor, err := svc.Client.Compute().Flavors(dom.Spec.Namespace).GetByID(refID(req.Server.FlavorRef))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusBadRequest, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+	if !compute.FlavorAccessible(flavor, proj) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if flavor.Spec.Disabled {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	img, err := svc.Client.Image().Images(k8sv1.NamespaceAll).GetByID(refID(req.Server.ImageRef))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusBadRequest, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+	img, err = image.SyncImageVolume(svc.Client.Image(), svc.Client.CDI(), img)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if !image.ImageAccessible(img, proj) || img.Spec.Status != image.IMAGE_STATUS_ACTIVE {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	rootSource, err := svc.serverRootDiskSource(img)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if flavor.Spec.Resources.MemoryMB < img.Spec.MinRam ||
+		(flavor.Spec.Resources.RootDiskMB != 0 &&
+			flavor.Spec.Resources.RootDiskMB/1024 < img.Spec.MinDisk) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var keypair *v1.Keypair
+	if req.Server.KeyName != "" {
+		keypair, err = svc.getUserKeypair(user, req.Server.KeyName)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				c.AbortWithError(http.StatusBadRequest, err)
+			} else {
+				c.AbortWithError(http.StatusInternalServerError, err)
+			}
+			return
+		}
+	}
+
+	if req.Server.Metadata == nil {
+		req.Server.Metadata = map[string]string{}
+	}
+	if !compute.IsValidServerMetadata(req.Server.Metadata) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if req.Server.Tags == nil {
+		req.Server.Tags = []string{}
+	}
+	if len(req.Server.Tags) > compute.SERVER_TAGS_MAX_ITEMS {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	for _, tag := range req.Server.Tags {
+		if !compute.IsValidServerTag(tag) {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+	}
+
+	// XXX the limits on injected files should come from quotas
+	if len(req.Server.Personality) > serverMaxInjectedFiles {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	files := []cloudinit.File{}
+	for _, personality := range req.Server.Personality {
+		if !cloudinit.IsValidFilePath(personality.Path) ||
+			len(personality.Path) > serverMaxInjectedFilePath {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		content, err := base64.StdEncoding.DecodeString(personality.Contents)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		if len(content) > serverMaxInjectedFileContent {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		files = append(files, cloudinit.File{
+			Path:    personality.Path,
+			Content: content,
+		})
+	}
+
+	var userData []byte
+	if req.Server.UserData != "" {
+		userData, err = base64.StdEncoding.DecodeString(req.Server.UserData)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if !svc.checkServerQuota(c, proj.Spec.Namespace, flavor) {
+		return
+	}
+
+	groupID := req.SchedulerHints.Group
+	if groupID == "" {
+		groupID = req.SchedulerHintsAlias.Group
+	}
+	var group *v1.ServerGroup
+	if groupID != "" {
+		group = svc.getServerGroupForServer(c, proj.Spec.Namespace, groupID)
+		if group == nil {
+			return
+		}
+	}
+
+	zone, host, ok := svc.getServerAvailabilityZone(c, req.Server.AvailabilityZone)
+	if !ok {
+		return
+	}
+
+	adminPass := req.Server.AdminPass
+	if adminPass == "" {
+		adminPass, err = crypto.GeneratePassword(12)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	id := string(uuid.NewUUID())
+	rootDisk := serverRootDiskName(id)
+
+	cloudConfig := &cloudinit.Config{
+		Hostname: serverHostname(req.Server.Name),
+		Password: adminPass,
+		Files:    files,
+		UserData: userData,
+	}
+	if keypair != nil {
+		cloudConfig.SSHAuthorizedKeys = []string{
+			strings.TrimSpace(keypair.Spec.PublicKey),
+		}
+	}
+	cloudData, err := cloudConfig.Build()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	cloudInitSecret := &k8sv1.LocalObjectReference{
+		Name: compute.ServerCloudInitSecretName(id),
+	}
+	cloudInit := kubevirtv1.Volume{
+		Name: "cloudinit",
+	}
+	configDrive := ""
+	if req.Server.ConfigDrive {
+		cloudInit.CloudInitConfigDrive = &kubevirtv1.CloudInitConfigDriveSource{
+			UserDataSecretRef: cloudInitSecret,
+		}
+		configDrive = "True"
+	} else {
+		cloudInit.CloudInitNoCloud = &kubevirtv1.CloudInitNoCloudSource{
+			UserDataSecretRef: cloudInitSecret,
+		}
+	}
+
+	running := true
+	bootOrder := uint(1)
+	vm := &kubevirtv1.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: id,
+			Labels: map[string]string{
+				compute.LabelServerID: id,
+			},
+			Annotations: map[string]string{
+				compute.AnnotationServerName:  req.Server.Name,
+				compute.AnnotationFlavorID:    flavor.Spec.ID,
+				compute.AnnotationFlavorName:  flavor.ObjectMeta.Name,
+				compute.AnnotationImageID:     img.Spec.ID,
+				compute.AnnotationUserID:      user.GetID(),
+				compute.AnnotationProjectID:   proj.GetID(),
+				compute.AnnotationCreated:     time.Now().Format(time.RFC3339),
+				compute.AnnotationConfigDrive: configDrive,
+			},
+		},
+		Spec: kubevirtv1.VirtualMachineSpec{
+			Running: &running,
+			DataVolumeTemplates: []cdiv1.DataVolume{
+				cdiv1.DataVolume{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: rootDisk,
+					},
+					Spec: cdiv1.DataVolumeSpec{
+						Source: rootSource,
+						PVC: &k8sv1.PersistentVolumeClaimSpec{
+							AccessModes: []k8sv1.PersistentVolumeAccessMode{
+								k8sv1.ReadWriteOnce,
+							},
+							Resources: k8sv1.ResourceRequirements{
+								Requests: k8sv1.ResourceList{
+									k8sv1.ResourceStorage: serverRootDiskSize(flavor, img),
+								},
+							},
+						},
+					},
+				},
+			},
+			Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						compute.LabelServerID: id,
+					},
+				},
+				Spec: kubevirtv1.VirtualMachineInstanceSpec{
+					Hostname: serverHostname(req.Server.Name),
+					Domain: kubevirtv1.DomainSpec{
+						Resources: kubevirtv1.ResourceRequirements{
+							Requests: k8sv1.ResourceList{
+								k8sv1.ResourceMemory: resource.MustParse(fmt.Sprintf("%dMi", flavor.Spec.Resources.MemoryMB)),
+							},
+						},
+						CPU: &kubevirtv1.CPU{
+							Cores: uint32(flavor.Spec.Resources.CPUCount),
+						},
+						Devices: kubevirtv1.Devices{
+							Disks: []kubevirtv1.Disk{
+								kubevirtv1.Disk{
+									Name:      "root",
+									BootOrder: &bootOrder,
+									Disk: &kubevirtv1.DiskTarget{
+										Bus: "virtio",
+									},
+								},
+								kubevirtv1.Disk{
+									Name: "cloudinit",
+									Disk: &kubevirtv1.DiskTarget{
+										Bus: "virtio",
+									},
+								},
+							},
+							Interfaces: []kubevirtv1.Interface{
+								kubevirtv1.Interface{
+									Name:   compute.ServerPodNetworkName,
+									Bridge: &kubevirtv1.InterfaceBridge{},
+								},
+							},
+						},
+					},
+					Networks: []kubevirtv1.Network{
+						kubevirtv1.Network{
+							Name: compute.ServerPodNetworkName,
+							Pod:  &kubevirtv1.PodNetwork{},
+						},
+					},
+					Volumes: []kubevirtv1.Volume{
+						kubevirtv1.Volume{
+							Name: "root",
+							DataVolume: &kubevirtv1.DataVolumeSource{
+								Name: rootDisk,
+							},
+						},
+						cloudInit,
+					},
+				},
+			},
+		},
+	}
+
+	if keypair != nil {
+		vm.ObjectMeta.Annotations[compute.AnnotationKeyName] = keypair.ObjectMeta.Name
+	}
+	err = compute.SetServerMetadata(vm, req.Server.Metadata)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	err = compute.SetServerTags(vm, req.Server.Tags)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	err = compute.SetServerFlavor(flavor, &vm.Spec.Template.Spec)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+	compute.SetServerAccessCredentials(&vm.Spec.Template.Spec, id)
+
+	if zone != "" {
+		vm.ObjectMeta.Annotations[compute.AnnotationAvailabilityZone] = zone
+		compute.SetAvailabilityZone(&vm.Spec.Template.Spec, zone)
+	}
+	if host != "" {
+		compute.SetHost(&vm.Spec.Template.Spec, host)
+	}
+
+	if group != nil {
+		vm.ObjectMeta.Labels[compute.LabelServerGroup] = group.ObjectMeta.Name
+		vm.Spec.Template.ObjectMeta.Labels[compute.LabelServerGroup] = group.ObjectMeta.Name
+
+		spec := &vm.Spec.Template.Spec
+		affinity := compute.ServerGroupAffinity(group)
+		if spec.Affinity == nil {
+			spec.Affinity = &k8sv1.Affinity{}
+		}
+		spec.Affinity.PodAffinity = affinity.PodAffinity
+		spec.Affinity.PodAntiAffinity = affinity.PodAntiAffinity
+	}
+
+	vm, err = svc.Client.Kubevirt().VirtualMachines(proj.Spec.Namespace).Create(vm)
+	if err != nil {
+		// Rejected by the object count quota
+		if errors.IsForbidden(err) {
+			c.AbortWithError(http.StatusForbidden, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+	defer svc.recordInstanceAction(c, vm, compute.INSTANCE_ACTION_CREATE, time.Now())
+
+	// Until the secrets exist, the instance pod cannot start
+	secret := serverSecret(vm, cloudInitSecret.Name, map[string][]byte{
+		compute.ServerCloudInitSecretKey: cloudData,
+	})
+	_, err = svc.K8SClient.CoreV1().Secrets(proj.Spec.Namespace).Create(secret)
+	if err != nil {
+		svc.abortServerCreate(c, vm, err)
+		return
+	}
+
+	secret = serverSecret(vm, compute.ServerCredentialsSecretName(id), map[string][]byte{
+		compute.ServerAdminUser(img.Spec.Metadata): []byte(adminPass),
+	})
+	_, err = svc.K8SClient.CoreV1().Secrets(proj.Spec.Namespace).Create(secret)
+	if err != nil {
+		svc.abortServerCreate(c, vm, err)
+		return
+	}
+
+	usage := compute.NewServerUsage(id, req.Server.Name, proj.GetID(), user.GetID(), flavor, time.Now())
+	_, err = svc.Client.Compute().ServerUsages(proj.Spec.Namespace).Create(usage)
+	if err != nil {
+		svc.abortServerCreate(c, vm, err)
+		return
+	}
+
+	res := ServerCreateRes{
+		Server: ServerNewInfo{
+			ID:         vm.ObjectMeta.Name,
+			Links:      svc.serverLinks(c, vm.ObjectMeta.Name),
+			DiskConfig: "AUTO",
+			SecurityGroups: []SecurityGroupInfo{
+				SecurityGroupInfo{
+					Name: "default",
+				},
+			},
+			AdminPass: adminPass,
+		},
+	}
+	c.JSON(http.StatusAccepted, res)
+}
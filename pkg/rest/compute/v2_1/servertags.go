@@ -0,0 +1,208 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+)
+
+type ServerTagsReq struct {
+	Tags []string `json:"tags"`
+}
+
+type ServerTagsRes struct {
+	Tags []string `json:"tags"`
+}
+
+// getServerTags fetches a server and its tags, aborting the
+// request and returning nil on failure
+func (svc *service) getServerTags(c *gin.Context) (*kubevirtv1.VirtualMachine, []string) {
+	vm, _ := svc.getServer(c, c.Param("id"))
+	if vm == nil {
+		return nil, nil
+	}
+
+	tags, err := compute.GetServerTags(vm)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return nil, nil
+	}
+
+	return vm, tags
+}
+
+func (svc *service) saveServerTags(c *gin.Context, vm *kubevirtv1.VirtualMachine, tags []string) bool {
+	if len(tags) > compute.SERVER_TAGS_MAX_ITEMS {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return false
+	}
+	for _, tag := range tags {
+		if !compute.IsValidServerTag(tag) {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return false
+		}
+	}
+
+	err := compute.SetServerTags(vm, tags)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return false
+	}
+
+	return svc.updateServer(c, vm)
+}
+
+func (svc *service) ServerTagList(c *gin.Context) {
+	_, tags := svc.getServerTags(c)
+	if tags == nil {
+		return
+	}
+
+	res := ServerTagsRes{
+		Tags: tags,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerTagReplace(c *gin.Context) {
+	req := ServerTagsReq{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if req.Tags == nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	vm, _ := svc.getServerTags(c)
+	if vm == nil {
+		return
+	}
+
+	// Duplicates are silently dropped, as with Nova
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, tag := range req.Tags {
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	if !svc.saveServerTags(c, vm, tags) {
+		return
+	}
+
+	res := ServerTagsRes{
+		Tags: tags,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServerTagDeleteAll(c *gin.Context) {
+	vm, _ := svc.getServerTags(c)
+	if vm == nil {
+		return
+	}
+
+	if !svc.saveServerTags(c, vm, []string{}) {
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+func (svc *service) ServerTagShow(c *gin.Context) {
+	tag := c.Param("tag")
+
+	_, tags := svc.getServerTags(c)
+	if tags == nil {
+		return
+	}
+
+	for _, val := range tags {
+		if val == tag {
+			c.String(http.StatusNoContent, "")
+			return
+		}
+	}
+
+	c.AbortWithStatus(http.StatusNotFound)
+}
+
+func (svc *service) ServerTagAdd(c *gin.Context) {
+	tag := c.Param("tag")
+
+	vm, tags := svc.getServerTags(c)
+	if vm == nil {
+		return
+	}
+
+	location := "http://" + c.Request.Host + svc.Prefix + "/servers/" + vm.ObjectMeta.Name + "/tags/" + tag
+
+	for _, val := range tags {
+		if val == tag {
+			c.Header("Location", location)
+			c.String(http.StatusNoContent, "")
+			return
+		}
+	}
+
+	if !svc.saveServerTags(c, vm, append(tags, tag)) {
+		return
+	}
+
+	c.Header("Location", location)
+	c.String(http.StatusCreated, "")
+}
+
+func (svc *service) ServerTagDelete(c *gin.Context) {
+	tag := c.Param("tag")
+
+	vm, tags := svc.getServerTags(c)
+	if vm == nil {
+		return
+	}
+
+	res := []string{}
+	for _, val := range tags {
+		if val != tag {
+			res = append(res, val)
+		}
+	}
+	if len(res) == len(tags) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	if !svc.saveServerTags(c, vm, res) {
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
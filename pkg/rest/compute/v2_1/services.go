@@ -0,0 +1,197 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+// The virt-handler pod on each node stands in for the
+// nova-compute service
+const SERVICE_BINARY_COMPUTE = "nova-compute"
+
+type ServiceListRes struct {
+	Services []ServiceInfoDetail `json:"services"`
+}
+
+type ServiceInfoDetail struct {
+	ID             string  `json:"id"`
+	Binary         string  `json:"binary"`
+	Host           string  `json:"host"`
+	Zone           string  `json:"zone"`
+	State          string  `json:"state"`
+	Status         string  `json:"status"`
+	DisabledReason *string `json:"disabled_reason"`
+	ForcedDown     bool    `json:"forced_down"`
+	UpdatedAt      *string `json:"updated_at"`
+}
+
+type ServiceUpdateReq struct {
+	Status         *string `json:"status"`
+	DisabledReason *string `json:"disabled_reason"`
+	ForcedDown     *bool   `json:"forced_down"`
+}
+
+type ServiceUpdateRes struct {
+	Service ServiceInfoDetail `json:"service"`
+}
+
+func serviceInfoDetail(hv *hypervisor) ServiceInfoDetail {
+	node := hv.Node
+	info := ServiceInfoDetail{
+		ID:             string(node.ObjectMeta.UID),
+		Binary:         SERVICE_BINARY_COMPUTE,
+		Host:           hv.Hostname(),
+		Zone:           compute.NodeAvailabilityZone(node),
+		State:          hv.State(),
+		Status:         hv.Status(),
+		DisabledReason: compute.NodeDisabledReason(node),
+		ForcedDown:     compute.NodeForcedDown(node),
+	}
+
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == k8sv1.NodeReady && !cond.LastHeartbeatTime.IsZero() {
+			updated := cond.LastHeartbeatTime.Format(time.RFC3339)
+			info.UpdatedAt = &updated
+		}
+	}
+
+	return info
+}
+
+func (svc *service) ServiceList(c *gin.Context) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	host := c.Query("host")
+	binary := c.Query("binary")
+
+	res := ServiceListRes{
+		Services: []ServiceInfoDetail{},
+	}
+
+	if binary != "" && binary != SERVICE_BINARY_COMPUTE {
+		c.JSON(http.StatusOK, res)
+		return
+	}
+
+	hvs, err := svc.getHypervisorList(c)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	for _, hv := range hvs {
+		if hv.Node == nil {
+			continue
+		}
+		if host != "" && hv.Hostname() != host {
+			continue
+		}
+		res.Services = append(res.Services, serviceInfoDetail(hv))
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) ServiceUpdate(c *gin.Context) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	id := c.Param("id")
+
+	var req ServiceUpdateReq
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Status == nil && req.DisabledReason == nil && req.ForcedDown == nil {
+		badRequest(c, fmt.Errorf("No service update requested"))
+		return
+	}
+	if req.Status != nil && *req.Status != "enabled" && *req.Status != "disabled" {
+		badRequest(c, fmt.Errorf("Invalid service status '%s'", *req.Status))
+		return
+	}
+	if req.DisabledReason != nil {
+		if req.Status == nil || *req.Status != "disabled" {
+			badRequest(c, fmt.Errorf("Specifying 'disabled_reason' with status other than 'disabled' is invalid"))
+			return
+		}
+		if len(*req.DisabledReason) > 255 {
+			badRequest(c, fmt.Errorf("Service disabled reason is too long"))
+			return
+		}
+	}
+
+	hvs, err := svc.getHypervisorList(c)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var hv *hypervisor
+	for idx := range hvs {
+		if hvs[idx].Node != nil && string(hvs[idx].Node.ObjectMeta.UID) == id {
+			hv = hvs[idx]
+			break
+		}
+	}
+	if hv == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	if req.Status != nil {
+		reason := ""
+		if req.DisabledReason != nil {
+			reason = *req.DisabledReason
+		}
+		compute.SetNodeDisabled(hv.Node, *req.Status == "disabled", reason)
+	}
+	if req.ForcedDown != nil {
+		compute.SetNodeForcedDown(hv.Node, *req.ForcedDown)
+	}
+
+	node, err := svc.K8SClient.CoreV1().Nodes().Update(hv.Node)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	hv.Node = node
+
+	res := ServiceUpdateRes{
+		Service: serviceInfoDetail(hv),
+	}
+
+	c.JSON(http.StatusOK, res)
+}
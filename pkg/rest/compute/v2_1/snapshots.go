@@ -0,0 +1,170 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	cdiv1 "github.com/dicot-project/dicot-api/pkg/api/cdi/v1"
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	"github.com/dicot-project/dicot-api/pkg/api/image"
+	imagev1 "github.com/dicot-project/dicot-api/pkg/api/image/v1"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+type ServerCreateImageInfo struct {
+	Name     string            `json:"name"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+type ServerCreateImageRes struct {
+	ImageID string `json:"image_id"`
+}
+
+// serverActionCreateImage saves the root disk of the server to a
+// new image. The disk is cloned to a volume by CDI, and the
+// image is active once the clone completes. The server must be
+// stopped, since the clone of a ReadWriteOnce volume cannot
+// start while an instance is using it.
+func (svc *service) serverActionCreateImage(c *gin.Context, vm *kubevirtv1.VirtualMachine, req *ServerCreateImageInfo) {
+	proj := middleware.RequiredTokenScopeProject(c)
+	user := middleware.RequiredTokenSubjectUser(c)
+	id := vm.ObjectMeta.Name
+
+	rootDisk := serverRootDisk(vm)
+	if rootDisk == nil || rootDisk.Spec.PVC == nil {
+		c.AbortWithStatus(http.StatusConflict)
+		return
+	}
+	pvc := rootDisk.Spec.PVC
+	size := pvc.Resources.Requests[k8sv1.ResourceStorage]
+	sizeBytes := uint64(size.Value())
+
+	// The new image inherits the properties of the image the
+	// server was booted from, if it still exists
+	baseID := vm.ObjectMeta.Annotations[compute.AnnotationImageID]
+	base, err := svc.Client.Image().Images(k8sv1.NamespaceAll).GetByID(baseID)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		base = nil
+	}
+
+	containerFormat := image.IMAGE_CONTAINER_FORMAT_BARE
+	diskFormat := image.IMAGE_DISK_FORMAT_RAW
+	md := map[string]string{}
+	minRam := uint64(0)
+	if base != nil {
+		for key, val := range base.Spec.Metadata {
+			md[key] = val
+		}
+		minRam = base.Spec.MinRam
+	}
+	md[image.IMAGE_PROPERTY_IMAGE_TYPE] = image.IMAGE_TYPE_SNAPSHOT
+	md[image.IMAGE_PROPERTY_BASE_IMAGE_REF] = baseID
+	md[image.IMAGE_PROPERTY_INSTANCE_UUID] = id
+	md[image.IMAGE_PROPERTY_USER_ID] = user.GetID()
+	for key, val := range req.Metadata {
+		md[key] = val
+	}
+
+	gib := uint64(1024 * 1024 * 1024)
+	imgID := string(uuid.NewUUID())
+	now := time.Now().Format(time.RFC3339)
+	img := &imagev1.Image{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "img-" + imgID,
+		},
+		Spec: imagev1.ImageSpec{
+			ID:              imgID,
+			Name:            &req.Name,
+			Status:          image.IMAGE_STATUS_SAVING,
+			ContainerFormat: &containerFormat,
+			DiskFormat:      &diskFormat,
+			Visibility:      image.IMAGE_VISIBILITY_PRIVATE,
+			Size:            &sizeBytes,
+			VirtualSize:     &sizeBytes,
+			Owner:           proj.GetID(),
+			MinDisk:         (sizeBytes + gib - 1) / gib,
+			MinRam:          minRam,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+			Tags:            []string{},
+			Metadata:        md,
+			Volume: &imagev1.ImageVolume{
+				Namespace: proj.Spec.Namespace,
+				Name:      image.ImageVolumeName(imgID),
+			},
+		},
+	}
+
+	imgClnt := svc.Client.Image().Images(proj.Spec.Namespace)
+	img, err = imgClnt.Create(img)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// XXX the server could be started again before the clone
+	// completes, which would then wait for the server to stop
+	dv := &cdiv1.DataVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: img.Spec.Volume.Name,
+		},
+		Spec: cdiv1.DataVolumeSpec{
+			Source: cdiv1.DataVolumeSource{
+				PVC: &cdiv1.DataVolumeSourcePVC{
+					Namespace: vm.ObjectMeta.Namespace,
+					Name:      rootDisk.ObjectMeta.Name,
+				},
+			},
+			PVC: &k8sv1.PersistentVolumeClaimSpec{
+				AccessModes: pvc.AccessModes,
+				Resources: k8sv1.ResourceRequirements{
+					Requests: k8sv1.ResourceList{
+						k8sv1.ResourceStorage: size,
+					},
+				},
+			},
+		},
+	}
+
+	_, err = svc.Client.CDI().DataVolumes(proj.Spec.Namespace).Create(dv)
+	if err != nil {
+		imgClnt.Delete(img.ObjectMeta.Name, nil)
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := ServerCreateImageRes{
+		ImageID: imgID,
+	}
+	c.JSON(http.StatusAccepted, res)
+}
@@ -0,0 +1,335 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v2_1
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	"github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+	"github.com/dicot-project/dicot-api/pkg/rest"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+type TenantUsageListRes struct {
+	TenantUsages []TenantUsageInfo `json:"tenant_usages"`
+	Links        []rest.LinkInfo   `json:"tenant_usages_links,omitempty"`
+}
+
+type TenantUsageShowRes struct {
+	TenantUsage TenantUsageInfo `json:"tenant_usage"`
+	Links       []rest.LinkInfo `json:"tenant_usage_links,omitempty"`
+}
+
+type TenantUsageInfo struct {
+	TenantID           string            `json:"tenant_id"`
+	Start              string            `json:"start"`
+	Stop               string            `json:"stop"`
+	TotalHours         float64           `json:"total_hours"`
+	TotalVCPUsUsage    float64           `json:"total_vcpus_usage"`
+	TotalMemoryMBUsage float64           `json:"total_memory_mb_usage"`
+	TotalLocalGBUsage  float64           `json:"total_local_gb_usage"`
+	ServerUsages       []ServerUsageInfo `json:"server_usages,omitempty"`
+}
+
+type ServerUsageInfo struct {
+	InstanceID string  `json:"instance_id"`
+	Name       string  `json:"name"`
+	TenantID   string  `json:"tenant_id"`
+	Flavor     string  `json:"flavor"`
+	VCPUs      uint64  `json:"vcpus"`
+	MemoryMB   uint64  `json:"memory_mb"`
+	LocalGB    uint64  `json:"local_gb"`
+	Hours      float64 `json:"hours"`
+	StartedAt  string  `json:"started_at"`
+	EndedAt    *string `json:"ended_at"`
+	State      string  `json:"state"`
+	Uptime     int64   `json:"uptime"`
+}
+
+// The formats of the start and end of the usage window accepted
+// by Nova. Times without a zone are UTC.
+var usageTimeFormats = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+}
+
+func parseUsageTime(val string, now time.Time) (time.Time, error) {
+	if val == "" {
+		return now, nil
+	}
+	var err error
+	for _, format := range usageTimeFormats {
+		var t time.Time
+		t, err = time.Parse(format, val)
+		if err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// getUsageWindow returns the window over which usage is
+// reported, which defaults to the current time. It aborts the
+// request and returns false on failure.
+func getUsageWindow(c *gin.Context) (time.Time, time.Time, bool) {
+	now := time.Now().UTC()
+	start, err := parseUsageTime(c.Query("start"), now)
+	if err != nil {
+		badRequest(c, fmt.Errorf("Invalid start time: %s", err))
+		return start, now, false
+	}
+	end, err := parseUsageTime(c.Query("end"), now)
+	if err != nil {
+		badRequest(c, fmt.Errorf("Invalid end time: %s", err))
+		return start, end, false
+	}
+	if end.Before(start) {
+		badRequest(c, fmt.Errorf("Invalid start time. The start time cannot occur after the end time."))
+		return start, end, false
+	}
+	return start, end, true
+}
+
+// updateServerUsage applies a change to the usage record of a
+// server. A record which was deleted by hand is not recreated,
+// since the usage before then is lost, and the change to the
+// server goes ahead without being accounted.
+func (svc *service) updateServerUsage(vm *kubevirtv1.VirtualMachine, update func(usage *v1.ServerUsage)) error {
+	clnt := svc.Client.Compute().ServerUsages(vm.ObjectMeta.Namespace)
+	usage, err := clnt.Get(vm.ObjectMeta.Name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	update(usage)
+	_, err = clnt.Update(usage)
+	return err
+}
+
+// getServerUsages returns the usage records of the servers which
+// existed within a window, paginated as done by microversion 2.40
+// and later. It aborts the request and returns false on failure.
+func (svc *service) getServerUsages(c *gin.Context, namespace string, start, end time.Time) ([]*v1.ServerUsage, []rest.LinkInfo, bool) {
+	ver := middleware.GetMicroVersion(c)
+	paginate := ver != nil && ver.AtLeast(2, 40)
+	marker := ""
+	filterLimit, limit := false, uint64(0)
+	if paginate {
+		marker = c.Query("marker")
+		filterLimit, limit = GetFilterUInt(c, "limit")
+	}
+
+	usages, err := svc.Client.Compute().ServerUsages(namespace).List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return nil, nil, false
+	}
+
+	res := []*v1.ServerUsage{}
+	seenMarker := marker == ""
+	for idx := range usages.Items {
+		usage := &usages.Items[idx]
+		if !seenMarker {
+			if usage.Spec.ServerID == marker {
+				seenMarker = true
+			}
+			continue
+		}
+
+		inWindow, err := compute.ServerUsageInWindow(usage, start, end)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return nil, nil, false
+		}
+		if !inWindow {
+			continue
+		}
+
+		res = append(res, usage)
+		if filterLimit && uint64(len(res)) >= limit {
+			break
+		}
+	}
+	if !seenMarker {
+		badRequest(c, fmt.Errorf("marker [%s] not found", marker))
+		return nil, nil, false
+	}
+
+	links := []rest.LinkInfo{}
+	if filterLimit && len(res) != 0 && uint64(len(res)) >= limit {
+		query := c.Request.URL.Query()
+		query.Set("marker", res[len(res)-1].Spec.ServerID)
+		links = append(links, rest.LinkInfo{
+			Rel:  "next",
+			HRef: "http://" + c.Request.Host + c.Request.URL.Path + "?" + query.Encode(),
+		})
+	}
+
+	return res, links, true
+}
+
+func serverUsageInfo(usage *v1.ServerUsage, start, end time.Time) (ServerUsageInfo, compute.UsageTotals, error) {
+	totals, err := compute.ServerUsageTotals(usage, start, end)
+	if err != nil {
+		return ServerUsageInfo{}, totals, err
+	}
+	started, ended, err := compute.ServerUsageTimes(usage)
+	if err != nil {
+		return ServerUsageInfo{}, totals, err
+	}
+
+	// XXX the state of servers which still exist is not
+	// looked up
+	info := ServerUsageInfo{
+		InstanceID: usage.Spec.ServerID,
+		Name:       usage.Spec.Name,
+		TenantID:   usage.Spec.ProjectID,
+		Hours:      totals.Hours,
+		StartedAt:  usage.Spec.StartedAt,
+		State:      "active",
+		Uptime:     int64(time.Now().Sub(started).Seconds()),
+	}
+	if ended != nil {
+		info.EndedAt = &usage.Spec.EndedAt
+		info.State = "terminated"
+		info.Uptime = int64(ended.Sub(started).Seconds())
+	}
+	if len(usage.Spec.Periods) != 0 {
+		period := &usage.Spec.Periods[len(usage.Spec.Periods)-1]
+		info.Flavor = period.FlavorName
+		info.VCPUs = period.Resources.CPUCount
+		info.MemoryMB = period.Resources.MemoryMB
+		info.LocalGB = compute.ServerUsageLocalGB(&period.Resources)
+	}
+
+	return info, totals, nil
+}
+
+// tenantUsageInfo sums the usage of servers which all belong
+// to the same project
+func tenantUsageInfo(tenantID string, usages []*v1.ServerUsage, start, end time.Time, detailed bool) (TenantUsageInfo, error) {
+	info := TenantUsageInfo{
+		TenantID: tenantID,
+		Start:    start.Format(time.RFC3339),
+		Stop:     end.Format(time.RFC3339),
+	}
+	if detailed {
+		info.ServerUsages = []ServerUsageInfo{}
+	}
+
+	for _, usage := range usages {
+		server, totals, err := serverUsageInfo(usage, start, end)
+		if err != nil {
+			return info, err
+		}
+		info.TotalHours += totals.Hours
+		info.TotalVCPUsUsage += totals.VCPUHours
+		info.TotalMemoryMBUsage += totals.MemoryMBHours
+		info.TotalLocalGBUsage += totals.LocalGBHours
+		if detailed {
+			info.ServerUsages = append(info.ServerUsages, server)
+		}
+	}
+
+	return info, nil
+}
+
+func (svc *service) TenantUsageList(c *gin.Context) {
+	if !middleware.TokenHasRole(c, "admin") {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	start, end, ok := getUsageWindow(c)
+	if !ok {
+		return
+	}
+	_, detailed := GetFilterBool(c, "detailed")
+
+	usages, links, ok := svc.getServerUsages(c, k8sv1.NamespaceAll, start, end)
+	if !ok {
+		return
+	}
+
+	tenants := []string{}
+	tenantUsages := make(map[string][]*v1.ServerUsage)
+	for _, usage := range usages {
+		id := usage.Spec.ProjectID
+		if _, ok := tenantUsages[id]; !ok {
+			tenants = append(tenants, id)
+		}
+		tenantUsages[id] = append(tenantUsages[id], usage)
+	}
+
+	res := TenantUsageListRes{
+		TenantUsages: []TenantUsageInfo{},
+		Links:        links,
+	}
+	for _, id := range tenants {
+		info, err := tenantUsageInfo(id, tenantUsages[id], start, end, detailed)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		res.TenantUsages = append(res.TenantUsages, info)
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) TenantUsageShow(c *gin.Context) {
+	proj := svc.getQuotaProject(c, c.Param("id"))
+	if proj == nil {
+		return
+	}
+
+	start, end, ok := getUsageWindow(c)
+	if !ok {
+		return
+	}
+
+	usages, links, ok := svc.getServerUsages(c, proj.Spec.Namespace, start, end)
+	if !ok {
+		return
+	}
+
+	info, err := tenantUsageInfo(proj.GetID(), usages, start, end, true)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := TenantUsageShowRes{
+		TenantUsage: info,
+		Links:       links,
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -21,6 +21,7 @@ package v2_1
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -39,7 +40,7 @@ func GetFilterUInt(c *gin.Context, name string) (bool, uint64) {
 
 func GetFilterBool(c *gin.Context, name string) (bool, bool) {
 	val := c.Query(name)
-	if name == "" || name == "None" {
+	if val == "" || strings.EqualFold(val, "none") {
 		return false, false
 	}
 	res, err := strconv.ParseBool(val)
@@ -0,0 +1,144 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package console
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/glog"
+	"golang.org/x/net/websocket"
+
+	"github.com/dicot-project/dicot-api/pkg/audit"
+	"github.com/dicot-project/dicot-api/pkg/auth"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+func (svc *service) auditAccess(c *gin.Context, tok *auth.Token, outcome string, status int) {
+	target := audit.Resource{
+		TypeURI: audit.TypeURIServer,
+		ID:      "unknown",
+	}
+	if tok != nil && tok.Console != nil {
+		target.ID = tok.Console.Server
+	}
+	ev := middleware.NewAuditEvent(c, audit.ActionRead, outcome, target)
+	if tok != nil {
+		ev.Initiator.Name = tok.Subject.UserName
+	}
+	if status != 0 {
+		ev.SetReason(status)
+	}
+	svc.Auditor.Emit(ev)
+}
+
+// pipe copies data in both directions until either side is
+// closed
+func pipe(a, b io.ReadWriteCloser) {
+	done := make(chan struct{}, 2)
+	forward := func(dst, src io.ReadWriteCloser) {
+		io.Copy(dst, src)
+		done <- struct{}{}
+	}
+	go forward(a, b)
+	go forward(b, a)
+	<-done
+	a.Close()
+	b.Close()
+	<-done
+}
+
+func (svc *service) ConsoleProxy(c *gin.Context) {
+	toksig := c.Query("token")
+	if toksig == "" {
+		svc.auditAccess(c, nil, audit.OutcomeFailure, http.StatusUnauthorized)
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	tok, err := svc.TokenManager.ValidateToken(toksig)
+	if err != nil {
+		svc.auditAccess(c, nil, audit.OutcomeFailure, http.StatusUnauthorized)
+		c.AbortWithError(http.StatusUnauthorized, err)
+		return
+	}
+	if tok.Console == nil {
+		svc.auditAccess(c, tok, audit.OutcomeFailure, http.StatusUnauthorized)
+		c.AbortWithError(http.StatusUnauthorized,
+			fmt.Errorf("Token %s is not a console token", tok.ID))
+		return
+	}
+
+	// Console tokens may only be used once
+	err = svc.TokenManager.RevokeToken(tok)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	clnt := svc.Client.Kubevirt().VirtualMachineInstances(tok.Console.Namespace)
+	var upstream io.ReadWriteCloser
+	switch tok.Console.Type {
+	case TypeVNC:
+		upstream, err = clnt.VNC(tok.Console.Server)
+	case TypeSerial:
+		upstream, err = clnt.SerialConsole(tok.Console.Server)
+	default:
+		err = fmt.Errorf("Unknown console type %s", tok.Console.Type)
+	}
+	if err != nil {
+		svc.auditAccess(c, tok, audit.OutcomeFailure, http.StatusBadGateway)
+		c.AbortWithError(http.StatusBadGateway, err)
+		return
+	}
+
+	// Ensures the upstream is closed even if the handshake
+	// with the client fails
+	defer upstream.Close()
+
+	svc.auditAccess(c, tok, audit.OutcomeSuccess, 0)
+
+	server := websocket.Server{
+		// The token authorises the request, so there is no
+		// need to check the origin
+		Handshake: func(cfg *websocket.Config, req *http.Request) error {
+			// noVNC requests the 'binary' protocol
+			for _, proto := range cfg.Protocol {
+				if proto == "binary" {
+					cfg.Protocol = []string{proto}
+					return nil
+				}
+			}
+			cfg.Protocol = nil
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			conn.PayloadType = websocket.BinaryFrame
+			glog.V(1).Infof("Connected %s console of %s/%s",
+				tok.Console.Type, tok.Console.Namespace, tok.Console.Server)
+			pipe(conn, upstream)
+			glog.V(1).Infof("Disconnected %s console of %s/%s",
+				tok.Console.Type, tok.Console.Namespace, tok.Console.Server)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
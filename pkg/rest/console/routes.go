@@ -0,0 +1,83 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package console
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/dicot-project/dicot-api/pkg/api"
+	"github.com/dicot-project/dicot-api/pkg/audit"
+	"github.com/dicot-project/dicot-api/pkg/auth"
+	"github.com/dicot-project/dicot-api/pkg/rest"
+)
+
+const (
+	DefaultPrefix = "/console"
+
+	// ProxyPath is where the websocket proxy is found
+	// relative to the service prefix
+	ProxyPath = "/websocket"
+
+	TypeVNC    = "vnc"
+	TypeSerial = "serial"
+)
+
+type service struct {
+	Client       api.Interface
+	Prefix       string
+	TokenManager auth.TokenManager
+	Auditor      audit.Auditor
+}
+
+// NewService creates the websocket proxy which connects clients
+// to server consoles. It is authorised by one-time console
+// tokens included in the URL, rather than API tokens, since
+// browsers can't add headers to websocket requests.
+func NewService(client api.Interface, tm auth.TokenManager, auditor audit.Auditor, prefix string) rest.Service {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	return &service{
+		Client:       client,
+		Prefix:       prefix,
+		TokenManager: tm,
+		Auditor:      auditor,
+	}
+}
+
+func (svc *service) GetPrefix() string {
+	return svc.Prefix
+}
+
+func (svc *service) GetName() string {
+	return "dicot-console"
+}
+
+func (svc *service) GetType() string {
+	return "console"
+}
+
+func (svc *service) GetUID() string {
+	return "0f4ff6b1-7a35-4c7b-9d51-4e8d2b8c1c6e"
+}
+
+func (svc *service) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET(ProxyPath, svc.ConsoleProxy)
+}
@@ -0,0 +1,86 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v3
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+	"github.com/dicot-project/dicot-api/pkg/audit"
+	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
+)
+
+// setAuditTarget records the object affected by the request,
+// once the handler has resolved it
+func setAuditTarget(c *gin.Context, typeURI string, id string, name string) {
+	c.Set("AuditTarget", audit.Resource{
+		TypeURI: typeURI,
+		ID:      id,
+		Name:    name,
+	})
+}
+
+// setAuditInitiator records the user performing the request,
+// for requests which are not authenticated by a token
+func setAuditInitiator(c *gin.Context, user *v1.User) {
+	c.Set("AuditInitiator", user)
+}
+
+// audited wraps a handler so that an audit event is emitted
+// once it completes, with the outcome determined by the HTTP
+// status. If the handler fails before identifying its target,
+// the value of the route parameter 'param' is used as the ID
+func (svc *service) audited(action string, typeURI string, param string, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handler(c)
+
+		target := audit.Resource{
+			TypeURI: typeURI,
+			ID:      "unknown",
+		}
+		if param != "" {
+			target.ID = c.Param(param)
+		}
+		if obj, ok := c.Get("AuditTarget"); ok {
+			if res, ok := obj.(audit.Resource); ok {
+				target = res
+			}
+		}
+
+		outcome := audit.OutcomeSuccess
+		status := c.Writer.Status()
+		if status >= http.StatusBadRequest {
+			outcome = audit.OutcomeFailure
+		}
+
+		ev := middleware.NewAuditEvent(c, action, outcome, target)
+		if obj, ok := c.Get("AuditInitiator"); ok {
+			if user, ok := obj.(*v1.User); ok {
+				ev.Initiator.ID = user.GetID()
+				ev.Initiator.Name = user.Spec.Name
+				ev.Initiator.DomainID = user.Spec.DomainID
+			}
+		}
+		ev.SetReason(status)
+		svc.Auditor.Emit(ev)
+	}
+}
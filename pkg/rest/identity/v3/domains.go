@@ -29,6 +29,7 @@ import (
 
 	"github.com/dicot-project/dicot-api/pkg/api/identity"
 	"github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+	"github.com/dicot-project/dicot-api/pkg/audit"
 	"github.com/dicot-project/dicot-api/pkg/rest"
 )
 
@@ -82,12 +83,12 @@ func (svc *service) DomainList(c *gin.Context) {
 		if project.Spec.Parent != "" {
 			continue
 		}
-		if name != "" && project.ObjectMeta.Name != name {
+		if name != "" && project.GetName() != name {
 			continue
 		}
 		res.Domains = append(res.Domains, DomainInfo{
-			ID:          string(project.ObjectMeta.UID),
-			Name:        project.ObjectMeta.Name,
+			ID:          project.GetID(),
+			Name:        project.GetName(),
 			Enabled:     project.Spec.Enabled,
 			Description: project.Spec.Description,
 		})
@@ -121,6 +122,7 @@ func (svc *service) DomainCreate(c *gin.Context) {
 			Name: req.Domain.Name,
 		},
 		Spec: v1.ProjectSpec{
+			Name:        req.Domain.Name,
 			Enabled:     req.Domain.Enabled,
 			Description: req.Domain.Description,
 			Namespace:   identity.FormatDomainNamespace(req.Domain.Name),
@@ -138,6 +140,7 @@ func (svc *service) DomainCreate(c *gin.Context) {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
+	setAuditTarget(c, audit.TypeURIDomain, project.GetID(), req.Domain.Name)
 
 	projectNS, err = svc.K8SClient.CoreV1().Namespaces().Create(projectNS)
 	if err != nil {
@@ -149,8 +152,8 @@ func (svc *service) DomainCreate(c *gin.Context) {
 	// XXX links
 	res := DomainShowRes{
 		Domain: DomainInfo{
-			ID:          string(project.ObjectMeta.UID),
-			Name:        project.ObjectMeta.Name,
+			ID:          project.GetID(),
+			Name:        project.GetName(),
 			Enabled:     project.Spec.Enabled,
 			Description: project.Spec.Description,
 		},
@@ -164,7 +167,7 @@ func (svc *service) DomainShow(c *gin.Context) {
 
 	clnt := svc.Client.Identity().Projects(v1.NamespaceSystem)
 
-	project, err := clnt.GetByUID(domainID)
+	project, err := clnt.GetByID(domainID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -177,8 +180,8 @@ func (svc *service) DomainShow(c *gin.Context) {
 	// XXX links
 	res := DomainShowRes{
 		Domain: DomainInfo{
-			ID:          string(project.ObjectMeta.UID),
-			Name:        project.ObjectMeta.Name,
+			ID:          project.GetID(),
+			Name:        project.GetName(),
 			Enabled:     project.Spec.Enabled,
 			Description: project.Spec.Description,
 		},
@@ -199,7 +202,7 @@ func (svc *service) DomainUpdate(c *gin.Context) {
 
 	clnt := svc.Client.Identity().Projects(v1.NamespaceSystem)
 
-	project, err := clnt.GetByUID(domainID)
+	project, err := clnt.GetByID(domainID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -225,8 +228,8 @@ func (svc *service) DomainUpdate(c *gin.Context) {
 
 	res := DomainShowRes{
 		Domain: DomainInfo{
-			ID:          string(project.ObjectMeta.UID),
-			Name:        project.ObjectMeta.Name,
+			ID:          project.GetID(),
+			Name:        project.GetName(),
 			Enabled:     project.Spec.Enabled,
 			Description: project.Spec.Description,
 		},
@@ -240,7 +243,7 @@ func (svc *service) DomainDelete(c *gin.Context) {
 
 	clnt := svc.Client.Identity().Projects(v1.NamespaceSystem)
 
-	project, err := clnt.GetByUID(domainID)
+	project, err := clnt.GetByID(domainID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -29,6 +29,7 @@ import (
 
 	"github.com/dicot-project/dicot-api/pkg/api/identity"
 	"github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+	"github.com/dicot-project/dicot-api/pkg/audit"
 	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
 )
 
@@ -72,7 +73,7 @@ func (svc *service) GroupList(c *gin.Context) {
 	groupNS := k8sv1.NamespaceAll
 	if domainID := c.Query("domain_id"); domainID != "" {
 		domClnt := svc.Client.Identity().Projects(v1.NamespaceSystem)
-		dom, err := domClnt.GetByUID(domainID)
+		dom, err := domClnt.GetByID(domainID)
 		if err != nil {
 			c.AbortWithError(http.StatusBadRequest, err)
 			return
@@ -97,7 +98,7 @@ func (svc *service) GroupList(c *gin.Context) {
 			continue
 		}
 		info := GroupInfo{
-			ID:          string(group.ObjectMeta.UID),
+			ID:          group.GetID(),
 			Name:        group.Spec.Name,
 			DomainID:    group.Spec.DomainID,
 			Description: group.Spec.Description,
@@ -121,7 +122,7 @@ func (svc *service) GroupCreate(c *gin.Context) {
 
 	var domNamespace string
 	if req.Group.DomainID != "" {
-		dom, err := domClnt.GetByUID(req.Group.DomainID)
+		dom, err := domClnt.GetByID(req.Group.DomainID)
 		if err != nil {
 			if errors.IsNotFound(err) {
 				c.AbortWithError(http.StatusBadRequest, err)
@@ -132,7 +133,7 @@ func (svc *service) GroupCreate(c *gin.Context) {
 		}
 		domNamespace = dom.Spec.Namespace
 	} else {
-		req.Group.DomainID = string(dom.ObjectMeta.UID)
+		req.Group.DomainID = dom.GetID()
 		domNamespace = dom.Spec.Namespace
 	}
 
@@ -164,11 +165,12 @@ func (svc *service) GroupCreate(c *gin.Context) {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
+	setAuditTarget(c, audit.TypeURIGroup, group.GetID(), req.Group.Name)
 
 	// XXX links
 	res := GroupShowRes{
 		Group: GroupInfo{
-			ID:          string(group.ObjectMeta.UID),
+			ID:          group.GetID(),
 			Name:        group.Spec.Name,
 			DomainID:    group.Spec.DomainID,
 			Description: group.Spec.Description,
@@ -183,7 +185,7 @@ func (svc *service) GroupShow(c *gin.Context) {
 
 	clnt := svc.Client.Identity().Groups(k8sv1.NamespaceAll)
 
-	group, err := clnt.GetByUID(groupID)
+	group, err := clnt.GetByID(groupID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -198,7 +200,7 @@ func (svc *service) GroupShow(c *gin.Context) {
 	// XXX links
 	res := GroupShowRes{
 		Group: GroupInfo{
-			ID:          string(group.ObjectMeta.UID),
+			ID:          group.GetID(),
 			Name:        group.Spec.Name,
 			DomainID:    group.Spec.DomainID,
 			Description: group.Spec.Description,
@@ -220,7 +222,7 @@ func (svc *service) GroupUpdate(c *gin.Context) {
 
 	clnt := svc.Client.Identity().Groups(k8sv1.NamespaceAll)
 
-	group, err := clnt.GetByUID(groupID)
+	group, err := clnt.GetByID(groupID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -249,7 +251,7 @@ func (svc *service) GroupUpdate(c *gin.Context) {
 
 	res := GroupShowRes{
 		Group: GroupInfo{
-			ID:          string(group.ObjectMeta.UID),
+			ID:          group.GetID(),
 			Name:        group.Spec.Name,
 			Description: group.Spec.Description,
 		},
@@ -263,7 +265,7 @@ func (svc *service) GroupDelete(c *gin.Context) {
 
 	clnt := svc.Client.Identity().Groups(k8sv1.NamespaceAll)
 
-	group, err := clnt.GetByUID(groupID)
+	group, err := clnt.GetByID(groupID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -289,7 +291,7 @@ func (svc *service) GroupUserList(c *gin.Context) {
 
 	groupClnt := svc.Client.Identity().Groups(k8sv1.NamespaceAll)
 
-	group, err := groupClnt.GetByUID(groupID)
+	group, err := groupClnt.GetByID(groupID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -316,13 +318,13 @@ func (svc *service) GroupUserList(c *gin.Context) {
 	}
 
 	for _, user := range users.Items {
-		_, ok := members[string(user.ObjectMeta.UID)]
+		_, ok := members[user.GetID()]
 		if !ok {
 			continue
 		}
 
 		info := UserInfo{
-			ID:               string(user.ObjectMeta.UID),
+			ID:               user.GetID(),
 			Name:             user.Spec.Name,
 			Enabled:          user.Spec.Enabled,
 			DomainID:         user.Spec.DomainID,
@@ -343,7 +345,7 @@ func (svc *service) GroupUserAdd(c *gin.Context) {
 
 	groupClnt := svc.Client.Identity().Groups(k8sv1.NamespaceAll)
 
-	group, err := groupClnt.GetByUID(groupID)
+	group, err := groupClnt.GetByID(groupID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -356,7 +358,7 @@ func (svc *service) GroupUserAdd(c *gin.Context) {
 	groupClnt = svc.Client.Identity().Groups(group.ObjectMeta.Namespace)
 
 	userClnt := svc.Client.Identity().Users(k8sv1.NamespaceAll)
-	user, err := userClnt.GetByUID(userID)
+	user, err := userClnt.GetByID(userID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -368,14 +370,14 @@ func (svc *service) GroupUserAdd(c *gin.Context) {
 
 	found := false
 	for _, id := range group.Spec.UserIDs {
-		if id == string(user.ObjectMeta.UID) {
+		if id == user.GetID() {
 			found = true
 			break
 		}
 	}
 
 	if !found {
-		group.Spec.UserIDs = append(group.Spec.UserIDs, string(user.ObjectMeta.UID))
+		group.Spec.UserIDs = append(group.Spec.UserIDs, user.GetID())
 
 		group, err = groupClnt.Update(group)
 		if err != nil {
@@ -393,7 +395,7 @@ func (svc *service) GroupUserCheck(c *gin.Context) {
 
 	groupClnt := svc.Client.Identity().Groups(k8sv1.NamespaceAll)
 
-	group, err := groupClnt.GetByUID(groupID)
+	group, err := groupClnt.GetByID(groupID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -429,7 +431,7 @@ func (svc *service) GroupUserDelete(c *gin.Context) {
 
 	groupClnt := svc.Client.Identity().Groups(k8sv1.NamespaceAll)
 
-	group, err := groupClnt.GetByUID(groupID)
+	group, err := groupClnt.GetByID(groupID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -30,6 +30,7 @@ import (
 
 	"github.com/dicot-project/dicot-api/pkg/api/identity"
 	"github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+	"github.com/dicot-project/dicot-api/pkg/audit"
 	"github.com/dicot-project/dicot-api/pkg/rest"
 )
 
@@ -99,15 +100,15 @@ func (svc *service) ProjectList(c *gin.Context) {
 				continue
 			}
 		}
-		if name != "" && project.ObjectMeta.Name != name {
+		if name != "" && project.GetName() != name {
 			continue
 		}
 		if parent != "" && project.Spec.Parent != parent {
 			continue
 		}
 		res.Projects = append(res.Projects, ProjectInfo{
-			ID:          string(project.ObjectMeta.UID),
-			Name:        project.ObjectMeta.Name,
+			ID:          project.GetID(),
+			Name:        project.GetName(),
 			Enabled:     project.Spec.Enabled,
 			Description: project.Spec.Description,
 			IsDomain:    isDom,
@@ -165,11 +166,11 @@ func (svc *service) ProjectCreate(c *gin.Context) {
 				return
 			}
 			domainName = domain.ObjectMeta.Name
-			domainID = string(domain.ObjectMeta.UID)
+			domainID = domain.GetID()
 			parentID = domainID
 		} else {
 			if req.Project.DomainID != "" {
-				domain, err := domClnt.GetByUID(req.Project.DomainID)
+				domain, err := domClnt.GetByID(req.Project.DomainID)
 				if err != nil {
 					if errors.IsNotFound(err) {
 						c.AbortWithError(http.StatusBadRequest, err)
@@ -180,14 +181,14 @@ func (svc *service) ProjectCreate(c *gin.Context) {
 				}
 
 				domainName = domain.ObjectMeta.Name
-				domainID = string(domain.ObjectMeta.UID)
+				domainID = domain.GetID()
 			}
 			if req.Project.ParentID == "" {
 				parentID = domainID
 			} else {
 				allProjClnt := svc.Client.Identity().Projects(k8sv1.NamespaceAll)
 
-				parent, err := allProjClnt.GetByUID(req.Project.ParentID)
+				parent, err := allProjClnt.GetByID(req.Project.ParentID)
 				if err != nil {
 					if errors.IsNotFound(err) {
 						c.AbortWithError(http.StatusBadRequest, err)
@@ -197,9 +198,9 @@ func (svc *service) ProjectCreate(c *gin.Context) {
 					return
 				}
 
-				parentID = string(parent.ObjectMeta.UID)
+				parentID = parent.GetID()
 				if domainID == "" {
-					domain, err := domClnt.GetByUID(parent.Spec.Domain)
+					domain, err := domClnt.GetByID(parent.Spec.Domain)
 					if err != nil {
 						if errors.IsNotFound(err) {
 							c.AbortWithError(http.StatusBadRequest, err)
@@ -209,7 +210,7 @@ func (svc *service) ProjectCreate(c *gin.Context) {
 						return
 					}
 
-					domainID = string(domain.ObjectMeta.UID)
+					domainID = domain.GetID()
 					domainName = domain.ObjectMeta.Name
 				} else if domainID != parent.Spec.Domain {
 					c.AbortWithStatus(http.StatusBadRequest)
@@ -227,6 +228,7 @@ func (svc *service) ProjectCreate(c *gin.Context) {
 			Name: req.Project.Name,
 		},
 		Spec: v1.ProjectSpec{
+			Name:        req.Project.Name,
 			Enabled:     req.Project.Enabled,
 			Description: req.Project.Description,
 			Parent:      parentID,
@@ -256,6 +258,7 @@ func (svc *service) ProjectCreate(c *gin.Context) {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
+	setAuditTarget(c, audit.TypeURIProject, project.GetID(), req.Project.Name)
 
 	projectNS, err = svc.K8SClient.CoreV1().Namespaces().Create(projectNS)
 	if err != nil {
@@ -267,8 +270,8 @@ func (svc *service) ProjectCreate(c *gin.Context) {
 	// XXX links
 	res := ProjectShowRes{
 		Project: ProjectInfo{
-			ID:          string(project.ObjectMeta.UID),
-			Name:        project.ObjectMeta.Name,
+			ID:          project.GetID(),
+			Name:        project.GetName(),
 			Enabled:     project.Spec.Enabled,
 			Description: project.Spec.Description,
 			IsDomain:    req.Project.IsDomain,
@@ -285,7 +288,7 @@ func (svc *service) ProjectShow(c *gin.Context) {
 
 	clnt := svc.Client.Identity().Projects(k8sv1.NamespaceAll)
 
-	project, err := clnt.GetByUID(projectID)
+	project, err := clnt.GetByID(projectID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusBadRequest, err)
@@ -298,8 +301,8 @@ func (svc *service) ProjectShow(c *gin.Context) {
 	// XXX links
 	res := ProjectShowRes{
 		Project: ProjectInfo{
-			ID:          string(project.ObjectMeta.UID),
-			Name:        project.ObjectMeta.Name,
+			ID:          project.GetID(),
+			Name:        project.GetName(),
 			Enabled:     project.Spec.Enabled,
 			Description: project.Spec.Description,
 			ParentID:    project.Spec.Parent,
@@ -322,7 +325,7 @@ func (svc *service) ProjectUpdate(c *gin.Context) {
 
 	clnt := svc.Client.Identity().Projects(k8sv1.NamespaceAll)
 
-	project, err := clnt.GetByUID(projectID)
+	project, err := clnt.GetByID(projectID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusBadRequest, err)
@@ -350,8 +353,8 @@ func (svc *service) ProjectUpdate(c *gin.Context) {
 
 	res := ProjectShowRes{
 		Project: ProjectInfo{
-			ID:          string(project.ObjectMeta.UID),
-			Name:        project.ObjectMeta.Name,
+			ID:          project.GetID(),
+			Name:        project.GetName(),
 			Enabled:     project.Spec.Enabled,
 			Description: project.Spec.Description,
 			ParentID:    project.Spec.Parent,
@@ -367,7 +370,7 @@ func (svc *service) ProjectDelete(c *gin.Context) {
 
 	clnt := svc.Client.Identity().Projects(k8sv1.NamespaceAll)
 
-	project, err := clnt.GetByUID(projectID)
+	project, err := clnt.GetByID(projectID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusBadRequest, err)
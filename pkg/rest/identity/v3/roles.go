@@ -0,0 +1,592 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package v3
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/identity"
+	"github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+	"github.com/dicot-project/dicot-api/pkg/audit"
+	"github.com/dicot-project/dicot-api/pkg/rest"
+)
+
+type RoleListRes struct {
+	Roles []RoleInfo `json:"roles"`
+}
+
+type RoleInfo struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	DomainID    *string  `json:"domain_id"`
+	Description string   `json:"description"`
+	Links       LinkInfo `json:"links"`
+}
+
+type RoleCreateReq struct {
+	Role RoleInfo `json:"role"`
+}
+
+type RoleUpdateReq struct {
+	Role RoleUpdateInfo `json:"role"`
+}
+
+type RoleUpdateInfo struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+type RoleShowRes struct {
+	Role RoleInfo `json:"role"`
+}
+
+type RoleInferenceRef struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Links LinkInfo `json:"links"`
+}
+
+type RoleInferenceInfo struct {
+	PriorRole RoleInferenceRef `json:"prior_role"`
+	Implies   RoleInferenceRef `json:"implies"`
+}
+
+type RoleInferenceRes struct {
+	RoleInference RoleInferenceInfo `json:"role_inference"`
+	Links         LinkInfo          `json:"links"`
+}
+
+type RoleInferenceListInfo struct {
+	PriorRole RoleInferenceRef   `json:"prior_role"`
+	Implies   []RoleInferenceRef `json:"implies"`
+}
+
+type RoleInferenceListRes struct {
+	RoleInference RoleInferenceListInfo `json:"role_inference"`
+	Links         LinkInfo              `json:"links"`
+}
+
+type RoleInferencesRes struct {
+	RoleInferences []RoleInferenceListInfo `json:"role_inferences"`
+	Links          LinkInfo                `json:"links"`
+}
+
+func (svc *service) roleLink(c *gin.Context, path string) LinkInfo {
+	return LinkInfo{
+		Self: "http://" + c.Request.Host + svc.Prefix + path,
+	}
+}
+
+func (svc *service) roleInfo(c *gin.Context, role *v1.Role) RoleInfo {
+	info := RoleInfo{
+		ID:          role.GetID(),
+		Name:        role.Spec.Name,
+		Description: role.Spec.Description,
+		Links:       svc.roleLink(c, "/roles/"+role.GetID()),
+	}
+	if role.Spec.DomainID != "" {
+		domainID := role.Spec.DomainID
+		info.DomainID = &domainID
+	}
+	return info
+}
+
+func (svc *service) roleInferenceRef(c *gin.Context, role *v1.Role) RoleInferenceRef {
+	return RoleInferenceRef{
+		ID:    role.GetID(),
+		Name:  role.Spec.Name,
+		Links: svc.roleLink(c, "/roles/"+role.GetID()),
+	}
+}
+
+func (svc *service) RoleList(c *gin.Context) {
+	name := c.Query("name")
+
+	roleNS := v1.NamespaceSystem
+	if domainID := c.Query("domain_id"); domainID != "" {
+		domClnt := svc.Client.Identity().Projects(v1.NamespaceSystem)
+		dom, err := domClnt.GetByID(domainID)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		roleNS = dom.Spec.Namespace
+	}
+	clnt := svc.Client.Identity().Roles(roleNS)
+
+	roles, err := clnt.List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := &RoleListRes{
+		Roles: []RoleInfo{},
+	}
+
+	for _, role := range roles.Items {
+		if name != "" && role.Spec.Name != name {
+			continue
+		}
+		res.Roles = append(res.Roles, svc.roleInfo(c, &role))
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) RoleCreate(c *gin.Context) {
+	var req RoleCreateReq
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if req.Role.Name == "" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	roleNS := v1.NamespaceSystem
+	domainID := ""
+	if req.Role.DomainID != nil && *req.Role.DomainID != "" {
+		domClnt := svc.Client.Identity().Projects(v1.NamespaceSystem)
+		dom, err := domClnt.GetByID(*req.Role.DomainID)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				c.AbortWithError(http.StatusBadRequest, err)
+			} else {
+				c.AbortWithError(http.StatusInternalServerError, err)
+			}
+			return
+		}
+		roleNS = dom.Spec.Namespace
+		domainID = dom.GetID()
+	}
+
+	clnt := svc.Client.Identity().Roles(roleNS)
+
+	exists, err := clnt.Exists(identity.SanitizeName(req.Role.Name))
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if exists {
+		c.AbortWithStatus(http.StatusConflict)
+		return
+	}
+
+	role := &v1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: identity.SanitizeName(req.Role.Name),
+		},
+		Spec: v1.RoleSpec{
+			Name:        req.Role.Name,
+			DomainID:    domainID,
+			Description: req.Role.Description,
+			Implies:     []string{},
+		},
+	}
+
+	role, err = clnt.Create(role)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	setAuditTarget(c, audit.TypeURIRole, role.GetID(), req.Role.Name)
+
+	res := RoleShowRes{
+		Role: svc.roleInfo(c, role),
+	}
+
+	c.JSON(http.StatusCreated, res)
+}
+
+func (svc *service) RoleShow(c *gin.Context) {
+	roleID := c.Param("roleID")
+
+	clnt := svc.Client.Identity().Roles(k8sv1.NamespaceAll)
+
+	role, err := clnt.GetByID(roleID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	res := RoleShowRes{
+		Role: svc.roleInfo(c, role),
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) RoleUpdate(c *gin.Context) {
+	var req RoleUpdateReq
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	roleID := c.Param("roleID")
+
+	clnt := svc.Client.Identity().Roles(k8sv1.NamespaceAll)
+
+	role, err := clnt.GetByID(roleID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	clnt = svc.Client.Identity().Roles(role.ObjectMeta.Namespace)
+
+	// XXX renaming would require changing the object name
+	if req.Role.Name != nil && *req.Role.Name != role.Spec.Name {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	if req.Role.Description != nil {
+		role.Spec.Description = *req.Role.Description
+	}
+
+	role, err = clnt.Update(role)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	res := RoleShowRes{
+		Role: svc.roleInfo(c, role),
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) RoleDelete(c *gin.Context) {
+	roleID := c.Param("roleID")
+
+	allClnt := svc.Client.Identity().Roles(k8sv1.NamespaceAll)
+
+	roles, err := allClnt.List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var role *v1.Role
+	for idx := range roles.Items {
+		if roles.Items[idx].GetID() == roleID {
+			role = &roles.Items[idx]
+			break
+		}
+	}
+	if role == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	clnt := svc.Client.Identity().Roles(role.ObjectMeta.Namespace)
+
+	err = clnt.Delete(role.ObjectMeta.Name, nil)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// Drop any inference rules which referred to the deleted role
+	for _, prior := range roles.Items {
+		implies := rest.StringListDel(prior.Spec.Implies, roleID)
+		if len(implies) == len(prior.Spec.Implies) {
+			continue
+		}
+		prior.Spec.Implies = implies
+		_, err = svc.Client.Identity().Roles(prior.ObjectMeta.Namespace).Update(&prior)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+func (svc *service) RoleImpliesList(c *gin.Context) {
+	priorID := c.Param("roleID")
+
+	clnt := svc.Client.Identity().Roles(k8sv1.NamespaceAll)
+
+	roles, err := clnt.List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var prior *v1.Role
+	byID := make(map[string]*v1.Role)
+	for idx := range roles.Items {
+		role := &roles.Items[idx]
+		byID[role.GetID()] = role
+		if role.GetID() == priorID {
+			prior = role
+		}
+	}
+	if prior == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	res := RoleInferenceListRes{
+		RoleInference: RoleInferenceListInfo{
+			PriorRole: svc.roleInferenceRef(c, prior),
+			Implies:   []RoleInferenceRef{},
+		},
+		Links: svc.roleLink(c, "/roles/"+priorID+"/implies"),
+	}
+
+	for _, id := range prior.Spec.Implies {
+		implied, ok := byID[id]
+		if !ok {
+			continue
+		}
+		res.RoleInference.Implies = append(res.RoleInference.Implies,
+			svc.roleInferenceRef(c, implied))
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) RoleImpliesCreate(c *gin.Context) {
+	priorID := c.Param("roleID")
+	impliedID := c.Param("impliedID")
+
+	clnt := svc.Client.Identity().Roles(k8sv1.NamespaceAll)
+
+	roles, err := clnt.List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var prior, implied *v1.Role
+	for idx := range roles.Items {
+		role := &roles.Items[idx]
+		if role.GetID() == priorID {
+			prior = role
+		}
+		if role.GetID() == impliedID {
+			implied = role
+		}
+	}
+	if prior == nil || implied == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	// Domain specific roles may imply global roles, but
+	// can never be implied themselves
+	if implied.Spec.DomainID != "" ||
+		identity.IsProhibitedImpliedRole(implied.Spec.Name) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if priorID == impliedID ||
+		identity.ImpliesRole(roles.Items, impliedID, priorID) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	implies := rest.StringListAdd(prior.Spec.Implies, impliedID)
+	if len(implies) != len(prior.Spec.Implies) {
+		prior.Spec.Implies = implies
+		prior, err = svc.Client.Identity().Roles(prior.ObjectMeta.Namespace).Update(prior)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	res := RoleInferenceRes{
+		RoleInference: RoleInferenceInfo{
+			PriorRole: svc.roleInferenceRef(c, prior),
+			Implies:   svc.roleInferenceRef(c, implied),
+		},
+		Links: svc.roleLink(c, "/roles/"+priorID+"/implies/"+impliedID),
+	}
+
+	c.JSON(http.StatusCreated, res)
+}
+
+func (svc *service) commonRoleImpliesShow(c *gin.Context) (*v1.Role, *v1.Role, bool) {
+	priorID := c.Param("roleID")
+	impliedID := c.Param("impliedID")
+
+	clnt := svc.Client.Identity().Roles(k8sv1.NamespaceAll)
+
+	prior, err := clnt.GetByID(priorID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return nil, nil, true
+	}
+
+	found := false
+	for _, id := range prior.Spec.Implies {
+		if id == impliedID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.AbortWithStatus(http.StatusNotFound)
+		return nil, nil, true
+	}
+
+	implied, err := clnt.GetByID(impliedID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return nil, nil, true
+	}
+
+	return prior, implied, false
+}
+
+func (svc *service) RoleImpliesShow(c *gin.Context) {
+	prior, implied, failed := svc.commonRoleImpliesShow(c)
+	if failed {
+		return
+	}
+
+	priorID := prior.GetID()
+	impliedID := implied.GetID()
+	res := RoleInferenceRes{
+		RoleInference: RoleInferenceInfo{
+			PriorRole: svc.roleInferenceRef(c, prior),
+			Implies:   svc.roleInferenceRef(c, implied),
+		},
+		Links: svc.roleLink(c, "/roles/"+priorID+"/implies/"+impliedID),
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (svc *service) RoleImpliesCheck(c *gin.Context) {
+	_, _, failed := svc.commonRoleImpliesShow(c)
+	if failed {
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+func (svc *service) RoleImpliesDelete(c *gin.Context) {
+	priorID := c.Param("roleID")
+	impliedID := c.Param("impliedID")
+
+	clnt := svc.Client.Identity().Roles(k8sv1.NamespaceAll)
+
+	prior, err := clnt.GetByID(priorID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	implies := rest.StringListDel(prior.Spec.Implies, impliedID)
+	if len(implies) == len(prior.Spec.Implies) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	prior.Spec.Implies = implies
+	_, err = svc.Client.Identity().Roles(prior.ObjectMeta.Namespace).Update(prior)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+func (svc *service) RoleInferenceList(c *gin.Context) {
+	clnt := svc.Client.Identity().Roles(k8sv1.NamespaceAll)
+
+	roles, err := clnt.List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	byID := make(map[string]*v1.Role)
+	for idx := range roles.Items {
+		byID[roles.Items[idx].GetID()] = &roles.Items[idx]
+	}
+
+	res := RoleInferencesRes{
+		RoleInferences: []RoleInferenceListInfo{},
+		Links:          svc.roleLink(c, "/role_inferences"),
+	}
+
+	for idx := range roles.Items {
+		prior := &roles.Items[idx]
+		if len(prior.Spec.Implies) == 0 {
+			continue
+		}
+
+		info := RoleInferenceListInfo{
+			PriorRole: svc.roleInferenceRef(c, prior),
+			Implies:   []RoleInferenceRef{},
+		}
+		for _, id := range prior.Spec.Implies {
+			implied, ok := byID[id]
+			if !ok {
+				continue
+			}
+			info.Implies = append(info.Implies, svc.roleInferenceRef(c, implied))
+		}
+		res.RoleInferences = append(res.RoleInferences, info)
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -24,6 +24,7 @@ import (
 	k8s "k8s.io/client-go/kubernetes"
 
 	"github.com/dicot-project/dicot-api/pkg/api"
+	"github.com/dicot-project/dicot-api/pkg/audit"
 	"github.com/dicot-project/dicot-api/pkg/auth"
 	"github.com/dicot-project/dicot-api/pkg/rest"
 	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
@@ -35,9 +36,10 @@ type service struct {
 	Prefix       string
 	Services     *rest.ServiceList
 	TokenManager auth.TokenManager
+	Auditor      audit.Auditor
 }
 
-func NewService(client api.Interface, k8sClient k8s.Interface, tm auth.TokenManager, svcs *rest.ServiceList, prefix string) rest.Service {
+func NewService(client api.Interface, k8sClient k8s.Interface, tm auth.TokenManager, auditor audit.Auditor, svcs *rest.ServiceList, prefix string) rest.Service {
 	if prefix == "" {
 		prefix = "/identity/v3"
 	}
@@ -47,6 +49,7 @@ func NewService(client api.Interface, k8sClient k8s.Interface, tm auth.TokenMana
 		Prefix:       prefix,
 		Services:     svcs,
 		TokenManager: tm,
+		Auditor:      auditor,
 	}
 }
 
@@ -67,37 +70,50 @@ func (svc *service) GetUID() string {
 }
 
 func (svc *service) RegisterRoutes(router *gin.RouterGroup) {
-	tokNoAnon := middleware.NewTokenHandler(svc.TokenManager, svc.Client.Identity()).Handler()
-	tokAllowAnon := middleware.NewTokenHandlerAllowAnon(svc.TokenManager, svc.Client.Identity()).Handler()
+	tokNoAnon := middleware.NewTokenHandler(svc.TokenManager, svc.Client.Identity(), svc.Auditor).Handler()
+	tokAllowAnon := middleware.NewTokenHandlerAllowAnon(svc.TokenManager, svc.Client.Identity(), svc.Auditor).Handler()
 
 	router.GET("/", svc.IndexGet)
-	router.POST("/auth/tokens", tokAllowAnon, svc.TokensPost)
+	router.POST("/auth/tokens", tokAllowAnon, svc.audited(audit.ActionAuthenticate, audit.TypeURIService, "", svc.TokensPost))
+	router.DELETE("/auth/tokens", tokNoAnon, svc.audited(audit.ActionDelete, audit.TypeURIToken, "", svc.TokensDelete))
 
 	router.GET("/domains", tokNoAnon, svc.DomainList)
-	router.POST("/domains", tokNoAnon, svc.DomainCreate)
+	router.POST("/domains", tokNoAnon, svc.audited(audit.ActionCreate, audit.TypeURIDomain, "", svc.DomainCreate))
 	router.GET("/domains/:domainID", tokNoAnon, svc.DomainShow)
-	router.PATCH("/domains/:domainID", tokNoAnon, svc.DomainUpdate)
-	router.DELETE("/domains/:domainID", tokNoAnon, svc.DomainDelete)
+	router.PATCH("/domains/:domainID", tokNoAnon, svc.audited(audit.ActionUpdate, audit.TypeURIDomain, "domainID", svc.DomainUpdate))
+	router.DELETE("/domains/:domainID", tokNoAnon, svc.audited(audit.ActionDelete, audit.TypeURIDomain, "domainID", svc.DomainDelete))
 
 	router.GET("/projects", tokNoAnon, svc.ProjectList)
-	router.POST("/projects", tokNoAnon, svc.ProjectCreate)
+	router.POST("/projects", tokNoAnon, svc.audited(audit.ActionCreate, audit.TypeURIProject, "", svc.ProjectCreate))
 	router.GET("/projects/:projectID", tokNoAnon, svc.ProjectShow)
-	router.PATCH("/projects/:projectID", tokNoAnon, svc.ProjectUpdate)
-	router.DELETE("/projects/:projectID", tokNoAnon, svc.ProjectDelete)
+	router.PATCH("/projects/:projectID", tokNoAnon, svc.audited(audit.ActionUpdate, audit.TypeURIProject, "projectID", svc.ProjectUpdate))
+	router.DELETE("/projects/:projectID", tokNoAnon, svc.audited(audit.ActionDelete, audit.TypeURIProject, "projectID", svc.ProjectDelete))
 
 	router.GET("/users", tokNoAnon, svc.UserList)
-	router.POST("/users", tokNoAnon, svc.UserCreate)
+	router.POST("/users", tokNoAnon, svc.audited(audit.ActionCreate, audit.TypeURIUser, "", svc.UserCreate))
 	router.GET("/users/:userID", tokNoAnon, svc.UserShow)
-	router.PATCH("/users/:userID", tokNoAnon, svc.UserUpdate)
-	router.DELETE("/users/:userID", tokNoAnon, svc.UserDelete)
+	router.PATCH("/users/:userID", tokNoAnon, svc.audited(audit.ActionUpdate, audit.TypeURIUser, "userID", svc.UserUpdate))
+	router.DELETE("/users/:userID", tokNoAnon, svc.audited(audit.ActionDelete, audit.TypeURIUser, "userID", svc.UserDelete))
 
 	router.GET("/groups", tokNoAnon, svc.GroupList)
-	router.POST("/groups", tokNoAnon, svc.GroupCreate)
+	router.POST("/groups", tokNoAnon, svc.audited(audit.ActionCreate, audit.TypeURIGroup, "", svc.GroupCreate))
 	router.GET("/groups/:groupID", tokNoAnon, svc.GroupShow)
-	router.PATCH("/groups/:groupID", tokNoAnon, svc.GroupUpdate)
-	router.DELETE("/groups/:groupID", tokNoAnon, svc.GroupDelete)
+	router.PATCH("/groups/:groupID", tokNoAnon, svc.audited(audit.ActionUpdate, audit.TypeURIGroup, "groupID", svc.GroupUpdate))
+	router.DELETE("/groups/:groupID", tokNoAnon, svc.audited(audit.ActionDelete, audit.TypeURIGroup, "groupID", svc.GroupDelete))
 	router.GET("/groups/:groupID/users", tokNoAnon, svc.GroupUserList)
-	router.PUT("/groups/:groupID/users/:userID", tokNoAnon, svc.GroupUserAdd)
+	router.PUT("/groups/:groupID/users/:userID", tokNoAnon, svc.audited(audit.ActionUpdate, audit.TypeURIGroup, "groupID", svc.GroupUserAdd))
 	router.HEAD("/groups/:groupID/users/:userID", tokNoAnon, svc.GroupUserCheck)
-	router.DELETE("/groups/:groupID/users/:userID", tokNoAnon, svc.GroupUserDelete)
+	router.DELETE("/groups/:groupID/users/:userID", tokNoAnon, svc.audited(audit.ActionUpdate, audit.TypeURIGroup, "groupID", svc.GroupUserDelete))
+
+	router.GET("/roles", tokNoAnon, svc.RoleList)
+	router.POST("/roles", tokNoAnon, svc.audited(audit.ActionCreate, audit.TypeURIRole, "", svc.RoleCreate))
+	router.GET("/roles/:roleID", tokNoAnon, svc.RoleShow)
+	router.PATCH("/roles/:roleID", tokNoAnon, svc.audited(audit.ActionUpdate, audit.TypeURIRole, "roleID", svc.RoleUpdate))
+	router.DELETE("/roles/:roleID", tokNoAnon, svc.audited(audit.ActionDelete, audit.TypeURIRole, "roleID", svc.RoleDelete))
+	router.GET("/roles/:roleID/implies", tokNoAnon, svc.RoleImpliesList)
+	router.PUT("/roles/:roleID/implies/:impliedID", tokNoAnon, svc.audited(audit.ActionUpdate, audit.TypeURIRole, "roleID", svc.RoleImpliesCreate))
+	router.GET("/roles/:roleID/implies/:impliedID", tokNoAnon, svc.RoleImpliesShow)
+	router.HEAD("/roles/:roleID/implies/:impliedID", tokNoAnon, svc.RoleImpliesCheck)
+	router.DELETE("/roles/:roleID/implies/:impliedID", tokNoAnon, svc.audited(audit.ActionUpdate, audit.TypeURIRole, "roleID", svc.RoleImpliesDelete))
+	router.GET("/role_inferences", tokNoAnon, svc.RoleInferenceList)
 }
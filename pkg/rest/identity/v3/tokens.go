@@ -21,14 +21,17 @@ package v3
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/uuid"
 
 	"github.com/dicot-project/dicot-api/pkg/api/identity"
 	"github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+	"github.com/dicot-project/dicot-api/pkg/audit"
 	"github.com/dicot-project/dicot-api/pkg/auth"
 	"github.com/dicot-project/dicot-api/pkg/crypto"
 )
@@ -77,7 +80,7 @@ type TokenRes struct {
 
 type TokenInfo struct {
 	Methods   []string           `json:"methods"`
-	Roles     []RoleInfo         `json:"roles"`
+	Roles     []RoleInfoRef      `json:"roles"`
 	ExpiresAt string             `json:"expires_at"`
 	IssuedAt  string             `json:"issued_at"`
 	Project   ProjectInfoRef     `json:"project"`
@@ -103,7 +106,7 @@ type TokenInfoEndpoint struct {
 	Interface string `json:"interface"`
 }
 
-type RoleInfo struct {
+type RoleInfoRef struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
 }
@@ -136,6 +139,8 @@ func (svc *service) TokensPost(c *gin.Context) {
 		return
 	}
 
+	setAuditTarget(c, audit.TypeURIService, svc.GetUID(), svc.GetName())
+
 	domClnt := svc.Client.Identity().Projects(v1.NamespaceSystem)
 	var userDomain *v1.Project
 	if req.Auth.Identity.Password.User.Domain.Name != "" {
@@ -145,7 +150,7 @@ func (svc *service) TokensPost(c *gin.Context) {
 			return
 		}
 	} else if req.Auth.Identity.Password.User.Domain.ID != "" {
-		userDomain, err = domClnt.GetByUID(req.Auth.Identity.Password.User.Domain.ID)
+		userDomain, err = domClnt.GetByID(req.Auth.Identity.Password.User.Domain.ID)
 		if err != nil {
 			c.AbortWithError(http.StatusUnauthorized, err)
 			return
@@ -162,12 +167,13 @@ func (svc *service) TokensPost(c *gin.Context) {
 	if req.Auth.Identity.Password.User.Name != "" {
 		user, err = userClnt.Get(req.Auth.Identity.Password.User.Name)
 	} else {
-		user, err = userClnt.GetByUID(req.Auth.Identity.Password.User.ID)
+		user, err = userClnt.GetByID(req.Auth.Identity.Password.User.ID)
 	}
 	if err != nil {
 		c.AbortWithError(http.StatusUnauthorized, err)
 		return
 	}
+	setAuditInitiator(c, user)
 
 	secret, err := svc.K8SClient.CoreV1().Secrets(userNamespace).Get(user.Spec.Password.SecretRef, metav1.GetOptions{})
 	if err != nil {
@@ -195,7 +201,7 @@ func (svc *service) TokensPost(c *gin.Context) {
 			return
 		}
 	} else if req.Auth.Scope.Project.Domain.ID != "" {
-		projectDomain, err = domClnt.GetByUID(req.Auth.Scope.Project.Domain.ID)
+		projectDomain, err = domClnt.GetByID(req.Auth.Scope.Project.Domain.ID)
 		if err != nil {
 			c.AbortWithError(http.StatusUnauthorized, err)
 			return
@@ -212,7 +218,7 @@ func (svc *service) TokensPost(c *gin.Context) {
 	if req.Auth.Scope.Project.Name != "" {
 		project, err = projectClnt.Get(req.Auth.Scope.Project.Name)
 	} else {
-		project, err = projectClnt.GetByUID(req.Auth.Scope.Project.ID)
+		project, err = projectClnt.GetByID(req.Auth.Scope.Project.ID)
 	}
 	if err != nil {
 		c.AbortWithError(http.StatusUnauthorized, err)
@@ -221,6 +227,37 @@ func (svc *service) TokensPost(c *gin.Context) {
 
 	// XXX validate user's access to the requested project
 
+	// XXX role assignments are not implemented yet, so everyone
+	// is granted the global admin role and whatever it implies
+	roleClnt := svc.Client.Identity().Roles(v1.NamespaceSystem)
+	globalRoles, err := roleClnt.List()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	assigned := []string{}
+	for _, role := range globalRoles.Items {
+		if role.Spec.Name == "admin" && role.Spec.DomainID == "" {
+			assigned = append(assigned, role.GetID())
+		}
+	}
+	roleRefs := []RoleInfoRef{}
+	roleNames := []string{}
+	for _, role := range identity.ExpandImpliedRoles(globalRoles.Items, assigned) {
+		roleRefs = append(roleRefs, RoleInfoRef{
+			ID:   role.GetID(),
+			Name: role.Spec.Name,
+		})
+		roleNames = append(roleNames, role.Spec.Name)
+	}
+	if len(roleRefs) == 0 {
+		roleRefs = append(roleRefs, RoleInfoRef{
+			ID:   "f56be11a-94a7-11e7-9f6d-e4b318e0afce",
+			Name: "admin",
+		})
+		roleNames = append(roleNames, "admin")
+	}
+
 	token := svc.TokenManager.NewToken()
 	token.Subject = auth.TokenSubject{
 		DomainName: userDomain.ObjectMeta.Name,
@@ -230,6 +267,7 @@ func (svc *service) TokensPost(c *gin.Context) {
 		DomainName:  projectDomain.ObjectMeta.Name,
 		ProjectName: project.ObjectMeta.Name,
 	}
+	token.Roles = roleNames
 
 	tokensig, err := svc.TokenManager.SignToken(token)
 	if err != nil {
@@ -237,6 +275,23 @@ func (svc *service) TokensPost(c *gin.Context) {
 		return
 	}
 
+	region := identity.DefaultRegion
+	baseURL := "http://" + c.Request.Host
+	catalogCfg, err := svc.K8SClient.CoreV1().ConfigMaps(v1.NamespaceSystem).Get(identity.CatalogConfigMap, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+	} else {
+		if val, ok := catalogCfg.Data[identity.CatalogKeyRegion]; ok && val != "" {
+			region = val
+		}
+		if val, ok := catalogCfg.Data[identity.CatalogKeyPublicURL]; ok && val != "" {
+			baseURL = strings.TrimSuffix(val, "/")
+		}
+	}
+
 	catalog := []TokenInfoCatalog{}
 
 	interfaces := []string{
@@ -249,9 +304,9 @@ func (svc *service) TokensPost(c *gin.Context) {
 		for _, iface := range interfaces {
 			endpoints = append(endpoints, TokenInfoEndpoint{
 				ID:        "4e7639cf-f78f-4cd2-aa2a-131196e25974",
-				URL:       "http://" + c.Request.Host + service.GetPrefix() + "/",
-				Region:    "RegionOne",
-				RegionID:  "d3fd5ef9-7eff-422a-8df1-f2bc523d3381",
+				URL:       baseURL + service.GetPrefix() + "/",
+				Region:    region,
+				RegionID:  region,
 				Interface: iface,
 			})
 		}
@@ -266,13 +321,8 @@ func (svc *service) TokensPost(c *gin.Context) {
 
 	res := &TokenRes{
 		Token: TokenInfo{
-			Methods: []string{"password"},
-			Roles: []RoleInfo{
-				RoleInfo{
-					ID:   "f56be11a-94a7-11e7-9f6d-e4b318e0afce",
-					Name: "admin",
-				},
-			},
+			Methods:   []string{"password"},
+			Roles:     roleRefs,
 			IssuedAt:  token.Issued.Format(time.RFC3339),
 			ExpiresAt: token.Expiry.Format(time.RFC3339),
 			IsDomain:  false,
@@ -281,18 +331,18 @@ func (svc *service) TokensPost(c *gin.Context) {
 			},
 			Project: ProjectInfoRef{
 				Domain: DomainInfoRef{
-					ID:   string(projectDomain.ObjectMeta.UID),
-					Name: projectDomain.ObjectMeta.Name,
+					ID:   projectDomain.GetID(),
+					Name: projectDomain.GetName(),
 				},
-				ID:   string(project.ObjectMeta.UID),
-				Name: project.ObjectMeta.Name,
+				ID:   project.GetID(),
+				Name: project.GetName(),
 			},
 			User: UserInfoRef{
 				Domain: DomainInfoRef{
-					ID:   string(userDomain.ObjectMeta.UID),
-					Name: userDomain.ObjectMeta.Name,
+					ID:   userDomain.GetID(),
+					Name: userDomain.GetName(),
 				},
-				ID:                string(user.ObjectMeta.UID),
+				ID:                user.GetID(),
 				Name:              user.Spec.Name,
 				PasswordExpiresAt: time.Now().Add(10 * time.Minute).Format(time.RFC3339),
 			},
@@ -305,3 +355,28 @@ func (svc *service) TokensPost(c *gin.Context) {
 	c.Header("X-Subject-Token", tokensig)
 	c.JSON(http.StatusOK, res)
 }
+
+func (svc *service) TokensDelete(c *gin.Context) {
+	toksig := c.GetHeader("X-Subject-Token")
+	if toksig == "" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	token, err := svc.TokenManager.ValidateToken(toksig)
+	if err != nil {
+		c.AbortWithError(http.StatusNotFound, err)
+		return
+	}
+	setAuditTarget(c, audit.TypeURIToken, token.ID, "")
+
+	// XXX check the caller is the token owner or an admin
+
+	err = svc.TokenManager.RevokeToken(token)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
@@ -29,6 +29,7 @@ import (
 
 	"github.com/dicot-project/dicot-api/pkg/api/identity"
 	"github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+	"github.com/dicot-project/dicot-api/pkg/audit"
 	"github.com/dicot-project/dicot-api/pkg/crypto"
 	"github.com/dicot-project/dicot-api/pkg/rest"
 	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
@@ -94,7 +95,7 @@ func (svc *service) UserList(c *gin.Context) {
 			continue
 		}
 		info := UserInfo{
-			ID:               string(user.ObjectMeta.UID),
+			ID:               user.GetID(),
 			Name:             user.Spec.Name,
 			Enabled:          user.Spec.Enabled,
 			DomainID:         user.Spec.DomainID,
@@ -124,7 +125,7 @@ func (svc *service) UserCreate(c *gin.Context) {
 
 	var domNamespace string
 	if req.User.DomainID != "" {
-		dom, err := domClnt.GetByUID(req.User.DomainID)
+		dom, err := domClnt.GetByID(req.User.DomainID)
 		if err != nil {
 			if errors.IsNotFound(err) {
 				c.AbortWithError(http.StatusBadRequest, err)
@@ -135,7 +136,7 @@ func (svc *service) UserCreate(c *gin.Context) {
 		}
 		domNamespace = dom.Spec.Namespace
 	} else {
-		req.User.DomainID = string(dom.ObjectMeta.UID)
+		req.User.DomainID = dom.GetID()
 		domNamespace = dom.Spec.Namespace
 	}
 
@@ -188,6 +189,7 @@ func (svc *service) UserCreate(c *gin.Context) {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
+	setAuditTarget(c, audit.TypeURIUser, user.GetID(), req.User.Name)
 
 	pwSecret, err = svc.K8SClient.CoreV1().Secrets(domNamespace).Create(pwSecret)
 	if err != nil {
@@ -199,7 +201,7 @@ func (svc *service) UserCreate(c *gin.Context) {
 	// XXX links
 	res := UserShowRes{
 		User: UserInfo{
-			ID:               string(user.ObjectMeta.UID),
+			ID:               user.GetID(),
 			Name:             user.Spec.Name,
 			Enabled:          user.Spec.Enabled,
 			DomainID:         user.Spec.DomainID,
@@ -220,7 +222,7 @@ func (svc *service) UserShow(c *gin.Context) {
 
 	clnt := svc.Client.Identity().Users(k8sv1.NamespaceAll)
 
-	user, err := clnt.GetByUID(userID)
+	user, err := clnt.GetByID(userID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -233,7 +235,7 @@ func (svc *service) UserShow(c *gin.Context) {
 	// XXX links
 	res := UserShowRes{
 		User: UserInfo{
-			ID:               string(user.ObjectMeta.UID),
+			ID:               user.GetID(),
 			Name:             user.Spec.Name,
 			Enabled:          user.Spec.Enabled,
 			DomainID:         user.Spec.DomainID,
@@ -261,7 +263,7 @@ func (svc *service) UserUpdate(c *gin.Context) {
 
 	clnt := svc.Client.Identity().Users(k8sv1.NamespaceAll)
 
-	user, err := clnt.GetByUID(userID)
+	user, err := clnt.GetByID(userID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -340,7 +342,7 @@ func (svc *service) UserUpdate(c *gin.Context) {
 
 	res := UserShowRes{
 		User: UserInfo{
-			ID:               string(user.ObjectMeta.UID),
+			ID:               user.GetID(),
 			Name:             user.Spec.Name,
 			Enabled:          user.Spec.Enabled,
 			DomainID:         user.Spec.DomainID,
@@ -361,7 +363,7 @@ func (svc *service) UserDelete(c *gin.Context) {
 
 	clnt := svc.Client.Identity().Users(k8sv1.NamespaceAll)
 
-	user, err := clnt.GetByUID(userID)
+	user, err := clnt.GetByID(userID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
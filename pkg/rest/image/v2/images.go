@@ -37,7 +37,6 @@ import (
 	"k8s.io/apimachinery/pkg/util/uuid"
 	k8sv1 "k8s.io/client-go/pkg/api/v1"
 
-	identityv1 "github.com/dicot-project/dicot-api/pkg/api/identity/v1"
 	"github.com/dicot-project/dicot-api/pkg/api/image"
 	"github.com/dicot-project/dicot-api/pkg/api/image/v1"
 	"github.com/dicot-project/dicot-api/pkg/rest"
@@ -153,26 +152,6 @@ type ImagePatchChange struct {
 
 type ImagePatchReq []ImagePatchChange
 
-func ImageAccessible(img *v1.Image, proj *identityv1.Project) bool {
-	if img.ObjectMeta.Namespace == proj.Spec.Namespace {
-		return true
-	}
-
-	switch img.Spec.Visibility {
-	case image.IMAGE_VISIBILITY_PUBLIC:
-		return true
-	case image.IMAGE_VISIBILITY_COMMUNITY:
-		return true
-	case image.IMAGE_VISIBILITY_SHARED:
-		// XXX validate sharing rules
-		return false
-	case image.IMAGE_VISIBILITY_PRIVATE:
-		return false
-	}
-
-	panic("Unexpected visibility")
-}
-
 func (svc *service) ImageList(c *gin.Context) {
 	proj := middleware.RequiredTokenScopeProject(c)
 
@@ -188,11 +167,18 @@ func (svc *service) ImageList(c *gin.Context) {
 		Images: []ImageInfo{},
 	}
 
-	for _, img := range imgs.Items {
-		if !ImageAccessible(&img, proj) {
+	for idx := range imgs.Items {
+		img := &imgs.Items[idx]
+		if !image.ImageAccessible(img, proj) {
 			continue
 		}
 
+		img, err = image.SyncImageVolume(svc.Client.Image(), svc.Client.CDI(), img)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
 		info := ImageInfo{
 			ID:              img.Spec.ID,
 			Name:            img.Spec.Name,
@@ -308,7 +294,7 @@ func (svc *service) ImageCreate(c *gin.Context) {
 			Status:          image.IMAGE_STATUS_QUEUED,
 			ContainerFormat: req.ContainerFormat,
 			DiskFormat:      req.DiskFormat,
-			Owner:           string(proj.ObjectMeta.UID),
+			Owner:           proj.GetID(),
 			MinDisk:         req.MinDisk,
 			MinRam:          req.MinRam,
 			Protected:       *req.Protected,
@@ -367,11 +353,17 @@ func (svc *service) ImageShow(c *gin.Context) {
 		return
 	}
 
-	if !ImageAccessible(img, proj) {
+	if !image.ImageAccessible(img, proj) {
 		c.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
 
+	img, err = image.SyncImageVolume(svc.Client.Image(), svc.Client.CDI(), img)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
 	res := ImageInfo{
 		ID:              img.Spec.ID,
 		Name:            img.Spec.Name,
@@ -424,6 +416,15 @@ func (svc *service) ImageDelete(c *gin.Context) {
 		return
 	}
 
+	if img.Spec.Volume != nil {
+		err = svc.Client.CDI().DataVolumes(img.Spec.Volume.Namespace).Delete(
+			img.Spec.Volume.Name, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+	}
+
 	c.String(http.StatusNoContent, "")
 }
 
@@ -777,7 +778,7 @@ func (svc *service) ImageDataDownload(c *gin.Context) {
 
 	clnt := svc.Client.Image().Images(proj.Spec.Namespace)
 
-	_, err := clnt.GetByID(imgID)
+	img, err := clnt.GetByID(imgID)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.AbortWithError(http.StatusNotFound, err)
@@ -787,6 +788,13 @@ func (svc *service) ImageDataDownload(c *gin.Context) {
 		return
 	}
 
+	// XXX the data of images saved from servers is only held
+	// in a volume, which cannot be read from here
+	if img.Spec.Volume != nil {
+		c.String(http.StatusNoContent, "")
+		return
+	}
+
 	name := filepath.Join(svc.ImageRepo, imgID)
 
 	c.Status(http.StatusOK)
@@ -23,6 +23,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/dicot-project/dicot-api/pkg/api"
+	"github.com/dicot-project/dicot-api/pkg/audit"
 	"github.com/dicot-project/dicot-api/pkg/auth"
 	"github.com/dicot-project/dicot-api/pkg/rest"
 	"github.com/dicot-project/dicot-api/pkg/rest/middleware"
@@ -33,10 +34,11 @@ type service struct {
 	Prefix       string
 	ServerID     string
 	TokenManager auth.TokenManager
+	Auditor      audit.Auditor
 	ImageRepo    string
 }
 
-func NewService(client api.Interface, tm auth.TokenManager, imagerepo string, serverID string, prefix string) rest.Service {
+func NewService(client api.Interface, tm auth.TokenManager, auditor audit.Auditor, imagerepo string, serverID string, prefix string) rest.Service {
 	if prefix == "" {
 		prefix = "/image"
 	}
@@ -45,6 +47,7 @@ func NewService(client api.Interface, tm auth.TokenManager, imagerepo string, se
 		Prefix:       prefix,
 		ServerID:     serverID,
 		TokenManager: tm,
+		Auditor:      auditor,
 		ImageRepo:    imagerepo,
 	}
 }
@@ -66,7 +69,7 @@ func (svc *service) GetUID() string {
 }
 
 func (svc *service) RegisterRoutes(router *gin.RouterGroup) {
-	router.Use(middleware.NewTokenHandler(svc.TokenManager, svc.Client.Identity()).Handler())
+	router.Use(middleware.NewTokenHandler(svc.TokenManager, svc.Client.Identity(), svc.Auditor).Handler())
 
 	router.GET("/", svc.IndexShow)
 	router.GET("/versions", svc.VersionIndexShow)
@@ -0,0 +1,311 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package metadata
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sv1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/dicot-project/dicot-api/pkg/api/compute"
+	computev1 "github.com/dicot-project/dicot-api/pkg/api/compute/v1"
+	identityv1 "github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+	kubevirtv1 "github.com/dicot-project/dicot-api/pkg/api/kubevirt/v1"
+	instancemd "github.com/dicot-project/dicot-api/pkg/metadata"
+)
+
+func vmiHasIP(vmi *kubevirtv1.VirtualMachineInstance, ip string) bool {
+	for _, iface := range vmi.Status.Interfaces {
+		if iface.IP == ip {
+			return true
+		}
+		for _, val := range iface.IPs {
+			if val == ip {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getServerKeypair fetches the keypair given when the server was
+// created, which belongs to the user who created it
+func (svc *service) getServerKeypair(userID, keyName string) (*computev1.Keypair, error) {
+	user, err := svc.Client.Identity().Users(k8sv1.NamespaceAll).GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.Client.Compute().Keypairs(user.ObjectMeta.Namespace).Get(
+		compute.KeypairObjectName(userID, keyName))
+}
+
+// findInstance locates the server instance with the given IP
+// address, which may be reported either on the instance itself,
+// or on the virt-launcher pod when the guest shares its address.
+// Returns nil if there is no such instance.
+func (svc *service) findInstance(ip string) (*kubevirtv1.VirtualMachineInstance, error) {
+	// XXX listing every instance on each request won't scale,
+	// a cache populated by a watch is needed
+	vmis, err := svc.Client.Kubevirt().VirtualMachineInstances(k8sv1.NamespaceAll).List()
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range vmis.Items {
+		vmi := &vmis.Items[idx]
+		if _, ok := vmi.ObjectMeta.Labels[compute.LabelServerID]; !ok {
+			continue
+		}
+		if vmiHasIP(vmi, ip) {
+			return vmi, nil
+		}
+	}
+
+	pods, err := svc.K8SClient.CoreV1().Pods(k8sv1.NamespaceAll).List(metav1.ListOptions{
+		LabelSelector: compute.LabelServerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.PodIP != ip {
+			continue
+		}
+		vmi, err := svc.Client.Kubevirt().VirtualMachineInstances(pod.ObjectMeta.Namespace).Get(
+			pod.ObjectMeta.Labels[compute.LabelServerID])
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return vmi, nil
+	}
+
+	return nil, nil
+}
+
+func (svc *service) getInstance(vmi *kubevirtv1.VirtualMachineInstance, ip string) (*instancemd.Instance, error) {
+	namespace := vmi.ObjectMeta.Namespace
+
+	vm, err := svc.Client.Kubevirt().VirtualMachines(namespace).Get(vmi.ObjectMeta.Name)
+	if err != nil {
+		return nil, err
+	}
+	annotations := vm.ObjectMeta.Annotations
+
+	inst := &instancemd.Instance{
+		UUID:             vm.ObjectMeta.Name,
+		Name:             annotations[compute.AnnotationServerName],
+		Hostname:         vmi.Spec.Hostname,
+		ProjectID:        annotations[compute.AnnotationProjectID],
+		AvailabilityZone: annotations[compute.AnnotationAvailabilityZone],
+		InstanceType:     annotations[compute.AnnotationFlavorName],
+		Keys:             []instancemd.Key{},
+	}
+
+	if inst.Hostname == "" {
+		inst.Hostname = vm.ObjectMeta.Name
+	}
+
+	if inst.AvailabilityZone == "" {
+		inst.AvailabilityZone = compute.DefaultAvailabilityZone
+		if vmi.Status.NodeName != "" {
+			node, err := svc.K8SClient.CoreV1().Nodes().Get(vmi.Status.NodeName, metav1.GetOptions{})
+			if err != nil {
+				if !errors.IsNotFound(err) {
+					return nil, err
+				}
+			} else {
+				inst.AvailabilityZone = compute.NodeAvailabilityZone(node)
+			}
+		}
+	}
+
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() != nil {
+		inst.LocalIPv4 = ip
+	}
+	if len(vmi.Status.Interfaces) != 0 {
+		inst.MACAddress = vmi.Status.Interfaces[0].MAC
+	}
+
+	inst.Password = compute.GetServerPassword(vm)
+
+	inst.Meta, err = compute.GetServerMetadata(vm)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyName, ok := annotations[compute.AnnotationKeyName]; ok {
+		keypair, err := svc.getServerKeypair(annotations[compute.AnnotationUserID], keyName)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, err
+			}
+		} else {
+			inst.Keys = append(inst.Keys, instancemd.Key{
+				Name: keyName,
+				Type: keypair.Spec.Type,
+				Data: keypair.Spec.PublicKey,
+			})
+		}
+	}
+
+	secret, err := svc.K8SClient.CoreV1().Secrets(namespace).Get(
+		compute.ServerCloudInitSecretName(vm.ObjectMeta.Name), metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	} else {
+		inst.UserData = secret.Data[compute.ServerCloudInitSecretKey]
+	}
+
+	vendorCfg, err := svc.K8SClient.CoreV1().ConfigMaps(identityv1.NamespaceSystem).Get(
+		compute.VendorDataConfigMap, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	} else {
+		inst.VendorData = []byte(vendorCfg.Data[compute.VendorDataKey])
+	}
+
+	return inst, nil
+}
+
+// requestInstance finds the server instance which made the
+// request. It returns nil if the request has been aborted.
+func (svc *service) requestInstance(c *gin.Context) (*kubevirtv1.VirtualMachineInstance, string) {
+	// The address the connection came from is used, rather
+	// than any forwarding headers, since guests could
+	// otherwise impersonate each other.
+	// XXX this prevents deployment behind an HTTP proxy
+	ip, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return nil, ""
+	}
+
+	vmi, err := svc.findInstance(ip)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return nil, ""
+	}
+	if vmi == nil {
+		glog.V(1).Infof("No instance found with address %s", ip)
+		c.AbortWithError(http.StatusNotFound, fmt.Errorf("No instance with address %s", ip))
+		return nil, ""
+	}
+
+	return vmi, ip
+}
+
+func (svc *service) MetadataShow(c *gin.Context) {
+	vmi, ip := svc.requestInstance(c)
+	if vmi == nil {
+		return
+	}
+
+	inst, err := svc.getInstance(vmi, ip)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	res, err := inst.Serve(c.Param("path"))
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if res == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	c.Data(http.StatusOK, res.ContentType, res.Body)
+}
+
+// MetadataPasswordSet lets the guest store its password, having
+// encrypted it with the public key from the metadata. As with
+// Nova it can only be stored once, until it is cleared through
+// the compute API.
+func (svc *service) MetadataPasswordSet(c *gin.Context) {
+	if !instancemd.IsPasswordPath(c.Param("path")) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	vmi, _ := svc.requestInstance(c)
+	if vmi == nil {
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, compute.SERVER_PASSWORD_MAX_LENGTH+1))
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if len(body) > compute.SERVER_PASSWORD_MAX_LENGTH {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("Password exceeds %d bytes", compute.SERVER_PASSWORD_MAX_LENGTH))
+		return
+	}
+
+	vm, err := svc.Client.Kubevirt().VirtualMachines(vmi.ObjectMeta.Namespace).Get(vmi.ObjectMeta.Name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.AbortWithError(http.StatusNotFound, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if compute.GetServerPassword(vm) != "" {
+		c.AbortWithError(http.StatusConflict, fmt.Errorf("Password already set"))
+		return
+	}
+
+	compute.SetServerPassword(vm, string(body))
+	_, err = svc.Client.Kubevirt().VirtualMachines(vm.ObjectMeta.Namespace).Update(vm)
+	if err != nil {
+		if errors.IsConflict(err) {
+			c.AbortWithError(http.StatusConflict, err)
+		} else {
+			c.AbortWithError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.String(http.StatusOK, "")
+}
@@ -0,0 +1,67 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package metadata
+
+import (
+	"github.com/gin-gonic/gin"
+	k8s "k8s.io/client-go/kubernetes"
+
+	"github.com/dicot-project/dicot-api/pkg/api"
+	"github.com/dicot-project/dicot-api/pkg/rest"
+)
+
+type service struct {
+	Client    api.Interface
+	K8SClient k8s.Interface
+	Prefix    string
+}
+
+// NewService creates the metadata service queried by guests.
+// It is not authenticated, identifying the server from the
+// address the request came from, so must be registered on a
+// separate listener which is only reachable from guests.
+func NewService(client api.Interface, k8sClient k8s.Interface, prefix string) rest.Service {
+	return &service{
+		Client:    client,
+		K8SClient: k8sClient,
+		Prefix:    prefix,
+	}
+}
+
+func (svc *service) GetPrefix() string {
+	return svc.Prefix
+}
+
+func (svc *service) GetName() string {
+	return "dicot-metadata"
+}
+
+func (svc *service) GetType() string {
+	return "metadata"
+}
+
+func (svc *service) GetUID() string {
+	return "6a3e4b52-cf5b-4d49-8a8e-07a3b1f8e0d4"
+}
+
+func (svc *service) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/*path", svc.MetadataShow)
+	router.POST("/*path", svc.MetadataPasswordSet)
+}
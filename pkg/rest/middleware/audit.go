@@ -0,0 +1,52 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/dicot-project/dicot-api/pkg/audit"
+)
+
+// NewAuditEvent creates an event for an action performed by
+// the request, with the user identified by the request token
+// (if any) as the initiator
+func NewAuditEvent(c *gin.Context, action, outcome string, target audit.Resource) *audit.Event {
+	initiator := audit.Resource{
+		TypeURI: audit.TypeURIUser,
+		ID:      "unknown",
+		Host: &audit.Host{
+			Address: c.ClientIP(),
+			Agent:   c.Request.UserAgent(),
+		},
+	}
+	if user := GetTokenSubjectUser(c); user != nil {
+		initiator.ID = user.GetID()
+		initiator.Name = user.Spec.Name
+		initiator.DomainID = user.Spec.DomainID
+	}
+	if project := GetTokenScopeProject(c); project != nil {
+		initiator.ProjectID = project.GetID()
+	}
+
+	ev := audit.NewEvent(action, outcome, initiator, target)
+	ev.RequestID = GetRequestID(c)
+	return ev
+}
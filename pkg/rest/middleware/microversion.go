@@ -89,6 +89,12 @@ func (ver *MicroVersion) InRange(min, max *MicroVersion) bool {
 	return false
 }
 
+// AtLeast reports whether the version is the given version
+// or newer
+func (ver *MicroVersion) AtLeast(major, micro int) bool {
+	return ver.Major > major || (ver.Major == major && ver.Micro >= micro)
+}
+
 type microVersionHandler struct {
 	Service       string
 	ServiceHeader string
@@ -112,7 +118,7 @@ func setMicroVersion(c *gin.Context, ver *MicroVersion) {
 
 func GetMicroVersion(c *gin.Context) *MicroVersion {
 	obj, ok := c.Get("MicroVersion")
-	if ok {
+	if !ok {
 		return nil
 	}
 	ver, ok := obj.(*MicroVersion)
@@ -172,6 +178,7 @@ func (h *microVersionHandler) Handler() gin.HandlerFunc {
 		res := MicroVersionErrorRes{
 			Errors: []MicroVersionErrorInfo{
 				MicroVersionErrorInfo{
+					RequestID:  GetRequestID(c),
 					Code:       h.Service + ".microversion-unsupported",
 					Status:     http.StatusNotAcceptable,
 					Title:      "Requested microversion is unsupported",
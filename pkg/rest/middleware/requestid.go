@@ -0,0 +1,54 @@
+/*
+ * This file is part of the Dicot project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2017 Red Hat, Inc.
+ *
+ */
+
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+const RequestIDHeader = "X-Openstack-Request-Id"
+
+type requestIDHandler struct {
+}
+
+func NewRequestIDHandler() Middleware {
+	return &requestIDHandler{}
+}
+
+func GetRequestID(c *gin.Context) string {
+	obj, ok := c.Get("RequestID")
+	if !ok {
+		return ""
+	}
+	id, ok := obj.(string)
+	if !ok {
+		return ""
+	}
+	return id
+}
+
+func (h *requestIDHandler) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := "req-" + string(uuid.NewUUID())
+		c.Set("RequestID", id)
+		c.Header(RequestIDHeader, id)
+	}
+}
@@ -20,6 +20,7 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -27,29 +28,32 @@ import (
 
 	"github.com/dicot-project/dicot-api/pkg/api/identity"
 	"github.com/dicot-project/dicot-api/pkg/api/identity/v1"
+	"github.com/dicot-project/dicot-api/pkg/audit"
 	"github.com/dicot-project/dicot-api/pkg/auth"
 )
 
 type tokenHandler struct {
 	TokenManager auth.TokenManager
 	Client       identity.Interface
+	Auditor      audit.Auditor
 	AllowAnon    bool
 }
 
-func newTokenHandler(tokenManager auth.TokenManager, client identity.Interface, allowAnon bool) Middleware {
+func newTokenHandler(tokenManager auth.TokenManager, client identity.Interface, auditor audit.Auditor, allowAnon bool) Middleware {
 	return &tokenHandler{
 		TokenManager: tokenManager,
 		Client:       client,
+		Auditor:      auditor,
 		AllowAnon:    allowAnon,
 	}
 }
 
-func NewTokenHandler(tokenManager auth.TokenManager, client identity.Interface) Middleware {
-	return newTokenHandler(tokenManager, client, false)
+func NewTokenHandler(tokenManager auth.TokenManager, client identity.Interface, auditor audit.Auditor) Middleware {
+	return newTokenHandler(tokenManager, client, auditor, false)
 }
 
-func NewTokenHandlerAllowAnon(tokenManager auth.TokenManager, client identity.Interface) Middleware {
-	return newTokenHandler(tokenManager, client, true)
+func NewTokenHandlerAllowAnon(tokenManager auth.TokenManager, client identity.Interface, auditor audit.Auditor) Middleware {
+	return newTokenHandler(tokenManager, client, auditor, true)
 }
 
 func (h *tokenHandler) setToken(c *gin.Context, tok *auth.Token) error {
@@ -81,10 +85,24 @@ func (h *tokenHandler) setToken(c *gin.Context, tok *auth.Token) error {
 	c.Set("TokenSubjectUser", user)
 	c.Set("TokenScopeDomain", domain)
 	c.Set("TokenScopeProject", project)
+	c.Set("TokenRoles", tok.Roles)
+	c.Set("Token", tok)
 
 	return nil
 }
 
+func GetToken(c *gin.Context) *auth.Token {
+	obj, ok := c.Get("Token")
+	if !ok {
+		return nil
+	}
+	tok, ok := obj.(*auth.Token)
+	if !ok {
+		return nil
+	}
+	return tok
+}
+
 func GetTokenSubjectUser(c *gin.Context) *v1.User {
 	obj, ok := c.Get("TokenSubjectUser")
 	if !ok {
@@ -145,6 +163,40 @@ func RequiredTokenScopeProject(c *gin.Context) *v1.Project {
 	return proj
 }
 
+func GetTokenRoles(c *gin.Context) []string {
+	obj, ok := c.Get("TokenRoles")
+	if !ok {
+		return []string{}
+	}
+	roles, ok := obj.([]string)
+	if !ok {
+		return []string{}
+	}
+	return roles
+}
+
+func TokenHasRole(c *gin.Context, name string) bool {
+	for _, role := range GetTokenRoles(c) {
+		if role == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *tokenHandler) auditFailure(c *gin.Context, tok *auth.Token) {
+	target := audit.Resource{
+		TypeURI: audit.TypeURIToken,
+		ID:      "unknown",
+	}
+	if tok != nil {
+		target.ID = tok.ID
+	}
+	ev := NewAuditEvent(c, audit.ActionAuthenticate, audit.OutcomeFailure, target)
+	ev.SetReason(http.StatusUnauthorized)
+	h.Auditor.Emit(ev)
+}
+
 func (h *tokenHandler) Handler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		toksig := c.GetHeader("X-Auth-Token")
@@ -159,12 +211,21 @@ func (h *tokenHandler) Handler() gin.HandlerFunc {
 		token, err := h.TokenManager.ValidateToken(toksig)
 
 		if err != nil {
+			h.auditFailure(c, nil)
 			c.AbortWithError(http.StatusUnauthorized, err)
 			return
 		}
 
+		if token.Console != nil {
+			h.auditFailure(c, token)
+			c.AbortWithError(http.StatusUnauthorized,
+				fmt.Errorf("Console token %s is not valid for API access", token.ID))
+			return
+		}
+
 		err = h.setToken(c, token)
 		if err != nil {
+			h.auditFailure(c, token)
 			c.AbortWithError(http.StatusUnauthorized, err)
 			return
 		}